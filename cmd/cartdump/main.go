@@ -54,7 +54,11 @@ func main() {
 
 	fmt.Println("Reading ROM file: ", filename)
 	// create cartridge
-	cart := cartridge.NewCartridge(obj)
+	cart, err := cartridge.NewCartridge(obj)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	// check if flag --raw is set
 	if internal.IsInStrArray("--raw", os.Args) {
@@ -2,9 +2,11 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/chigopher/pathlib"
@@ -13,8 +15,12 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/camera"
 	"github.com/duysqubix/gobc/internal/cartridge"
+	"github.com/duysqubix/gobc/internal/fourplayer"
+	"github.com/duysqubix/gobc/internal/link"
 	"github.com/duysqubix/gobc/internal/motherboard"
+	"github.com/duysqubix/gobc/internal/printer"
 	"github.com/duysqubix/gobc/internal/windows"
 )
 
@@ -188,6 +194,37 @@ func runAction(ctx *cli.Context) error {
 		force_dmg = true
 	}
 
+	var sgb bool
+	if model := ctx.String("model"); model != "" {
+		if force_cgb || force_dmg {
+			return cli.Exit("error: --model replaces --force-cgb and --force-dmg; pick one", 1)
+		}
+		switch model {
+		case "dmg":
+			force_dmg = true
+		case "cgb":
+			force_cgb = true
+		case "sgb":
+			sgb = true
+		default:
+			return cli.Exit(fmt.Sprintf("error: unknown model %q; pick dmg, cgb or sgb", model), 1)
+		}
+	}
+
+	clock := cartridge.ClockRealTime
+	var clockStart time.Time
+	switch rtc := ctx.String("rtc"); rtc {
+	case "", "realtime":
+	case "emulated":
+		clock = cartridge.ClockEmulated
+	default:
+		start, err := time.Parse(time.RFC3339, rtc)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("error: unknown clock %q; pick realtime, emulated or a time such as 2001-11-21T08:00:00Z", rtc), 1)
+		}
+		clock, clockStart = cartridge.ClockFixed, start
+	}
+
 	if !ctx.Args().Present() {
 		cli.ShowAppHelpAndExit(ctx, 0)
 	}
@@ -206,13 +243,115 @@ func runAction(ctx *cli.Context) error {
 
 	}
 
+	serialFlags := 0
+	for _, name := range []string{"serial-out", "printer", "link-listen", "link-connect", "four-player"} {
+		if ctx.String(name) != "" {
+			serialFlags++
+		}
+	}
+	if serialFlags > 1 {
+		return cli.Exit("error: --serial-out, --printer, --link-listen, --link-connect and --four-player all use the link port; pick one", 1)
+	}
+
+	irFlags := 0
+	for _, name := range []string{"ir-listen", "ir-connect"} {
+		if ctx.String(name) != "" {
+			irFlags++
+		}
+	}
+	if ctx.Bool("ir-noise") {
+		irFlags++
+	}
+	if irFlags > 1 {
+		return cli.Exit("error: --ir-listen, --ir-connect and --ir-noise all use the infrared port; pick one", 1)
+	}
+
 	romfile := ctx.Args().First()
 	audioEnabled := !ctx.Bool("no-audio") && !ctx.Bool("no-gui")
 	audioSmooth := ctx.Bool("audio-smooth")
-	if rate := ctx.Int("audio-rate"); rate > 0 {
-		motherboard.SetAudioSampleRateOverride(rate)
+	audioRate := ctx.Int("audio-rate")
+	fastCPU := ctx.Bool("fast-cpu")
+	var err error
+	g, err = windows.NewGoBoyColor(romfile, breakpoints, force_cgb, force_dmg, sgb, panicOnStuck, randomize, audioEnabled, audioRate, audioSmooth, fastCPU, ctx.String("mapper"), clock, clockStart)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+	}
+
+	if out := ctx.String("serial-out"); out != "" {
+		w := io.Writer(os.Stdout)
+		if out != "-" {
+			f, err := os.Create(out)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+			}
+			defer f.Close()
+			w = f
+		}
+		g.Mb.Serial.Connect(motherboard.NewSerialWriter(w))
+	}
+
+	if dir := ctx.String("printer"); dir != "" {
+		p := printer.New(dir)
+		g.Mb.Serial.Connect(p)
+		defer p.Close()
+	}
+
+	if addr := ctx.String("link-listen"); addr != "" {
+		l, err := link.Listen(addr, g.Mb.Serial)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+		}
+		defer l.Close()
+	}
+	if addr := ctx.String("link-connect"); addr != "" {
+		l, err := link.Dial(addr, g.Mb.Serial)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+		}
+		defer l.Close()
+	}
+
+	if addr := ctx.String("four-player"); addr != "" {
+		a := fourplayer.New()
+		a.Plug(1, fourplayer.LocalPort(g.Mb.Serial))
+		a.ClockFrom(g.Mb.Serial)
+		if err := a.Listen(addr); err != nil {
+			return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+		}
+		defer a.Close()
+	}
+
+	if irFlags > 0 && !g.Mb.Cgb && !g.Mb.Cartridge.HasInfrared() {
+		logger.Warn("the infrared port only exists in CGB mode or on HuC cartridges")
+	}
+	if ctx.Bool("ir-noise") {
+		g.Mb.Infrared.Connect(motherboard.IdleNoise())
+	}
+	if addr := ctx.String("ir-listen"); addr != "" {
+		l, err := link.ListenInfrared(addr, g.Mb.Infrared)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+		}
+		defer l.Close()
+	}
+	if addr := ctx.String("ir-connect"); addr != "" {
+		l, err := link.DialInfrared(addr, g.Mb.Infrared)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+		}
+		defer l.Close()
+	}
+
+	if path := ctx.String("camera"); path != "" {
+		src, err := camera.Open(path)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+		}
+		if _, ok := g.Mb.Cartridge.CartType.(*cartridge.CameraCartridge); !ok {
+			logger.Warn("--camera only does something with a Game Boy Camera cartridge")
+		}
+		g.Mb.Cartridge.Sensor = src
 	}
-	g = windows.NewGoBoyColor(romfile, breakpoints, force_cgb, force_dmg, panicOnStuck, randomize, audioEnabled, audioSmooth)
 
 	if ctx.Bool("debug") {
 		windows.SetDebugInfo(true)
@@ -229,7 +368,9 @@ func runAction(ctx *cli.Context) error {
 	}
 
 	// save SRAM state
-	cartridge.SaveSRAM(g.Mb.Cartridge.GetFilename(), &g.Mb.Cartridge.RamBanks, g.Mb.Cartridge.RamBankCount)
+	if err := g.Mb.Cartridge.Save(); err != nil {
+		return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+	}
 
 	// default save state
 	return cli.Exit("", 0)
@@ -259,7 +400,10 @@ func cartdumpAction(ctx *cli.Context) error {
 	}
 
 	fmt.Println("Reading ROM file:", filename)
-	cart := cartridge.NewCartridge(obj)
+	cart, err := cartridge.NewCartridge(obj)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("error: %v", err), 1)
+	}
 
 	if ctx.Bool("raw") {
 		cart.RawHeaderDump()
@@ -295,6 +439,7 @@ const keybindingsHelp = `KEYBINDINGS:
      A                             B button
      Enter                         Start
      Right Shift                   Select
+     I / J / K / L                 Tilt away / left / towards / right (MBC7 cartridges)
      R                             Reset emulator
      F1                            Toggle Grid overlay
      F2                            Toggle Debug Information (opens debug windows)
@@ -336,7 +481,19 @@ EXAMPLES:
    gobc run roms/cpu_instrs.gb --debug                # run with debug windows
    gobc run roms/cpu_instrs.gb --breakpoints 0x100,0x200
    gobc run roms/pokemon.gb --force-cgb               # force CGB mode on a DMG ROM
-   gobc run roms/blargg.gb --no-gui                   # headless (for test ROMs in CI)
+   gobc run roms/dkland.gb --model sgb                # Super Game Boy colours and border
+   gobc run roms/blargg.gb --no-gui --serial-out -    # headless, test ROM output to stdout
+   gobc run roms/f1race.gb --four-player :5800        # DMG-07 four-player adapter: player 1 hosts...
+   gobc run roms/f1race.gb --link-connect localhost:5800 # ...players 2-4 plug in with a link cable
+   gobc run roms/gold.gbc --ir-listen :5900           # infrared (Mystery Gift): first player waits...
+   gobc run roms/silver.gbc --ir-connect localhost:5900 # ...second player points at it
+   gobc run roms/tcg.gbc --ir-listen :5900            # the HuC1 cartridge IR works the same way (card trades)
+   gobc run roms/zelda.gb --printer prints            # Game Boy Printer, one PNG per print
+   gobc run roms/camera.gb --camera photos            # Game Boy Camera, shown each picture in photos/ in turn
+   gobc run roms/crystal.gbc --rtc emulated          # cartridge clock only moves while the game runs
+   gobc run roms/multicart.gb --mapper rocket         # unlicensed cartridge the header doesn't describe
+   gobc run roms/red.gb --link-listen :5700           # link cable: first player waits...
+   gobc run roms/blue.gb --link-connect localhost:5700 # ...second player connects
    LOG_LEVEL=debug gobc run roms/zelda.gb             # raise log verbosity
 
    gobc cartdump roms/pokemon.gb                      # write cartdump.txt
@@ -362,10 +519,59 @@ func main() {
 			Name:  "force-dmg",
 			Usage: "Force DMG mode on a CGB ROM",
 		},
+		&cli.StringFlag{
+			Name:  "model",
+			Usage: "Emulate `MODEL`: dmg, cgb or sgb (Super Game Boy, with SGB colours and border). Defaults to what the cartridge header asks for",
+		},
 		&cli.BoolFlag{
 			Name:  "no-gui",
 			Usage: "Run without GUI (headless, useful for test ROMs / CI)",
 		},
+		&cli.StringFlag{
+			Name:  "serial-out",
+			Usage: "Write every byte the ROM sends over the link port to `FILE` (\"-\" for stdout), e.g. Blargg test ROM results",
+		},
+		&cli.StringFlag{
+			Name:  "printer",
+			Usage: "Plug a Game Boy Printer into the link port, saving each print as a PNG in `DIR`",
+		},
+		&cli.StringFlag{
+			Name:  "link-listen",
+			Usage: "Wait for another gobc to connect a link cable on `ADDR` (e.g. :5700) before starting",
+		},
+		&cli.StringFlag{
+			Name:  "link-connect",
+			Usage: "Connect a link cable to another gobc listening on `HOST:PORT`",
+		},
+		&cli.StringFlag{
+			Name:  "four-player",
+			Usage: "Plug into a DMG-07 four-player adapter as player 1, taking players 2-4 as other gobc instances connect with --link-connect to `ADDR` (e.g. :5800)",
+		},
+		&cli.StringFlag{
+			Name:  "ir-listen",
+			Usage: "Wait for another gobc to point its infrared port at this one on `ADDR` (e.g. :5900) before starting (CGB or HuC cartridge)",
+		},
+		&cli.StringFlag{
+			Name:  "ir-connect",
+			Usage: "Point the infrared port at another gobc listening on `HOST:PORT` (CGB or HuC cartridge)",
+		},
+		&cli.BoolFlag{
+			Name:  "ir-noise",
+			Usage: "Let the infrared receiver pick up stray light from the room, as with no partner in front of it (CGB or HuC cartridge)",
+		},
+		&cli.StringFlag{
+			Name:  "camera",
+			Usage: "Show the Game Boy Camera's sensor the picture in `PATH`, a PNG or JPEG file, or each one in a directory in turn",
+		},
+		&cli.StringFlag{
+			Name:  "mapper",
+			Usage: "Run the cartridge on the unlicensed mapper `NAME` (" + strings.Join(cartridge.Mappers(), ", ") + ") instead of the one its header names or gobc guesses",
+		},
+		&cli.StringFlag{
+			Name:  "rtc",
+			Usage: "Run the cartridge clock (MBC3, HuC3, TAMA5) in `MODE`: realtime catches up on the time since the save, emulated only counts emulated time, and an RFC 3339 time catches up to that time instead of now",
+			Value: "realtime",
+		},
 		&cli.BoolFlag{
 			Name:  "no-audio",
 			Usage: "Disable audio output",
@@ -379,6 +585,10 @@ func main() {
 			Name:  "audio-smooth",
 			Usage: "Eliminate audio chop on slow CPUs by measuring host throughput at startup and matching the speaker rate to the producer rate. Costs a ~2% pitch drop on a 98%-speed host (about one third of a semitone — usually below the detectable threshold).",
 		},
+		&cli.BoolFlag{
+			Name:  "fast-cpu",
+			Usage: "Run each CPU instruction atomically instead of M-cycle by M-cycle. Faster, but breaks games and test ROMs that depend on mid-instruction memory timing.",
+		},
 		&cli.BoolFlag{
 			Name:  "panic-on-stuck",
 			Usage: "Panic when the CPU is detected as stuck",
@@ -0,0 +1,377 @@
+// Package emulator is the public, embeddable entry point into gobc.
+//
+// It wraps the internal motherboard behind a small, stable API: load a
+// ROM from bytes, advance by frame or by instruction, read the frame
+// buffer, drive the joypad, and move SRAM / save states in and out
+// through plain byte slices and io streams. The package has no GUI or
+// audio dependencies, so it builds and runs headless.
+//
+//	emu, err := emulator.New(rom, &emulator.Options{SkipBootROM: true})
+//	if err != nil { ... }
+//	for emu.RunFrame() {
+//		img := emu.Framebuffer()
+//		...
+//	}
+package emulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/cartridge"
+	"github.com/duysqubix/gobc/internal/motherboard"
+)
+
+// Screen dimensions in pixels.
+const (
+	ScreenWidth  = internal.GB_SCREEN_WIDTH
+	ScreenHeight = internal.GB_SCREEN_HEIGHT
+
+	// A Super Game Boy's picture, border included.
+	SGBScreenWidth  = internal.SGB_SCREEN_WIDTH
+	SGBScreenHeight = internal.SGB_SCREEN_HEIGHT
+)
+
+// CyclesPerFrame is the number of CPU cycles in one 59.73 Hz frame at
+// single speed (154 scanlines × 456 cycles).
+const CyclesPerFrame = 154 * 456
+
+// ErrSRAMSize is returned by SetSRAM when the data is shorter than the
+// cartridge's RAM, or what follows the RAM is not what its battery keeps.
+var ErrSRAMSize = errors.New("emulator: SRAM size mismatch")
+
+// Errors returned by New for ROM images that cannot be loaded.
+var (
+	ErrTruncatedROM   = cartridge.ErrTruncatedROM
+	ErrROMSize        = cartridge.ErrROMSize
+	ErrRAMSize        = cartridge.ErrRAMSize
+	ErrUnsupportedMBC = cartridge.ErrUnsupportedMBC
+	ErrChecksum       = cartridge.ErrChecksum
+)
+
+// Errors returned by LoadState for snapshots that cannot be restored.
+var (
+	ErrStateVersion   = internal.ErrStateVersion
+	ErrTruncatedState = internal.ErrTruncatedState
+)
+
+// ClockMode is how the clock of a battery-backed cartridge, such as the
+// MBC3's, covers the time between a save and the next load.
+type ClockMode = cartridge.ClockMode
+
+const (
+	ClockRealTime = cartridge.ClockRealTime // catch up on the wall-clock time since the save
+	ClockEmulated = cartridge.ClockEmulated // only move with emulated cycles, for runs that repeat exactly
+	ClockFixed    = cartridge.ClockFixed    // catch up to Options.ClockStart
+)
+
+// Button identifies one of the eight Game Boy joypad inputs.
+type Button uint8
+
+const (
+	ButtonA Button = iota
+	ButtonB
+	ButtonSelect
+	ButtonStart
+	ButtonRight
+	ButtonLeft
+	ButtonUp
+	ButtonDown
+)
+
+// buttonKeys maps a Button to its motherboard press/release events.
+var buttonKeys = [...][2]motherboard.Key{
+	ButtonA:      {motherboard.APress, motherboard.ARelease},
+	ButtonB:      {motherboard.BPress, motherboard.BRelease},
+	ButtonSelect: {motherboard.SelectPress, motherboard.SelectRelease},
+	ButtonStart:  {motherboard.StartPress, motherboard.StartRelease},
+	ButtonRight:  {motherboard.RightArrowPress, motherboard.RightArrowRelease},
+	ButtonLeft:   {motherboard.LeftArrowPress, motherboard.LeftArrowRelease},
+	ButtonUp:     {motherboard.UpArrowPress, motherboard.UpArrowRelease},
+	ButtonDown:   {motherboard.DownArrowPress, motherboard.DownArrowRelease},
+}
+
+// Options tweaks how the emulated machine is built. The zero value runs
+// the ROM in the mode its header asks for, starting from the boot ROM.
+type Options struct {
+	Name        string // informational ROM name (e.g. the file base name)
+	ForceCGB    bool   // run a DMG ROM in CGB mode
+	ForceDMG    bool   // run a CGB ROM in DMG mode
+	SGB         bool   // run as a Super Game Boy: DMG mode, with SGB colours and border
+	Randomize   bool   // randomize RAM contents on power-on
+	SkipBootROM bool   // start at $0100 with post-boot register values
+	FastCPU     bool   // run each instruction atomically; faster, but not M-cycle accurate
+	Mapper      string // unlicensed mapper to run the ROM on, see cartridge.Mappers; empty guesses
+
+	Clock      ClockMode // how the cartridge's clock catches up on a save; the zero value in real time
+	ClockStart time.Time // when the machine is started, for ClockFixed
+}
+
+// Emulator is a single Game Boy / Game Boy Color instance. It is not
+// safe for concurrent use; drive each instance from one goroutine.
+type Emulator struct {
+	mb   *motherboard.Motherboard
+	opts Options
+	sgb  *motherboard.SGBFrame // composed SGB picture, allocated on first use
+}
+
+// New builds an emulator around an in-memory ROM image. opts may be nil.
+// Malformed images are reported with one of the ROM errors above.
+func New(rom []byte, opts *Options) (*Emulator, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if rom == nil {
+		rom = []byte{} // a nil Rom makes the motherboard fall back to a file
+	}
+
+	mb, err := motherboard.NewMotherboard(&motherboard.MotherboardParams{
+		Rom:        rom,
+		RomName:    opts.Name,
+		Randomize:  opts.Randomize,
+		ForceCgb:   opts.ForceCGB,
+		ForceDmg:   opts.ForceDMG,
+		Sgb:        opts.SGB,
+		FastCPU:    opts.FastCPU,
+		Mapper:     opts.Mapper,
+		Clock:      opts.Clock,
+		ClockStart: opts.ClockStart,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	emu := &Emulator{mb: mb, opts: *opts}
+	emu.applyBootOptions()
+	return emu, nil
+}
+
+func (e *Emulator) applyBootOptions() {
+	if e.opts.SkipBootROM {
+		e.mb.SkipBootROM()
+	}
+}
+
+// Reset power-cycles the machine. Cartridge RAM is preserved.
+func (e *Emulator) Reset() {
+	e.mb.Reset()
+	e.applyBootOptions()
+}
+
+// CGB reports whether the machine is running in Game Boy Color mode.
+func (e *Emulator) CGB() bool {
+	return e.mb.Cgb
+}
+
+// SGB reports whether the machine is running as a Super Game Boy.
+func (e *Emulator) SGB() bool {
+	return e.mb.Sgb
+}
+
+// Title returns the cartridge title from the ROM header.
+func (e *Emulator) Title() string {
+	return string(bytes.TrimRight([]byte(e.mb.Cartridge.GetTitle()), "\x00"))
+}
+
+// StepInstruction executes one CPU instruction (or one idle M-cycle
+// while halted) and returns the number of clock cycles it took. It
+// returns 0 once the CPU has stopped.
+func (e *Emulator) StepInstruction() int {
+	ok, cycles := e.mb.Tick()
+	if !ok {
+		return 0
+	}
+	return int(cycles)
+}
+
+// RunFrame runs the machine until the PPU finishes the next frame, or
+// for one frame's worth of cycles when the LCD is off. It returns false
+// once the CPU has stopped.
+func (e *Emulator) RunFrame() bool {
+	budget := CyclesPerFrame
+	if e.mb.DoubleSpeed() {
+		budget *= 2
+	}
+
+	start := e.mb.Lcd.FrameCount
+	for ran := 0; ran < budget && e.mb.Lcd.FrameCount == start; {
+		cycles := e.StepInstruction()
+		if cycles == 0 {
+			return false
+		}
+		ran += cycles
+	}
+	return true
+}
+
+// ScreenSize returns the size of the frames Framebuffer returns:
+// ScreenWidth × ScreenHeight, or SGBScreenWidth × SGBScreenHeight on a
+// Super Game Boy, whose frames include the border.
+func (e *Emulator) ScreenSize() (width, height int) {
+	if e.mb.Sgb {
+		return SGBScreenWidth, SGBScreenHeight
+	}
+	return ScreenWidth, ScreenHeight
+}
+
+// Framebuffer returns a copy of the most recently completed frame.
+func (e *Emulator) Framebuffer() *image.RGBA {
+	w, h := e.ScreenSize()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	e.CopyFramebuffer(img)
+	return img
+}
+
+// CopyFramebuffer writes the most recently completed frame into img,
+// whose bounds must start at (0,0) and be at least ScreenSize. Reusing
+// one image avoids an allocation per frame.
+func (e *Emulator) CopyFramebuffer(img *image.RGBA) {
+	if e.mb.Sgb {
+		if e.sgb == nil {
+			e.sgb = new(motherboard.SGBFrame)
+		}
+		e.mb.Super.Compose(e.sgb)
+		copyPixels(img, SGBScreenWidth, SGBScreenHeight, func(x, y int) [3]uint8 { return e.sgb[x][y] })
+		return
+	}
+	data := &e.mb.Lcd.PreparedData
+	copyPixels(img, ScreenWidth, ScreenHeight, func(x, y int) [3]uint8 { return data[x][y] })
+}
+
+// copyPixels copies a width × height picture into img.
+func copyPixels(img *image.RGBA, width, height int, at func(x, y int) [3]uint8) {
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			px := at(x, y)
+			row[x*4+0] = px[0]
+			row[x*4+1] = px[1]
+			row[x*4+2] = px[2]
+			row[x*4+3] = 0xFF
+		}
+	}
+}
+
+// Press holds a joypad button down.
+func (e *Emulator) Press(b Button) {
+	if int(b) < len(buttonKeys) {
+		e.mb.ButtonEvent(buttonKeys[b][0])
+	}
+}
+
+// Release lets a joypad button go.
+func (e *Emulator) Release(b Button) {
+	if int(b) < len(buttonKeys) {
+		e.mb.ButtonEvent(buttonKeys[b][1])
+	}
+}
+
+// PressPlayer holds a button down on the joypad of player (1-4). Players
+// 2-4 are only read on a Super Game Boy, by games that ask for them.
+func (e *Emulator) PressPlayer(player int, b Button) {
+	if int(b) < len(buttonKeys) && player >= 1 && player <= 4 {
+		e.mb.PlayerButtonEvent(player, buttonKeys[b][0])
+	}
+}
+
+// ReleasePlayer lets a button go on the joypad of player (1-4).
+func (e *Emulator) ReleasePlayer(player int, b Button) {
+	if int(b) < len(buttonKeys) && player >= 1 && player <= 4 {
+		e.mb.PlayerButtonEvent(player, buttonKeys[b][1])
+	}
+}
+
+// Tilt tilts the cartridge x g to the right and y g towards the player,
+// and holds it there. Only MBC7 cartridges, which carry an
+// accelerometer, notice; level is 0, 0.
+func (e *Emulator) Tilt(x, y float64) {
+	e.mb.TiltEvent(x, y)
+}
+
+// OnRumble calls f each time a rumble cartridge's motor starts or stops,
+// with whether it is now running, or stops calling anything if f is nil.
+// f runs on the goroutine driving the emulator, in the middle of a frame.
+func (e *Emulator) OnRumble(f func(on bool)) {
+	e.mb.Cartridge.Rumble = f
+}
+
+// SerialDevice is a peripheral plugged into the link port. It is called
+// once per shift clock the Game Boy drives.
+type SerialDevice = motherboard.SerialDevice
+
+// SerialWriter returns a SerialDevice that copies every byte the ROM sends
+// over the link port to w, which is how Blargg's test ROMs report results.
+func SerialWriter(w io.Writer) SerialDevice {
+	return motherboard.NewSerialWriter(w)
+}
+
+// ConnectSerial plugs dev into the link port, or unplugs it if dev is
+// nil. Nothing is plugged in by default.
+func (e *Emulator) ConnectSerial(dev SerialDevice) {
+	e.mb.Serial.Connect(dev)
+}
+
+// Peek returns the byte the CPU would read at addr, including banked
+// cartridge, VRAM and WRAM regions. Cartridge ROM reads leave the mapper
+// as it was, even one that counts them; other reads have no side effects
+// beyond those of the equivalent CPU read.
+func (e *Emulator) Peek(addr uint16) uint8 {
+	return e.mb.Peek(addr)
+}
+
+// SRAM returns a copy of what the cartridge's battery keeps, laid out as
+// gobc writes it to a .sav file: the RAM, then anything else the battery
+// keeps, such as the 48-byte clock footer of an MBC3 with a timer. Carts
+// without RAM return an empty slice.
+func (e *Emulator) SRAM() []byte {
+	cart := e.mb.Cartridge
+	size := cart.SRAMSize()
+	out := make([]byte, 0, size)
+	for i := 0; len(out) < size; i++ {
+		out = append(out, cart.RamBanks[i][:min(size-len(out), int(cartridge.RAM_BANK_SIZE))]...)
+	}
+	return append(out, cart.BatteryExtra()...)
+}
+
+// SetSRAM replaces what the cartridge's battery keeps, typically with the
+// contents of a .sav file. data holds the RAM, then optionally whatever
+// else the battery keeps, as SRAM returns them; a clock saved there
+// catches up as Options.Clock says, as it does when a .sav is loaded.
+func (e *Emulator) SetSRAM(data []byte) error {
+	cart := e.mb.Cartridge
+	size := cart.SRAMSize()
+	if len(data) < size {
+		return fmt.Errorf("%w: got %d bytes, want %d", ErrSRAMSize, len(data), size)
+	}
+	if extra := data[size:]; len(extra) > 0 {
+		if err := cart.SetBatteryExtra(extra); err != nil {
+			return fmt.Errorf("%w: %w", ErrSRAMSize, err)
+		}
+	}
+	for i := 0; i*int(cartridge.RAM_BANK_SIZE) < size; i++ {
+		copy(cart.RamBanks[i][:], data[i*int(cartridge.RAM_BANK_SIZE):size])
+	}
+	return nil
+}
+
+// SaveState writes a snapshot of the whole machine to w.
+func (e *Emulator) SaveState(w io.Writer) error {
+	_, err := w.Write(e.mb.Serialize().Bytes())
+	return err
+}
+
+// LoadState restores a snapshot previously written by SaveState. The
+// snapshot must come from the same ROM. If the snapshot is rejected the
+// machine is left exactly as it was.
+func (e *Emulator) LoadState(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return internal.RestoreState(e.mb, data)
+}
@@ -0,0 +1,351 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	internal.Logger.SetOutput(io.Discard)
+	internal.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+// testROM returns a 32 KiB image with the given cartridge type, RAM size
+// code and program placed at the $0100 entry point, plus a valid header
+// checksum.
+func testROM(cartType, ramSize uint8, program ...byte) []byte {
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], program)
+	copy(rom[0x134:], "EMUTEST")
+	rom[0x147] = cartType
+	rom[0x149] = ramSize
+
+	var checksum uint8
+	for i := 0x134; i <= 0x14C; i++ {
+		checksum -= rom[i] + 1
+	}
+	rom[0x14D] = checksum
+	return rom
+}
+
+// counterProgram increments $C000 forever:
+//
+//	LD HL,$C000 ; loop: INC (HL) ; JR loop
+var counterProgram = []byte{0x21, 0x00, 0xC0, 0x34, 0x18, 0xFD}
+
+func newTestEmulator(t *testing.T, rom []byte) *Emulator {
+	t.Helper()
+	emu, err := New(rom, &Options{SkipBootROM: true})
+	require.NoError(t, err)
+	return emu
+}
+
+func TestNew_InvalidROM(t *testing.T) {
+	emu, err := New(nil, nil)
+	assert.ErrorIs(t, err, ErrTruncatedROM)
+	assert.Nil(t, emu)
+
+	rom := testROM(0x00, 0x00, counterProgram...)
+	rom[0x14D]++
+	_, err = New(rom, nil)
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	_, err = New(testROM(0x21, 0x00, counterProgram...), nil)
+	assert.ErrorIs(t, err, ErrUnsupportedMBC)
+}
+
+func TestNew_Title(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x00, 0x00, counterProgram...))
+	assert.Equal(t, "EMUTEST", emu.Title())
+	assert.False(t, emu.CGB())
+}
+
+func TestStepInstruction(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x00, 0x00, counterProgram...))
+	emu.mb.Memory.Wram[0][0] = 0
+
+	assert.Equal(t, 12, emu.StepInstruction()) // LD HL,d16
+	assert.Equal(t, 12, emu.StepInstruction()) // INC (HL)
+	assert.Equal(t, uint8(1), emu.Peek(0xC000))
+}
+
+func TestRunFrame_ProducesFrames(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x00, 0x00, counterProgram...))
+
+	start := emu.mb.Lcd.FrameCount
+	for i := 0; i < 3; i++ {
+		require.True(t, emu.RunFrame())
+	}
+	assert.Equal(t, start+3, emu.mb.Lcd.FrameCount)
+
+	img := emu.Framebuffer()
+	assert.Equal(t, ScreenWidth, img.Bounds().Dx())
+	assert.Equal(t, ScreenHeight, img.Bounds().Dy())
+	assert.Equal(t, uint8(0xFF), img.Pix[3], "alpha must be opaque")
+}
+
+// sgbPacketProgram sends the packet at $0200 through P1, the way SGB games
+// do, then idles:
+//
+//	LD HL,$0200 ; XOR A ; LDH ($00),A ; LD A,$30 ; LDH ($00),A ; LD B,16
+//	byte: LD A,(HL+) ; LD E,A ; LD C,8
+//	bit:  LD A,$10 ; BIT 0,E ; JR NZ,+2 ; LD A,$20 ; LDH ($00),A ; LD A,$30
+//	      LDH ($00),A ; RR E ; DEC C ; JR NZ,bit ; DEC B ; JR NZ,byte
+//	      LD A,$20 ; LDH ($00),A ; LD A,$30 ; LDH ($00),A
+//	loop: NOP ; JR loop
+var sgbPacketProgram = []byte{
+	0x21, 0x00, 0x02, 0xAF, 0xE0, 0x00, 0x3E, 0x30, 0xE0, 0x00, 0x06, 0x10,
+	0x2A, 0x5F, 0x0E, 0x08,
+	0x3E, 0x10, 0xCB, 0x43, 0x20, 0x02, 0x3E, 0x20, 0xE0, 0x00, 0x3E, 0x30,
+	0xE0, 0x00, 0xCB, 0x1B, 0x0D, 0x20, 0xED, 0x05, 0x20, 0xE6,
+	0x3E, 0x20, 0xE0, 0x00, 0x3E, 0x30, 0xE0, 0x00,
+	0x00, 0x18, 0xFD,
+}
+
+func TestSGB_Border(t *testing.T) {
+	rom := testROM(0x00, 0x00, sgbPacketProgram...)
+	rom[0x146], rom[0x14B] = 0x03, 0x33 // SGB functions on
+	rom[0x14D] -= 0x03 + 0x33
+	copy(rom[0x200:], []byte{0x00<<3 | 1, 0x1F, 0x00}) // PAL01, colour 0 red
+
+	emu, err := New(rom, &Options{SkipBootROM: true, SGB: true})
+	require.NoError(t, err)
+	assert.True(t, emu.SGB())
+	assert.False(t, emu.CGB())
+	for range 2 {
+		require.True(t, emu.RunFrame())
+	}
+
+	w, h := emu.ScreenSize()
+	assert.Equal(t, SGBScreenWidth, w)
+	assert.Equal(t, SGBScreenHeight, h)
+	img := emu.Framebuffer()
+	assert.Equal(t, SGBScreenWidth, img.Bounds().Dx())
+	assert.Equal(t, SGBScreenHeight, img.Bounds().Dy())
+	assert.Equal(t, []uint8{0xFF, 0x00, 0x00, 0xFF}, img.Pix[:4], "an empty border shows colour 0")
+}
+
+func TestButtons(t *testing.T) {
+	// loop: LD A,$10 ; LDH ($00),A ; LDH A,($00) ; LD ($C000),A ; JR loop
+	program := []byte{0x3E, 0x10, 0xE0, 0x00, 0xF0, 0x00, 0xEA, 0x00, 0xC0, 0x18, 0xF5}
+	emu := newTestEmulator(t, testROM(0x00, 0x00, program...))
+
+	emu.Press(ButtonStart)
+	emu.RunFrame()
+	assert.Equal(t, uint8(0x07), emu.Peek(0xC000)&0x0F, "start held: P13 low")
+
+	emu.Release(ButtonStart)
+	emu.RunFrame()
+	assert.Equal(t, uint8(0x0F), emu.Peek(0xC000)&0x0F, "no buttons held")
+}
+
+func TestTilt(t *testing.T) {
+	// LD A,$0A ; LD ($0000),A ; LD A,$40 ; LD ($4000),A
+	// loop: LD A,$55 ; LD ($A000),A ; LD A,$AA ; LD ($A010),A
+	//       LD A,($A020) ; LD ($C000),A ; LD A,($A030) ; LD ($C001),A ; JR loop
+	program := []byte{
+		0x3E, 0x0A, 0xEA, 0x00, 0x00, 0x3E, 0x40, 0xEA, 0x00, 0x40,
+		0x3E, 0x55, 0xEA, 0x00, 0xA0, 0x3E, 0xAA, 0xEA, 0x10, 0xA0,
+		0xFA, 0x20, 0xA0, 0xEA, 0x00, 0xC0, 0xFA, 0x30, 0xA0, 0xEA, 0x01, 0xC0, 0x18, 0xE8,
+	}
+	emu := newTestEmulator(t, testROM(0x22, 0x00, program...)) // MBC7
+
+	emu.RunFrame()
+	assert.Equal(t, uint8(0xD0), emu.Peek(0xC000), "level")
+	assert.Equal(t, uint8(0x81), emu.Peek(0xC001))
+
+	emu.Tilt(1, 0)
+	emu.RunFrame()
+	assert.Equal(t, uint8(0x40), emu.Peek(0xC000), "1 g to the right")
+	assert.Equal(t, uint8(0x82), emu.Peek(0xC001))
+}
+
+func TestOnRumble(t *testing.T) {
+	// LD A,$08 ; LD ($4000),A ; LD A,$00 ; LD ($4000),A
+	// LD A,$08 ; LD ($4000),A ; JR @
+	program := []byte{
+		0x3E, 0x08, 0xEA, 0x00, 0x40, 0x3E, 0x00, 0xEA, 0x00, 0x40,
+		0x3E, 0x08, 0xEA, 0x00, 0x40, 0x18, 0xFE,
+	}
+	emu := newTestEmulator(t, testROM(0x1C, 0x00, program...)) // MBC5+RUMBLE
+
+	var events []bool
+	emu.OnRumble(func(on bool) { events = append(events, on) })
+	emu.RunFrame()
+	assert.Equal(t, []bool{true, false, true}, events)
+}
+
+func TestSRAM_RoundTrip(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x03, 0x02, counterProgram...)) // MBC1+RAM+BATTERY, 8 KiB
+
+	sram := emu.SRAM()
+	require.Len(t, sram, 0x2000)
+
+	for i := range sram {
+		sram[i] = uint8(i)
+	}
+	require.NoError(t, emu.SetSRAM(sram))
+	assert.Equal(t, sram, emu.SRAM())
+
+	assert.ErrorIs(t, emu.SetSRAM(sram[:10]), ErrSRAMSize)
+}
+
+func TestSRAM_NoRAM(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x00, 0x00, counterProgram...))
+	assert.Empty(t, emu.SRAM())
+	assert.NoError(t, emu.SetSRAM(nil))
+}
+
+func TestSRAM_ClockFooter(t *testing.T) {
+	rom := testROM(0x10, 0x02, counterProgram...) // MBC3+TIMER+RAM+BATTERY, 8 KiB
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// RAM, then the footer: 5 live and 5 latched registers as 32-bit
+	// words, and the Unix time of the save
+	sav := make([]byte, 0x2000+48)
+	sav[0] = 0x42
+	footer := sav[0x2000:]
+	for i, v := range []uint32{5, 6, 7, 8, 0, 5, 6, 7, 8, 0} {
+		binary.LittleEndian.PutUint32(footer[4*i:], v)
+	}
+	binary.LittleEndian.PutUint64(footer[40:], uint64(start.Unix()))
+
+	emu, err := New(rom, &Options{SkipBootROM: true, Clock: ClockFixed, ClockStart: start.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, emu.SRAM(), 0x2000+48)
+	require.NoError(t, emu.SetSRAM(sav))
+
+	got := emu.SRAM()
+	require.Len(t, got, 0x2000+48)
+	assert.Equal(t, uint8(0x42), got[0])
+	footer = got[0x2000:]
+	assert.Equal(t, uint32(8), binary.LittleEndian.Uint32(footer[8:]), "an hour passed since the save")
+	assert.Equal(t, uint32(7), binary.LittleEndian.Uint32(footer[28:]), "latched hours wait for a latch")
+	assert.Equal(t, uint64(start.Add(time.Hour).Unix()), binary.LittleEndian.Uint64(footer[40:]))
+
+	// and back into a fresh machine, with no more time passed
+	emu2, err := New(rom, &Options{SkipBootROM: true, Clock: ClockFixed, ClockStart: start.Add(time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, emu2.SetSRAM(got))
+	assert.Equal(t, got, emu2.SRAM())
+
+	assert.ErrorIs(t, emu2.SetSRAM(got[:0x2000+10]), ErrSRAMSize)
+}
+
+func TestSRAM_NoFooter(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x03, 0x02, counterProgram...)) // MBC1+RAM+BATTERY, 8 KiB
+	assert.ErrorIs(t, emu.SetSRAM(make([]byte, 0x2000+48)), ErrSRAMSize)
+}
+
+func TestSaveLoadState(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x00, 0x00, counterProgram...))
+	emu.RunFrame()
+
+	var state bytes.Buffer
+	require.NoError(t, emu.SaveState(&state))
+	saved := emu.Peek(0xC000)
+	pc := emu.mb.Cpu.Registers.PC
+
+	emu.RunFrame()
+	require.NotEqual(t, saved, emu.Peek(0xC000))
+
+	require.NoError(t, emu.LoadState(&state))
+	assert.Equal(t, saved, emu.Peek(0xC000))
+	assert.Equal(t, pc, emu.mb.Cpu.Registers.PC)
+}
+
+func TestLoadState_RejectsBadSnapshots(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x00, 0x00, counterProgram...))
+	emu.RunFrame()
+
+	var state bytes.Buffer
+	require.NoError(t, emu.SaveState(&state))
+	good := state.Bytes()
+	pc := emu.mb.Cpu.Registers.PC
+	counter := emu.Peek(0xC000)
+
+	assert.ErrorIs(t, emu.LoadState(bytes.NewReader(nil)), ErrStateVersion)
+	assert.ErrorIs(t, emu.LoadState(bytes.NewReader([]byte("NOPE\x01\x00"))), ErrStateVersion)
+
+	badVersion := append([]byte(nil), good...)
+	badVersion[4]++
+	assert.ErrorIs(t, emu.LoadState(bytes.NewReader(badVersion)), ErrStateVersion)
+
+	assert.ErrorIs(t, emu.LoadState(bytes.NewReader(good[:len(good)/2])), ErrTruncatedState)
+	assert.Equal(t, pc, emu.mb.Cpu.Registers.PC, "rejected state must not change the machine")
+	assert.Equal(t, counter, emu.Peek(0xC000))
+}
+
+func TestReset_KeepsSkipBootROM(t *testing.T) {
+	emu := newTestEmulator(t, testROM(0x00, 0x00, counterProgram...))
+	emu.RunFrame()
+	emu.Reset()
+	assert.False(t, emu.mb.BootRomEnabled())
+	assert.Equal(t, uint16(0x100), emu.mb.Cpu.Registers.PC)
+}
+
+func TestConcurrentInstances(t *testing.T) {
+	// MBC3+TIMER+RAM+BATTERY so every instance also ticks its own RTC.
+	rom := testROM(0x10, 0x02, counterProgram...)
+
+	const n = 4
+	emus := make([]*Emulator, n)
+	for i := range emus {
+		emus[i] = newTestEmulator(t, rom)
+	}
+	emus[0].mb.ChangePalette()
+
+	var wg sync.WaitGroup
+	for i, emu := range emus {
+		wg.Add(1)
+		go func(frames int) {
+			defer wg.Done()
+			for f := 0; f < frames; f++ {
+				emu.RunFrame()
+			}
+		}(i + 1)
+	}
+	wg.Wait()
+
+	for i, emu := range emus {
+		assert.Equal(t, uint64(i+1), emu.mb.Lcd.FrameCount, "instance %d", i)
+	}
+	assert.NotEqual(t, emus[0].mb.DmgPalette, emus[1].mb.DmgPalette)
+	assert.NotSame(t, emus[0].mb.Cartridge.Rtc, emus[1].mb.Cartridge.Rtc)
+}
+
+// TestMooneyeMBC2 runs the Mooneye MBC2 tests bundled in default_rom. They
+// report a pass by sending the Fibonacci numbers 3 5 8 13 21 34 over the
+// link port, and a failure as six $42s.
+func TestMooneyeMBC2(t *testing.T) {
+	roms, err := filepath.Glob("../default_rom/mooneye_test_suite/emulator-only/mbc2/*.gb")
+	require.NoError(t, err)
+	require.Len(t, roms, 7)
+
+	for _, path := range roms {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			rom, err := os.ReadFile(path)
+			require.NoError(t, err)
+			emu := newTestEmulator(t, rom)
+			var out bytes.Buffer
+			emu.ConnectSerial(SerialWriter(&out))
+
+			for frame := 0; frame < 600 && out.Len() < 6; frame++ {
+				emu.RunFrame()
+			}
+			assert.Equal(t, []byte{3, 5, 8, 13, 21, 34}, out.Bytes())
+		})
+	}
+}
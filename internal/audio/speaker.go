@@ -0,0 +1,113 @@
+// Package audio — speaker.go
+//
+// beep wiring for the APU. The motherboard package only knows about the
+// motherboard.AudioOutput interface so it (and the public emulator
+// package) builds without beep/oto; the GUI front-end hands a Speaker
+// to NewMotherboard to get real sound.
+//
+// References:
+//   - github.com/gopxl/beep/v2 — Streamer interface, speaker.Init/Play
+
+package audio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/motherboard"
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// bufferDuration is the speaker.Init period divisor: time.Second/5 = ~200 ms latency.
+const bufferDuration = 5
+
+var errNoAudioDevice = errors.New("audio: no audio device detected")
+
+// Process-wide flag because beep's speaker is a global singleton.
+var speakerInitialized bool
+
+// Speaker plays APU samples through the host audio device via beep.
+type Speaker struct{}
+
+// NewSpeaker returns an AudioOutput backed by beep's speaker.
+func NewSpeaker() *Speaker {
+	return &Speaker{}
+}
+
+var _ motherboard.AudioOutput = (*Speaker)(nil)
+
+// Play brings the beep speaker up and connects the APU ring buffer to
+// it. Idempotent across emulator resets (beep's speaker is a
+// process-global singleton that rejects double-Init).
+//
+// Failure modes are reported as errors; the APU degrades to silent
+// operation when Play fails:
+//
+//   - No audio device present (audioAvailable() == false) → skip the
+//     speaker.Init call entirely. This is the common case on headless
+//     servers and on WSL without libasound2-plugins installed.
+//   - speaker.Init returns an error.
+//
+// libasound itself writes config-parse diagnostics directly to fd 2
+// before returning the error code. We wrap speaker.Init in
+// silenceStderr() so end users don't see the ALSA spew when their
+// system has a partial audio config.
+func (s *Speaker) Play(sampleRate int, src motherboard.SampleStream) error {
+	if !audioAvailable() {
+		internal.Logger.Info("APU: no audio device detected; running silent. " +
+			"On WSL install `libasound2-plugins` and add an `~/.asoundrc` pulse PCM; " +
+			"on bare Linux install pulseaudio or pipewire-pulse.")
+		return errNoAudioDevice
+	}
+
+	sr := beep.SampleRate(sampleRate)
+	if !speakerInitialized {
+		restore := silenceStderr()
+		err := speaker.Init(sr, sr.N(time.Second/bufferDuration))
+		restore()
+		if err != nil {
+			return err
+		}
+		speakerInitialized = true
+	}
+	speaker.Clear()
+	speaker.Play(src)
+	return nil
+}
+
+// Stop silences the speaker. Idempotent.
+func (s *Speaker) Stop() {
+	if speakerInitialized {
+		speaker.Clear()
+	}
+}
+
+// audioAvailable reports whether the host has SOME plausible audio
+// sink we can target. Returns true when any of:
+//
+//   - /dev/snd exists and contains entries (real ALSA cards)
+//   - $PULSE_SERVER points at a PulseAudio socket (covers WSLg via
+//     /mnt/wslg/PulseServer)
+//   - $XDG_RUNTIME_DIR/pulse/native exists (standard Linux PulseAudio)
+//
+// Returning true does NOT guarantee audio will work — libasound may
+// still fail to open the PCM — but returning false reliably avoids
+// the ALSA error spam on hosts with no audio path at all.
+func audioAvailable() bool {
+	if entries, err := os.ReadDir("/dev/snd"); err == nil && len(entries) > 0 {
+		return true
+	}
+	if os.Getenv("PULSE_SERVER") != "" {
+		return true
+	}
+	if r := os.Getenv("XDG_RUNTIME_DIR"); r != "" {
+		if _, err := os.Stat(filepath.Join(r, "pulse", "native")); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -1,4 +1,4 @@
-// Package motherboard — apu_stderr_other.go
+// Package audio — stderr_other.go
 //
 // No-op silenceStderr for non-Unix platforms (Windows). Audio drivers
 // on those platforms don't dump diagnostic spew to stderr the way
@@ -6,7 +6,7 @@
 
 //go:build !unix
 
-package motherboard
+package audio
 
 func silenceStderr() (restore func()) {
 	return func() {}
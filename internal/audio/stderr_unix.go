@@ -1,4 +1,4 @@
-// Package motherboard — apu_stderr_unix.go
+// Package audio — stderr_unix.go
 //
 // libasound writes its config-parse diagnostics directly to fd 2 via
 // its built-in error handler (see `snd_lib_error_set_handler`). oto
@@ -13,7 +13,7 @@
 
 //go:build unix
 
-package motherboard
+package audio
 
 import (
 	"os"
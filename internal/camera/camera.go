@@ -0,0 +1,128 @@
+// Package camera — camera.go
+//
+// Pictures for the Game Boy Camera's sensor, taken from image files
+// rather than a webcam. Each file is scaled to cover the sensor's
+// 128x112 pixels, cropped to the middle and turned grey once, when it is
+// opened; a directory of them plays as a sequence, one file per capture,
+// starting over after the last.
+
+package camera
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/duysqubix/gobc/internal/cartridge"
+)
+
+// Files is a cartridge.Sensor that shows the camera a list of pictures
+// in turn.
+type Files struct {
+	frames []*image.Gray
+	next   int
+}
+
+// Open loads the picture at path, or every PNG and JPEG in it when it is
+// a directory, in name order.
+func Open(path string) (*Files, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("camera: %w", err)
+	}
+	paths := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("camera: %w", err)
+		}
+		paths = paths[:0]
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".png", ".jpg", ".jpeg":
+				paths = append(paths, filepath.Join(path, e.Name()))
+			}
+		}
+		slices.Sort(paths)
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("camera: no PNG or JPEG files in %s", path)
+		}
+	}
+
+	f := &Files{}
+	for _, p := range paths {
+		img, err := load(p)
+		if err != nil {
+			return nil, err
+		}
+		f.frames = append(f.frames, Frame(img))
+	}
+	return f, nil
+}
+
+func load(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("camera: %w", err)
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if errors.Is(err, image.ErrFormat) {
+		return nil, fmt.Errorf("camera: %s is not a PNG or JPEG", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("camera: decoding %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Capture returns the next picture.
+func (f *Files) Capture() *image.Gray {
+	frame := f.frames[f.next]
+	f.next = (f.next + 1) % len(f.frames)
+	return frame
+}
+
+// Frame turns img into what the sensor sees: scaled so it covers the
+// sensor, the middle of it, in grey. Each pixel averages the part of img
+// it covers.
+func Frame(img image.Image) *image.Gray {
+	const w, h = cartridge.CameraWidth, cartridge.CameraHeight
+	out := image.NewGray(image.Rect(0, 0, w, h))
+	b := img.Bounds()
+	if b.Empty() {
+		return out
+	}
+
+	// the largest w:h window that fits, in the middle
+	cw, ch := b.Dx(), b.Dx()*h/w
+	if ch > b.Dy() {
+		cw, ch = b.Dy()*w/h, b.Dy()
+	}
+	x0, y0 := b.Min.X+(b.Dx()-cw)/2, b.Min.Y+(b.Dy()-ch)/2
+
+	for y := range h {
+		sy0, sy1 := y0+y*ch/h, y0+(y+1)*ch/h
+		sy1 = max(sy1, sy0+1)
+		for x := range w {
+			sx0, sx1 := x0+x*cw/w, x0+(x+1)*cw/w
+			sx1 = max(sx1, sx0+1)
+			var sum, n int
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					sum += int(color.GrayModel.Convert(img.At(sx, sy)).(color.Gray).Y)
+					n++
+				}
+			}
+			out.SetGray(x, y, color.Gray{Y: uint8(sum / n)})
+		}
+	}
+	return out
+}
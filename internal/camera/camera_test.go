@@ -0,0 +1,299 @@
+package camera
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/cartridge"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "regenerate the input pictures and golden tiles in testdata")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	internal.Logger.SetOutput(io.Discard)
+	internal.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+func writeImage(t *testing.T, path string, img image.Image) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	if filepath.Ext(path) == ".jpg" {
+		require.NoError(t, jpeg.Encode(f, img, &jpeg.Options{Quality: 90}))
+		return
+	}
+	require.NoError(t, png.Encode(f, img))
+}
+
+// gradient runs black to white left to right, with a white disc in the
+// middle for the edges to catch.
+func gradient() image.Image {
+	img := image.NewGray(image.Rect(0, 0, 256, 224))
+	for y := range 224 {
+		for x := range 256 {
+			v := uint8(x)
+			if math.Hypot(float64(x-128), float64(y-112)) < 48 {
+				v = 0xFF
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+// photo is a 4:3 colour picture, wider than the sensor, of stripes and
+// a sky.
+func photo() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for y := range 480 {
+		for x := range 640 {
+			c := color.RGBA{0x40, 0x90, 0xE0, 0xFF}
+			if y > 240 {
+				c = color.RGBA{0x30, 0xA0, 0x30, 0xFF}
+				if (x/40)%2 == 0 {
+					c = color.RGBA{0xD0, 0xC0, 0x40, 0xFF}
+				}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func checks(size int, invert bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, cartridge.CameraWidth, cartridge.CameraHeight))
+	for y := range cartridge.CameraHeight {
+		for x := range cartridge.CameraWidth {
+			if (x/size+y/size)%2 == 0 != invert {
+				img.SetGray(x, y, color.Gray{Y: 0xFF})
+			}
+		}
+	}
+	return img
+}
+
+// bayer is a dither matrix the way the camera's own ROM lays one out:
+// three thresholds per position, spread over the 4x4 ordered pattern.
+func bayer() [48]uint8 {
+	order := [16]uint8{0, 8, 2, 10, 12, 4, 14, 6, 3, 11, 1, 9, 15, 7, 13, 5}
+	var m [48]uint8
+	for i, o := range order {
+		m[i*3] = 0x30 + o*2
+		m[i*3+1] = 0x70 + o*2
+		m[i*3+2] = 0xB0 + o*2
+	}
+	return m
+}
+
+func newCamera(t *testing.T, sensor cartridge.Sensor) *cartridge.Cartridge {
+	t.Helper()
+	rom := make([]byte, 4*int(cartridge.MEMORY_BANK_SIZE))
+	copy(rom[cartridge.TITLE_START_ADDR:], "GAMEBOYCAMERA")
+	rom[cartridge.CARTRIDGE_TYPE_ADDR] = 0xFC
+	rom[cartridge.ROM_SIZE_ADDR] = 0x01
+	rom[cartridge.SRAM_SIZE_ADDR] = 0x04
+	var sum uint8
+	for _, b := range rom[cartridge.TITLE_START_ADDR:cartridge.HEADER_CHECKSUM_ADDR] {
+		sum -= b + 1
+	}
+	rom[cartridge.HEADER_CHECKSUM_ADDR] = sum
+	t.Chdir(t.TempDir())
+
+	cart, err := cartridge.NewCartridgeFromBytes("camera", rom)
+	require.NoError(t, err)
+	cart.Sensor = sensor
+	return cart
+}
+
+// shoot takes a picture with the registers from $A001 on set to regs and
+// the dither matrix to bayer(), and returns the tiles it leaves in RAM.
+func shoot(t *testing.T, cart *cartridge.Cartridge, regs []uint8) []byte {
+	t.Helper()
+	c := cart.CartType
+	c.SetItem(0x4000, 0x10)
+	for i, v := range regs {
+		c.SetItem(0xA001+uint16(i), v)
+	}
+	for i, v := range bayer() {
+		c.SetItem(0xA006+uint16(i), v)
+	}
+	c.SetItem(0xA000, 0x03)
+	for c.GetItem(0xA000)&0x01 != 0 {
+		cycles, ok := cart.NextEvent()
+		require.True(t, ok)
+		cart.Tick(cycles)
+	}
+	c.SetItem(0x4000, 0x00)
+	tiles := make([]byte, 16*14*16)
+	for i := range tiles {
+		tiles[i] = c.GetItem(0xA100 + uint16(i))
+	}
+	return tiles
+}
+
+func golden(t *testing.T, testdata, name string, tiles []byte) {
+	t.Helper()
+	path := filepath.Join(testdata, name+".2bpp")
+	if *update {
+		require.NoError(t, os.WriteFile(path, tiles, 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, tiles, path)
+}
+
+func TestCamera_GoldenTiles(t *testing.T) {
+	// absolute, as newCamera moves to a scratch directory for the save
+	testdata, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Join(testdata, "sequence"), 0o755))
+		writeImage(t, filepath.Join(testdata, "gradient.png"), gradient())
+		writeImage(t, filepath.Join(testdata, "photo.jpg"), photo())
+		writeImage(t, filepath.Join(testdata, "sequence", "1.png"), checks(8, false))
+		writeImage(t, filepath.Join(testdata, "sequence", "2.png"), checks(16, true))
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		regs  []uint8
+	}{
+		// 2D edges at 100%, as the camera's ROM takes pictures
+		{"gradient", "gradient.png", []uint8{0xE0, 0x10, 0x00, 0x20}},
+		// no edges, 6 dB of gain over half the light, inverted
+		{"photo", "photo.jpg", []uint8{0x04, 0x08, 0x00, 0x08}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := Open(filepath.Join(testdata, tc.input))
+			require.NoError(t, err)
+			cart := newCamera(t, src)
+			golden(t, testdata, tc.name, shoot(t, cart, tc.regs))
+		})
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		src, err := Open(filepath.Join(testdata, "sequence"))
+		require.NoError(t, err)
+		cart := newCamera(t, src)
+		regs := []uint8{0x00, 0x10, 0x00}
+		first, second, third := shoot(t, cart, regs), shoot(t, cart, regs), shoot(t, cart, regs)
+		assert.Equal(t, first, third, "the sequence starts over")
+		golden(t, testdata, "sequence-1", first)
+		golden(t, testdata, "sequence-2", second)
+	})
+}
+
+// TestCamera_HandDerivedTile checks tiles worked out on paper rather than
+// by the code under test. A flat mid grey, 128, with no gain and no edge
+// enhancement reaches the matrix as 128 at the unit exposure $1000 and 64
+// at half of it. bayer() puts the thresholds 48+2o, 112+2o and 176+2o at
+// each pixel of the 4x4 pattern, o being its place in the order
+//
+//	 0  8  2 10
+//	12  4 14  6
+//	 3 11  1  9
+//	15  7 13  5
+//
+// so 128 falls below the second threshold, dark grey, where o > 8 and
+// below only the third, light grey, elsewhere; 64 falls below the first,
+// black, where o > 8 and below the second, dark grey, elsewhere. Each row
+// of 8 pixels repeats its row of the pattern twice, and each 4 rows of a
+// tile repeat too.
+func TestCamera_HandDerivedTile(t *testing.T) {
+	flat := image.NewGray(image.Rect(0, 0, cartridge.CameraWidth, cartridge.CameraHeight))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0x80
+	}
+
+	for _, tc := range []struct {
+		name string
+		regs []uint8
+		tile []byte // four rows, low plane then high plane, twice
+	}{
+		// light grey (1) and dark grey (2):
+		//	1 1 1 2  ->  $EE $11
+		//	2 1 2 1  ->  $55 $AA
+		//	1 2 1 2  ->  $AA $55
+		//	2 1 2 1  ->  $55 $AA
+		{"unit exposure", []uint8{0x00, 0x10, 0x00, 0x00}, []byte{
+			0xEE, 0x11, 0x55, 0xAA, 0xAA, 0x55, 0x55, 0xAA,
+		}},
+		// dark grey (2) and black (3):
+		//	2 2 2 3  ->  $11 $FF
+		//	3 2 3 2  ->  $AA $FF
+		//	2 3 2 3  ->  $55 $FF
+		//	3 2 3 2  ->  $AA $FF
+		{"half exposure", []uint8{0x00, 0x08, 0x00, 0x00}, []byte{
+			0x11, 0xFF, 0xAA, 0xFF, 0x55, 0xFF, 0xAA, 0xFF,
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cart := newCamera(t, &Files{frames: []*image.Gray{flat}})
+			tiles := shoot(t, cart, tc.regs)
+			want := append(slices.Clone(tc.tile), tc.tile...)
+			for i := 0; i < len(tiles); i += 16 {
+				require.Equal(t, want, tiles[i:i+16], "tile %d", i/16)
+			}
+		})
+	}
+}
+
+func TestFrame_CoversTheSensor(t *testing.T) {
+	// white in the middle 256 columns of 512, black either side
+	img := image.NewGray(image.Rect(10, 20, 522, 132))
+	for y := 20; y < 132; y++ {
+		for x := 138; x < 394; x++ {
+			img.SetGray(x, y, color.Gray{Y: 0xFF})
+		}
+	}
+	frame := Frame(img)
+	assert.Equal(t, image.Rect(0, 0, cartridge.CameraWidth, cartridge.CameraHeight), frame.Bounds())
+	for _, v := range frame.Pix {
+		require.Equal(t, uint8(0xFF), v, "cropped to the middle")
+	}
+
+	// one-pixel checks average out to grey when halved
+	frame = Frame(checks(1, false))
+	assert.Equal(t, uint8(0xFF), frame.GrayAt(0, 0).Y, "same size, no averaging")
+	img = image.NewGray(image.Rect(0, 0, 2*cartridge.CameraWidth, 2*cartridge.CameraHeight))
+	for i := range img.Pix {
+		if (i%img.Stride+i/img.Stride)%2 == 0 {
+			img.Pix[i] = 0xFF
+		}
+	}
+	for _, v := range Frame(img).Pix {
+		require.Equal(t, uint8(0x7F), v)
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Open(filepath.Join(dir, "missing.png"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = Open(dir)
+	assert.ErrorContains(t, err, "no PNG or JPEG files")
+
+	bad := filepath.Join(dir, "bad.png")
+	require.NoError(t, os.WriteFile(bad, []byte("not a picture"), 0o644))
+	_, err = Open(bad)
+	assert.ErrorContains(t, err, "not a PNG or JPEG")
+}
@@ -0,0 +1,272 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math"
+)
+
+// Sensor is the Pocket Camera's image sensor. Capture returns the next
+// picture, CameraWidth x CameraHeight with 255 the brightest.
+type Sensor interface {
+	Capture() *image.Gray
+}
+
+const (
+	CameraWidth  = 128
+	CameraHeight = 112
+)
+
+// CameraCartridge is the Pocket Camera's mapper (MAC-GBD) with its
+// M64282FP sensor. ROM banks as on MBC1 but without the bank 0 quirk, and
+// 128 KiB of RAM in 16 banks. RAM bank $10 maps the camera registers at
+// A000-A07F (mirrored through BFFF) instead:
+//
+//	$A000  bit 0 starts a capture and reads 1 until it is done,
+//	       bits 2-1 are kept for the sensor
+//	$A001  bit 7 N, bits 6-5 VH edge mode (1 vertical, 2 horizontal,
+//	       3 both), bits 4-0 gain in 1.5 dB steps
+//	$A002  exposure time, high byte, in 16 us steps
+//	$A003  exposure time, low byte
+//	$A004  bits 6-4 edge enhancement ratio, bit 3 invert,
+//	       bits 2-0 output reference voltage
+//	$A005  bits 7-6 zero point calibration, bits 5-0 output offset
+//	$A006-$A035  4x4 dither matrix, three thresholds per pixel
+//
+// All but $A000 read back as $00. A finished capture lands in RAM bank 0
+// from $A100 as 16x14 tiles. Neither the output reference, offset nor
+// zero point calibration is modelled.
+type CameraCartridge struct {
+	parent        *Cartridge
+	romBankSelect uint16
+	ramBankSelect uint8 // $10 for the registers
+	regs          [cameraRegCount]uint8
+	busy          uint64 // cycles left of the capture running
+	hasBattery    bool
+}
+
+const (
+	cameraRegCount     = 0x36
+	cameraRegBank      = 0x10
+	cameraMatrix       = 0x06
+	cameraTileData     = 0x0100
+	cameraGrey         = 0x80   // what the sensor sees with nothing plugged in
+	cameraUnitExposure = 0x1000 // exposure that passes the light as it is
+	cameraGainStep     = 1.5    // dB per gain step
+	cameraBaseSteps    = 32446
+)
+
+// cameraEdgeRatio is how strongly the edge modes push a pixel away from
+// its neighbours, by ratio register value.
+var cameraEdgeRatio = [8]float64{0.5, 0.75, 1, 1.25, 2, 3, 4, 5}
+
+func (c *CameraCartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romBankSelect) // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.ramBankSelect) // RAM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.regs)          // Camera registers
+	binary.Write(buf, binary.LittleEndian, c.busy)          // Capture cycles left
+	binary.Write(buf, binary.LittleEndian, c.hasBattery)    // Has Battery
+	logger.Debug("Serialized Pocket Camera state")
+	return buf
+}
+
+func (c *CameraCartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&c.romBankSelect, &c.ramBankSelect, &c.regs, &c.busy, &c.hasBattery} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CameraCartridge) Init() error {
+	// the camera always carries 128 KiB, whatever the header says
+	c.parent.RamBankCount = 16
+	if c.hasBattery {
+		return LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
+	}
+	return nil
+}
+
+func (c *CameraCartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x2000:
+		c.parent.RamBankEnabled = (value & 0x0f) == 0x0a
+
+	case 0x2000 <= addr && addr < 0x4000:
+		c.romBankSelect = uint16(value & 0x3f)
+
+	case 0x4000 <= addr && addr < 0x6000:
+		c.ramBankSelect = value & 0x1f
+
+	case 0x6000 <= addr && addr < 0x8000:
+		// no banking mode on the camera
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if c.ramBankSelect&cameraRegBank != 0 {
+			// the registers don't need RAM enabled
+			c.setReg(uint8(addr&0x7f), value)
+			return
+		}
+		if !c.parent.RamBankEnabled {
+			return
+		}
+		c.parent.RamBankSelected = uint16(c.ramBankSelect) % c.parent.RamBankCount
+		c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000] = value
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *CameraCartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[0][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		c.parent.RomBankSelected = c.romBankSelect % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if c.ramBankSelect&cameraRegBank != 0 {
+			if addr&0x7f == 0 {
+				return c.regs[0] & 0x07
+			}
+			return 0x00
+		}
+		if c.busy > 0 {
+			// the sensor has the RAM while it captures
+			return 0x00
+		}
+		// RAM reads don't need RAM enabled, only writes do
+		c.parent.RamBankSelected = uint16(c.ramBankSelect) % c.parent.RamBankCount
+		return c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000]
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
+
+func (c *CameraCartridge) setReg(reg uint8, value uint8) {
+	if reg >= cameraRegCount {
+		return
+	}
+	if reg != 0 {
+		c.regs[reg] = value
+		return
+	}
+	c.regs[0] = c.regs[0]&0x01 | value&0x06
+	if value&0x01 != 0 && c.busy == 0 {
+		c.regs[0] |= 0x01
+		c.busy = c.captureCycles()
+	}
+}
+
+// captureCycles returns how long a capture with the current registers
+// takes: a fixed readout, a little more without N, and the exposure.
+func (c *CameraCartridge) captureCycles() uint64 {
+	steps := uint64(cameraBaseSteps) + 16*uint64(c.exposure())
+	if c.regs[1]&0x80 == 0 {
+		steps += 512
+	}
+	return 4 * steps
+}
+
+func (c *CameraCartridge) exposure() uint16 {
+	return uint16(c.regs[2])<<8 | uint16(c.regs[3])
+}
+
+// Tick runs the capture in progress, if any.
+func (c *CameraCartridge) Tick(cycles uint64) {
+	if c.busy == 0 {
+		return
+	}
+	if cycles < c.busy {
+		c.busy -= cycles
+		return
+	}
+	c.busy = 0
+	c.regs[0] &^= 0x01
+	c.capture()
+}
+
+// NextEvent returns the cycles left until the capture in progress is done.
+func (c *CameraCartridge) NextEvent() (cycles uint64, ok bool) {
+	return c.busy, c.busy > 0
+}
+
+// capture takes a picture and writes it to RAM as tiles. The sensor's
+// light is scaled by the exposure and gain, its edges enhanced the way
+// N/VH ask, and each pixel compared against its three thresholds in the
+// dither matrix: below the first it is black, below the second dark
+// grey, below the third light grey, and white otherwise.
+func (c *CameraCartridge) capture() {
+	var light [CameraHeight][CameraWidth]float64
+	var frame *image.Gray
+	if c.parent.Sensor != nil {
+		frame = c.parent.Sensor.Capture()
+	}
+	level := float64(c.exposure()) / cameraUnitExposure * c.gain()
+	for y := range CameraHeight {
+		for x := range CameraWidth {
+			v := float64(cameraGrey)
+			if frame != nil {
+				b := frame.Bounds()
+				v = float64(frame.GrayAt(b.Min.X+x, b.Min.Y+y).Y)
+			}
+			light[y][x] = v * level
+		}
+	}
+
+	ratio := cameraEdgeRatio[c.regs[4]>>4&0x07]
+	vertical, horizontal := c.regs[1]&0x20 != 0, c.regs[1]&0x40 != 0
+	invert := c.regs[4]&0x08 != 0
+	tiles := c.parent.RamBanks[0][cameraTileData:]
+	for y := range CameraHeight {
+		for x := range CameraWidth {
+			v := light[y][x]
+			if vertical {
+				v += ratio * (2*light[y][x] - light[max(y-1, 0)][x] - light[min(y+1, CameraHeight-1)][x])
+			}
+			if horizontal {
+				v += ratio * (2*light[y][x] - light[y][max(x-1, 0)] - light[y][min(x+1, CameraWidth-1)])
+			}
+			v = min(max(v, 0), 255)
+			if invert {
+				v = 255 - v
+			}
+
+			m := c.regs[cameraMatrix+((y&3)*4+(x&3))*3:]
+			colour := uint8(0)
+			switch {
+			case v < float64(m[0]):
+				colour = 3
+			case v < float64(m[1]):
+				colour = 2
+			case v < float64(m[2]):
+				colour = 1
+			}
+
+			i := ((y/8)*(CameraWidth/8)+x/8)*16 + (y%8)*2
+			bit := uint8(0x80) >> (x % 8)
+			tiles[i] &^= bit
+			tiles[i+1] &^= bit
+			if colour&0x01 != 0 {
+				tiles[i] |= bit
+			}
+			if colour&0x02 != 0 {
+				tiles[i+1] |= bit
+			}
+		}
+	}
+}
+
+// gain returns the sensor amplifier's gain as a factor, 1 at gain 0.
+func (c *CameraCartridge) gain() float64 {
+	return math.Pow(10, cameraGainStep*float64(c.regs[1]&0x1f)/20)
+}
@@ -5,11 +5,14 @@ package cartridge
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	"github.com/chigopher/pathlib"
 	"github.com/duysqubix/gobc/internal"
@@ -17,10 +20,21 @@ import (
 	"github.com/olekukonko/tablewriter/tw"
 )
 
+// Errors returned while loading a ROM image. Callers can match them with
+// errors.Is; the wrapped message carries the offending header value.
+var (
+	ErrTruncatedROM   = errors.New("cartridge: ROM image is truncated")
+	ErrROMSize        = errors.New("cartridge: ROM size does not match header")
+	ErrRAMSize        = errors.New("cartridge: invalid RAM size in header")
+	ErrUnsupportedMBC = errors.New("cartridge: unsupported cartridge type")
+	ErrChecksum       = errors.New("cartridge: header checksum mismatch")
+	ErrSRAMSize       = errors.New("cartridge: save file size does not match cartridge RAM")
+)
+
 type CartridgeType interface {
 	SetItem(uint16, uint8)
 	GetItem(uint16) uint8
-	Init()
+	Init() error
 	Serialize() *bytes.Buffer
 	Deserialize(*bytes.Buffer) error
 }
@@ -59,6 +73,41 @@ var CARTRIDGE_TABLE = map[uint8]func(*Cartridge) CartridgeType{
 		}
 	},
 
+	// MBC2
+	0x05: func(c *Cartridge) CartridgeType {
+		return &Mbc2Cartridge{
+			parent:        c,
+			romBankSelect: 1,
+		}
+	},
+
+	// MBC2+BATTERY
+	0x06: func(c *Cartridge) CartridgeType {
+		return &Mbc2Cartridge{
+			parent:        c,
+			romBankSelect: 1,
+			hasBattery:    true,
+		}
+	},
+
+	// MMM01
+	0x0B: func(c *Cartridge) CartridgeType {
+		return &Mmm01Cartridge{parent: c}
+	},
+
+	// MMM01+RAM
+	0x0C: func(c *Cartridge) CartridgeType {
+		return &Mmm01Cartridge{parent: c}
+	},
+
+	// MMM01+RAM+BATTERY
+	0x0D: func(c *Cartridge) CartridgeType {
+		return &Mmm01Cartridge{
+			parent:     c,
+			hasBattery: true,
+		}
+	},
+
 	// MBC3+TIMER+BATTERY
 	0x0F: func(c *Cartridge) CartridgeType {
 		return &Mbc3Cartridge{
@@ -76,6 +125,17 @@ var CARTRIDGE_TABLE = map[uint8]func(*Cartridge) CartridgeType{
 			hasRTC:     true,
 		}
 	},
+
+	// MBC3
+	0x11: func(c *Cartridge) CartridgeType {
+		return &Mbc3Cartridge{parent: c}
+	},
+
+	// MBC3+RAM
+	0x12: func(c *Cartridge) CartridgeType {
+		return &Mbc3Cartridge{parent: c}
+	},
+
 	// MBC3+RAM+BATTERY
 	0x13: func(c *Cartridge) CartridgeType {
 		return &Mbc3Cartridge{
@@ -92,6 +152,12 @@ var CARTRIDGE_TABLE = map[uint8]func(*Cartridge) CartridgeType{
 			parent: c,
 		}
 	},
+	// MBC5+RAM
+	0x1A: func(c *Cartridge) CartridgeType {
+		return &Mbc5Cartridge{
+			parent: c,
+		}
+	},
 	// MBC5+RAM+BATTERY
 	0x1b: func(c *Cartridge) CartridgeType {
 		return &Mbc5Cartridge{
@@ -99,10 +165,133 @@ var CARTRIDGE_TABLE = map[uint8]func(*Cartridge) CartridgeType{
 			hasBattery: true,
 		}
 	},
+	// MBC5+RUMBLE
+	0x1C: func(c *Cartridge) CartridgeType {
+		return &Mbc5Cartridge{
+			parent:    c,
+			hasRumble: true,
+		}
+	},
+	// MBC5+RUMBLE+RAM
+	0x1D: func(c *Cartridge) CartridgeType {
+		return &Mbc5Cartridge{
+			parent:    c,
+			hasRumble: true,
+		}
+	},
+	// MBC5+RUMBLE+RAM+BATTERY
+	0x1E: func(c *Cartridge) CartridgeType {
+		return &Mbc5Cartridge{
+			parent:     c,
+			hasBattery: true,
+			hasRumble:  true,
+		}
+	},
+
+	// MBC6
+	0x20: func(c *Cartridge) CartridgeType {
+		return &Mbc6Cartridge{
+			parent:     c,
+			romBank:    [2]uint8{2, 3},
+			hasBattery: true,
+		}
+	},
+
+	// MBC7+SENSOR+RUMBLE+RAM+BATTERY
+	0x22: func(c *Cartridge) CartridgeType {
+		return &Mbc7Cartridge{
+			parent:        c,
+			romBankSelect: 1,
+			hasBattery:    true,
+		}
+	},
+
+	// POCKET CAMERA
+	0xFC: func(c *Cartridge) CartridgeType {
+		return &CameraCartridge{
+			parent:        c,
+			romBankSelect: 1,
+			hasBattery:    true,
+		}
+	},
+
+	// BANDAI TAMA5
+	0xFD: func(c *Cartridge) CartridgeType {
+		return &Tama5Cartridge{
+			parent:     c,
+			hasBattery: true,
+		}
+	},
+
+	// HuC3
+	0xFE: func(c *Cartridge) CartridgeType {
+		return &HuC3Cartridge{
+			parent:        c,
+			romBankSelect: 1,
+			hasBattery:    true,
+		}
+	},
+
+	// HuC1+RAM+BATTERY
+	0xFF: func(c *Cartridge) CartridgeType {
+		return &HuC1Cartridge{
+			parent:        c,
+			romBankSelect: 1,
+			hasBattery:    true,
+		}
+	},
+}
+
+// ticker is the part of a cartridge that runs on the CPU clock: the
+// MBC3's Rtc while RtcEnabled, or the mapper itself when it keeps time
+// its own way.
+type ticker interface {
+	Tick(cycles uint64)
+	NextEvent() (cycles uint64, ok bool)
+}
+
+// speaker is implemented by mappers with a speaker of their own.
+type speaker interface {
+	Tone() (hz float64, on bool)
+}
+
+// tilter is implemented by mappers with an accelerometer.
+type tilter interface {
+	SetTilt(x, y float64)
+}
+
+// batteryExtra is implemented by mappers whose battery keeps more than
+// RAM alive, such as a clock. The bytes follow the RAM banks in the save
+// file.
+type batteryExtra interface {
+	saveExtra() []byte
+	loadExtra(data []byte) error
+}
+
+// flashBacked is implemented by mappers with flash memory, which keeps
+// without a battery and is saved to a file of its own beside the RAM's.
+type flashBacked interface {
+	saveFlash(romName string) error
+}
+
+// smallRAM is implemented by mappers whose battery keeps less than a
+// whole RAM bank. Their save files hold just that much, as other
+// emulators' do.
+type smallRAM interface {
+	sramSize() int
+}
+
+// peeker is implemented by mappers whose ROM reads change their state.
+// peek reads without changing it.
+type peeker interface {
+	peek(addr uint16) uint8
 }
 
-// global real-time clock
-var Grtc *RTC = NewRTC()
+// bootLocked is implemented by mappers that stay locked until the boot
+// ROM has read the header through them.
+type bootLocked interface {
+	skipBoot()
+}
 
 type Cartridge struct {
 	Filename  string        // Filename of the ROM
@@ -124,8 +313,20 @@ type Cartridge struct {
 
 	// RTC
 	RtcEnabled bool // whether RTC is enabled
+	Rtc        *RTC // real-time clock; only ticked when RtcEnabled
+
+	IR     Transceiver   // IR port for cartridges that carry one; nil leaves them in the dark
+	Sensor Sensor        // image sensor of a Pocket Camera; nil shows it flat grey
+	Rumble func(on bool) // called as a rumble cartridge's motor starts and stops; may be nil
 
 	MemoryModel uint8 // 0 = 16/8, 1 = 4/32
+
+	Mapper string // unlicensed mapper from UNLICENSED_TABLE; empty when the header's type is believed
+
+	Clock      ClockMode // how the battery's clocks catch up on a save
+	ClockStart time.Time // when the machine is started, for ClockFixed
+
+	headerBank int // bank holding the cartridge header, see findHeader
 }
 
 func (c *Cartridge) GetFilename() string {
@@ -134,17 +335,151 @@ func (c *Cartridge) GetFilename() string {
 	return filename[0 : len(filename)-len(ext)]
 }
 
+// header returns the ROM bank that holds the cartridge header.
+func (c *Cartridge) header() []uint8 {
+	return c.RomBanks[c.headerBank]
+}
+
 func (c *Cartridge) GetTitle() string {
-	return string(c.RomBanks[0][TITLE_START_ADDR:TITLE_END_ADDR])
+	return string(c.header()[TITLE_START_ADDR:TITLE_END_ADDR])
 }
 
-func (c *Cartridge) Tick(cycles uint64) {
+// ticker returns what runs on the cartridge, or nil when nothing does.
+func (c *Cartridge) ticker() ticker {
+	if t, ok := c.CartType.(ticker); ok {
+		return t
+	}
 	if c.RtcEnabled {
-		Grtc.Tick(cycles)
+		return c.Rtc
+	}
+	return nil
+}
+
+func (c *Cartridge) Tick(cycles uint64) {
+	if t := c.ticker(); t != nil {
+		t.Tick(cycles)
 
 		// print RTC values
-		// logger.Debugf("RTC: %02d:%02d:%02d, %02d/%02d", Grtc.H, Grtc.M, Grtc.S, Grtc.DH, Grtc.DL)
+		// logger.Debugf("RTC: %02d:%02d:%02d, %02d/%02d", c.Rtc.H, c.Rtc.M, c.Rtc.S, c.Rtc.DH, c.Rtc.DL)
+	}
+}
+
+// NextEvent returns the cycles left until the cartridge next changes state
+// on its own, such as the RTC's next second. ok is false for cartridges
+// with nothing running.
+func (c *Cartridge) NextEvent() (cycles uint64, ok bool) {
+	t := c.ticker()
+	if t == nil {
+		return 0, false
+	}
+	return t.NextEvent()
+}
+
+// Tone reports the tone the cartridge's own speaker is playing, if it has
+// one and it is playing.
+func (c *Cartridge) Tone() (hz float64, on bool) {
+	if s, ok := c.CartType.(speaker); ok {
+		return s.Tone()
+	}
+	return 0, false
+}
+
+// Tilt tilts the cartridge x g to the right and y g towards the player,
+// for cartridges with an accelerometer.
+func (c *Cartridge) Tilt(x, y float64) {
+	if t, ok := c.CartType.(tilter); ok {
+		t.SetTilt(x, y)
+	}
+}
+
+// Peek returns what reading addr in ROM would, without the side effects
+// some mappers give their reads.
+func (c *Cartridge) Peek(addr uint16) uint8 {
+	if p, ok := c.CartType.(peeker); ok {
+		return p.peek(addr)
+	}
+	return c.CartType.GetItem(addr)
+}
+
+// SkipBoot leaves the mapper as the boot ROM would have, for a machine
+// started straight at $0100.
+func (c *Cartridge) SkipBoot() {
+	if b, ok := c.CartType.(bootLocked); ok {
+		b.skipBoot()
+	}
+}
+
+// SRAMSize returns how many bytes of RAM the battery keeps, which is what
+// the save file starts with.
+func (c *Cartridge) SRAMSize() int {
+	if s, ok := c.CartType.(smallRAM); ok {
+		return s.sramSize()
+	}
+	return int(c.RamBankCount) * int(RAM_BANK_SIZE)
+}
+
+// BatteryExtra returns what the battery keeps besides RAM, such as a
+// clock, as it follows the RAM in the save file. It is nil for mappers
+// that keep nothing more.
+func (c *Cartridge) BatteryExtra() []byte {
+	if b, ok := c.CartType.(batteryExtra); ok {
+		return b.saveExtra()
 	}
+	return nil
+}
+
+// SetBatteryExtra restores what BatteryExtra returned, as read from the
+// save file after the RAM.
+func (c *Cartridge) SetBatteryExtra(data []byte) error {
+	b, ok := c.CartType.(batteryExtra)
+	if !ok || b.saveExtra() == nil {
+		return fmt.Errorf("%w: %d bytes after RAM, but the battery keeps nothing more", ErrSRAMSize, len(data))
+	}
+	return b.loadExtra(data)
+}
+
+// Save writes the battery-backed state to <rom>.sav: the RAM banks, then
+// anything else the battery keeps, such as a clock. Flash memory goes to
+// <rom>.flash.
+func (c *Cartridge) Save() error {
+	name := c.GetFilename()
+	if err := saveSRAMSize(name, &c.RamBanks, c.SRAMSize()); err != nil {
+		return err
+	}
+	if f, ok := c.CartType.(flashBacked); ok {
+		if err := f.saveFlash(name); err != nil {
+			return err
+		}
+	}
+	extra := c.BatteryExtra()
+	if extra == nil {
+		return nil
+	}
+	file, err := os.OpenFile(name+".sav", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("cartridge: opening save file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(extra); err != nil {
+		return fmt.Errorf("cartridge: writing save file: %w", err)
+	}
+	return file.Close()
+}
+
+// loadSaveExtra returns what <romName>.sav holds past its ramBankCount
+// RAM banks, or nil when there is no save file or nothing past them.
+func loadSaveExtra(romName string, ramSize int) ([]byte, error) {
+	data, err := os.ReadFile(romName + ".sav")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cartridge: reading save file: %w", err)
+	}
+	if len(data) <= ramSize {
+		return nil, nil
+	}
+	return data[ramSize:], nil
 }
 
 func (c *Cartridge) Serialize() *bytes.Buffer {
@@ -215,6 +550,7 @@ func LoadRomBanks(rom_data []byte, dummy_data bool) [][]uint8 {
 			bank[j] = 0xff // fill with 0xff
 		}
 		bank[CARTRIDGE_TYPE_ADDR] = 0x0
+		bank[SRAM_SIZE_ADDR] = 0x0
 		rom_banks = append(rom_banks, bank)
 		return rom_banks
 	}
@@ -233,7 +569,8 @@ func LoadRomBanks(rom_data []byte, dummy_data bool) [][]uint8 {
 		for j := range bank {
 			bank[j] = 0xff
 		}
-		rom_banks = append(rom_banks, rom_data[i:end])
+		copy(bank, rom_data[i:end])
+		rom_banks = append(rom_banks, bank)
 	}
 
 	return rom_banks
@@ -270,38 +607,103 @@ func LoadRomBanksV2(rom_data []byte, dummy_data bool) [128][MEMORY_BANK_SIZE]uin
 }
 
 func (c *Cartridge) GetCartType() string {
-	cart_type_addr := c.RomBanks[0][CARTRIDGE_TYPE_ADDR]
+	if c.Mapper != "" {
+		return UnlicensedTypeMap[c.Mapper]
+	}
+	cart_type_addr := c.header()[CARTRIDGE_TYPE_ADDR]
 	return CartridgeTypeMap[cart_type_addr]
 }
 
-func NewCartridge(Filename *pathlib.Path) *Cartridge {
-	var rom_data []byte
-	var err error
-	// var rom_banks [128][MEMORY_BANK_SIZE]uint8
-	var rom_banks [][]uint8
-	var fname string
+func NewCartridge(Filename *pathlib.Path) (*Cartridge, error) {
+	return NewCartridgeWithOptions(Filename, LoadOptions{})
+}
 
-	if Filename != nil {
-		rom_data, err = Filename.ReadFile()
-		fname = Filename.Name()
-		if err != nil {
-			internal.Logger.Panicf("Error reading ROM file: %s", err)
-		}
-		rom_banks = LoadRomBanks(rom_data, false)
+// LoadOptions tweaks how a cartridge is built. The zero value believes the
+// header, unless the ROM is recognised as unlicensed, and runs clocks in
+// real time.
+type LoadOptions struct {
+	Mapper     string    // unlicensed mapper from UNLICENSED_TABLE to build instead of guessing
+	Clock      ClockMode // how the battery's clocks catch up on a save
+	ClockStart time.Time // when the machine is started, for ClockFixed
+}
 
-	} else {
+// NewCartridgeWithMapper is NewCartridge for a ROM whose header can't be
+// trusted: mapper names the unlicensed mapper from UNLICENSED_TABLE to
+// build instead of guessing. An empty mapper guesses as NewCartridge does.
+func NewCartridgeWithMapper(Filename *pathlib.Path, mapper string) (*Cartridge, error) {
+	return NewCartridgeWithOptions(Filename, LoadOptions{Mapper: mapper})
+}
+
+// NewCartridgeWithOptions is NewCartridge with the options in opts.
+func NewCartridgeWithOptions(Filename *pathlib.Path, opts LoadOptions) (*Cartridge, error) {
+	if Filename == nil {
 		logger.Warn("No ROM file specified, running tests")
-		rom_banks = LoadRomBanks(nil, true)
-		fname = ""
+		return loadCartridge("", nil, true, LoadOptions{})
+	}
+
+	rom_data, err := Filename.ReadFile()
+	if err != nil {
+		return nil, fmt.Errorf("cartridge: reading ROM file: %w", err)
+	}
+
+	cart, err := loadCartridge(Filename.Name(), rom_data, false, opts)
+	if err != nil {
+		return nil, err
+	}
+	cart.Dump(os.Stdout)
+	return cart, nil
+}
+
+// NewCartridgeFromBytes builds a cartridge from an in-memory ROM image.
+// name is used as the base for the SRAM / save-state filenames and may
+// be empty. Unlike NewCartridge it never writes the header dump to
+// stdout, which makes it the entry point for embedders.
+func NewCartridgeFromBytes(name string, rom []byte) (*Cartridge, error) {
+	return loadCartridge(name, rom, false, LoadOptions{})
+}
+
+// NewCartridgeFromBytesWithMapper is NewCartridgeFromBytes with the
+// unlicensed mapper named, as for NewCartridgeWithMapper.
+func NewCartridgeFromBytesWithMapper(name string, rom []byte, mapper string) (*Cartridge, error) {
+	return loadCartridge(name, rom, false, LoadOptions{Mapper: mapper})
+}
+
+// NewCartridgeFromBytesWithOptions is NewCartridgeFromBytes with the
+// options in opts.
+func NewCartridgeFromBytesWithOptions(name string, rom []byte, opts LoadOptions) (*Cartridge, error) {
+	return loadCartridge(name, rom, false, opts)
+}
+
+// loadCartridge validates the header of rom_data and builds the matching
+// MBC. dummy builds a blank ROM-only cartridge and skips validation.
+// opts.Mapper names an unlicensed mapper to build instead; when empty, one
+// is only used if detectMapper recognises the ROM.
+func loadCartridge(fname string, rom_data []byte, dummy bool, opts LoadOptions) (*Cartridge, error) {
+	if !dummy && len(rom_data) <= int(HEADER_END_ADDR) {
+		return nil, fmt.Errorf("%w: %d bytes is shorter than the cartridge header", ErrTruncatedROM, len(rom_data))
+	}
+
+	rom_banks := LoadRomBanks(rom_data, dummy)
+	headerBank := findHeader(rom_banks)
+	header := rom_banks[headerBank]
+
+	mapper := opts.Mapper
+	if mapper == "" && !dummy {
+		if mapper = detectMapper(rom_banks); mapper != "" {
+			logger.Infof("Detected unlicensed mapper: %s (override with --mapper)", mapper)
+		}
+	}
+	if mapper != "" {
+		return loadUnlicensed(fname, rom_banks, headerBank, mapper)
 	}
 
 	var ramBankCount uint16
 
-	switch rom_banks[0][SRAM_SIZE_ADDR] {
+	switch header[SRAM_SIZE_ADDR] {
 	case 0x00:
 		ramBankCount = 0
 	case 0x01:
-		logger.Panicf("RAM size is unused")
+		return nil, fmt.Errorf("%w: %02X is unused", ErrRAMSize, header[SRAM_SIZE_ADDR])
 	case 0x02:
 		ramBankCount = 1
 	case 0x03:
@@ -311,7 +713,7 @@ func NewCartridge(Filename *pathlib.Path) *Cartridge {
 	case 0x05:
 		ramBankCount = 8
 	default:
-		logger.Panicf("Invalid RAM size: %02X", rom_banks[0][SRAM_SIZE_ADDR])
+		return nil, fmt.Errorf("%w: %02X", ErrRAMSize, header[SRAM_SIZE_ADDR])
 	}
 
 	// Some carts (Blargg halt_bug, interrupt_time) declare a +RAM type
@@ -319,7 +721,7 @@ func NewCartridge(Filename *pathlib.Path) *Cartridge {
 	// Real hardware in this situation usually has 8 KiB of RAM wired
 	// on the MBC itself, and test ROMs rely on $A000-$A0FF for their
 	// "DE B0 61" signature. Promote 0 banks to 1 bank for any +RAM type.
-	cartType := rom_banks[0][CARTRIDGE_TYPE_ADDR]
+	cartType := header[CARTRIDGE_TYPE_ADDR]
 	cartHasRAM := cartType == 0x02 || cartType == 0x03 ||
 		cartType == 0x08 || cartType == 0x09 ||
 		cartType == 0x0C || cartType == 0x0D ||
@@ -332,7 +734,7 @@ func NewCartridge(Filename *pathlib.Path) *Cartridge {
 	}
 
 	var romBankCount uint16
-	switch rom_banks[0][ROM_SIZE_ADDR] {
+	switch header[ROM_SIZE_ADDR] {
 	case 0x00:
 		romBankCount = 2
 	case 0x01:
@@ -351,10 +753,18 @@ func NewCartridge(Filename *pathlib.Path) *Cartridge {
 		romBankCount = 256
 	case 0x08:
 		romBankCount = 512
+	default:
+		if !dummy {
+			return nil, fmt.Errorf("%w: unknown ROM size code %02X", ErrROMSize, header[ROM_SIZE_ADDR])
+		}
 	}
-	logger.Debugf("Detected ROM bank count: %d, Calculated Number of ROM Banks: %d", romBankCount, len(rom_data)/int(MEMORY_BANK_SIZE))
-	if romBankCount != uint16(len(rom_data)/int(MEMORY_BANK_SIZE)) {
-		logger.Fatalf("ROM bank count mismatch. Expected %d, got %d", romBankCount, len(rom_data)/int(MEMORY_BANK_SIZE))
+	fileBanks := len(rom_data) / int(MEMORY_BANK_SIZE)
+	logger.Debugf("Detected ROM bank count: %d, Calculated Number of ROM Banks: %d", romBankCount, fileBanks)
+	if int(romBankCount) > fileBanks {
+		return nil, fmt.Errorf("%w: header declares %d banks, file holds %d", ErrTruncatedROM, romBankCount, fileBanks)
+	}
+	if int(romBankCount) < fileBanks {
+		return nil, fmt.Errorf("%w: header declares %d banks, file holds %d", ErrROMSize, romBankCount, fileBanks)
 	}
 
 	cart := Cartridge{
@@ -366,45 +776,86 @@ func NewCartridge(Filename *pathlib.Path) *Cartridge {
 		RamBankCount:    ramBankCount,
 		MemoryModel:     0,
 		Randomize:       false,
+		Rtc:             NewRTC(),
+		Clock:           opts.Clock,
+		ClockStart:      opts.ClockStart,
+		headerBank:      headerBank,
+	}
+
+	if calc_checksum, valid := cart.ValidateChecksum(); !valid && !dummy {
+		return nil, fmt.Errorf("%w: expected %02X, got %02X", ErrChecksum, cart.header()[HEADER_CHECKSUM_ADDR], calc_checksum)
 	}
 
-	cart_type_addr := rom_banks[0][CARTRIDGE_TYPE_ADDR]
-	cartTypeConstructor := CARTRIDGE_TABLE[rom_banks[0][CARTRIDGE_TYPE_ADDR]]
+	cart_type_addr := header[CARTRIDGE_TYPE_ADDR]
+	cartTypeConstructor := CARTRIDGE_TABLE[cart_type_addr]
 
 	if cartTypeConstructor == nil {
-		logger.Errorf("Cartridge type not supported: %02X", cart_type_addr)
-		os.Exit(0)
+		return nil, fmt.Errorf("%w: %02X (%s)", ErrUnsupportedMBC, cart_type_addr, CartridgeTypeMap[cart_type_addr])
 	}
 	cart.CartType = cartTypeConstructor(&cart)
-	cart.CartType.Init()
-
-	calc_checksum, valid := cart.ValidateChecksum()
-	if !valid {
-		logger.Fatalf("Checksum invalid. Expected %02X, got %02X", cart.RomBanks[0][HEADER_CHECKSUM_ADDR], calc_checksum)
+	if err := cart.CartType.Init(); err != nil {
+		return nil, err
 	}
 
 	// initialize RAM banks to maximum size of 128KiB
 	// cart.initRambanks()
 
 	logger.Info("Cartridge RAM Initialized")
-	cart.Dump(os.Stdout)
-	logger.Infof("ROM file loaded successfully: %s", Filename)
+	logger.Infof("ROM file loaded successfully: %s", fname)
 	logger.Infof("Cartridge Initialized: %s", reflect.TypeOf(cart.CartType))
 	logger.Infof("ROM Banks: %d, Size: %dKb", cart.RomBanksCount, cart.RomBanksCount*16)
 	logger.Infof("RAM Banks: %d, Size: %dKb", cart.RamBankCount, cart.RamBankCount*8)
 	logger.Infof("RTC Support: %t", cart.RtcEnabled)
-	return &cart
+	return &cart, nil
 }
 
-func (c *Cartridge) ValidateChecksum() (uint8, bool) {
+// findHeader returns the bank holding the cartridge header. That is bank
+// 0, except in MMM01 dumps taken straight off the cartridge: the mapper
+// boots into the last 32 KiB, so the menu's header starts the
+// second-to-last bank while bank 0 carries the first game's.
+func findHeader(rom_banks [][]uint8) int {
+	last := len(rom_banks) - 2
+	if last <= 0 || isMmm01(rom_banks[0]) {
+		return 0
+	}
+	if bank := rom_banks[last]; isMmm01(bank) && headerChecksum(bank) == bank[HEADER_CHECKSUM_ADDR] {
+		logger.Debugf("Found MMM01 header in bank %d", last)
+		return last
+	}
+	return 0
+}
+
+func isMmm01(bank []uint8) bool {
+	if len(bank) <= int(HEADER_END_ADDR) {
+		return false
+	}
+	t := bank[CARTRIDGE_TYPE_ADDR]
+	return t == 0x0B || t == 0x0C || t == 0x0D
+}
+
+func headerChecksum(bank []uint8) uint8 {
 	var checksum uint8 = 0
 
 	for i := TITLE_START_ADDR; i <= MASK_ROM_VERSION_NUMBER_ADDR; i++ {
-		checksum -= c.RomBanks[0][i] + 1
+		checksum -= bank[i] + 1
 	}
+	return checksum
+}
 
-	return checksum, checksum == c.RomBanks[0][HEADER_CHECKSUM_ADDR]
+func (c *Cartridge) ValidateChecksum() (uint8, bool) {
+	checksum := headerChecksum(c.header())
+	return checksum, checksum == c.header()[HEADER_CHECKSUM_ADDR]
+}
+
+// HasInfrared reports whether the cartridge carries its own IR LED and
+// receiver, reached through IR.
+func (c *Cartridge) HasInfrared() bool {
+	if c.RomBanksCount == 0 {
+		return false
+	}
 
+	t := c.header()[CARTRIDGE_TYPE_ADDR]
+	return t == 0xFE || t == 0xFF
 }
 
 func (c *Cartridge) CgbModeEnabled() bool {
@@ -413,26 +864,37 @@ func (c *Cartridge) CgbModeEnabled() bool {
 		return false
 	}
 
-	return c.RomBanks[0][CBG_FLAG_ADDR] == 0x80 || c.RomBanks[0][CBG_FLAG_ADDR] == 0xC0
+	return c.header()[CBG_FLAG_ADDR] == 0x80 || c.header()[CBG_FLAG_ADDR] == 0xC0
+}
+
+// SgbFunctionsEnabled reports whether the header lets a Super Game Boy
+// take commands from the game: the SGB flag must be $03 and the old
+// licensee code $33, or the SGB BIOS ignores every packet.
+func (c *Cartridge) SgbFunctionsEnabled() bool {
+	if c.RomBanksCount == 0 {
+		return false
+	}
+
+	return c.header()[SGB_FLAG_ADDR] == 0x03 && c.header()[OLD_LICENSEE_CODE_ADDR] == 0x33
 }
 
 func (c *Cartridge) Dump(writer io.Writer) {
-	title := c.RomBanks[0][TITLE_START_ADDR : TITLE_END_ADDR+1]
-	license1 := NewLicenseeCodeMap[c.RomBanks[0][NEW_LICENSEE_CODE_START_ADDR]]
-	license2 := NewLicenseeCodeMap[c.RomBanks[0][NEW_LICENSEE_CODE_END_ADDR]]
+	title := c.header()[TITLE_START_ADDR : TITLE_END_ADDR+1]
+	license1 := NewLicenseeCodeMap[c.header()[NEW_LICENSEE_CODE_START_ADDR]]
+	license2 := NewLicenseeCodeMap[c.header()[NEW_LICENSEE_CODE_END_ADDR]]
 
-	cartridge_type := CartridgeTypeMap[c.RomBanks[0][CARTRIDGE_TYPE_ADDR]]
-	rom_size := RomSizeMap[c.RomBanks[0][ROM_SIZE_ADDR]]
-	ram_size := RamSizeMap[c.RomBanks[0][SRAM_SIZE_ADDR]]
+	cartridge_type := CartridgeTypeMap[c.header()[CARTRIDGE_TYPE_ADDR]]
+	rom_size := RomSizeMap[c.header()[ROM_SIZE_ADDR]]
+	ram_size := RamSizeMap[c.header()[SRAM_SIZE_ADDR]]
 
-	oldlicense1 := OldLicenseeCodeMap[c.RomBanks[0][OLD_LICENSEE_CODE_ADDR]]
+	oldlicense1 := OldLicenseeCodeMap[c.header()[OLD_LICENSEE_CODE_ADDR]]
 
 	sbg_mode_enabled := "No"
-	if c.RomBanks[0][SGB_FLAG_ADDR] == 0x03 {
+	if c.header()[SGB_FLAG_ADDR] == 0x03 {
 		sbg_mode_enabled = "Yes"
 	}
 
-	cgb_mode := c.RomBanks[0][CBG_FLAG_ADDR]
+	cgb_mode := c.header()[CBG_FLAG_ADDR]
 	var cgb_mode_desc string
 	if c.CgbModeEnabled() {
 		cgb_mode_desc = CgbFlagMap[cgb_mode]
@@ -451,9 +913,9 @@ func (c *Cartridge) Dump(writer io.Writer) {
 		{"ROM Size", rom_size.String()},
 		{"RAM Size", ram_size.String()},
 		{"Old Licensee Code", oldlicense1},
-		{"Header Checksum", fmt.Sprintf("$%02X", c.RomBanks[0][HEADER_CHECKSUM_ADDR])},
+		{"Header Checksum", fmt.Sprintf("$%02X", c.header()[HEADER_CHECKSUM_ADDR])},
 		{"Header Checksum Valid", fmt.Sprintf("%t", valid)},
-		{"Global Checksum", fmt.Sprintf("$%02X", c.RomBanks[0][GLOBAL_CHECKSUM_START_ADDR])},
+		{"Global Checksum", fmt.Sprintf("$%02X", c.header()[GLOBAL_CHECKSUM_START_ADDR])},
 	}
 
 	table := tablewriter.NewTable(writer, tablewriter.WithRowAlignment(tw.AlignLeft))
@@ -553,7 +1015,7 @@ func (c *Cartridge) RawHeaderDump() {
 	table := tablewriter.NewTable(os.Stdout, tablewriter.WithRowAlignment(tw.AlignLeft))
 	table.Header([]string{"Address", "Value", "Description"})
 
-	for _i, v := range c.RomBanks[0][HEADER_START_ADDR : HEADER_END_ADDR+1] {
+	for _i, v := range c.header()[HEADER_START_ADDR : HEADER_END_ADDR+1] {
 		var desc string
 		i := uint16(_i) + HEADER_START_ADDR
 		switch {
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/chigopher/pathlib"
@@ -148,6 +150,13 @@ func makeFakeROM(t *testing.T, opts ...romOption) *pathlib.Path {
 	return writeTempROM(t, buildROM(opts...))
 }
 
+func mustNewCartridge(t *testing.T, path *pathlib.Path) *Cartridge {
+	t.Helper()
+	cart, err := NewCartridge(path)
+	require.NoError(t, err)
+	return cart
+}
+
 func newCartFromHeader(bank0 []uint8) *Cartridge {
 	banks := make([][]uint8, 1)
 	banks[0] = make([]uint8, MEMORY_BANK_SIZE)
@@ -170,7 +179,7 @@ func TestMakeFakeROM_DefaultsAreValid(t *testing.T) {
 	assert.Equal(t, uint8(0x00), data[CARTRIDGE_TYPE_ADDR])
 	assert.Equal(t, uint8(0x00), data[ROM_SIZE_ADDR])
 
-	cart := NewCartridge(path)
+	cart := mustNewCartridge(t, path)
 	require.NotNil(t, cart)
 	calc, ok := cart.ValidateChecksum()
 	assert.True(t, ok, "default ROM header checksum should be valid")
@@ -258,6 +267,32 @@ func TestCartridge_SGBFlag(t *testing.T) {
 	}
 }
 
+func TestCartridge_SgbFunctionsEnabled(t *testing.T) {
+	cases := []struct {
+		name     string
+		flag     uint8
+		licensee uint8
+		want     bool
+	}{
+		{"SGB flag and licensee $33", 0x03, 0x33, true},
+		{"SGB flag, other licensee", 0x03, 0x01, false},
+		{"licensee $33, no SGB flag", 0x00, 0x33, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rom := buildROM(withSGBFlag(tc.flag), withOldLicensee(tc.licensee))
+			cart := newCartFromHeader(rom[:MEMORY_BANK_SIZE])
+			assert.Equal(t, tc.want, cart.SgbFunctionsEnabled())
+		})
+	}
+}
+
+func TestCartridge_HasInfrared(t *testing.T) {
+	assert.True(t, newCartFromHeader(buildROM(withType(0xFF))[:MEMORY_BANK_SIZE]).HasInfrared())
+	assert.False(t, newCartFromHeader(buildROM(withType(0x03))[:MEMORY_BANK_SIZE]).HasInfrared())
+	assert.False(t, (&Cartridge{}).HasInfrared())
+}
+
 func TestCartridge_TitleExtraction(t *testing.T) {
 	cases := []struct {
 		name  string
@@ -492,6 +527,26 @@ func TestNewCartridge_HeaderParsing(t *testing.T) {
 				assert.False(t, mbc.hasRTC, "0x13 should not enable RTC")
 			},
 		},
+		{
+			name:        "MBC3 (0x11)",
+			typeByte:    0x11,
+			wantTypeFmt: "*cartridge.Mbc3Cartridge",
+			check: func(t *testing.T, cart *Cartridge) {
+				mbc := cart.CartType.(*Mbc3Cartridge)
+				assert.False(t, mbc.hasBattery)
+				assert.False(t, mbc.hasRTC)
+				assert.False(t, cart.RtcEnabled)
+			},
+		},
+		{
+			name:        "MBC3+RAM (0x12)",
+			typeByte:    0x12,
+			wantTypeFmt: "*cartridge.Mbc3Cartridge",
+			check: func(t *testing.T, cart *Cartridge) {
+				assert.Equal(t, uint16(1), cart.RamBankCount)
+				assert.False(t, cart.CartType.(*Mbc3Cartridge).hasBattery)
+			},
+		},
 		{
 			name:        "MBC5 (0x19)",
 			typeByte:    0x19,
@@ -502,17 +557,58 @@ func TestNewCartridge_HeaderParsing(t *testing.T) {
 				assert.Equal(t, uint8(0), mbc.romBankHi)
 			},
 		},
+		{
+			name:        "MBC5+RAM (0x1A)",
+			typeByte:    0x1A,
+			wantTypeFmt: "*cartridge.Mbc5Cartridge",
+			check: func(t *testing.T, cart *Cartridge) {
+				mbc := cart.CartType.(*Mbc5Cartridge)
+				assert.False(t, mbc.hasBattery)
+				assert.False(t, mbc.hasRumble)
+			},
+		},
 		{
 			name:        "MBC5+RAM+BATTERY (0x1B)",
 			typeByte:    0x1b,
 			wantTypeFmt: "*cartridge.Mbc5Cartridge",
+			check: func(t *testing.T, cart *Cartridge) {
+				assert.True(t, cart.CartType.(*Mbc5Cartridge).hasBattery)
+			},
+		},
+		{
+			name:        "MBC5+RUMBLE (0x1C)",
+			typeByte:    0x1C,
+			wantTypeFmt: "*cartridge.Mbc5Cartridge",
+			check: func(t *testing.T, cart *Cartridge) {
+				mbc := cart.CartType.(*Mbc5Cartridge)
+				assert.False(t, mbc.hasBattery)
+				assert.True(t, mbc.hasRumble)
+			},
+		},
+		{
+			name:        "MBC5+RUMBLE+RAM (0x1D)",
+			typeByte:    0x1D,
+			wantTypeFmt: "*cartridge.Mbc5Cartridge",
+			check: func(t *testing.T, cart *Cartridge) {
+				assert.True(t, cart.CartType.(*Mbc5Cartridge).hasRumble)
+			},
+		},
+		{
+			name:        "MBC5+RUMBLE+RAM+BATTERY (0x1E)",
+			typeByte:    0x1E,
+			wantTypeFmt: "*cartridge.Mbc5Cartridge",
+			check: func(t *testing.T, cart *Cartridge) {
+				mbc := cart.CartType.(*Mbc5Cartridge)
+				assert.True(t, mbc.hasBattery)
+				assert.True(t, mbc.hasRumble)
+			},
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			path := makeFakeROM(t, withType(tc.typeByte))
-			cart := NewCartridge(path)
+			cart := mustNewCartridge(t, path)
 			require.NotNil(t, cart)
 			gotType := typeNameOf(cart.CartType)
 			assert.Equal(t, tc.wantTypeFmt, gotType,
@@ -539,7 +635,7 @@ func TestNewCartridge_ROMSize(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.humanSize, func(t *testing.T) {
 			path := makeFakeROM(t, withRomSize(tc.b))
-			cart := NewCartridge(path)
+			cart := mustNewCartridge(t, path)
 			require.NotNil(t, cart)
 			assert.Equal(t, tc.wantBanks, cart.RomBanksCount)
 			assert.Len(t, cart.RomBanks, int(tc.wantBanks))
@@ -567,7 +663,7 @@ func TestNewCartridge_RAMSize(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.label, func(t *testing.T) {
 			path := makeFakeROM(t, withType(0x02), withRamSize(tc.b))
-			cart := NewCartridge(path)
+			cart := mustNewCartridge(t, path)
 			require.NotNil(t, cart)
 			assert.Equal(t, tc.wantBanks, cart.RamBankCount)
 		})
@@ -579,14 +675,14 @@ func TestNewCartridge_RAMSize(t *testing.T) {
 // cart with size=0 stays at 0 banks.
 func TestNewCartridge_NoRAMTypeKeepsZero(t *testing.T) {
 	path := makeFakeROM(t, withType(0x00), withRamSize(0x00))
-	cart := NewCartridge(path)
+	cart := mustNewCartridge(t, path)
 	require.NotNil(t, cart)
 	assert.Equal(t, uint16(0), cart.RamBankCount)
 }
 
 func TestNewCartridge_PreservesTitle(t *testing.T) {
 	path := makeFakeROM(t, withTitle("TEST"))
-	cart := NewCartridge(path)
+	cart := mustNewCartridge(t, path)
 	require.NotNil(t, cart)
 	assert.Equal(t, "TEST\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00", cart.GetTitle())
 }
@@ -596,9 +692,35 @@ func TestNewCartridge_DetectsCGBFlag(t *testing.T) {
 	pathCgb := makeFakeROM(t, withCGBFlag(0x80))
 	pathCgbOnly := makeFakeROM(t, withCGBFlag(0xC0))
 
-	assert.False(t, NewCartridge(pathDmg).CgbModeEnabled())
-	assert.True(t, NewCartridge(pathCgb).CgbModeEnabled())
-	assert.True(t, NewCartridge(pathCgbOnly).CgbModeEnabled())
+	assert.False(t, mustNewCartridge(t, pathDmg).CgbModeEnabled())
+	assert.True(t, mustNewCartridge(t, pathCgb).CgbModeEnabled())
+	assert.True(t, mustNewCartridge(t, pathCgbOnly).CgbModeEnabled())
+}
+
+// TestNewCartridge_MMM01HeaderAtEnd loads an MMM01 dump as it comes off
+// the cartridge: bank 0 starts the first game, and the mapper's own
+// header belongs to the menu in the last 32 KiB.
+func TestNewCartridge_MMM01HeaderAtEnd(t *testing.T) {
+	rom := buildROM(withRomSize(0x01), withType(0x01), withTitle("GAME"))
+	menu := buildROM(withRomSize(0x01), withType(0x0D), withRamSize(0x03), withTitle("MENU"))
+	menuStart := 2 * int(MEMORY_BANK_SIZE)
+	copy(rom[menuStart:], menu[:MEMORY_BANK_SIZE])
+	rom[menuStart] = 0x42 // first byte of the menu, where the CPU boots into
+
+	cart, err := NewCartridgeFromBytes("", rom)
+	require.NoError(t, err)
+	assert.IsType(t, &Mmm01Cartridge{}, cart.CartType)
+	assert.Equal(t, "MMM01+RAM+BATTERY", cart.GetCartType())
+	assert.Equal(t, "MENU", strings.TrimRight(cart.GetTitle(), "\x00"))
+	assert.Equal(t, uint16(4), cart.RamBankCount)
+	assert.Equal(t, uint8(0x42), cart.CartType.GetItem(0x0000))
+
+	// a header at the front wins, as in dumps with the menu moved there
+	front := buildROM(withRomSize(0x01), withType(0x0B), withTitle("FRONT"))
+	copy(front[menuStart:], menu[:MEMORY_BANK_SIZE])
+	cart, err = NewCartridgeFromBytes("", front)
+	require.NoError(t, err)
+	assert.Equal(t, "FRONT", strings.TrimRight(cart.GetTitle(), "\x00"))
 }
 
 func TestCartridge_Dump_ContainsExpectedFields(t *testing.T) {
@@ -632,22 +754,116 @@ func TestCartridge_RawHeaderDump_DoesNotPanic(t *testing.T) {
 }
 
 func TestCartridge_Tick_AdvancesRtcWhenEnabled(t *testing.T) {
-	cart := &Cartridge{RtcEnabled: true}
-	Grtc = NewRTC()
+	cart := &Cartridge{RtcEnabled: true, Rtc: NewRTC()}
 	cart.Tick(RTCCycles)
-	assert.Equal(t, uint8(1), Grtc.s, "tick of RTCCycles should advance seconds by 1")
+	assert.Equal(t, uint8(1), cart.Rtc.s, "tick of RTCCycles should advance seconds by 1")
 }
 
 func TestCartridge_Tick_NoopWhenRtcDisabled(t *testing.T) {
-	cart := &Cartridge{RtcEnabled: false}
-	Grtc = NewRTC()
+	cart := &Cartridge{RtcEnabled: false, Rtc: NewRTC()}
 	cart.Tick(RTCCycles * 5)
-	assert.Equal(t, uint8(0), Grtc.s, "tick should be a no-op when RtcEnabled=false")
+	assert.Equal(t, uint8(0), cart.Rtc.s, "tick should be a no-op when RtcEnabled=false")
+}
+
+func TestNewCartridge_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		rom  []byte
+		want error
+	}{
+		{"shorter than header", buildROM()[:0x100], ErrTruncatedROM},
+		{"fewer banks than header", buildROM(withRomSize(0x01))[:2*MEMORY_BANK_SIZE], ErrTruncatedROM},
+		{"more banks than header", append(buildROM(), make([]byte, MEMORY_BANK_SIZE)...), ErrROMSize},
+		{"unknown ROM size code", buildROM(withRomSize(0x09)), ErrROMSize},
+		{"unused RAM size code", buildROM(withRamSize(0x01)), ErrRAMSize},
+		{"invalid RAM size code", buildROM(withRamSize(0x06)), ErrRAMSize},
+		{"unsupported cartridge type", buildROM(withType(0x21)), ErrUnsupportedMBC},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cart, err := NewCartridgeFromBytes("", tc.rom)
+			assert.ErrorIs(t, err, tc.want)
+			assert.Nil(t, cart)
+		})
+	}
+
+	t.Run("bad checksum", func(t *testing.T) {
+		rom := buildROM()
+		rom[HEADER_CHECKSUM_ADDR]++
+		_, err := NewCartridgeFromBytes("", rom)
+		assert.ErrorIs(t, err, ErrChecksum)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := NewCartridge(pathlib.NewPath(filepath.Join(t.TempDir(), "missing.gb")))
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func TestSRAM_SaveLoadRoundTrip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "game")
+	var src, dst [16][RAM_BANK_SIZE]uint8
+	src[0][0] = 0x12
+	src[3][RAM_BANK_SIZE-1] = 0x34
+
+	require.NoError(t, SaveSRAM(name, &src, 4))
+	require.NoError(t, LoadSRAM(name, &dst, 4))
+	assert.Equal(t, src, dst)
+}
+
+func TestLoadSRAM_MissingFileIsNotAnError(t *testing.T) {
+	var banks [16][RAM_BANK_SIZE]uint8
+	assert.NoError(t, LoadSRAM(filepath.Join(t.TempDir(), "game"), &banks, 1))
+}
+
+func TestLoadSRAM_ShortFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "game")
+	require.NoError(t, os.WriteFile(name+".sav", make([]byte, RAM_BANK_SIZE), 0o644))
+
+	var banks [16][RAM_BANK_SIZE]uint8
+	assert.ErrorIs(t, LoadSRAM(name, &banks, 4), ErrSRAMSize)
+}
+
+// FuzzNewCartridgeFromBytes feeds arbitrary header bytes and image
+// lengths through the loader. Every input must either load or return an
+// error; none may panic.
+func FuzzNewCartridgeFromBytes(f *testing.F) {
+	valid := buildROM()
+	f.Add(valid[HEADER_START_ADDR:HEADER_END_ADDR+1], uint32(len(valid)))
+	f.Add(buildROM(withType(0x13), withRamSize(0x03))[HEADER_START_ADDR:HEADER_END_ADDR+1], uint32(len(valid)))
+	f.Add(buildROM(withRomSize(0x01))[HEADER_START_ADDR:HEADER_END_ADDR+1], uint32(len(valid)))
+	f.Add([]byte{}, uint32(0x100))
+
+	f.Fuzz(func(t *testing.T, header []byte, size uint32) {
+		rom := make([]byte, size%(8*uint32(MEMORY_BANK_SIZE)+1))
+		copy(rom, buildROM())
+		if len(rom) > int(HEADER_START_ADDR) {
+			copy(rom[HEADER_START_ADDR:], header)
+		}
+
+		cart, err := NewCartridgeFromBytes("", rom)
+		if err != nil {
+			assert.Nil(t, cart)
+			return
+		}
+		cart.Dump(io.Discard)
+		for addr := uint16(0); addr < 0x8000; addr += 0x1000 {
+			cart.CartType.GetItem(addr)
+		}
+	})
+}
+
+func TestCartridge_Tick_RtcIsPerCartridge(t *testing.T) {
+	a := &Cartridge{RtcEnabled: true, Rtc: NewRTC()}
+	b := &Cartridge{RtcEnabled: true, Rtc: NewRTC()}
+	a.Tick(RTCCycles)
+	assert.Equal(t, uint8(1), a.Rtc.s)
+	assert.Equal(t, uint8(0), b.Rtc.s, "ticking one cartridge must not advance another's clock")
 }
 
 func TestCartridge_Serialize_Deserialize_RoundTrip(t *testing.T) {
 	path := makeFakeROM(t, withType(0x01))
-	src := NewCartridge(path)
+	src := mustNewCartridge(t, path)
 	require.NotNil(t, src)
 	src.RamBankCount = 1
 	src.RamBankSelected = 0
@@ -657,7 +873,7 @@ func TestCartridge_Serialize_Deserialize_RoundTrip(t *testing.T) {
 
 	buf := src.Serialize()
 
-	dst := NewCartridge(path)
+	dst := mustNewCartridge(t, path)
 	require.NotNil(t, dst)
 	require.NoError(t, dst.Deserialize(bytes.NewBuffer(buf.Bytes())))
 
@@ -0,0 +1,118 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Transceiver is the IR LED and receiver that Hudson cartridges carry. The
+// motherboard plugs its infrared port in here, so these cartridges reach
+// the same partners as the CGB's own port.
+type Transceiver interface {
+	SetLED(on bool)
+	Light() bool // whether the receiver sees IR light
+}
+
+// HuC1Cartridge is Hudson's MBC1 lookalike with an IR transceiver in
+// place of a RAM enable: writing $0E to $0000-$1FFF turns A000-BFFF into
+// the transceiver, anything else back into RAM. In IR mode bit 0 drives
+// the LED, and reads return $C1 while light comes in, $C0 otherwise.
+type HuC1Cartridge struct {
+	parent        *Cartridge
+	romBankSelect uint16
+	ramBankSelect uint16
+	irMode        bool
+	hasBattery    bool
+}
+
+func (c *HuC1Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romBankSelect) // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.ramBankSelect) // RAM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.irMode)        // IR Mode
+	binary.Write(buf, binary.LittleEndian, c.hasBattery)    // Has Battery
+	logger.Debug("Serialized HuC1 state")
+	return buf
+}
+
+func (c *HuC1Cartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&c.romBankSelect, &c.ramBankSelect, &c.irMode, &c.hasBattery} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *HuC1Cartridge) Init() error {
+	if c.hasBattery {
+		return LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
+	}
+	return nil
+}
+
+func (c *HuC1Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x2000:
+		c.irMode = value&0x0f == 0x0e
+
+	case 0x2000 <= addr && addr < 0x4000:
+		// as on MBC1, bank 0 can't be mapped twice
+		value &= 0x3f
+		if value == 0 {
+			value = 1
+		}
+		c.romBankSelect = uint16(value)
+
+	case 0x4000 <= addr && addr < 0x6000:
+		c.ramBankSelect = uint16(value) & 0x3
+
+	case 0x6000 <= addr && addr < 0x8000:
+		// no banking mode on HuC1
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if c.irMode {
+			if c.parent.IR != nil {
+				c.parent.IR.SetLED(value&0x01 != 0)
+			}
+			return
+		}
+		if c.parent.RamBankCount == 0 {
+			return
+		}
+		c.parent.RamBankSelected = c.ramBankSelect % c.parent.RamBankCount
+		c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000] = value
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *HuC1Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[0][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		c.parent.RomBankSelected = c.romBankSelect % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if c.irMode {
+			if c.parent.IR != nil && c.parent.IR.Light() {
+				return 0xc1
+			}
+			return 0xc0
+		}
+		if c.parent.RamBankCount == 0 {
+			return 0xff
+		}
+		c.parent.RamBankSelected = c.ramBankSelect % c.parent.RamBankCount
+		return c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000]
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
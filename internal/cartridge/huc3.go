@@ -0,0 +1,311 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// HuC3Cartridge is Hudson's mapper with a real-time clock, an IR
+// transceiver and a piezo speaker. $0000-$1FFF selects what A000-BFFF
+// is:
+//
+//	$0  RAM, read only
+//	$A  RAM, read/write
+//	$B  write a command to the HuC3 (high nibble command, low argument)
+//	$C  read the response: the command in bits 6-4, its result in 3-0
+//	$D  semaphore: bit 0 reads 1 once the command has run
+//	$E  IR: bit 0 drives the LED, reads $C1 while light comes in
+//
+// Anything else leaves A000-BFFF reading $FF. The HuC3 has 256 nibbles of
+// scratch memory, walked with an address register, and copies the clock
+// in and out of it on request:
+//
+//	$1x  read the nibble at the address into the response, then step on
+//	$2x  write x at the address
+//	$3x  write x at the address, then step on
+//	$4x  set the address' low nibble
+//	$5x  set the address' high nibble
+//	$60  copy the clock to $00-$06: minutes of the day, then days
+//	$61  set the clock from $00-$06
+//	$62  status; the result is 1
+//	$6E  play tone n on the speaker, n read from $27; 0 silences it
+//
+// $58-$5E hold the alarm, minutes then days like the clock, and bit 0 of
+// $5F arms it. When the clock reaches it the speaker rings until the
+// game sends its next command.
+type HuC3Cartridge struct {
+	parent        *Cartridge
+	romBankSelect uint16
+	ramBankSelect uint16
+	mode          uint8
+	addr          uint8
+	response      uint8
+	scratch       [256]uint8 // one nibble each
+	minutes       uint16     // minutes into the current day
+	days          uint16
+	cycles        uint64 // towards the next minute
+	tone          uint8  // tone on the speaker, 0 when quiet
+	ringing       bool   // the alarm has gone off
+	hasBattery    bool
+}
+
+const (
+	huc3MinuteCycles = 60 * RTCCycles
+	huc3AlarmTone    = 9
+)
+
+// huc3Tone returns the pitch of tone n, a semitone apart from $1 = C6.
+func huc3Tone(n uint8) float64 {
+	return 1046.5 * math.Pow(2, float64(n-1)/12)
+}
+
+func (c *HuC3Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romBankSelect) // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.ramBankSelect) // RAM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.mode)          // Mode
+	binary.Write(buf, binary.LittleEndian, c.addr)          // Scratch address
+	binary.Write(buf, binary.LittleEndian, c.response)      // Response
+	binary.Write(buf, binary.LittleEndian, c.scratch)       // Scratch memory
+	binary.Write(buf, binary.LittleEndian, c.minutes)       // Clock minutes
+	binary.Write(buf, binary.LittleEndian, c.days)          // Clock days
+	binary.Write(buf, binary.LittleEndian, c.cycles)        // Cycles towards the next minute
+	binary.Write(buf, binary.LittleEndian, c.tone)          // Speaker tone
+	binary.Write(buf, binary.LittleEndian, c.ringing)       // Alarm ringing
+	binary.Write(buf, binary.LittleEndian, c.hasBattery)    // Has Battery
+	logger.Debug("Serialized HuC3 state")
+	return buf
+}
+
+func (c *HuC3Cartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{
+		&c.romBankSelect, &c.ramBankSelect, &c.mode, &c.addr, &c.response,
+		&c.scratch, &c.minutes, &c.days, &c.cycles, &c.tone, &c.ringing,
+		&c.hasBattery,
+	} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *HuC3Cartridge) Init() error {
+	c.parent.RtcEnabled = true
+	if !c.hasBattery {
+		return nil
+	}
+	if err := LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount); err != nil {
+		return err
+	}
+	extra, err := loadSaveExtra(c.parent.GetFilename(), c.parent.SRAMSize())
+	if err != nil || extra == nil {
+		return err
+	}
+	return c.loadExtra(extra)
+}
+
+// The clock goes in the save file after RAM, in a layout of gobc's own:
+// the Unix time of the save, the clock's minutes and days, the alarm's,
+// and whether it is armed.
+const huc3ExtraSize = 17
+
+func (c *HuC3Cartridge) saveExtra() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, uint64(c.parent.clockNow().Unix()))
+	binary.Write(buf, binary.LittleEndian, c.minutes)
+	binary.Write(buf, binary.LittleEndian, c.days)
+	binary.Write(buf, binary.LittleEndian, uint16(c.nibbles(0x58, 3)))
+	binary.Write(buf, binary.LittleEndian, uint16(c.nibbles(0x5B, 4)))
+	binary.Write(buf, binary.LittleEndian, c.scratch[0x5F]&0x01)
+	return buf.Bytes()
+}
+
+func (c *HuC3Cartridge) loadExtra(data []byte) error {
+	if len(data) < huc3ExtraSize {
+		return fmt.Errorf("%w: %d bytes of clock data, want %d", ErrSRAMSize, len(data), huc3ExtraSize)
+	}
+	var saved uint64
+	var alarmMinutes, alarmDays uint16
+	r := bytes.NewReader(data)
+	for _, v := range []any{&saved, &c.minutes, &c.days, &alarmMinutes, &alarmDays, &c.scratch[0x5F]} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	c.minutes %= 24 * 60
+	c.setNibbles(0x58, 3, uint32(alarmMinutes))
+	c.setNibbles(0x5B, 4, uint32(alarmDays))
+	c.scratch[0x5F] &= 0x01
+
+	// the clock kept running while the game was off
+	if elapsed := c.parent.clockElapsed(saved); elapsed > 0 {
+		total := uint64(c.days)*24*60 + uint64(c.minutes) + uint64(elapsed/time.Minute)
+		c.minutes = uint16(total % (24 * 60))
+		c.days = uint16(total / (24 * 60))
+	}
+	return nil
+}
+
+// nibbles reads n scratch nibbles from addr on as a little-endian number.
+func (c *HuC3Cartridge) nibbles(addr uint8, n int) uint32 {
+	var v uint32
+	for i := n - 1; i >= 0; i-- {
+		v = v<<4 | uint32(c.scratch[addr+uint8(i)])
+	}
+	return v
+}
+
+func (c *HuC3Cartridge) setNibbles(addr uint8, n int, v uint32) {
+	for i := range n {
+		c.scratch[addr+uint8(i)] = uint8(v>>(4*i)) & 0x0f
+	}
+}
+
+func (c *HuC3Cartridge) command(value uint8) {
+	c.ringing = false
+	cmd, arg := value>>4&0x07, value&0x0f
+	switch cmd {
+	case 0x1:
+		c.response = cmd<<4 | c.scratch[c.addr]
+		c.addr++
+	case 0x2, 0x3:
+		c.scratch[c.addr] = arg
+		if cmd == 0x3 {
+			c.addr++
+		}
+	case 0x4:
+		c.addr = c.addr&0xf0 | arg
+	case 0x5:
+		c.addr = c.addr&0x0f | arg<<4
+	case 0x6:
+		c.response = cmd << 4
+		switch arg {
+		case 0x0:
+			c.setNibbles(0x00, 3, uint32(c.minutes))
+			c.setNibbles(0x03, 4, uint32(c.days))
+		case 0x1:
+			c.minutes = uint16(c.nibbles(0x00, 3)) % (24 * 60)
+			c.days = uint16(c.nibbles(0x03, 4))
+			c.cycles = 0
+		case 0x2:
+			c.response |= 0x01
+		case 0xE:
+			c.tone = c.scratch[0x27]
+		default:
+			logger.Debugf("HuC3: unknown command %#02x", value)
+		}
+	default:
+		logger.Debugf("HuC3: unknown command %#02x", value)
+	}
+}
+
+func (c *HuC3Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x2000:
+		c.mode = value & 0x0f
+		c.parent.RamBankEnabled = c.mode == 0x0a
+
+	case 0x2000 <= addr && addr < 0x4000:
+		c.romBankSelect = uint16(value & 0x7f)
+
+	case 0x4000 <= addr && addr < 0x6000:
+		c.ramBankSelect = uint16(value & 0x03)
+
+	case 0x6000 <= addr && addr < 0x8000:
+		// unused on HuC3
+
+	case 0xA000 <= addr && addr < 0xC000:
+		switch c.mode {
+		case 0xa:
+			if c.parent.RamBankCount == 0 {
+				return
+			}
+			c.parent.RamBankSelected = c.ramBankSelect % c.parent.RamBankCount
+			c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000] = value
+		case 0xb:
+			c.command(value)
+		case 0xe:
+			if c.parent.IR != nil {
+				c.parent.IR.SetLED(value&0x01 != 0)
+			}
+		}
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *HuC3Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[0][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		c.parent.RomBankSelected = c.romBankSelect % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		switch c.mode {
+		case 0x0, 0xa:
+			if c.parent.RamBankCount == 0 {
+				return 0xff
+			}
+			c.parent.RamBankSelected = c.ramBankSelect % c.parent.RamBankCount
+			return c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000]
+		case 0xc:
+			return c.response
+		case 0xd:
+			// commands run at once, so the HuC3 is never busy
+			return 0x01
+		case 0xe:
+			if c.parent.IR != nil && c.parent.IR.Light() {
+				return 0xc1
+			}
+			return 0xc0
+		}
+		return 0xff
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
+
+// Tick runs the clock, a minute at a time.
+func (c *HuC3Cartridge) Tick(cycles uint64) {
+	c.cycles += cycles
+	for c.cycles >= huc3MinuteCycles {
+		c.cycles -= huc3MinuteCycles
+		c.minutes++
+		if c.minutes == 24*60 {
+			c.minutes = 0
+			c.days++
+		}
+		if c.scratch[0x5F]&0x01 != 0 &&
+			uint32(c.minutes) == c.nibbles(0x58, 3) && uint32(c.days) == c.nibbles(0x5B, 4) {
+			c.ringing = true
+		}
+	}
+}
+
+// NextEvent returns the cycles left until the clock's next minute.
+func (c *HuC3Cartridge) NextEvent() (cycles uint64, ok bool) {
+	return huc3MinuteCycles - c.cycles, true
+}
+
+// Tone reports what the speaker plays: its pitch, and whether it plays at
+// all.
+func (c *HuC3Cartridge) Tone() (hz float64, on bool) {
+	switch {
+	case c.ringing:
+		return huc3Tone(huc3AlarmTone), true
+	case c.tone != 0:
+		return huc3Tone(c.tone), true
+	}
+	return 0, false
+}
@@ -0,0 +1,69 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// M161Cartridge is the M161 multicart mapper. It switches the whole
+// 32 KiB of ROM at once, and only once: the first write to $4000-$5FFF
+// selects the 32 KiB bank in bits 2-0 and the bank then stays until
+// power-off, so each game is safe from its own bank writes. There is no
+// RAM.
+type M161Cartridge struct {
+	parent  *Cartridge
+	bank    uint8 // 32 KiB bank
+	latched bool  // the bank has been chosen
+}
+
+func (c *M161Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.bank)    // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.latched) // Bank latched
+	logger.Debug("Serialized M161 state")
+	return buf
+}
+
+func (c *M161Cartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&c.bank, &c.latched} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *M161Cartridge) Init() error {
+	return nil
+}
+
+func (c *M161Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case 0x4000 <= addr && addr < 0x6000:
+		if !c.latched {
+			c.bank, c.latched = value&0x07, true
+		}
+
+	case addr < 0x8000, 0xA000 <= addr && addr < 0xC000:
+		// nothing else on the cartridge
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *M161Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x8000:
+		c.parent.RomBankSelected = (2*uint16(c.bank) + addr/0x4000) % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr%0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		return 0xff
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
@@ -5,12 +5,50 @@ import (
 	"encoding/binary"
 )
 
+// Mbc1Cartridge is the MBC1. Multicarts (MBC1M) wire the chip
+// differently: the upper bank bits land on bank bit 4 rather than 5, so
+// each of the four games sees 16 banks of its own. They are spotted by a
+// second game's header, Nintendo logo and all, in bank $10.
 type Mbc1Cartridge struct {
 	parent        *Cartridge
 	romBankSelect uint16
 	ramBankSelect uint16
 	mode          bool
 	hasBattery    bool
+	multicart     bool // MBC1M wiring, from the ROM, not saved
+}
+
+// nintendoLogo is the logo every cartridge header carries at $0104.
+var nintendoLogo = [...]uint8{
+	0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B, 0x03, 0x73, 0x00, 0x83, 0x00, 0x0C, 0x00, 0x0D,
+	0x00, 0x08, 0x11, 0x1F, 0x88, 0x89, 0x00, 0x0E, 0xDC, 0xCC, 0x6E, 0xE6, 0xDD, 0xDD, 0xD9, 0x99,
+	0xBB, 0xBB, 0x67, 0x63, 0x6E, 0x0E, 0xEC, 0xCC, 0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E,
+}
+
+// isMbc1m reports whether rom_banks look like an MBC1M multicart: 1 MiB
+// with the logo at the start of bank $10, where the second game begins.
+func isMbc1m(rom_banks [][]uint8) bool {
+	if len(rom_banks) != 64 {
+		return false
+	}
+	return bytes.Equal(rom_banks[0x10][NINTENDO_LOGO_START_ADDR:NINTENDO_LOGO_END_ADDR+1], nintendoLogo[:])
+}
+
+// upperBank returns the bank bits the 2-bit register adds.
+func (c *Mbc1Cartridge) upperBank() uint16 {
+	if c.multicart {
+		return c.ramBankSelect << 4
+	}
+	return c.ramBankSelect << 5
+}
+
+// lowerBank returns the bank bits the 5-bit register gives, bit 4 of
+// which a multicart leaves unconnected.
+func (c *Mbc1Cartridge) lowerBank() uint16 {
+	if c.multicart {
+		return c.romBankSelect & 0x0f
+	}
+	return c.romBankSelect
 }
 
 func (c *Mbc1Cartridge) Serialize() *bytes.Buffer {
@@ -43,10 +81,15 @@ func (c *Mbc1Cartridge) Deserialize(data *bytes.Buffer) error {
 	return nil
 }
 
-func (c *Mbc1Cartridge) Init() {
+func (c *Mbc1Cartridge) Init() error {
+	c.multicart = isMbc1m(c.parent.RomBanks)
+	if c.multicart {
+		logger.Info("Found an MBC1M multicart")
+	}
 	if c.hasBattery {
-		LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
+		return LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
 	}
+	return nil
 }
 
 func (c *Mbc1Cartridge) SetItem(addr uint16, value uint8) {
@@ -90,7 +133,7 @@ func (c *Mbc1Cartridge) SetItem(addr uint16, value uint8) {
 		}
 		c.parent.RamBanks[bank][addr-0xA000] = value
 	default:
-		logger.Panicf("Memory write error! Can't write %#x to %#x\n", value, addr)
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
 	}
 }
 
@@ -98,14 +141,14 @@ func (c *Mbc1Cartridge) GetItem(addr uint16) uint8 {
 	switch {
 	case addr < 0x4000:
 		if c.parent.MemoryModel == 1 {
-			c.parent.RomBankSelected = (c.ramBankSelect << 5) % c.parent.RomBanksCount
+			c.parent.RomBankSelected = c.upperBank() % c.parent.RomBanksCount
 		} else {
 			c.parent.RomBankSelected = 0
 		}
 		return c.parent.RomBanks[c.parent.RomBankSelected][addr]
 
 	case 0x4000 <= addr && addr < 0x8000:
-		c.parent.RomBankSelected = (c.ramBankSelect<<5)%c.parent.RomBanksCount | c.romBankSelect
+		c.parent.RomBankSelected = c.upperBank()%c.parent.RomBanksCount | c.lowerBank()
 		bank := c.parent.RomBankSelected % c.parent.RomBanksCount
 		return c.parent.RomBanks[bank][addr-0x4000]
 
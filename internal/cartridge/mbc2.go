@@ -0,0 +1,107 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// MBC2 carries 512 half-bytes of RAM on the chip itself. It is kept in
+// the first bytes of RAM bank 0, and the save file holds those 512 bytes
+// alone, a nibble to a byte.
+const mbc2RamSize = 0x200
+
+type Mbc2Cartridge struct {
+	parent        *Cartridge
+	romBankSelect uint16
+	hasBattery    bool
+}
+
+func (c *Mbc2Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romBankSelect) // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.hasBattery)    // Has Battery
+	logger.Debug("Serialized MBC2 state")
+	return buf
+}
+
+func (c *Mbc2Cartridge) Deserialize(data *bytes.Buffer) error {
+	if err := binary.Read(data, binary.LittleEndian, &c.romBankSelect); err != nil {
+		return err
+	}
+
+	if err := binary.Read(data, binary.LittleEndian, &c.hasBattery); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Mbc2Cartridge) Init() error {
+	// the header declares no RAM for MBC2, the chip brings its own
+	c.parent.RamBankCount = 1
+	if !c.hasBattery {
+		return nil
+	}
+	if err := loadSRAMSize(c.parent.GetFilename(), &c.parent.RamBanks, mbc2RamSize); err != nil {
+		return err
+	}
+	// other emulators may save the unwired upper nibbles as 1s
+	for i := range mbc2RamSize {
+		c.parent.RamBanks[0][i] &= 0x0f
+	}
+	return nil
+}
+
+func (c *Mbc2Cartridge) sramSize() int {
+	return mbc2RamSize
+}
+
+func (c *Mbc2Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x4000:
+		// address bit 8 picks the register
+		if addr&0x0100 == 0 {
+			c.parent.RamBankEnabled = (value & 0x0f) == 0x0a
+			return
+		}
+
+		value &= 0x0f
+		if value == 0 {
+			value = 1
+		}
+		c.romBankSelect = uint16(value)
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.parent.RamBankEnabled {
+			return
+		}
+		// only the low nibble is stored; the 512 cells repeat through A000-BFFF
+		c.parent.RamBanks[0][(addr-0xA000)%mbc2RamSize] = value & 0x0f
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *Mbc2Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[0][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		c.parent.RomBankSelected = c.romBankSelect % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.parent.RamBankEnabled {
+			return 0xff
+		}
+		// the upper nibble isn't wired and reads as 1s
+		return 0xf0 | c.parent.RamBanks[0][(addr-0xA000)%mbc2RamSize]
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
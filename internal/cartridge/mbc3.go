@@ -3,25 +3,90 @@ package cartridge
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 )
 
+// Mbc3Cartridge is the MBC3, and the MBC30 of Pokémon Crystal's Japanese
+// release: the same chip with an eighth ROM bank bit and a third RAM bank
+// bit, for 4 MiB of ROM and 64 KiB of RAM. Cartridges whose header asks
+// for more than the MBC3 can address get the MBC30.
 type Mbc3Cartridge struct {
 	parent     *Cartridge
 	hasBattery bool
 	hasRTC     bool
 	latchGate1 bool
+	mbc30      bool // from the header, not saved
 }
 
-func (c *Mbc3Cartridge) Init() {
+func (c *Mbc3Cartridge) Init() error {
+	if c.hasRTC {
+		c.parent.RtcEnabled = true
+	}
+	c.mbc30 = c.parent.RomBanksCount > 128 || c.parent.RamBankCount > 4
+	if c.mbc30 {
+		logger.Debug("MBC3 cartridge needs an MBC30")
+	}
 
 	// load save file if exists
-	if c.hasBattery {
-		LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
+	if !c.hasBattery {
+		return nil
+	}
+	if err := LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount); err != nil {
+		return err
+	}
+	if !c.hasRTC {
+		return nil
 	}
+	extra, err := loadSaveExtra(c.parent.GetFilename(), c.parent.SRAMSize())
+	if err != nil || extra == nil {
+		return err
+	}
+	return c.loadExtra(extra)
+}
 
-	if c.hasRTC {
-		c.parent.RtcEnabled = true
+// The clock goes in the save file after RAM in the footer VBA and BGB
+// write: the clock's registers and then the latched ones, each as a 32-bit
+// word, and the Unix time of the save. VBA's older footer has a 32-bit
+// time.
+const (
+	mbc3ExtraSize    = 48
+	mbc3ExtraSizeVBA = 44
+)
+
+func (c *Mbc3Cartridge) saveExtra() []byte {
+	if !c.hasRTC {
+		return nil
+	}
+	r := c.parent.Rtc
+	buf := new(bytes.Buffer)
+	for _, v := range []uint8{r.s, r.m, r.h, r.dl, r.dh, r.S, r.M, r.H, r.DL, r.DH} {
+		binary.Write(buf, binary.LittleEndian, uint32(v))
+	}
+	binary.Write(buf, binary.LittleEndian, uint64(c.parent.clockNow().Unix()))
+	return buf.Bytes()
+}
+
+func (c *Mbc3Cartridge) loadExtra(data []byte) error {
+	if len(data) < mbc3ExtraSizeVBA {
+		return fmt.Errorf("%w: %d bytes of clock data, want %d", ErrSRAMSize, len(data), mbc3ExtraSize)
 	}
+	r := c.parent.Rtc
+	masks := [5]uint8{MaskS, MaskM, MaskH, MaskDL, MaskDH}
+	regs := [...]*uint8{&r.s, &r.m, &r.h, &r.dl, &r.dh, &r.S, &r.M, &r.H, &r.DL, &r.DH}
+	for i, reg := range regs {
+		*reg = uint8(binary.LittleEndian.Uint32(data[4*i:])) & masks[i%5]
+	}
+	r.latchSet = true
+
+	var saved uint64
+	if len(data) >= mbc3ExtraSize {
+		saved = binary.LittleEndian.Uint64(data[40:])
+	} else {
+		saved = uint64(binary.LittleEndian.Uint32(data[40:]))
+	}
+	// the clock kept running while the game was off
+	r.Advance(c.parent.clockElapsed(saved))
+	return nil
 }
 
 func (c *Mbc3Cartridge) Serialize() *bytes.Buffer {
@@ -29,7 +94,7 @@ func (c *Mbc3Cartridge) Serialize() *bytes.Buffer {
 	binary.Write(buf, binary.LittleEndian, c.hasBattery) // Has Battery
 	binary.Write(buf, binary.LittleEndian, c.hasRTC)     // Has RTC
 	binary.Write(buf, binary.LittleEndian, c.latchGate1) // Latch Gate 1
-	binary.Write(buf, binary.LittleEndian, Grtc.Serialize().Bytes())
+	binary.Write(buf, binary.LittleEndian, c.parent.Rtc.Serialize().Bytes())
 
 	logger.Debug("Serialized MBC3 state")
 	return buf
@@ -48,7 +113,7 @@ func (c *Mbc3Cartridge) Deserialize(data *bytes.Buffer) error {
 		return err
 	}
 
-	if err := Grtc.Deserialize(data); err != nil {
+	if err := c.parent.Rtc.Deserialize(data); err != nil {
 		return err
 	}
 
@@ -68,7 +133,9 @@ func (c *Mbc3Cartridge) SetItem(addr uint16, value uint8) {
 		}
 
 	case 0x2000 <= addr && addr < 0x4000:
-		// value &= 0b01111111 // passes MBC30 test allowing upto 256 banks (4MB ROM)
+		if !c.mbc30 {
+			value &= 0x7f
+		}
 		if value == 0 {
 			value = 1
 		}
@@ -76,6 +143,10 @@ func (c *Mbc3Cartridge) SetItem(addr uint16, value uint8) {
 		c.parent.RomBankSelected = uint16(value)
 
 	case 0x4000 <= addr && addr < 0x6000:
+		// RAM banks 0-3, or 0-7 on the MBC30; the RTC registers at 8-C
+		if value < 0x08 && !c.mbc30 {
+			value &= 0x03
+		}
 		c.parent.RamBankSelected = uint16(value)
 
 	case 0x6000 <= addr && addr < 0x8000:
@@ -84,7 +155,7 @@ func (c *Mbc3Cartridge) SetItem(addr uint16, value uint8) {
 				c.latchGate1 = false
 			} else if value == 1 {
 				if !c.latchGate1 {
-					Grtc.Latch()
+					c.parent.Rtc.Latch()
 				}
 				c.latchGate1 = true
 			} else {
@@ -105,7 +176,7 @@ func (c *Mbc3Cartridge) SetItem(addr uint16, value uint8) {
 				}
 				c.parent.RamBanks[bank][addr-0xA000] = value
 			} else if c.hasRTC && 0x08 <= c.parent.RamBankSelected && c.parent.RamBankSelected <= 0x0C {
-				Grtc.SetItem(c.parent.RamBankSelected, value)
+				c.parent.Rtc.SetItem(c.parent.RamBankSelected, value)
 			} else {
 				logger.Errorf("Setting invalid RAM bank: %#x", c.parent.RamBankSelected)
 			}
@@ -136,7 +207,7 @@ func (c *Mbc3Cartridge) GetItem(addr uint16) uint8 {
 			}
 			return c.parent.RamBanks[bank][addr-0xA000]
 		} else if c.hasRTC && (0x08 <= c.parent.RamBankSelected && c.parent.RamBankSelected <= 0x0C) {
-			value := Grtc.GetItem(c.parent.RamBankSelected)
+			value := c.parent.Rtc.GetItem(c.parent.RamBankSelected)
 			// logger.Debugf("Reading from RTC register %#x: %d", c.parent.RamBankSelected, value)
 			return value
 		} else {
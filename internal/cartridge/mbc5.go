@@ -5,20 +5,26 @@ import (
 	"encoding/binary"
 )
 
+// Mbc5Cartridge is the MBC5. On rumble cartridges bit 3 of the RAM bank
+// register drives the motor instead of selecting a bank; the parent's
+// Rumble hears each time it starts or stops.
 type Mbc5Cartridge struct {
 	parent     *Cartridge
 	hasBattery bool
 	hasRumble  bool
 	romBankLow uint8
 	romBankHi  uint8
+	motor      bool // rumble motor running
 }
 
-func (c *Mbc5Cartridge) Init() {
-	c.hasBattery = true
-	c.hasRumble = false
+func (c *Mbc5Cartridge) Init() error {
 	c.romBankLow = 1
 	c.romBankHi = 0
 	logger.Debugf("Initializing MBC5, with ROM bank %d", c.GetRomBank())
+	if c.hasBattery {
+		return LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
+	}
+	return nil
 }
 
 func (c *Mbc5Cartridge) Serialize() *bytes.Buffer {
@@ -27,6 +33,7 @@ func (c *Mbc5Cartridge) Serialize() *bytes.Buffer {
 	binary.Write(buf, binary.LittleEndian, c.hasRumble)  // Has Rumble
 	binary.Write(buf, binary.LittleEndian, c.romBankLow) // ROM Bank Low
 	binary.Write(buf, binary.LittleEndian, c.romBankHi)  // ROM Bank Hi
+	binary.Write(buf, binary.LittleEndian, c.motor)      // Rumble motor
 	return buf
 
 }
@@ -48,6 +55,10 @@ func (c *Mbc5Cartridge) Deserialize(data *bytes.Buffer) error {
 		return err
 	}
 
+	if err := binary.Read(data, binary.LittleEndian, &c.motor); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -71,12 +82,22 @@ func (c *Mbc5Cartridge) SetItem(addr uint16, value uint8) {
 		c.romBankHi = value & 0x01
 
 	case 0x4000 <= addr && addr < 0x6000:
-		// RAM Bank Number 4bits
-		c.parent.RamBankSelected = uint16(value & 0x0f)
+		// RAM Bank Number 4bits, or 3 and the motor on rumble cartridges
+		if !c.hasRumble {
+			c.parent.RamBankSelected = uint16(value & 0x0f)
+			return
+		}
+		c.parent.RamBankSelected = uint16(value & 0x07)
+		if motor := value&0x08 != 0; motor != c.motor {
+			c.motor = motor
+			if c.parent.Rumble != nil {
+				c.parent.Rumble(motor)
+			}
+		}
 	case 0xA000 <= addr && addr < 0xC000:
 		// External RAM
 		if c.parent.RamBankEnabled && c.parent.RamBankCount > 0 {
-			ramBank := c.parent.RamBankSelected & c.parent.RamBankCount
+			ramBank := c.parent.RamBankSelected % c.parent.RamBankCount
 			c.parent.RamBanks[ramBank][addr-0xA000] = value
 		}
 	}
@@ -98,7 +119,7 @@ func (c *Mbc5Cartridge) GetItem(addr uint16) uint8 {
 	case 0xA000 <= addr && addr < 0xC000:
 		// External RAM
 		if c.parent.RamBankEnabled && c.parent.RamBankCount > 0 {
-			ramBank := c.parent.RamBankSelected & c.parent.RamBankCount
+			ramBank := c.parent.RamBankSelected % c.parent.RamBankCount
 
 			return c.parent.RamBanks[ramBank][addr-0xA000]
 		}
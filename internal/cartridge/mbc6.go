@@ -0,0 +1,288 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Mbc6Cartridge is the MBC6 of Net de Get: Minigame @ 100, with 1 MiB of
+// Macronix flash beside the ROM and 32 KiB of RAM. Both ROM areas are cut
+// into two independent windows, 4000-5FFF (A) and 6000-7FFF (B), each
+// showing an 8 KiB bank of ROM or of flash; A000-AFFF and B000-BFFF show
+// a 4 KiB RAM bank each.
+//
+//	$0000-$03FF  $0A enables RAM
+//	$0400-$07FF  RAM bank A, 0-7
+//	$0800-$0BFF  RAM bank B, 0-7
+//	$0C00-$0FFF  bit 0 enables flash; only while flash writes are enabled
+//	$1000        bit 0 enables flash writes
+//	$2000-$27FF  ROM/flash bank A
+//	$2800-$2FFF  $08 maps flash in window A, $00 ROM
+//	$3000-$37FF  ROM/flash bank B
+//	$3800-$3FFF  $08 maps flash in window B, $00 ROM
+//
+// The flash takes the usual unlock sequence, $AA to $5555 then $55 to
+// $2AAA in flash addresses (bank 2 at $5555 and bank 1 at $4AAA through
+// window A), followed by a command:
+//
+//	$A0  program the byte written next, which can only clear bits
+//	$80  erase, once unlocked again: $30 to a sector erases its 128 KiB,
+//	     $10 to $5555 the whole chip
+//	$90  ID mode: flash reads $C2 (Macronix) at even addresses and $81
+//	     (MX29F008) at odd ones
+//	$F0  back to reading the flash, also written outside a sequence
+//
+// Programs and erases finish at once, so status polls see the new data
+// straight away. The flash keeps itself without a battery and is saved
+// to <rom>.flash next to the RAM's <rom>.sav.
+type Mbc6Cartridge struct {
+	parent           *Cartridge
+	romBank          [2]uint8 // 8 KiB bank in windows A and B
+	romFlash         [2]bool  // window shows flash rather than ROM
+	ramBank          [2]uint8 // 4 KiB bank in windows A and B
+	flashEnabled     bool
+	flashWriteEnable bool
+	flashStep        uint8 // unlock writes seen
+	flashErase       bool  // $80 seen, waiting for the erase command
+	flashProgram     bool  // $A0 seen, waiting for the byte
+	flashID          bool
+	flash            [mbc6FlashSize]uint8
+	hasBattery       bool
+}
+
+const (
+	mbc6FlashSize   = 0x100000
+	mbc6SectorSize  = 0x20000
+	mbc6BankSize    = 0x2000
+	mbc6RamBankSize = 0x1000
+	mbc6Maker       = 0xc2 // Macronix
+	mbc6Device      = 0x81 // MX29F008
+)
+
+func (c *Mbc6Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romBank)          // ROM/flash banks
+	binary.Write(buf, binary.LittleEndian, c.romFlash)         // Windows mapping flash
+	binary.Write(buf, binary.LittleEndian, c.ramBank)          // RAM banks
+	binary.Write(buf, binary.LittleEndian, c.flashEnabled)     // Flash enabled
+	binary.Write(buf, binary.LittleEndian, c.flashWriteEnable) // Flash writes enabled
+	binary.Write(buf, binary.LittleEndian, c.flashStep)        // Flash unlock step
+	binary.Write(buf, binary.LittleEndian, c.flashErase)       // Flash erase pending
+	binary.Write(buf, binary.LittleEndian, c.flashProgram)     // Flash program pending
+	binary.Write(buf, binary.LittleEndian, c.flashID)          // Flash ID mode
+	binary.Write(buf, binary.LittleEndian, c.flash)            // Flash
+	binary.Write(buf, binary.LittleEndian, c.hasBattery)       // Has Battery
+	logger.Debug("Serialized MBC6 state")
+	return buf
+}
+
+func (c *Mbc6Cartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{
+		&c.romBank, &c.romFlash, &c.ramBank, &c.flashEnabled, &c.flashWriteEnable,
+		&c.flashStep, &c.flashErase, &c.flashProgram, &c.flashID, &c.flash,
+		&c.hasBattery,
+	} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Mbc6Cartridge) Init() error {
+	// 32 KiB of RAM, whatever the header says
+	c.parent.RamBankCount = 4
+	if err := c.loadFlash(c.parent.GetFilename()); err != nil {
+		return err
+	}
+	if c.hasBattery {
+		return LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
+	}
+	return nil
+}
+
+// loadFlash reads the flash from <romName>.flash, or leaves it erased
+// when there is no such file.
+func (c *Mbc6Cartridge) loadFlash(romName string) error {
+	data, err := os.ReadFile(romName + ".flash")
+	if errors.Is(err, fs.ErrNotExist) {
+		for i := range c.flash {
+			c.flash[i] = 0xff
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cartridge: reading flash file: %w", err)
+	}
+	if len(data) != mbc6FlashSize {
+		return fmt.Errorf("%w: %s.flash holds %d bytes, want %d", ErrSRAMSize, romName, len(data), mbc6FlashSize)
+	}
+	copy(c.flash[:], data)
+	logger.Infof("Loaded %d bytes of flash from %s.flash", len(data), romName)
+	return nil
+}
+
+func (c *Mbc6Cartridge) saveFlash(romName string) error {
+	if err := os.WriteFile(romName+".flash", c.flash[:], 0o644); err != nil {
+		return fmt.Errorf("cartridge: writing flash file: %w", err)
+	}
+	logger.Infof("Saved %d bytes of flash to %s.flash", mbc6FlashSize, romName)
+	return nil
+}
+
+func (c *Mbc6Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x0400:
+		c.parent.RamBankEnabled = (value & 0x0f) == 0x0a
+
+	case 0x0400 <= addr && addr < 0x0C00:
+		c.ramBank[addr>>11&0x01] = value & 0x07
+
+	case 0x0C00 <= addr && addr < 0x1000:
+		if c.flashWriteEnable {
+			c.flashEnabled = value&0x01 != 0
+		}
+
+	case 0x1000 <= addr && addr < 0x2000:
+		if addr == 0x1000 {
+			c.flashWriteEnable = value&0x01 != 0
+		}
+
+	case 0x2000 <= addr && addr < 0x4000:
+		w := addr >> 12 & 0x01
+		if addr&0x0800 == 0 {
+			c.romBank[w] = value & 0x7f
+		} else {
+			c.romFlash[w] = value&0x08 != 0
+		}
+
+	case 0x4000 <= addr && addr < 0x8000:
+		w := addr >> 13 & 0x01
+		if c.romFlash[w] && c.flashEnabled && c.flashWriteEnable {
+			c.writeFlash(uint32(c.romBank[w])*mbc6BankSize+uint32(addr&0x1fff), value)
+		}
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.parent.RamBankEnabled {
+			return
+		}
+		bank, offset := c.ram(addr)
+		c.parent.RamBanks[bank][offset] = value
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *Mbc6Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[0][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		w := addr >> 13 & 0x01
+		offset := uint32(addr & 0x1fff)
+		if c.romFlash[w] {
+			if !c.flashEnabled {
+				return 0xff
+			}
+			if c.flashID {
+				if offset&0x01 == 0 {
+					return mbc6Maker
+				}
+				return mbc6Device
+			}
+			return c.flash[uint32(c.romBank[w])*mbc6BankSize+offset]
+		}
+		// two 8 KiB banks to a 16 KiB one
+		bank := (uint16(c.romBank[w]) >> 1) % c.parent.RomBanksCount
+		c.parent.RomBankSelected = bank
+		return c.parent.RomBanks[bank][uint32(c.romBank[w]&0x01)*mbc6BankSize+offset]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.parent.RamBankEnabled {
+			return 0xff
+		}
+		bank, offset := c.ram(addr)
+		return c.parent.RamBanks[bank][offset]
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
+
+// ram returns where addr, in A000-BFFF, lands in the RAM banks.
+func (c *Mbc6Cartridge) ram(addr uint16) (bank uint16, offset uint16) {
+	b := uint16(c.ramBank[addr>>12&0x01])
+	bank = (b >> 1) % c.parent.RamBankCount
+	c.parent.RamBankSelected = bank
+	return bank, (b&0x01)*mbc6RamBankSize + addr&0x0fff
+}
+
+// writeFlash feeds a write to flash address a to the chip's command
+// sequencer.
+func (c *Mbc6Cartridge) writeFlash(a uint32, value uint8) {
+	if c.flashProgram {
+		c.flash[a] &= value
+		c.flashProgram = false
+		return
+	}
+	if value == 0xf0 {
+		c.flashStep, c.flashErase, c.flashID = 0, false, false
+		return
+	}
+
+	switch c.flashStep {
+	case 0:
+		if a&0x7fff == 0x5555 && value == 0xaa {
+			c.flashStep = 1
+		}
+	case 1:
+		c.flashStep = 0
+		if a&0x7fff == 0x2aaa && value == 0x55 {
+			c.flashStep = 2
+		}
+	case 2:
+		c.flashStep = 0
+		c.flashCommand(a, value)
+	}
+}
+
+func (c *Mbc6Cartridge) flashCommand(a uint32, value uint8) {
+	if c.flashErase {
+		c.flashErase = false
+		switch {
+		case value == 0x30:
+			sector := a &^ (mbc6SectorSize - 1)
+			c.erase(c.flash[sector : sector+mbc6SectorSize])
+		case value == 0x10 && a&0x7fff == 0x5555:
+			c.erase(c.flash[:])
+		}
+		return
+	}
+	if a&0x7fff != 0x5555 {
+		return
+	}
+	switch value {
+	case 0xa0:
+		c.flashProgram = true
+	case 0x80:
+		c.flashErase = true
+	case 0x90:
+		c.flashID = true
+	default:
+		logger.Debugf("MBC6: unknown flash command %#02x", value)
+	}
+}
+
+func (c *Mbc6Cartridge) erase(flash []uint8) {
+	for i := range flash {
+		flash[i] = 0xff
+	}
+}
@@ -0,0 +1,319 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Mbc7Cartridge is the MBC7 of Kirby Tilt 'n' Tumble and Command Master:
+// an accelerometer and a 93LC56 serial EEPROM in place of RAM. A000-AFFF
+// is a register window, open once both $0A is written to $0000-$1FFF and
+// $40 to $4000-$5FFF, with the register in address bits 7-4:
+//
+//	Ax0x  write $55 to erase the latched readings
+//	Ax1x  write $AA to latch the accelerometer, once erased
+//	Ax2x  X reading, low byte
+//	Ax3x  X reading, high byte
+//	Ax4x  Y reading, low byte
+//	Ax5x  Y reading, high byte
+//	Ax6x  reads $00
+//	Ax7x  reads $FF
+//	Ax8x  EEPROM pins: bit 7 CS, bit 6 CLK, bit 1 DI, bit 0 DO
+//
+// Level, both readings are $81D0; each g of tilt moves them $70, X up
+// tilting right and Y up tilting towards the player. Erased, they read
+// $8000.
+//
+// The EEPROM is 128 16-bit words, which the game bit-bangs a command at
+// a time while CS is high, DI read on each rising CLK edge: a start bit,
+// a 2-bit opcode and an 8-bit address, then data for writes.
+//
+//	10 aaaaaaaa          READ: DO gives a 0, then the words from a on
+//	01 aaaaaaaa dddd...  WRITE d to word a
+//	11 aaaaaaaa          ERASE word a to $FFFF
+//	00 11xxxxxx          EWEN: allow writes and erases
+//	00 00xxxxxx          EWDS: forbid them again
+//	00 01xxxxxx dddd...  WRAL: write d to every word
+//	00 10xxxxxx          ERAL: erase every word
+//
+// Writes finish at once, so DO reads 1, ready, outside of READ. The words
+// sit little-endian at the start of RAM bank 0, and the save file holds
+// those 256 bytes alone.
+type Mbc7Cartridge struct {
+	parent        *Cartridge
+	romBankSelect uint16
+	ramEnable2    bool // $40 written to $4000-$5FFF
+
+	tiltX, tiltY float64 // tilt in g, from the player
+	x, y         uint16  // latched readings
+	erased       bool    // readings erased, ready to latch
+
+	pins         uint8  // EEPROM pins as last written
+	eeprom       uint8  // EEPROM protocol state
+	bits         uint8  // bits shifted in or out in this state
+	shift        uint16 // bits shifted in
+	addr         uint8  // word addressed
+	do           bool   // EEPROM data out
+	writeEnabled bool
+	hasBattery   bool
+}
+
+const (
+	mbc7Level      = 0x81D0 // reading with the cartridge level
+	mbc7PerG       = 0x70   // reading change per g of tilt
+	mbc7Erased     = 0x8000
+	mbc7EEPROMSize = 0x100
+)
+
+// EEPROM protocol states.
+const (
+	mbc7Idle     = iota // waiting for CS
+	mbc7Start           // waiting for the start bit
+	mbc7Command         // shifting in opcode and address
+	mbc7Read            // shifting out words
+	mbc7Write           // shifting in data for WRITE
+	mbc7WriteAll        // shifting in data for WRAL
+	mbc7Done            // command run, waiting for CS to drop
+)
+
+func (c *Mbc7Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romBankSelect) // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.ramEnable2)    // RAM Enable 2
+	binary.Write(buf, binary.LittleEndian, c.x)             // Latched X
+	binary.Write(buf, binary.LittleEndian, c.y)             // Latched Y
+	binary.Write(buf, binary.LittleEndian, c.erased)        // Readings erased
+	binary.Write(buf, binary.LittleEndian, c.pins)          // EEPROM pins
+	binary.Write(buf, binary.LittleEndian, c.eeprom)        // EEPROM state
+	binary.Write(buf, binary.LittleEndian, c.bits)          // EEPROM bit count
+	binary.Write(buf, binary.LittleEndian, c.shift)         // EEPROM shift register
+	binary.Write(buf, binary.LittleEndian, c.addr)          // EEPROM address
+	binary.Write(buf, binary.LittleEndian, c.do)            // EEPROM data out
+	binary.Write(buf, binary.LittleEndian, c.writeEnabled)  // EEPROM writes enabled
+	binary.Write(buf, binary.LittleEndian, c.hasBattery)    // Has Battery
+	logger.Debug("Serialized MBC7 state")
+	return buf
+}
+
+func (c *Mbc7Cartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{
+		&c.romBankSelect, &c.ramEnable2, &c.x, &c.y, &c.erased,
+		&c.pins, &c.eeprom, &c.bits, &c.shift, &c.addr, &c.do, &c.writeEnabled,
+		&c.hasBattery,
+	} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Mbc7Cartridge) Init() error {
+	// the header declares no RAM, the EEPROM lives in bank 0
+	c.parent.RamBankCount = 1
+	c.x, c.y = mbc7Erased, mbc7Erased
+	c.do = true
+	if c.hasBattery {
+		return loadSRAMSize(c.parent.GetFilename(), &c.parent.RamBanks, mbc7EEPROMSize)
+	}
+	return nil
+}
+
+func (c *Mbc7Cartridge) sramSize() int {
+	return mbc7EEPROMSize
+}
+
+// SetTilt tilts the cartridge x g to the right and y g towards the
+// player. The game sees it at its next latch.
+func (c *Mbc7Cartridge) SetTilt(x, y float64) {
+	c.tiltX, c.tiltY = x, y
+}
+
+// mbc7Reading returns what the accelerometer reads at g of tilt.
+func mbc7Reading(g float64) uint16 {
+	v := mbc7Level + g*mbc7PerG
+	return uint16(min(max(v, 0), 0xffff))
+}
+
+func (c *Mbc7Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x2000:
+		c.parent.RamBankEnabled = (value & 0x0f) == 0x0a
+
+	case 0x2000 <= addr && addr < 0x4000:
+		c.romBankSelect = uint16(value)
+
+	case 0x4000 <= addr && addr < 0x6000:
+		c.ramEnable2 = value == 0x40
+
+	case 0x6000 <= addr && addr < 0x8000:
+		// nothing here on MBC7
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.registersOpen() || addr >= 0xB000 {
+			return
+		}
+		switch addr >> 4 & 0x0f {
+		case 0x0:
+			if value == 0x55 {
+				c.x, c.y = mbc7Erased, mbc7Erased
+				c.erased = true
+			}
+		case 0x1:
+			if value == 0xaa && c.erased {
+				c.x, c.y = mbc7Reading(c.tiltX), mbc7Reading(c.tiltY)
+				c.erased = false
+			}
+		case 0x8:
+			c.setPins(value)
+		}
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *Mbc7Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[0][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		c.parent.RomBankSelected = c.romBankSelect % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.registersOpen() || addr >= 0xB000 {
+			return 0xff
+		}
+		switch addr >> 4 & 0x0f {
+		case 0x2:
+			return uint8(c.x)
+		case 0x3:
+			return uint8(c.x >> 8)
+		case 0x4:
+			return uint8(c.y)
+		case 0x5:
+			return uint8(c.y >> 8)
+		case 0x6:
+			return 0x00
+		case 0x8:
+			v := c.pins & 0xc2
+			if c.do {
+				v |= 0x01
+			}
+			return v
+		}
+		return 0xff
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
+
+func (c *Mbc7Cartridge) registersOpen() bool {
+	return c.parent.RamBankEnabled && c.ramEnable2
+}
+
+func (c *Mbc7Cartridge) word(i uint8) uint16 {
+	i &= 0x7f
+	return binary.LittleEndian.Uint16(c.parent.RamBanks[0][2*int(i):])
+}
+
+func (c *Mbc7Cartridge) setWord(i uint8, v uint16) {
+	i &= 0x7f
+	binary.LittleEndian.PutUint16(c.parent.RamBanks[0][2*int(i):], v)
+}
+
+// setPins drives the EEPROM's pins.
+func (c *Mbc7Cartridge) setPins(value uint8) {
+	cs, clk, di := value&0x80 != 0, value&0x40 != 0, value&0x02 != 0
+	rising := clk && c.pins&0x40 == 0
+	c.pins = value
+
+	if !cs {
+		c.eeprom, c.do = mbc7Idle, true
+		return
+	}
+	if c.eeprom == mbc7Idle {
+		c.eeprom = mbc7Start
+	}
+	if !rising {
+		return
+	}
+
+	var bit uint16
+	if di {
+		bit = 1
+	}
+	switch c.eeprom {
+	case mbc7Start:
+		if di {
+			c.eeprom, c.bits, c.shift = mbc7Command, 0, 0
+		}
+
+	case mbc7Command:
+		c.shift = c.shift<<1 | bit
+		c.bits++
+		if c.bits == 10 {
+			c.command(uint8(c.shift>>8), uint8(c.shift))
+		}
+
+	case mbc7Read:
+		c.do = c.word(c.addr)&(0x8000>>c.bits) != 0
+		c.bits++
+		if c.bits == 16 {
+			c.bits = 0
+			c.addr = (c.addr + 1) & 0x7f
+		}
+
+	case mbc7Write, mbc7WriteAll:
+		c.shift = c.shift<<1 | bit
+		c.bits++
+		if c.bits < 16 {
+			return
+		}
+		if c.writeEnabled {
+			if c.eeprom == mbc7Write {
+				c.setWord(c.addr, c.shift)
+			} else {
+				for i := range mbc7EEPROMSize / 2 {
+					c.setWord(uint8(i), c.shift)
+				}
+			}
+		}
+		c.eeprom = mbc7Done
+	}
+}
+
+func (c *Mbc7Cartridge) command(op, addr uint8) {
+	c.addr, c.bits, c.shift = addr&0x7f, 0, 0
+	c.eeprom = mbc7Done
+	switch op {
+	case 0x2: // READ
+		c.eeprom, c.do = mbc7Read, false
+	case 0x1: // WRITE
+		c.eeprom = mbc7Write
+	case 0x3: // ERASE
+		if c.writeEnabled {
+			c.setWord(c.addr, 0xffff)
+		}
+	case 0x0:
+		switch addr >> 6 {
+		case 0x3: // EWEN
+			c.writeEnabled = true
+		case 0x0: // EWDS
+			c.writeEnabled = false
+		case 0x1: // WRAL
+			c.eeprom = mbc7WriteAll
+		case 0x2: // ERAL
+			if c.writeEnabled {
+				for i := range mbc7EEPROMSize / 2 {
+					c.setWord(uint8(i), 0xffff)
+				}
+			}
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package cartridge
 
 import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/duysqubix/gobc/internal"
 	"github.com/stretchr/testify/assert"
@@ -27,11 +33,10 @@ func mbcNewTestCart(romBanks, ramBanks int) *Cartridge {
 		RomBanksCount: uint16(romBanks),
 		RamBankCount:  uint16(ramBanks),
 		MemoryModel:   0,
+		Rtc:           NewRTC(),
 	}
 }
 
-func mbcResetGrtc() { *Grtc = RTC{} }
-
 func TestROMOnly_GetItem(t *testing.T) {
 	cart := mbcNewTestCart(2, 0)
 	rom := &RomOnlyCartridge{parent: cart}
@@ -132,131 +137,1597 @@ func TestMBC1_GetItem_SwitchableBank(t *testing.T) {
 	}
 }
 
-// High bank bits live in the 0x4000-0x5FFF register, not the lower-byte
-// register. Writing 1 to each selects bank (1<<5)|1 == 0x21.
-func TestMBC1_UpperBankBits_Mode0(t *testing.T) {
-	_, mbc := mbcNewMBC1(t, 64, 1)
-	mbc.SetItem(0x2000, 0x01)
-	mbc.SetItem(0x4000, 0x01)
-	assert.Equal(t, uint8(0x21), mbc.GetItem(0x4000))
+// High bank bits live in the 0x4000-0x5FFF register, not the lower-byte
+// register. Writing 1 to each selects bank (1<<5)|1 == 0x21.
+func TestMBC1_UpperBankBits_Mode0(t *testing.T) {
+	_, mbc := mbcNewMBC1(t, 64, 1)
+	mbc.SetItem(0x2000, 0x01)
+	mbc.SetItem(0x4000, 0x01)
+	assert.Equal(t, uint8(0x21), mbc.GetItem(0x4000))
+}
+
+func TestMBC1_UpperBankBits_MaskedTo2Bits(t *testing.T) {
+	_, mbc := mbcNewMBC1(t, 128, 1)
+	mbc.SetItem(0x2000, 0x01)
+	mbc.SetItem(0x4000, 0xFF)
+	assert.Equal(t, uint8(0x61), mbc.GetItem(0x4000))
+}
+
+func TestMBC1_BankingMode_Register(t *testing.T) {
+	cart, mbc := mbcNewMBC1(t, 64, 4)
+
+	mbc.SetItem(0x6000, 0x01)
+	assert.True(t, mbc.mode)
+	assert.Equal(t, uint8(1), cart.MemoryModel)
+
+	mbc.SetItem(0x7FFF, 0x00)
+	assert.False(t, mbc.mode)
+	assert.Equal(t, uint8(0), cart.MemoryModel)
+
+	mbc.SetItem(0x6000, 0xFE)
+	assert.False(t, mbc.mode)
+	mbc.SetItem(0x6000, 0xFF)
+	assert.True(t, mbc.mode)
+}
+
+func TestMBC1_Mode1_AffectsLowBankRead(t *testing.T) {
+	_, mbc := mbcNewMBC1(t, 64, 1)
+	mbc.SetItem(0x4000, 0x01)
+	mbc.SetItem(0x6000, 0x01)
+	assert.Equal(t, uint8(0x20), mbc.GetItem(0x0000))
+}
+
+func TestMBC1_RAMEnable(t *testing.T) {
+	cart, mbc := mbcNewMBC1(t, 8, 1)
+
+	assert.False(t, cart.RamBankEnabled)
+
+	mbc.SetItem(0x0000, 0x0A)
+	assert.True(t, cart.RamBankEnabled)
+
+	mbc.SetItem(0x0000, 0x00)
+	assert.False(t, cart.RamBankEnabled)
+
+	mbc.SetItem(0x0000, 0x0A)
+	assert.True(t, cart.RamBankEnabled)
+	mbc.SetItem(0x1FFF, 0xFF)
+	assert.False(t, cart.RamBankEnabled)
+
+	mbc.SetItem(0x0000, 0x1A)
+	assert.True(t, cart.RamBankEnabled)
+}
+
+func TestMBC1_RAM_DisabledReadsFF(t *testing.T) {
+	_, mbc := mbcNewMBC1(t, 8, 1)
+	for _, addr := range []uint16{0xA000, 0xABCD, 0xBFFF} {
+		assert.Equal(t, uint8(0xFF), mbc.GetItem(addr))
+	}
+}
+
+func TestMBC1_RAM_DisabledWritesDropped(t *testing.T) {
+	cart, mbc := mbcNewMBC1(t, 8, 1)
+	mbc.SetItem(0xA000, 0x42)
+	assert.Equal(t, uint8(0x00), cart.RamBanks[0][0])
+}
+
+func TestMBC1_RAM_EnabledReadWrite(t *testing.T) {
+	cart, mbc := mbcNewMBC1(t, 8, 4)
+
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0xA000, 0xAB)
+	mbc.SetItem(0xA001, 0xCD)
+	assert.Equal(t, uint8(0xAB), mbc.GetItem(0xA000))
+	assert.Equal(t, uint8(0xCD), mbc.GetItem(0xA001))
+	assert.Equal(t, uint8(0xAB), cart.RamBanks[0][0])
+}
+
+func TestMBC1_RAM_Mode1_BankSwitch(t *testing.T) {
+	cart, mbc := mbcNewMBC1(t, 8, 4)
+
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0x6000, 0x01)
+	mbc.SetItem(0x4000, 0x02)
+
+	mbc.SetItem(0xA000, 0x77)
+	assert.Equal(t, uint8(0x77), cart.RamBanks[2][0])
+
+	mbc.SetItem(0x4000, 0x03)
+	mbc.SetItem(0xA000, 0x88)
+	assert.Equal(t, uint8(0x88), cart.RamBanks[3][0])
+	assert.Equal(t, uint8(0x77), cart.RamBanks[2][0])
+
+	mbc.SetItem(0x4000, 0x02)
+	assert.Equal(t, uint8(0x77), mbc.GetItem(0xA000))
+	mbc.SetItem(0x4000, 0x03)
+	assert.Equal(t, uint8(0x88), mbc.GetItem(0xA000))
+}
+
+func TestMBC1_SerializeRoundtrip(t *testing.T) {
+	_, mbc := mbcNewMBC1(t, 8, 1)
+	mbc.SetItem(0x2000, 0x05)
+	mbc.SetItem(0x4000, 0x02)
+	mbc.SetItem(0x6000, 0x01)
+	mbc.hasBattery = true
+
+	buf := mbc.Serialize()
+	other := &Mbc1Cartridge{parent: mbcNewTestCart(8, 1)}
+	require.NoError(t, other.Deserialize(buf))
+
+	assert.Equal(t, uint16(5), other.romBankSelect)
+	assert.Equal(t, uint16(2), other.ramBankSelect)
+	assert.True(t, other.mode)
+	assert.True(t, other.hasBattery)
+}
+
+func TestMBC1_Init_NoBatteryIsNoOp(t *testing.T) {
+	_, mbc := mbcNewMBC1(t, 8, 0)
+	assert.NotPanics(t, func() { mbc.Init() })
+}
+
+func TestMBC1M_Detection(t *testing.T) {
+	cart, mbc := mbcNewMBC1(t, 64, 0)
+	require.NoError(t, mbc.Init())
+	assert.False(t, mbc.multicart, "1 MiB without a second header")
+
+	copy(cart.RomBanks[0x10][NINTENDO_LOGO_START_ADDR:], nintendoLogo[:])
+	require.NoError(t, mbc.Init())
+	assert.True(t, mbc.multicart)
+
+	cart, mbc = mbcNewMBC1(t, 32, 0)
+	copy(cart.RomBanks[0x10][NINTENDO_LOGO_START_ADDR:], nintendoLogo[:])
+	require.NoError(t, mbc.Init())
+	assert.False(t, mbc.multicart, "only 1 MiB multicarts exist")
+}
+
+func TestMBC1M_Banking(t *testing.T) {
+	cart, mbc := mbcNewMBC1(t, 64, 0)
+	copy(cart.RomBanks[0x10][NINTENDO_LOGO_START_ADDR:], nintendoLogo[:])
+	require.NoError(t, mbc.Init())
+
+	cases := []struct {
+		name      string
+		upper     uint8
+		lower     uint8
+		wantBank0 uint8
+		wantBankN uint8
+	}{
+		{"first game", 0, 0x03, 0x00, 0x03},
+		{"second game", 1, 0x03, 0x00, 0x13},
+		{"bit 4 not wired", 1, 0x13, 0x00, 0x13},
+		{"bank 0 quirk on 5 bits", 2, 0x10, 0x00, 0x20},
+		{"zero becomes one", 3, 0x00, 0x00, 0x31},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mbc.SetItem(0x6000, 0x00)
+			mbc.SetItem(0x4000, tc.upper)
+			mbc.SetItem(0x2000, tc.lower)
+			assert.Equal(t, tc.wantBank0, mbc.GetItem(0x0000))
+			assert.Equal(t, tc.wantBankN, mbc.GetItem(0x4000))
+		})
+	}
+
+	// mode 1 maps each game's first bank at $0000
+	mbc.SetItem(0x6000, 0x01)
+	mbc.SetItem(0x4000, 0x02)
+	assert.Equal(t, uint8(0x20), mbc.GetItem(0x0000))
+}
+
+func mbcNewMBC2(t *testing.T, romBanks int) (*Cartridge, *Mbc2Cartridge) {
+	t.Helper()
+	cart := mbcNewTestCart(romBanks, 0)
+	mbc := &Mbc2Cartridge{parent: cart, romBankSelect: 1}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
+	return cart, mbc
+}
+
+func TestMBC2_Init_BringsOwnRAM(t *testing.T) {
+	cart, _ := mbcNewMBC2(t, 16)
+	assert.Equal(t, uint16(1), cart.RamBankCount)
+}
+
+func TestMBC2_RegisterSelectedByAddressBit8(t *testing.T) {
+	cart, mbc := mbcNewMBC2(t, 16)
+
+	mbc.SetItem(0x0100, 0x0A)
+	assert.False(t, cart.RamBankEnabled, "A8 set writes the ROM bank")
+	assert.Equal(t, uint8(0x0A), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x3EFF, 0x0A)
+	assert.True(t, cart.RamBankEnabled, "A8 clear writes RAM enable")
+	assert.Equal(t, uint8(0x0A), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x2000, 0x1A)
+	assert.True(t, cart.RamBankEnabled)
+	mbc.SetItem(0x2000, 0x00)
+	assert.False(t, cart.RamBankEnabled)
+}
+
+func TestMBC2_GetItem_SwitchableBank(t *testing.T) {
+	cases := []struct {
+		name     string
+		writeVal uint8
+		wantBank uint8
+	}{
+		{"write_3_selects_3", 0x03, 0x03},
+		{"write_15_selects_15", 0x0F, 0x0F},
+		{"write_0_becomes_1", 0x00, 0x01},
+		{"upper_bits_masked_off", 0xF2, 0x02},
+		{"zero_after_mask_becomes_1", 0x10, 0x01},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mbc := mbcNewMBC2(t, 16)
+			mbc.SetItem(0x2100, tc.writeVal)
+			assert.Equal(t, tc.wantBank, mbc.GetItem(0x4000))
+			assert.Equal(t, tc.wantBank, mbc.GetItem(0x7FFF))
+			assert.Equal(t, uint8(0), mbc.GetItem(0x3FFF), "bank 0 is fixed")
+		})
+	}
+}
+
+func TestMBC2_GetItem_BankWrapAround(t *testing.T) {
+	_, mbc := mbcNewMBC2(t, 4)
+	mbc.SetItem(0x2100, 0x06)
+	assert.Equal(t, uint8(2), mbc.GetItem(0x4000))
+}
+
+func TestMBC2_RAM_DisabledReadsFF(t *testing.T) {
+	cart, mbc := mbcNewMBC2(t, 16)
+	cart.RamBanks[0][0] = 0x05
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA000))
+
+	mbc.SetItem(0xA001, 0x03)
+	mbc.SetItem(0x0000, 0x0A)
+	assert.Equal(t, uint8(0xF0), mbc.GetItem(0xA001), "writes while disabled are dropped")
+}
+
+func TestMBC2_RAM_HalfBytes(t *testing.T) {
+	_, mbc := mbcNewMBC2(t, 16)
+	mbc.SetItem(0x0000, 0x0A)
+
+	mbc.SetItem(0xA000, 0x5C)
+	assert.Equal(t, uint8(0xFC), mbc.GetItem(0xA000), "upper nibble reads as 1s")
+	mbc.SetItem(0xA1FF, 0x03)
+	assert.Equal(t, uint8(0xF3), mbc.GetItem(0xA1FF))
+}
+
+func TestMBC2_RAM_Echo(t *testing.T) {
+	_, mbc := mbcNewMBC2(t, 16)
+	mbc.SetItem(0x0000, 0x0A)
+
+	mbc.SetItem(0xA012, 0x07)
+	for _, addr := range []uint16{0xA212, 0xA812, 0xB012, 0xBE12} {
+		assert.Equal(t, uint8(0xF7), mbc.GetItem(addr), "%#04x", addr)
+	}
+	mbc.SetItem(0xBFFF, 0x09)
+	assert.Equal(t, uint8(0xF9), mbc.GetItem(0xA1FF))
+}
+
+func TestMBC2_SerializeRoundtrip(t *testing.T) {
+	_, mbc := mbcNewMBC2(t, 16)
+	mbc.SetItem(0x2100, 0x09)
+	mbc.hasBattery = true
+
+	buf := mbc.Serialize()
+	other := &Mbc2Cartridge{parent: mbcNewTestCart(16, 0)}
+	require.NoError(t, other.Deserialize(buf))
+
+	assert.Equal(t, uint16(9), other.romBankSelect)
+	assert.True(t, other.hasBattery)
+}
+
+func TestMBC2_SaveIs512Bytes(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0x06
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+
+	// as other emulators save it, upper nibbles set
+	sav := bytes.Repeat([]byte{0xF0}, mbc2RamSize)
+	sav[0x000], sav[0x1FF] = 0xF3, 0xFC
+	require.NoError(t, os.WriteFile("ffl.sav", sav, 0o644))
+
+	cart, err := NewCartridgeFromBytes("ffl", rom)
+	require.NoError(t, err)
+	mbc := cart.CartType.(*Mbc2Cartridge)
+	mbc.SetItem(0x0000, 0x0A)
+	assert.Equal(t, uint8(0xF3), mbc.GetItem(0xA000))
+	assert.Equal(t, uint8(0xFC), mbc.GetItem(0xA1FF))
+	assert.Equal(t, mbc2RamSize, cart.SRAMSize())
+
+	mbc.SetItem(0xA100, 0x05)
+	require.NoError(t, cart.Save())
+	saved, err := os.ReadFile("ffl.sav")
+	require.NoError(t, err)
+	require.Len(t, saved, mbc2RamSize)
+	assert.Equal(t, []byte{0x03, 0x05, 0x0C}, []byte{saved[0x000], saved[0x100], saved[0x1FF]})
+}
+
+func mbcNewMMM01(t *testing.T, romBanks, ramBanks int) (*Cartridge, *Mmm01Cartridge) {
+	t.Helper()
+	cart := mbcNewTestCart(romBanks, ramBanks)
+	mbc := &Mmm01Cartridge{parent: cart}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
+	return cart, mbc
+}
+
+// mmm01SelectGame does what a compilation's menu does for a game of 8
+// banks at bank 32: base in ROM bank mid, bits 4-3 fixed by the mask.
+func mmm01SelectGame(mbc *Mmm01Cartridge) {
+	mbc.SetItem(0x2000, 0x20)
+	mbc.SetItem(0x6000, 0x30)
+	mbc.SetItem(0x0000, 0x40)
+}
+
+func TestMMM01_Unmapped_ShowsLast32K(t *testing.T) {
+	_, mbc := mbcNewMMM01(t, 64, 0)
+	assert.Equal(t, uint8(62), mbc.GetItem(0x0000))
+	assert.Equal(t, uint8(63), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x2000, 0x05)
+	mbc.SetItem(0x4000, 0x30)
+	assert.Equal(t, uint8(62), mbc.GetItem(0x3FFF), "bank writes wait for the map bit")
+	assert.Equal(t, uint8(63), mbc.GetItem(0x7FFF))
+}
+
+func TestMMM01_Mapped_GameWindow(t *testing.T) {
+	_, mbc := mbcNewMMM01(t, 64, 0)
+	mmm01SelectGame(mbc)
+	assert.True(t, mbc.mapped)
+
+	assert.Equal(t, uint8(32), mbc.GetItem(0x0000), "the game's bank 0 is the base")
+	assert.Equal(t, uint8(33), mbc.GetItem(0x4000))
+
+	cases := []struct {
+		name     string
+		writeVal uint8
+		wantBank uint8
+	}{
+		{"write_5_selects_base_plus_5", 0x05, 37},
+		{"fixed_bits_ignored", 0x1F, 39},
+		{"zero_becomes_1", 0x18, 33},
+		{"mid_bits_frozen", 0x62, 34},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mbc.SetItem(0x2000, tc.writeVal)
+			assert.Equal(t, tc.wantBank, mbc.GetItem(0x4000))
+			assert.Equal(t, uint8(32), mbc.GetItem(0x0000))
+		})
+	}
+}
+
+func TestMMM01_Mapped_RegistersLocked(t *testing.T) {
+	_, mbc := mbcNewMMM01(t, 512, 0)
+	mbc.SetItem(0x4000, 0x40) // mode lock
+	mmm01SelectGame(mbc)
+
+	mbc.SetItem(0x6000, 0x01)
+	assert.False(t, mbc.mode, "mode write disabled")
+	mbc.SetItem(0x6000, 0x7C)
+	assert.Equal(t, uint8(0x18), mbc.romMask)
+	assert.False(t, mbc.multiplex)
+	mbc.SetItem(0x4000, 0x3C)
+	assert.Zero(t, mbc.romHigh)
+	assert.Zero(t, mbc.ramHigh)
+	mbc.SetItem(0x0000, 0x30)
+	assert.Zero(t, mbc.ramMask)
+	assert.True(t, mbc.mapped, "only a reset unmaps")
+}
+
+func TestMMM01_ROMBankHigh(t *testing.T) {
+	cart, mbc := mbcNewMMM01(t, 512, 0)
+	for i := range cart.RomBanks {
+		cart.RomBanks[i][0] = uint8(i >> 1)
+	}
+	mbc.SetItem(0x4000, 0x30)
+	mbc.SetItem(0x0000, 0x40)
+	assert.Equal(t, uint8(0x180>>1), mbc.GetItem(0x0000))
+	mbc.SetItem(0x2000, 0x02)
+	assert.Equal(t, uint8(0x182>>1), mbc.GetItem(0x4000))
+}
+
+func TestMMM01_Multiplex(t *testing.T) {
+	_, mbc := mbcNewMMM01(t, 128, 4)
+	mbc.SetItem(0x6000, 0x40)
+	mbc.SetItem(0x0000, 0x4A)
+
+	mbc.SetItem(0x4000, 0x01)
+	assert.Equal(t, uint8(33), mbc.GetItem(0x4000), "RAM bank low drives ROM bank bits 6-5")
+	assert.Equal(t, uint8(0), mbc.GetItem(0x0000), "mode 0 keeps them off bank 0")
+
+	mbc.SetItem(0x6000, 0x01)
+	assert.Equal(t, uint8(32), mbc.GetItem(0x0000))
+}
+
+func TestMMM01_RAM(t *testing.T) {
+	cart, mbc := mbcNewMMM01(t, 64, 4)
+	mbc.SetItem(0x0000, 0x4A)
+	mbc.SetItem(0x6000, 0x01)
+
+	mbc.SetItem(0x4000, 0x02)
+	mbc.SetItem(0xA010, 0x5A)
+	assert.Equal(t, uint8(0x5A), cart.RamBanks[2][0x10])
+	assert.Equal(t, uint8(0x5A), mbc.GetItem(0xA010))
+
+	mbc.SetItem(0x6000, 0x00)
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0xA010), "mode 0 pins RAM bank 0")
+
+	mbc.SetItem(0x0000, 0x00)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA010))
+	mbc.SetItem(0xA010, 0x11)
+	assert.Equal(t, uint8(0x00), cart.RamBanks[0][0x10])
+}
+
+func TestMMM01_SerializeRoundtrip(t *testing.T) {
+	_, mbc := mbcNewMMM01(t, 64, 4)
+	mbc.SetItem(0x4000, 0x7E)
+	mbc.SetItem(0x6000, 0x75)
+	mbc.SetItem(0x2000, 0x6B)
+	mbc.SetItem(0x0000, 0x7A)
+	mbc.hasBattery = true
+
+	buf := mbc.Serialize()
+	other := &Mmm01Cartridge{parent: mbcNewTestCart(64, 4)}
+	require.NoError(t, other.Deserialize(buf))
+
+	other.parent = mbc.parent
+	assert.Equal(t, mbc, other)
+	assert.Equal(t, uint8(0x0B), other.romLow)
+	assert.Equal(t, uint8(0x03), other.romHigh)
+	assert.Equal(t, uint8(0x1A), other.romMask)
+	assert.True(t, other.mapped)
+	assert.True(t, other.multiplex)
+}
+
+// mbcTransceiver is an IR transceiver that records the LED and sees
+// whatever light is set.
+type mbcTransceiver struct {
+	led, light bool
+}
+
+func (r *mbcTransceiver) SetLED(on bool) { r.led = on }
+func (r *mbcTransceiver) Light() bool    { return r.light }
+
+func mbcNewHuC1(t *testing.T, romBanks, ramBanks int) (*Cartridge, *HuC1Cartridge, *mbcTransceiver) {
+	t.Helper()
+	cart := mbcNewTestCart(romBanks, ramBanks)
+	ir := &mbcTransceiver{}
+	cart.IR = ir
+	mbc := &HuC1Cartridge{parent: cart, romBankSelect: 1}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
+	return cart, mbc, ir
+}
+
+func TestHuC1_GetItem_SwitchableBank(t *testing.T) {
+	cases := []struct {
+		name     string
+		writeVal uint8
+		wantBank uint8
+	}{
+		{"write_5_selects_5", 0x05, 0x05},
+		{"write_63_selects_63", 0x3F, 0x3F},
+		{"write_0_becomes_1", 0x00, 0x01},
+		{"upper_bits_masked_off", 0xC7, 0x07},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mbc, _ := mbcNewHuC1(t, 64, 1)
+			mbc.SetItem(0x2000, tc.writeVal)
+			assert.Equal(t, tc.wantBank, mbc.GetItem(0x4000))
+			assert.Equal(t, uint8(0), mbc.GetItem(0x0000))
+		})
+	}
+}
+
+func TestHuC1_RAM_BankSwitch(t *testing.T) {
+	cart, mbc, _ := mbcNewHuC1(t, 8, 4)
+
+	mbc.SetItem(0x4000, 0x02)
+	mbc.SetItem(0xA000, 0x77)
+	assert.Equal(t, uint8(0x77), cart.RamBanks[2][0], "RAM needs no enable")
+
+	mbc.SetItem(0x4000, 0x03)
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0xA000))
+	mbc.SetItem(0x4000, 0x02)
+	assert.Equal(t, uint8(0x77), mbc.GetItem(0xA000))
+	mbc.SetItem(0x6000, 0x01)
+	assert.Equal(t, uint8(0x77), mbc.GetItem(0xA000), "no banking mode")
+}
+
+func TestHuC1_IRMode(t *testing.T) {
+	cart, mbc, ir := mbcNewHuC1(t, 8, 1)
+	mbc.SetItem(0xA000, 0x42)
+
+	mbc.SetItem(0x0000, 0x0E)
+	assert.Equal(t, uint8(0xC0), mbc.GetItem(0xA000))
+	ir.light = true
+	assert.Equal(t, uint8(0xC1), mbc.GetItem(0xB123))
+
+	mbc.SetItem(0xA000, 0xFF)
+	assert.True(t, ir.led)
+	mbc.SetItem(0xA000, 0xFE)
+	assert.False(t, ir.led)
+	assert.Equal(t, uint8(0x42), cart.RamBanks[0][0], "IR writes leave RAM alone")
+
+	mbc.SetItem(0x0000, 0x0A)
+	assert.Equal(t, uint8(0x42), mbc.GetItem(0xA000))
+	mbc.SetItem(0xA000, 0x01)
+	assert.False(t, ir.led)
+}
+
+func TestHuC1_IRMode_NothingPluggedIn(t *testing.T) {
+	cart, mbc, _ := mbcNewHuC1(t, 8, 1)
+	cart.IR = nil
+	mbc.SetItem(0x0000, 0x0E)
+	assert.NotPanics(t, func() { mbc.SetItem(0xA000, 0x01) })
+	assert.Equal(t, uint8(0xC0), mbc.GetItem(0xA000))
+}
+
+func TestHuC1_SerializeRoundtrip(t *testing.T) {
+	_, mbc, _ := mbcNewHuC1(t, 64, 4)
+	mbc.SetItem(0x2000, 0x21)
+	mbc.SetItem(0x4000, 0x03)
+	mbc.SetItem(0x0000, 0x0E)
+	mbc.hasBattery = true
+
+	buf := mbc.Serialize()
+	other := &HuC1Cartridge{parent: mbcNewTestCart(64, 4)}
+	require.NoError(t, other.Deserialize(buf))
+
+	assert.Equal(t, uint16(0x21), other.romBankSelect)
+	assert.Equal(t, uint16(3), other.ramBankSelect)
+	assert.True(t, other.irMode)
+	assert.True(t, other.hasBattery)
+}
+
+func mbcNewHuC3(t *testing.T, romBanks, ramBanks int) (*Cartridge, *HuC3Cartridge, *mbcTransceiver) {
+	t.Helper()
+	cart := mbcNewTestCart(romBanks, ramBanks)
+	ir := &mbcTransceiver{}
+	cart.IR = ir
+	mbc := &HuC3Cartridge{parent: cart, romBankSelect: 1}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
+	return cart, mbc, ir
+}
+
+// huc3Commands sends commands to the HuC3 the way games do: mode $B,
+// the command, then the semaphore.
+func huc3Commands(mbc *HuC3Cartridge, cmds ...uint8) {
+	for _, cmd := range cmds {
+		mbc.SetItem(0x0000, 0x0B)
+		mbc.SetItem(0xA000, cmd)
+		mbc.SetItem(0x0000, 0x0D)
+		mbc.SetItem(0xA000, 0xFE)
+	}
+}
+
+// huc3Read reads n nibbles from the scratch address on, lowest first.
+func huc3Read(t *testing.T, mbc *HuC3Cartridge, addr uint8, n int) []uint8 {
+	t.Helper()
+	huc3Commands(mbc, 0x40|addr&0x0F, 0x50|addr>>4)
+	out := make([]uint8, n)
+	for i := range out {
+		huc3Commands(mbc, 0x10)
+		mbc.SetItem(0x0000, 0x0D)
+		require.Equal(t, uint8(0x01), mbc.GetItem(0xA000))
+		mbc.SetItem(0x0000, 0x0C)
+		got := mbc.GetItem(0xA000)
+		require.Equal(t, uint8(0x10), got&0xF0, "the response echoes the command")
+		out[i] = got & 0x0F
+	}
+	return out
+}
+
+func TestHuC3_Banking(t *testing.T) {
+	cart, mbc, _ := mbcNewHuC3(t, 128, 4)
+	mbc.SetItem(0x2000, 0x00)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x4000), "bank 0 maps as itself")
+	mbc.SetItem(0x2000, 0xFF)
+	assert.Equal(t, uint8(0x7F), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0x4000, 0x02)
+	mbc.SetItem(0xA123, 0x99)
+	assert.Equal(t, uint8(0x99), cart.RamBanks[2][0x123])
+
+	mbc.SetItem(0x0000, 0x00)
+	assert.Equal(t, uint8(0x99), mbc.GetItem(0xA123), "mode 0 reads RAM")
+	mbc.SetItem(0xA123, 0x11)
+	assert.Equal(t, uint8(0x99), cart.RamBanks[2][0x123], "but can't write it")
+
+	mbc.SetItem(0x0000, 0x03)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA123))
+}
+
+func TestHuC3_ScratchMemory(t *testing.T) {
+	_, mbc, _ := mbcNewHuC3(t, 8, 1)
+	huc3Commands(mbc, 0x40, 0x52, 0x31, 0x32, 0x2F)
+	assert.Equal(t, []uint8{0x1, 0x2, 0xF}, huc3Read(t, mbc, 0x20, 3))
+	assert.Equal(t, uint8(0x23), mbc.addr, "reads step on")
+}
+
+func TestHuC3_Clock(t *testing.T) {
+	cart, mbc, _ := mbcNewHuC3(t, 8, 1)
+	require.True(t, cart.RtcEnabled)
+
+	// set 23:59 on day $123
+	huc3Commands(mbc, 0x40, 0x50,
+		0x3F, 0x39, 0x35, // 1439 minutes
+		0x33, 0x32, 0x31, 0x30, // day $0123
+		0x61)
+	assert.Equal(t, uint16(1439), mbc.minutes)
+	assert.Equal(t, uint16(0x123), mbc.days)
+
+	next, ok := cart.NextEvent()
+	require.True(t, ok)
+	assert.Equal(t, uint64(huc3MinuteCycles), next)
+	cart.Tick(huc3MinuteCycles - 1)
+	assert.Equal(t, uint16(1439), mbc.minutes)
+	cart.Tick(1)
+	assert.Equal(t, uint16(0), mbc.minutes)
+	assert.Equal(t, uint16(0x124), mbc.days)
+
+	cart.Tick(2 * huc3MinuteCycles)
+	assert.Equal(t, []uint8{0xF, 0x9, 0x5, 0x3, 0x2, 0x1, 0x0}, huc3Read(t, mbc, 0x00, 7), "unchanged until copied")
+	huc3Commands(mbc, 0x60)
+	assert.Equal(t, []uint8{0x2, 0x0, 0x0, 0x4, 0x2, 0x1, 0x0}, huc3Read(t, mbc, 0x00, 7))
+
+	huc3Commands(mbc, 0x62)
+	mbc.SetItem(0x0000, 0x0C)
+	assert.Equal(t, uint8(0x61), mbc.GetItem(0xA000), "status")
+}
+
+func TestHuC3_Speaker(t *testing.T) {
+	cart, mbc, _ := mbcNewHuC3(t, 8, 1)
+	_, on := cart.Tone()
+	assert.False(t, on)
+
+	huc3Commands(mbc, 0x47, 0x52, 0x2D, 0x6E)
+	hz, on := cart.Tone()
+	assert.True(t, on)
+	assert.InDelta(t, 2093.0, hz, 0.5, "tone $D is an octave over tone $1")
+
+	huc3Commands(mbc, 0x20, 0x6E)
+	_, on = cart.Tone()
+	assert.False(t, on)
+}
+
+func TestHuC3_Alarm(t *testing.T) {
+	cart, mbc, _ := mbcNewHuC3(t, 8, 1)
+	// alarm at minute 2 of day 0, armed
+	huc3Commands(mbc, 0x48, 0x55, 0x32, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x31)
+
+	cart.Tick(huc3MinuteCycles)
+	_, on := cart.Tone()
+	assert.False(t, on)
+	cart.Tick(huc3MinuteCycles)
+	hz, on := cart.Tone()
+	assert.True(t, on, "the alarm rings")
+	assert.Equal(t, huc3Tone(huc3AlarmTone), hz)
+
+	huc3Commands(mbc, 0x62)
+	_, on = cart.Tone()
+	assert.False(t, on, "until the game talks to the HuC3")
+}
+
+func TestHuC3_IRMode(t *testing.T) {
+	_, mbc, ir := mbcNewHuC3(t, 8, 1)
+	mbc.SetItem(0x0000, 0x0E)
+	assert.Equal(t, uint8(0xC0), mbc.GetItem(0xA000))
+	ir.light = true
+	assert.Equal(t, uint8(0xC1), mbc.GetItem(0xA000))
+	mbc.SetItem(0xA000, 0x01)
+	assert.True(t, ir.led)
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0xA000, 0x00)
+	assert.True(t, ir.led, "RAM writes leave the LED alone")
+}
+
+func TestHuC3_SaveKeepsClock(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0xFE
+	rom[SRAM_SIZE_ADDR] = 0x03
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+
+	cart, err := NewCartridgeFromBytes("robopon", rom)
+	require.NoError(t, err)
+	mbc := cart.CartType.(*HuC3Cartridge)
+	cart.RamBanks[3][0x10] = 0x42
+	mbc.minutes, mbc.days = 600, 0x1234
+	huc3Commands(mbc, 0x48, 0x55, 0x3A, 0x30, 0x30, 0x37, 0x30, 0x30, 0x30, 0x31)
+	require.NoError(t, cart.Save())
+
+	info, err := os.Stat("robopon.sav")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4*RAM_BANK_SIZE+huc3ExtraSize), info.Size())
+
+	cart, err = NewCartridgeFromBytes("robopon", rom)
+	require.NoError(t, err)
+	loaded := cart.CartType.(*HuC3Cartridge)
+	assert.Equal(t, uint8(0x42), cart.RamBanks[3][0x10])
+	assert.Equal(t, uint16(600), loaded.minutes)
+	assert.Equal(t, uint16(0x1234), loaded.days)
+	assert.Equal(t, mbc.scratch[0x58:0x60], loaded.scratch[0x58:0x60], "the alarm")
+}
+
+func TestHuC3_ClockRunsWhileOff(t *testing.T) {
+	_, mbc, _ := mbcNewHuC3(t, 8, 1)
+	mbc.minutes, mbc.days = 24*60-30, 9
+	extra := mbc.saveExtra()
+	require.Len(t, extra, huc3ExtraSize)
+	saved := binary.LittleEndian.Uint64(extra)
+	binary.LittleEndian.PutUint64(extra, saved-2*60*60) // saved two hours ago
+
+	_, loaded, _ := mbcNewHuC3(t, 8, 1)
+	require.NoError(t, loaded.loadExtra(extra))
+	assert.Equal(t, uint16(90), loaded.minutes)
+	assert.Equal(t, uint16(10), loaded.days)
+
+	assert.ErrorIs(t, loaded.loadExtra(extra[:8]), ErrSRAMSize)
+}
+
+func TestHuC3_SerializeRoundtrip(t *testing.T) {
+	_, mbc, _ := mbcNewHuC3(t, 64, 4)
+	mbc.SetItem(0x2000, 0x21)
+	mbc.SetItem(0x4000, 0x03)
+	huc3Commands(mbc, 0x47, 0x52, 0x35, 0x6E, 0x10)
+	mbc.minutes, mbc.days, mbc.cycles = 61, 7, 1234
+	mbc.hasBattery = true
+
+	buf := mbc.Serialize()
+	other := &HuC3Cartridge{parent: mbcNewTestCart(64, 4)}
+	require.NoError(t, other.Deserialize(buf))
+
+	other.parent = mbc.parent
+	assert.Equal(t, mbc, other)
+}
+
+// mbcSensor shows the camera the same picture every time.
+type mbcSensor struct {
+	frame *image.Gray
+}
+
+func (s *mbcSensor) Capture() *image.Gray { return s.frame }
+
+func mbcFlatFrame(v uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, CameraWidth, CameraHeight))
+	for i := range img.Pix {
+		img.Pix[i] = v
+	}
+	return img
+}
+
+func mbcNewCamera(t *testing.T, frame *image.Gray) (*Cartridge, *CameraCartridge) {
+	t.Helper()
+	cart := mbcNewTestCart(64, 4)
+	cart.Sensor = &mbcSensor{frame}
+	mbc := &CameraCartridge{parent: cart, romBankSelect: 1}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
+	return cart, mbc
+}
+
+// cameraSetup writes the sensor registers from $A001 on, and the same
+// three thresholds at every position of the dither matrix.
+func cameraSetup(mbc *CameraCartridge, regs [5]uint8, thresholds [3]uint8) {
+	mbc.SetItem(0x4000, 0x10)
+	for i, v := range regs {
+		mbc.SetItem(0xA001+uint16(i), v)
+	}
+	for i := range 16 {
+		for j, v := range thresholds {
+			mbc.SetItem(0xA006+uint16(i*3+j), v)
+		}
+	}
+}
+
+// cameraShoot starts a capture and runs it to the end.
+func cameraShoot(t *testing.T, cart *Cartridge, mbc *CameraCartridge) {
+	t.Helper()
+	mbc.SetItem(0x4000, 0x10)
+	mbc.SetItem(0xA000, 0x01)
+	cycles, ok := cart.NextEvent()
+	require.True(t, ok)
+	cart.Tick(cycles)
+	_, ok = cart.NextEvent()
+	require.False(t, ok)
+}
+
+// cameraPixel returns the colour the last capture left at x, y.
+func cameraPixel(cart *Cartridge, x, y int) uint8 {
+	i := 0x100 + ((y/8)*16+x/8)*16 + (y%8)*2
+	bit := 7 - x%8
+	return cart.RamBanks[0][i]>>bit&1 | (cart.RamBanks[0][i+1]>>bit&1)<<1
+}
+
+func TestCamera_Banking(t *testing.T) {
+	cart, mbc := mbcNewCamera(t, nil)
+	assert.Equal(t, uint16(16), cart.RamBankCount, "128 KiB whatever the header says")
+
+	mbc.SetItem(0x2000, 0x00)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x4000), "bank 0 maps as itself")
+	mbc.SetItem(0x2000, 0x7F)
+	assert.Equal(t, uint8(0x3F%64), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x4000, 0x0F)
+	mbc.SetItem(0xA000, 0x12)
+	assert.Equal(t, uint8(0x00), cart.RamBanks[15][0], "writes need RAM enabled")
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0xA000, 0x12)
+	assert.Equal(t, uint8(0x12), cart.RamBanks[15][0])
+	mbc.SetItem(0x0000, 0x00)
+	assert.Equal(t, uint8(0x12), mbc.GetItem(0xA000), "reads don't")
+
+	mbc.SetItem(0x4000, 0x10)
+	mbc.SetItem(0xA081, 0x55) // $A001 mirrored
+	assert.Equal(t, uint8(0x55), mbc.regs[1], "registers don't need RAM enabled either")
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0xA001), "only $A000 reads back")
+	mbc.SetItem(0xA000, 0x06)
+	assert.Equal(t, uint8(0x06), mbc.GetItem(0xA000))
+	assert.Equal(t, uint8(0x00), cart.RamBanks[0][1], "registers aren't RAM")
+}
+
+func TestCamera_CaptureTiming(t *testing.T) {
+	cart, mbc := mbcNewCamera(t, mbcFlatFrame(0xFF))
+	cameraSetup(mbc, [5]uint8{0x00, 0x01, 0x00}, [3]uint8{0x40, 0x80, 0xC0})
+	cart.RamBanks[0][0x100] = 0x99
+
+	mbc.SetItem(0xA000, 0x03)
+	assert.Equal(t, uint8(0x03), mbc.GetItem(0xA000), "busy")
+	cycles, ok := cart.NextEvent()
+	require.True(t, ok)
+	assert.Equal(t, uint64(4*(32446+512+16*0x100)), cycles)
+
+	mbc.SetItem(0x4000, 0x00)
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0xA100), "RAM reads 0 during a capture")
+	cart.Tick(cycles - 1)
+	mbc.SetItem(0x4000, 0x10)
+	assert.Equal(t, uint8(0x03), mbc.GetItem(0xA000))
+	cart.Tick(1)
+	assert.Equal(t, uint8(0x02), mbc.GetItem(0xA000), "done")
+	assert.Equal(t, uint8(0xFF), cart.RamBanks[0][0x100], "a dark picture is in")
+
+	mbc.SetItem(0xA001, 0x80) // N
+	mbc.SetItem(0xA000, 0x01)
+	cycles, _ = cart.NextEvent()
+	assert.Equal(t, uint64(4*(32446+16*0x100)), cycles, "N skips a step")
+}
+
+func TestCamera_Dither(t *testing.T) {
+	cart, mbc := mbcNewCamera(t, mbcFlatFrame(0x80))
+	cameraSetup(mbc, [5]uint8{0x00, 0x10, 0x00}, [3]uint8{})
+	for x, th := range [4][3]uint8{{200, 220, 240}, {100, 200, 220}, {50, 100, 200}, {10, 20, 30}} {
+		for y := range 4 {
+			for j, v := range th {
+				mbc.SetItem(0xA006+uint16((y*4+x)*3+j), v)
+			}
+		}
+	}
+	cart.RamBanks[0][0x0FF] = 0x77
+	cart.RamBanks[0][0xF00] = 0x77
+	cameraShoot(t, cart, mbc)
+
+	// colours 3, 2, 1, 0 across every row
+	for i := 0x100; i < 0xF00; i += 2 {
+		require.Equal(t, uint8(0xAA), cart.RamBanks[0][i], "%#x", i)
+		require.Equal(t, uint8(0xCC), cart.RamBanks[0][i+1], "%#x", i+1)
+	}
+	assert.Equal(t, uint8(0x77), cart.RamBanks[0][0x0FF])
+	assert.Equal(t, uint8(0x77), cart.RamBanks[0][0xF00])
+}
+
+func TestCamera_ExposureGainInvert(t *testing.T) {
+	cart, mbc := mbcNewCamera(t, mbcFlatFrame(0xFF))
+	thresholds := [3]uint8{0x80, 0x80, 0x80}
+
+	cameraSetup(mbc, [5]uint8{0x00, 0x08, 0x00}, thresholds)
+	cameraShoot(t, cart, mbc)
+	assert.Equal(t, uint8(3), cameraPixel(cart, 0, 0), "half the light")
+
+	cameraSetup(mbc, [5]uint8{0x04, 0x08, 0x00}, thresholds)
+	cameraShoot(t, cart, mbc)
+	assert.Equal(t, uint8(0), cameraPixel(cart, 0, 0), "6 dB makes up for it")
+
+	cameraSetup(mbc, [5]uint8{0x04, 0x08, 0x00, 0x08}, thresholds)
+	cameraShoot(t, cart, mbc)
+	assert.Equal(t, uint8(3), cameraPixel(cart, 0, 0), "inverted")
+
+	cart.Sensor = nil
+	cameraSetup(mbc, [5]uint8{0x00, 0x10, 0x00}, [3]uint8{0x81, 0x81, 0x81})
+	cameraShoot(t, cart, mbc)
+	assert.Equal(t, uint8(3), cameraPixel(cart, 0, 0), "no sensor sees grey")
+}
+
+func TestCamera_EdgeEnhancement(t *testing.T) {
+	frame := mbcFlatFrame(100)
+	frame.SetGray(5, 5, color.Gray{Y: 200})
+	cart, mbc := mbcNewCamera(t, frame)
+	thresholds := [3]uint8{50, 150, 250}
+
+	cameraSetup(mbc, [5]uint8{0x40, 0x10, 0x00, 0x20}, thresholds)
+	cameraShoot(t, cart, mbc)
+	assert.Equal(t, uint8(0), cameraPixel(cart, 5, 5), "the bright pixel stands out")
+	assert.Equal(t, uint8(3), cameraPixel(cart, 4, 5), "its neighbours darken")
+	assert.Equal(t, uint8(3), cameraPixel(cart, 6, 5))
+	assert.Equal(t, uint8(2), cameraPixel(cart, 3, 5))
+	assert.Equal(t, uint8(2), cameraPixel(cart, 5, 4), "horizontally only")
+
+	cameraSetup(mbc, [5]uint8{0x20, 0x10, 0x00, 0x20}, thresholds)
+	cameraShoot(t, cart, mbc)
+	assert.Equal(t, uint8(3), cameraPixel(cart, 5, 4))
+	assert.Equal(t, uint8(3), cameraPixel(cart, 5, 6))
+	assert.Equal(t, uint8(2), cameraPixel(cart, 4, 5), "vertically only")
+
+	cameraSetup(mbc, [5]uint8{0x00, 0x10, 0x00, 0x20}, thresholds)
+	cameraShoot(t, cart, mbc)
+	assert.Equal(t, uint8(1), cameraPixel(cart, 5, 5), "no edge mode")
+	assert.Equal(t, uint8(2), cameraPixel(cart, 4, 5))
+}
+
+func TestCamera_SerializeRoundtrip(t *testing.T) {
+	_, mbc := mbcNewCamera(t, nil)
+	mbc.SetItem(0x2000, 0x21)
+	cameraSetup(mbc, [5]uint8{0xE4, 0x12, 0x34, 0x56, 0x78}, [3]uint8{1, 2, 3})
+	mbc.SetItem(0xA000, 0x01)
+	mbc.hasBattery = true
+
+	buf := mbc.Serialize()
+	other := &CameraCartridge{parent: mbcNewTestCart(64, 4)}
+	require.NoError(t, other.Deserialize(buf))
+
+	other.parent = mbc.parent
+	assert.Equal(t, mbc, other)
+}
+
+func mbcNewTama5(t *testing.T) (*Cartridge, *Tama5Cartridge) {
+	t.Helper()
+	cart := mbcNewTestCart(32, 1)
+	mbc := &Tama5Cartridge{parent: cart}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
+	return cart, mbc
+}
+
+func tama5Write(mbc *Tama5Cartridge, reg, value uint8) {
+	mbc.SetItem(0xA001, reg)
+	mbc.SetItem(0xA000, value)
+}
+
+// tama5Command runs cmd on addr with data, the way games do: data, then
+// the command, then the address.
+func tama5Command(mbc *Tama5Cartridge, cmd, addr, data uint8) {
+	tama5Write(mbc, 0x4, data&0x0F)
+	tama5Write(mbc, 0x5, data>>4)
+	tama5Write(mbc, 0x6, cmd<<1|addr>>4)
+	tama5Write(mbc, 0x7, addr&0x0F)
+}
+
+func tama5Result(mbc *Tama5Cartridge) uint8 {
+	mbc.SetItem(0xA001, 0x0C)
+	lo := mbc.GetItem(0xA000)
+	mbc.SetItem(0xA001, 0x0D)
+	hi := mbc.GetItem(0xA000)
+	return hi<<4 | lo&0x0F
+}
+
+// tama5SetPage writes the nibbles to the TAMA6 page, from register 0 on.
+func tama5SetPage(mbc *Tama5Cartridge, page uint8, nibbles ...uint8) {
+	for reg, v := range nibbles {
+		tama5Command(mbc, 0x4, page*2, v<<4|uint8(reg))
+	}
+}
+
+func TestTama5_Ports(t *testing.T) {
+	_, mbc := mbcNewTama5(t)
+	mbc.SetItem(0xA001, 0x0A)
+	assert.Equal(t, uint8(0xF1), mbc.GetItem(0xA000), "ready")
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA001))
+
+	mbc.SetItem(0x2000, 0x03)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x4000), "nothing answers outside A000-BFFF")
+	tama5Write(mbc, 0x0, 0x05)
+	tama5Write(mbc, 0x1, 0x01)
+	assert.Equal(t, uint8(21), mbc.GetItem(0x4000))
+	tama5Write(mbc, 0x1, 0x0E)
+	assert.Equal(t, uint8(5), mbc.GetItem(0x4000), "one high bit")
+}
+
+func TestTama5_RAM(t *testing.T) {
+	cart, mbc := mbcNewTama5(t)
+	assert.Equal(t, uint16(1), cart.RamBankCount)
+
+	tama5Command(mbc, 0x0, 0x13, 0x5A)
+	tama5Command(mbc, 0x0, 0x02, 0xC3)
+	assert.Equal(t, uint8(0x5A), cart.RamBanks[0][0x13])
+
+	tama5Command(mbc, 0x1, 0x13, 0x00)
+	assert.Equal(t, uint8(0x5A), tama5Result(mbc))
+	tama5Command(mbc, 0x1, 0x02, 0x00)
+	assert.Equal(t, uint8(0xC3), tama5Result(mbc))
+}
+
+func TestTama5_Clock(t *testing.T) {
+	cart, mbc := mbcNewTama5(t)
+	_, ok := cart.NextEvent()
+	assert.False(t, ok, "stopped until the game starts it")
+
+	// 23:59:58 on Tuesday 28/02/23
+	tama5SetPage(mbc, tama6Clock, 8, 5, 9, 5, 3, 2, 2, 8, 2, 2, 0, 3, 2)
+	tama5Command(mbc, 0x2, 0x01, 0x00)
+	next, ok := cart.NextEvent()
+	require.True(t, ok)
+	assert.Equal(t, uint64(RTCCycles), next)
+
+	cart.Tick(2 * RTCCycles)
+	assert.Equal(t, [tama6PageSize]uint8{0, 0, 0, 0, 0, 0, 3, 1, 0, 3, 0, 3, 2}, mbc.pages[tama6Clock],
+		"00:00:00 on Wednesday 01/03/23")
+
+	tama5Command(mbc, 0x2, 0x04, 0x42) // minutes
+	tama5Command(mbc, 0x2, 0x05, 0x17) // hours
+	tama5Command(mbc, 0x2, 0x06, 0x00)
+	assert.Equal(t, uint8(0x42), tama5Result(mbc))
+	tama5Command(mbc, 0x2, 0x07, 0x00)
+	assert.Equal(t, uint8(0x17), tama5Result(mbc))
+	tama5Command(mbc, 0x4, 0x01, tama6Second)
+	assert.Equal(t, uint8(0), tama5Result(mbc), "setting minutes clears the seconds")
+
+	// leap years
+	tama5SetPage(mbc, tama6Clock, 9, 5, 9, 5, 3, 2, 0, 8, 2, 2, 0, 4, 2)
+	cart.Tick(RTCCycles)
+	tama5Command(mbc, 0x4, 0x01, tama6Day)
+	assert.Equal(t, uint8(9), tama5Result(mbc), "29/02/24")
+
+	tama5Command(mbc, 0x2, 0x00, 0x00)
+	cart.Tick(100 * RTCCycles)
+	tama5Command(mbc, 0x4, 0x01, tama6Second)
+	assert.Equal(t, uint8(0), tama5Result(mbc), "stopped")
+}
+
+func TestTama5_Pages(t *testing.T) {
+	_, mbc := mbcNewTama5(t)
+	tama5Command(mbc, 0x4, 0x04, 0x7C)
+	tama5Command(mbc, 0x4, 0x06, 0x3C)
+	tama5Command(mbc, 0x4, 0x05, 0x0C)
+	assert.Equal(t, uint8(0x7), tama5Result(mbc))
+	tama5Command(mbc, 0x4, 0x07, 0x0C)
+	assert.Equal(t, uint8(0x3), tama5Result(mbc))
+
+	tama5Command(mbc, 0x4, 0x04, 0x7D)
+	assert.Equal(t, uint8(0), mbc.pages[2][0], "registers past $C are ignored")
+}
+
+func TestTama5_Alarm(t *testing.T) {
+	cart, mbc := mbcNewTama5(t)
+	tama5SetPage(mbc, tama6Alarm, 0, 0, 0, 3, 7, 0) // 07:30
+	tama5SetPage(mbc, tama6Clock, 8, 5, 9, 2, 7, 0) // 07:29:58
+	tama5Command(mbc, 0x2, 0x01, 0x00)
+
+	cart.Tick(2 * RTCCycles)
+	tama5Command(mbc, 0x2, 0x12, 0x00)
+	assert.Equal(t, uint8(0), tama5Result(mbc), "the alarm is off")
+
+	tama5SetPage(mbc, tama6Clock, 9, 5, 9, 2, 7, 0)
+	tama5Command(mbc, 0x2, 0x11, 0x00)
+	cart.Tick(RTCCycles)
+	tama5Command(mbc, 0x2, 0x12, 0x00)
+	assert.Equal(t, uint8(1), tama5Result(mbc), "the alarm went off")
+	tama5Command(mbc, 0x2, 0x12, 0x00)
+	assert.Equal(t, uint8(0), tama5Result(mbc), "reading clears it")
+}
+
+func TestTama5_SaveKeepsClock(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0xFD
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+
+	cart, err := NewCartridgeFromBytes("tamagotchi", rom)
+	require.NoError(t, err)
+	mbc := cart.CartType.(*Tama5Cartridge)
+	tama5Command(mbc, 0x0, 0x1F, 0x99)
+	tama5SetPage(mbc, tama6Alarm, 0, 0, 5, 4, 1, 2)
+	tama5Command(mbc, 0x2, 0x11, 0x00)
+	require.NoError(t, cart.Save())
+
+	sav, err := os.ReadFile("tamagotchi.sav")
+	require.NoError(t, err)
+	require.Len(t, sav, tama5RamSize+tama5ExtraSize, "32 bytes of RAM, then the clock")
+	assert.Equal(t, uint8(0x99), sav[0x1F])
+
+	cart, err = NewCartridgeFromBytes("tamagotchi", rom)
+	require.NoError(t, err)
+	loaded := cart.CartType.(*Tama5Cartridge)
+	assert.Equal(t, uint8(0x99), cart.RamBanks[0][0x1F])
+	assert.Equal(t, mbc.pages, loaded.pages)
+	assert.True(t, loaded.alarmOn)
+	assert.False(t, loaded.running)
+}
+
+func TestTama5_ClockRunsWhileOff(t *testing.T) {
+	_, mbc := mbcNewTama5(t)
+	tama5SetPage(mbc, tama6Clock, 0, 3, 0, 3, 3, 2, 6, 1, 3, 2, 1, 9, 9) // 23:30:30 on Saturday 31/12/99
+	tama5Command(mbc, 0x2, 0x01, 0x00)
+	extra := mbc.saveExtra()
+	require.Len(t, extra, tama5ExtraSize)
+	saved := binary.LittleEndian.Uint64(extra)
+	binary.LittleEndian.PutUint64(extra, saved-3600) // saved an hour ago
+
+	_, loaded := mbcNewTama5(t)
+	require.NoError(t, loaded.loadExtra(extra))
+	assert.Equal(t, [tama6PageSize]uint8{0, 3, 0, 3, 0, 0, 0, 1, 0, 1, 0, 0, 0}, loaded.pages[tama6Clock],
+		"00:30:30 on Sunday 01/01/00")
+
+	assert.ErrorIs(t, loaded.loadExtra(extra[:20]), ErrSRAMSize)
+}
+
+func TestTama5_SerializeRoundtrip(t *testing.T) {
+	_, mbc := mbcNewTama5(t)
+	tama5Write(mbc, 0x0, 0x07)
+	tama5SetPage(mbc, tama6Clock, 1, 2, 3, 4, 5, 1)
+	tama5Command(mbc, 0x2, 0x01, 0x00)
+	tama5Command(mbc, 0x2, 0x11, 0x00)
+	mbc.Tick(1234)
+	mbc.hasBattery = true
+
+	buf := mbc.Serialize()
+	other := &Tama5Cartridge{parent: mbcNewTestCart(32, 1)}
+	require.NoError(t, other.Deserialize(buf))
+
+	other.parent = mbc.parent
+	assert.Equal(t, mbc, other)
+}
+
+func mbcNewMBC6(t *testing.T) (*Cartridge, *Mbc6Cartridge) {
+	t.Helper()
+	t.Chdir(t.TempDir()) // Init looks for a flash file
+	cart := mbcNewTestCart(8, 0)
+	for bank := range cart.RomBanks {
+		cart.RomBanks[bank][0x2000] = uint8(bank)<<4 | 0x08 // upper 8 KiB
+	}
+	mbc := &Mbc6Cartridge{parent: cart, romBank: [2]uint8{2, 3}}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
+	return cart, mbc
+}
+
+// mbc6Flash maps flash bank in window A with flash and its writes enabled.
+func mbc6Flash(mbc *Mbc6Cartridge, bank uint8) {
+	mbc.SetItem(0x1000, 0x01)
+	mbc.SetItem(0x0C00, 0x01)
+	mbc.SetItem(0x2000, bank)
+	mbc.SetItem(0x2800, 0x08)
+}
+
+// mbc6Unlock sends the flash's unlock sequence through window A.
+func mbc6Unlock(mbc *Mbc6Cartridge) {
+	mbc.SetItem(0x2000, 2)
+	mbc.SetItem(0x5555, 0xAA)
+	mbc.SetItem(0x2000, 1)
+	mbc.SetItem(0x4AAA, 0x55)
+}
+
+// mbc6Command unlocks the flash and sends cmd, leaving bank mapped in
+// window A again.
+func mbc6Command(mbc *Mbc6Cartridge, bank, cmd uint8) {
+	mbc6Unlock(mbc)
+	mbc.SetItem(0x2000, 2)
+	mbc.SetItem(0x5555, cmd)
+	mbc.SetItem(0x2000, bank)
+}
+
+func TestMBC6_Banking(t *testing.T) {
+	cart, mbc := mbcNewMBC6(t)
+	assert.Equal(t, uint16(4), cart.RamBankCount, "32 KiB whatever the header says")
+
+	assert.Equal(t, uint8(0x01), mbc.GetItem(0x4000), "boots on bank 2")
+	assert.Equal(t, uint8(0x18), mbc.GetItem(0x6000), "and bank 3")
+
+	mbc.SetItem(0x2000, 0x0B)
+	mbc.SetItem(0x3000, 0x04)
+	assert.Equal(t, uint8(0x58), mbc.GetItem(0x4000), "upper half of 16 KiB bank 5")
+	assert.Equal(t, uint8(0x05), mbc.GetItem(0x4001))
+	assert.Equal(t, uint8(0x02), mbc.GetItem(0x6000), "lower half of 16 KiB bank 2")
+	assert.Equal(t, uint8(0x02), mbc.GetItem(0x7FFF))
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0x3FFF), "bank 0 fixed")
+
+	mbc.SetItem(0x27FF, 0x00)
+	mbc.SetItem(0x37FF, 0x0B)
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0x4000), "windows swap freely")
+	assert.Equal(t, uint8(0x58), mbc.GetItem(0x6000))
+
+	mbc.SetItem(0xA000, 0x11)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA000), "RAM disabled")
+	mbc.SetItem(0x0000, 0x0A)
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0xA000), "write ignored while disabled")
+
+	for bank := range uint8(8) {
+		mbc.SetItem(0x0400, bank)
+		mbc.SetItem(0xA000, 0x40|bank)
+	}
+	mbc.SetItem(0x0400, 0x05)
+	mbc.SetItem(0x0800, 0x02)
+	assert.Equal(t, uint8(0x45), mbc.GetItem(0xA000))
+	assert.Equal(t, uint8(0x42), mbc.GetItem(0xB000))
+	assert.Equal(t, uint8(0x45), cart.RamBanks[2][0x1000], "4 KiB banks, two to a RAM bank")
+	assert.Equal(t, uint8(0x42), cart.RamBanks[1][0x0000])
+	mbc.SetItem(0xBFFF, 0x99)
+	assert.Equal(t, uint8(0x99), cart.RamBanks[1][0x0FFF])
+}
+
+func TestMBC6_FlashEnable(t *testing.T) {
+	_, mbc := mbcNewMBC6(t)
+	mbc.SetItem(0x2800, 0x08)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4000), "flash disabled")
+
+	mbc.SetItem(0x0C00, 0x01)
+	assert.False(t, mbc.flashEnabled, "needs flash writes enabled first")
+	mbc.SetItem(0x1000, 0x01)
+	mbc.SetItem(0x0C00, 0x01)
+	assert.True(t, mbc.flashEnabled)
+	mbc.SetItem(0x1000, 0x00)
+	mbc.SetItem(0x0C00, 0x00)
+	assert.True(t, mbc.flashEnabled, "locked in again")
+
+	mbc.flash[2*0x2000] = 0x5A
+	assert.Equal(t, uint8(0x5A), mbc.GetItem(0x4000), "flash bank 2")
+	assert.Equal(t, uint8(0x18), mbc.GetItem(0x6000), "window B still ROM")
+	mbc.SetItem(0x2800, 0x00)
+	assert.Equal(t, uint8(0x01), mbc.GetItem(0x4000), "back to ROM")
 }
 
-func TestMBC1_UpperBankBits_MaskedTo2Bits(t *testing.T) {
-	_, mbc := mbcNewMBC1(t, 128, 1)
-	mbc.SetItem(0x2000, 0x01)
-	mbc.SetItem(0x4000, 0xFF)
-	assert.Equal(t, uint8(0x61), mbc.GetItem(0x4000))
-}
+func TestMBC6_FlashProgram(t *testing.T) {
+	_, mbc := mbcNewMBC6(t)
+	mbc6Flash(mbc, 0x40)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4123), "erased")
 
-func TestMBC1_BankingMode_Register(t *testing.T) {
-	cart, mbc := mbcNewMBC1(t, 64, 4)
+	mbc.SetItem(0x4123, 0x12)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4123), "needs a command")
 
-	mbc.SetItem(0x6000, 0x01)
-	assert.True(t, mbc.mode)
-	assert.Equal(t, uint8(1), cart.MemoryModel)
+	mbc6Command(mbc, 0x40, 0xA0)
+	mbc.SetItem(0x4123, 0x3C)
+	assert.Equal(t, uint8(0x3C), mbc.GetItem(0x4123))
+	assert.Equal(t, uint8(0x3C), mbc.flash[0x40*0x2000+0x123])
+	mbc.SetItem(0x4124, 0x3C)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4124), "one byte per command")
 
-	mbc.SetItem(0x7FFF, 0x00)
-	assert.False(t, mbc.mode)
-	assert.Equal(t, uint8(0), cart.MemoryModel)
+	mbc6Command(mbc, 0x40, 0xA0)
+	mbc.SetItem(0x4123, 0xF0)
+	assert.Equal(t, uint8(0x30), mbc.GetItem(0x4123), "programming only clears bits")
 
-	mbc.SetItem(0x6000, 0xFE)
-	assert.False(t, mbc.mode)
-	mbc.SetItem(0x6000, 0xFF)
-	assert.True(t, mbc.mode)
+	mbc6Command(mbc, 0x41, 0xA0)
+	mbc.SetItem(0x1000, 0x00)
+	mbc.SetItem(0x4000, 0x00)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4000), "writes disabled")
+	mbc.SetItem(0x1000, 0x01)
+	mbc.SetItem(0x4000, 0x00)
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0x4000), "the command waits")
+
+	// a wrong unlock write starts over
+	mbc.SetItem(0x2000, 2)
+	mbc.SetItem(0x5555, 0xAA)
+	mbc.SetItem(0x5555, 0x55)
+	mbc.SetItem(0x5555, 0xA0)
+	mbc.SetItem(0x2000, 0x40)
+	mbc.SetItem(0x4200, 0x00)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4200))
 }
 
-func TestMBC1_Mode1_AffectsLowBankRead(t *testing.T) {
-	_, mbc := mbcNewMBC1(t, 64, 1)
-	mbc.SetItem(0x4000, 0x01)
-	mbc.SetItem(0x6000, 0x01)
-	assert.Equal(t, uint8(0x20), mbc.GetItem(0x0000))
-}
+func TestMBC6_FlashErase(t *testing.T) {
+	_, mbc := mbcNewMBC6(t)
+	mbc6Flash(mbc, 0)
+	for i := range mbc.flash {
+		mbc.flash[i] = 0x00
+	}
 
-func TestMBC1_RAMEnable(t *testing.T) {
-	cart, mbc := mbcNewMBC1(t, 8, 1)
+	// bank $15 lies in the second 128 KiB sector, banks $10-$1F
+	mbc6Command(mbc, 0x15, 0x80)
+	mbc6Unlock(mbc)
+	mbc.SetItem(0x2000, 0x15)
+	mbc.SetItem(0x4ABC, 0x30)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4ABC))
+	assert.Equal(t, uint8(0xFF), mbc.flash[0x10*0x2000])
+	assert.Equal(t, uint8(0xFF), mbc.flash[0x20*0x2000-1])
+	assert.Equal(t, uint8(0x00), mbc.flash[0x10*0x2000-1], "sector before untouched")
+	assert.Equal(t, uint8(0x00), mbc.flash[0x20*0x2000], "sector after untouched")
+
+	mbc6Command(mbc, 0x00, 0x80)
+	mbc6Command(mbc, 0x00, 0x10)
+	left := 0
+	for _, b := range mbc.flash {
+		if b != 0xFF {
+			left++
+		}
+	}
+	assert.Zero(t, left, "chip erased")
+}
 
-	assert.False(t, cart.RamBankEnabled)
+func TestMBC6_FlashID(t *testing.T) {
+	_, mbc := mbcNewMBC6(t)
+	mbc6Flash(mbc, 0x7F)
+	mbc6Command(mbc, 0x7F, 0x90)
+	assert.Equal(t, uint8(0xC2), mbc.GetItem(0x4000), "Macronix")
+	assert.Equal(t, uint8(0x81), mbc.GetItem(0x4001), "MX29F008")
+	mbc.SetItem(0x2800, 0x00)
+	assert.Equal(t, uint8(0x78), mbc.GetItem(0x4000), "ROM unaffected")
+	mbc.SetItem(0x2800, 0x08)
+
+	mbc.SetItem(0x4000, 0xF0)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x4000), "reading the flash again")
+}
 
+func TestMBC6_SaveKeepsFlash(t *testing.T) {
+	rom := make([]byte, 64*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0x20
+	rom[ROM_SIZE_ADDR] = 0x05
+	rom[SRAM_SIZE_ADDR] = 0x03
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+
+	cart, err := NewCartridgeFromBytes("netdeget", rom)
+	require.NoError(t, err)
+	mbc := cart.CartType.(*Mbc6Cartridge)
+	mbc6Flash(mbc, 0x33)
+	mbc6Command(mbc, 0x33, 0xA0)
+	mbc.SetItem(0x5FFF, 0x42)
 	mbc.SetItem(0x0000, 0x0A)
-	assert.True(t, cart.RamBankEnabled)
+	mbc.SetItem(0x0800, 0x07)
+	mbc.SetItem(0xB000, 0x24)
+	require.NoError(t, cart.Save())
+
+	info, err := os.Stat("netdeget.flash")
+	require.NoError(t, err)
+	assert.Equal(t, int64(mbc6FlashSize), info.Size())
+	info, err = os.Stat("netdeget.sav")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4*RAM_BANK_SIZE), info.Size(), "RAM only")
+
+	cart, err = NewCartridgeFromBytes("netdeget", rom)
+	require.NoError(t, err)
+	loaded := cart.CartType.(*Mbc6Cartridge)
+	assert.Equal(t, mbc.flash, loaded.flash)
+	assert.Equal(t, uint8(0x24), cart.RamBanks[3][0x1000])
+
+	require.NoError(t, os.WriteFile("netdeget.flash", []byte{0xFF}, 0o644))
+	_, err = NewCartridgeFromBytes("netdeget", rom)
+	assert.ErrorIs(t, err, ErrSRAMSize)
+}
 
-	mbc.SetItem(0x0000, 0x00)
-	assert.False(t, cart.RamBankEnabled)
+func TestMBC6_SerializeRoundtrip(t *testing.T) {
+	_, mbc := mbcNewMBC6(t)
+	mbc6Flash(mbc, 0x12)
+	mbc6Command(mbc, 0x12, 0xA0)
+	mbc.SetItem(0x4321, 0x77)
+	mbc6Command(mbc, 0x12, 0x80)
+	mbc.SetItem(0x0400, 0x03)
+	mbc.SetItem(0x3000, 0x09)
+	mbc.SetItem(0x3800, 0x08)
+
+	buf := mbc.Serialize()
+	other := &Mbc6Cartridge{parent: mbc.parent}
+	require.NoError(t, other.Deserialize(buf))
+	assert.Equal(t, mbc, other)
+}
 
+func mbcNewMBC7(t *testing.T) (*Cartridge, *Mbc7Cartridge) {
+	t.Helper()
+	cart := mbcNewTestCart(64, 0)
+	mbc := &Mbc7Cartridge{parent: cart, romBankSelect: 1}
+	require.NoError(t, mbc.Init())
+	cart.CartType = mbc
 	mbc.SetItem(0x0000, 0x0A)
-	assert.True(t, cart.RamBankEnabled)
-	mbc.SetItem(0x1FFF, 0xFF)
-	assert.False(t, cart.RamBankEnabled)
+	mbc.SetItem(0x4000, 0x40)
+	return cart, mbc
+}
 
-	mbc.SetItem(0x0000, 0x1A)
-	assert.True(t, cart.RamBankEnabled)
+// mbc7Clock shifts bits into the EEPROM, MSB first, and returns DO after
+// each rising edge.
+func mbc7Clock(mbc *Mbc7Cartridge, n int, bits uint32) uint32 {
+	var out uint32
+	for i := n - 1; i >= 0; i-- {
+		di := uint8(bits>>i&1) << 1
+		mbc.SetItem(0xA080, 0x80|di)
+		mbc.SetItem(0xA080, 0xC0|di)
+		out = out<<1 | uint32(mbc.GetItem(0xA080)&0x01)
+	}
+	return out
 }
 
-func TestMBC1_RAM_DisabledReadsFF(t *testing.T) {
-	_, mbc := mbcNewMBC1(t, 8, 1)
-	for _, addr := range []uint16{0xA000, 0xABCD, 0xBFFF} {
-		assert.Equal(t, uint8(0xFF), mbc.GetItem(addr))
+// mbc7Send runs one EEPROM command: CS up, the start bit, opcode and
+// address, any data, and CS down again.
+func mbc7Send(mbc *Mbc7Cartridge, op, addr uint8, data ...uint16) {
+	mbc.SetItem(0xA080, 0x00)
+	mbc7Clock(mbc, 11, 1<<10|uint32(op)<<8|uint32(addr))
+	for _, d := range data {
+		mbc7Clock(mbc, 16, uint32(d))
 	}
+	mbc.SetItem(0xA080, 0x00)
 }
 
-func TestMBC1_RAM_DisabledWritesDropped(t *testing.T) {
-	cart, mbc := mbcNewMBC1(t, 8, 1)
-	mbc.SetItem(0xA000, 0x42)
-	assert.Equal(t, uint8(0x00), cart.RamBanks[0][0])
+// mbc7ReadWords reads n words from addr on.
+func mbc7ReadWords(t *testing.T, mbc *Mbc7Cartridge, addr uint8, n int) []uint16 {
+	t.Helper()
+	mbc.SetItem(0xA080, 0x00)
+	mbc7Clock(mbc, 11, 1<<10|0x2<<8|uint32(addr))
+	require.Equal(t, uint8(0), mbc.GetItem(0xA080)&0x01, "dummy 0")
+	out := make([]uint16, n)
+	for i := range out {
+		out[i] = uint16(mbc7Clock(mbc, 16, 0))
+	}
+	mbc.SetItem(0xA080, 0x00)
+	return out
 }
 
-func TestMBC1_RAM_EnabledReadWrite(t *testing.T) {
-	cart, mbc := mbcNewMBC1(t, 8, 4)
+func TestMBC7_Registers(t *testing.T) {
+	cart, mbc := mbcNewMBC7(t)
+	assert.Equal(t, uint16(1), cart.RamBankCount)
 
-	mbc.SetItem(0x0000, 0x0A)
-	mbc.SetItem(0xA000, 0xAB)
-	mbc.SetItem(0xA001, 0xCD)
-	assert.Equal(t, uint8(0xAB), mbc.GetItem(0xA000))
-	assert.Equal(t, uint8(0xCD), mbc.GetItem(0xA001))
-	assert.Equal(t, uint8(0xAB), cart.RamBanks[0][0])
+	mbc.SetItem(0x2000, 0x00)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x4000), "bank 0 maps as itself")
+	mbc.SetItem(0x2000, 0x2A)
+	assert.Equal(t, uint8(0x2A), mbc.GetItem(0x4000))
+
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0xA060))
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA070))
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xB020), "nothing at B000-BFFF")
+
+	mbc.SetItem(0x4000, 0x00)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA020), "closed without $40 at $4000")
+	mbc.SetItem(0x4000, 0x40)
+	mbc.SetItem(0x0000, 0x00)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA020), "closed without RAM enabled")
 }
 
-func TestMBC1_RAM_Mode1_BankSwitch(t *testing.T) {
-	cart, mbc := mbcNewMBC1(t, 8, 4)
+func TestMBC7_Accelerometer(t *testing.T) {
+	cart, mbc := mbcNewMBC7(t)
+	read := func() (x, y uint16) {
+		return uint16(mbc.GetItem(0xA030))<<8 | uint16(mbc.GetItem(0xA020)),
+			uint16(mbc.GetItem(0xA050))<<8 | uint16(mbc.GetItem(0xA040))
+	}
+	x, y := read()
+	assert.Equal(t, uint16(0x8000), x)
+	assert.Equal(t, uint16(0x8000), y)
+
+	mbc.SetItem(0xA010, 0xAA)
+	x, _ = read()
+	assert.Equal(t, uint16(0x8000), x, "no latch before an erase")
+
+	mbc.SetItem(0xA000, 0x55)
+	mbc.SetItem(0xA010, 0xAA)
+	x, y = read()
+	assert.Equal(t, uint16(0x81D0), x, "level")
+	assert.Equal(t, uint16(0x81D0), y)
+
+	cart.Tilt(0.5, -1)
+	x, _ = read()
+	assert.Equal(t, uint16(0x81D0), x, "latched")
+	mbc.SetItem(0xA000, 0x55)
+	x, _ = read()
+	assert.Equal(t, uint16(0x8000), x, "erased")
+	mbc.SetItem(0xA010, 0xAA)
+	x, y = read()
+	assert.Equal(t, uint16(0x81D0+0x38), x)
+	assert.Equal(t, uint16(0x81D0-0x70), y)
+
+	cart.Tilt(-1000, 1000)
+	mbc.SetItem(0xA000, 0x55)
+	mbc.SetItem(0xA010, 0xAA)
+	x, y = read()
+	assert.Equal(t, uint16(0x0000), x, "clamped")
+	assert.Equal(t, uint16(0xFFFF), y)
+}
+
+func TestMBC7_EEPROM(t *testing.T) {
+	cart, mbc := mbcNewMBC7(t)
+	assert.Equal(t, uint8(0x01), mbc.GetItem(0xA080)&0x01, "DO idles high")
+
+	mbc7Send(mbc, 0x1, 0x05, 0x1234)
+	assert.Equal(t, []uint16{0x0000}, mbc7ReadWords(t, mbc, 0x05, 1), "writes need EWEN")
+
+	mbc7Send(mbc, 0x0, 0xC0) // EWEN
+	mbc7Send(mbc, 0x1, 0x05, 0x1234)
+	mbc7Send(mbc, 0x1, 0x06, 0xBEEF)
+	assert.Equal(t, []uint16{0x1234, 0xBEEF, 0x0000}, mbc7ReadWords(t, mbc, 0x05, 3), "reads run on")
+	assert.Equal(t, []uint8{0x34, 0x12}, cart.RamBanks[0][10:12], "little-endian in RAM")
+	assert.Equal(t, []uint16{0x0000, 0x0000}, mbc7ReadWords(t, mbc, 0x7F, 2), "and wrap")
+
+	mbc7Send(mbc, 0x3, 0x05) // ERASE
+	assert.Equal(t, []uint16{0xFFFF, 0xBEEF}, mbc7ReadWords(t, mbc, 0x05, 2))
+
+	mbc7Send(mbc, 0x0, 0x40, 0xA5A5) // WRAL
+	assert.Equal(t, []uint16{0xA5A5, 0xA5A5}, mbc7ReadWords(t, mbc, 0x00, 2))
+	assert.Equal(t, []uint16{0xA5A5}, mbc7ReadWords(t, mbc, 0x7F, 1))
+
+	mbc7Send(mbc, 0x0, 0x00) // EWDS
+	mbc7Send(mbc, 0x0, 0x80) // ERAL
+	assert.Equal(t, []uint16{0xA5A5}, mbc7ReadWords(t, mbc, 0x10, 1), "writes forbidden again")
+	mbc7Send(mbc, 0x0, 0xC0)
+	mbc7Send(mbc, 0x0, 0x80)
+	assert.Equal(t, []uint16{0xFFFF}, mbc7ReadWords(t, mbc, 0x10, 1))
+	for _, b := range cart.RamBanks[0][:0x100] {
+		require.Equal(t, uint8(0xFF), b)
+	}
+	assert.Equal(t, uint8(0x00), cart.RamBanks[0][0x100], "only 256 bytes")
+}
+
+func TestMBC7_EEPROMIgnoresLeadingZeros(t *testing.T) {
+	_, mbc := mbcNewMBC7(t)
+	mbc7Send(mbc, 0x0, 0xC0)
+	mbc.SetItem(0xA080, 0x00)
+	mbc7Clock(mbc, 3, 0) // zeros before the start bit
+	mbc7Clock(mbc, 11+16, (1<<10|0x1<<8|0x20)<<16|0x4242)
+	mbc.SetItem(0xA080, 0x00)
+	assert.Equal(t, []uint16{0x4242}, mbc7ReadWords(t, mbc, 0x20, 1))
+}
 
+func TestMBC7_SaveIsTheEEPROM(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0x22
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+
+	cart, err := NewCartridgeFromBytes("kirby", rom)
+	require.NoError(t, err)
+	mbc := cart.CartType.(*Mbc7Cartridge)
 	mbc.SetItem(0x0000, 0x0A)
-	mbc.SetItem(0x6000, 0x01)
-	mbc.SetItem(0x4000, 0x02)
+	mbc.SetItem(0x4000, 0x40)
+	mbc7Send(mbc, 0x0, 0xC0)
+	mbc7Send(mbc, 0x1, 0x33, 0xCAFE)
+	require.NoError(t, cart.Save())
+
+	cart, err = NewCartridgeFromBytes("kirby", rom)
+	require.NoError(t, err)
+	mbc = cart.CartType.(*Mbc7Cartridge)
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0x4000, 0x40)
+	assert.Equal(t, []uint16{0xCAFE}, mbc7ReadWords(t, mbc, 0x33, 1))
 
-	mbc.SetItem(0xA000, 0x77)
-	assert.Equal(t, uint8(0x77), cart.RamBanks[2][0])
+	info, err := os.Stat("kirby.sav")
+	require.NoError(t, err)
+	assert.Equal(t, int64(mbc7EEPROMSize), info.Size())
+}
 
-	mbc.SetItem(0x4000, 0x03)
-	mbc.SetItem(0xA000, 0x88)
-	assert.Equal(t, uint8(0x88), cart.RamBanks[3][0])
-	assert.Equal(t, uint8(0x77), cart.RamBanks[2][0])
+func TestMBC7_Loads256ByteSave(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0x22
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
 
-	mbc.SetItem(0x4000, 0x02)
-	assert.Equal(t, uint8(0x77), mbc.GetItem(0xA000))
-	mbc.SetItem(0x4000, 0x03)
-	assert.Equal(t, uint8(0x88), mbc.GetItem(0xA000))
+	sav := bytes.Repeat([]byte{0xFF}, mbc7EEPROMSize)
+	binary.LittleEndian.PutUint16(sav[2*0x7F:], 0x1234)
+	require.NoError(t, os.WriteFile("kirby.sav", sav, 0o644))
+
+	cart, err := NewCartridgeFromBytes("kirby", rom)
+	require.NoError(t, err)
+	mbc := cart.CartType.(*Mbc7Cartridge)
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0x4000, 0x40)
+	assert.Equal(t, []uint16{0xFFFF, 0x1234}, mbc7ReadWords(t, mbc, 0x7E, 2))
 }
 
-func TestMBC1_SerializeRoundtrip(t *testing.T) {
-	_, mbc := mbcNewMBC1(t, 8, 1)
-	mbc.SetItem(0x2000, 0x05)
-	mbc.SetItem(0x4000, 0x02)
-	mbc.SetItem(0x6000, 0x01)
-	mbc.hasBattery = true
+func TestMBC7_SerializeRoundtrip(t *testing.T) {
+	_, mbc := mbcNewMBC7(t)
+	mbc.SetItem(0x2000, 0x21)
+	mbc.SetTilt(0.25, 0.5)
+	mbc.SetItem(0xA000, 0x55)
+	mbc.SetItem(0xA010, 0xAA)
+	mbc7Send(mbc, 0x0, 0xC0)
+	mbc.SetItem(0xA080, 0x80)
+	mbc7Clock(mbc, 5, 0x15) // halfway through a command
 
 	buf := mbc.Serialize()
-	other := &Mbc1Cartridge{parent: mbcNewTestCart(8, 1)}
+	other := &Mbc7Cartridge{parent: mbcNewTestCart(64, 1)}
 	require.NoError(t, other.Deserialize(buf))
 
-	assert.Equal(t, uint16(5), other.romBankSelect)
-	assert.Equal(t, uint16(2), other.ramBankSelect)
-	assert.True(t, other.mode)
-	assert.True(t, other.hasBattery)
-}
-
-func TestMBC1_Init_NoBatteryIsNoOp(t *testing.T) {
-	_, mbc := mbcNewMBC1(t, 8, 0)
-	assert.NotPanics(t, func() { mbc.Init() })
+	other.parent = mbc.parent
+	other.tiltX, other.tiltY = mbc.tiltX, mbc.tiltY // input, not state
+	assert.Equal(t, mbc, other)
 }
 
 func mbcNewMBC3(t *testing.T, romBanks, ramBanks int, hasRTC bool) (*Cartridge, *Mbc3Cartridge) {
@@ -265,9 +1736,6 @@ func mbcNewMBC3(t *testing.T, romBanks, ramBanks int, hasRTC bool) (*Cartridge,
 	cart.RomBankSelected = 1
 	mbc := &Mbc3Cartridge{parent: cart, hasRTC: hasRTC}
 	cart.CartType = mbc
-	if hasRTC {
-		mbcResetGrtc()
-	}
 	return cart, mbc
 }
 
@@ -361,8 +1829,8 @@ func TestMBC3_RTC_RegisterSelect(t *testing.T) {
 func TestMBC3_RTC_LatchSequence(t *testing.T) {
 	cart, mbc := mbcNewMBC3(t, 8, 1, true)
 
-	Grtc.s, Grtc.m, Grtc.h = 30, 15, 5
-	Grtc.dl, Grtc.dh = 0x40, 0x00
+	cart.Rtc.s, cart.Rtc.m, cart.Rtc.h = 30, 15, 5
+	cart.Rtc.dl, cart.Rtc.dh = 0x40, 0x00
 
 	mbc.SetItem(0x0000, 0x0A)
 	cart.RamBankSelected = 0x08
@@ -370,11 +1838,11 @@ func TestMBC3_RTC_LatchSequence(t *testing.T) {
 
 	mbc.SetItem(0x6000, 0x00)
 	mbc.SetItem(0x6000, 0x01)
-	assert.True(t, Grtc.latchSet)
-	assert.Equal(t, uint8(30), Grtc.S)
-	assert.Equal(t, uint8(15), Grtc.M)
-	assert.Equal(t, uint8(5), Grtc.H)
-	assert.Equal(t, uint8(0x40), Grtc.DL)
+	assert.True(t, cart.Rtc.latchSet)
+	assert.Equal(t, uint8(30), cart.Rtc.S)
+	assert.Equal(t, uint8(15), cart.Rtc.M)
+	assert.Equal(t, uint8(5), cart.Rtc.H)
+	assert.Equal(t, uint8(0x40), cart.Rtc.DL)
 
 	cart.RamBankSelected = 0x08
 	assert.Equal(t, uint8(30), mbc.GetItem(0xA000))
@@ -391,18 +1859,18 @@ func TestMBC3_RTC_LatchRequires_0Then1(t *testing.T) {
 	mbc.SetItem(0x0000, 0x0A)
 	cart.RamBankSelected = 0x08
 
-	Grtc.s = 10
+	cart.Rtc.s = 10
 	mbc.SetItem(0x6000, 0x00)
 	mbc.SetItem(0x6000, 0x01)
-	assert.Equal(t, uint8(10), Grtc.S)
+	assert.Equal(t, uint8(10), cart.Rtc.S)
 
-	Grtc.s = 20
+	cart.Rtc.s = 20
 	mbc.SetItem(0x6000, 0x01)
-	assert.Equal(t, uint8(10), Grtc.S)
+	assert.Equal(t, uint8(10), cart.Rtc.S)
 
 	mbc.SetItem(0x6000, 0x00)
 	mbc.SetItem(0x6000, 0x01)
-	assert.Equal(t, uint8(20), Grtc.S)
+	assert.Equal(t, uint8(20), cart.Rtc.S)
 }
 
 func TestMBC3_RTC_WriteRegisters(t *testing.T) {
@@ -410,12 +1878,12 @@ func TestMBC3_RTC_WriteRegisters(t *testing.T) {
 	mbc.SetItem(0x0000, 0x0A)
 	cart.RamBankSelected = 0x08
 	mbc.SetItem(0xA000, 42)
-	assert.Equal(t, uint8(42), Grtc.s)
-	assert.Equal(t, uint8(42), Grtc.S)
+	assert.Equal(t, uint8(42), cart.Rtc.s)
+	assert.Equal(t, uint8(42), cart.Rtc.S)
 
 	cart.RamBankSelected = 0x09
 	mbc.SetItem(0xA000, 30)
-	assert.Equal(t, uint8(30), Grtc.m)
+	assert.Equal(t, uint8(30), cart.Rtc.m)
 }
 
 func TestMBC3_NoRTC_LatchIsNoOp(t *testing.T) {
@@ -423,19 +1891,17 @@ func TestMBC3_NoRTC_LatchIsNoOp(t *testing.T) {
 	mbc.SetItem(0x0000, 0x0A)
 	cart.RamBankSelected = 0x08
 
-	mbcResetGrtc()
 	mbc.SetItem(0x6000, 0x00)
 	mbc.SetItem(0x6000, 0x01)
-	assert.False(t, Grtc.latchSet)
+	assert.False(t, cart.Rtc.latchSet)
 	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA000))
 }
 
 func TestMBC3_SerializeRoundtrip(t *testing.T) {
-	_, mbc := mbcNewMBC3(t, 8, 1, true)
-	mbcResetGrtc()
+	cart, mbc := mbcNewMBC3(t, 8, 1, true)
 	mbc.hasBattery = true
 	mbc.latchGate1 = true
-	Grtc.S = 33
+	cart.Rtc.S = 33
 
 	buf := mbc.Serialize()
 	other := &Mbc3Cartridge{parent: mbcNewTestCart(8, 1)}
@@ -443,7 +1909,7 @@ func TestMBC3_SerializeRoundtrip(t *testing.T) {
 	assert.True(t, other.hasBattery)
 	assert.True(t, other.hasRTC)
 	assert.True(t, other.latchGate1)
-	assert.Equal(t, uint8(33), Grtc.S)
+	assert.Equal(t, uint8(33), other.parent.Rtc.S)
 }
 
 func TestMBC3_Init_NoBatteryNoRTC(t *testing.T) {
@@ -458,6 +1924,154 @@ func TestMBC3_Init_RTCEnabled(t *testing.T) {
 	assert.True(t, cart.RtcEnabled)
 }
 
+func TestMBC3_SaveKeepsClock(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0x10
+	rom[SRAM_SIZE_ADDR] = 0x03
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+	opts := LoadOptions{Clock: ClockEmulated}
+
+	cart, err := NewCartridgeFromBytesWithOptions("crystal", rom, opts)
+	require.NoError(t, err)
+	cart.RamBanks[3][0x10] = 0x42
+	rtc := cart.Rtc
+	rtc.s, rtc.m, rtc.h, rtc.dl, rtc.dh = 30, 45, 12, 0x34, 0x01
+	cart.CartType.SetItem(0x6000, 0x00)
+	cart.CartType.SetItem(0x6000, 0x01)
+	rtc.s = 31
+	require.NoError(t, cart.Save())
+
+	info, err := os.Stat("crystal.sav")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4*RAM_BANK_SIZE+mbc3ExtraSize), info.Size())
+
+	cart, err = NewCartridgeFromBytesWithOptions("crystal", rom, opts)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0x42), cart.RamBanks[3][0x10])
+	loaded := cart.Rtc
+	assert.Equal(t, [5]uint8{31, 45, 12, 0x34, 0x01}, [5]uint8{loaded.s, loaded.m, loaded.h, loaded.dl, loaded.dh})
+	assert.Equal(t, uint8(30), loaded.GetItem(0x08), "the latched seconds")
+
+	mbc := cart.CartType.(*Mbc3Cartridge)
+	assert.ErrorIs(t, mbc.loadExtra(make([]byte, 20)), ErrSRAMSize)
+}
+
+func TestMBC3_SaveWithoutRTCHasNoFooter(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0x13
+	rom[SRAM_SIZE_ADDR] = 0x03
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+
+	cart, err := NewCartridgeFromBytes("red", rom)
+	require.NoError(t, err)
+	require.NoError(t, cart.Save())
+	info, err := os.Stat("red.sav")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4*RAM_BANK_SIZE), info.Size())
+}
+
+func TestMBC3_ClockModes(t *testing.T) {
+	_, mbc := mbcNewMBC3(t, 8, 1, true)
+	rtc := mbc.parent.Rtc
+	rtc.s, rtc.m, rtc.h, rtc.dl = 0, 30, 22, 9
+	extra := mbc.saveExtra()
+	require.Len(t, extra, mbc3ExtraSize)
+	saved := binary.LittleEndian.Uint64(extra[40:])
+	binary.LittleEndian.PutUint64(extra[40:], saved-2*60*60) // saved two hours ago
+
+	cases := []struct {
+		name  string
+		clock ClockMode
+		start time.Time
+		want  [4]uint8 // s, m, h, dl
+	}{
+		{"real time", ClockRealTime, time.Time{}, [4]uint8{0, 30, 0, 10}},
+		{"emulated", ClockEmulated, time.Time{}, [4]uint8{0, 30, 22, 9}},
+		{"fixed", ClockFixed, time.Unix(int64(saved)+3*24*60*60+5, 0), [4]uint8{5, 30, 0, 13}},
+		{"fixed before the save", ClockFixed, time.Unix(int64(saved)-24*60*60, 0), [4]uint8{0, 30, 22, 9}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cart, loaded := mbcNewMBC3(t, 8, 1, true)
+			cart.Clock, cart.ClockStart = tc.clock, tc.start
+			require.NoError(t, loaded.loadExtra(extra))
+			r := cart.Rtc
+			assert.Equal(t, tc.want, [4]uint8{r.s, r.m, r.h, r.dl})
+		})
+	}
+
+	cart, fixed := mbcNewMBC3(t, 8, 1, true)
+	cart.Clock, cart.ClockStart = ClockFixed, time.Unix(1e9, 0)
+	assert.Equal(t, uint64(1e9), binary.LittleEndian.Uint64(fixed.saveExtra()[40:]), "stamped with the fixed time")
+}
+
+func TestMBC3_LoadsVBAFooter(t *testing.T) {
+	extra := make([]byte, mbc3ExtraSizeVBA)
+	for i, v := range []uint32{10, 20, 3, 0x40, 0x40, 1, 2, 3, 4, 0} {
+		binary.LittleEndian.PutUint32(extra[4*i:], v)
+	}
+	binary.LittleEndian.PutUint32(extra[40:], 1) // halted, so the date doesn't matter
+
+	cart, mbc := mbcNewMBC3(t, 8, 1, true)
+	require.NoError(t, mbc.loadExtra(extra))
+	r := cart.Rtc
+	assert.Equal(t, [5]uint8{10, 20, 3, 0x40, 0x40}, [5]uint8{r.s, r.m, r.h, r.dl, r.dh})
+	assert.Equal(t, [5]uint8{1, 2, 3, 4, 0}, [5]uint8{r.S, r.M, r.H, r.DL, r.DH})
+}
+
+func TestMBC3_ROMAndRAMBankBits(t *testing.T) {
+	cart, mbc := mbcNewMBC3(t, 128, 4, true)
+	require.NoError(t, mbc.Init())
+	assert.False(t, mbc.mbc30)
+	mbc.SetItem(0x2000, 0xC5)
+	assert.Equal(t, uint16(0x45), cart.RomBankSelected, "7 bits on the MBC3")
+	mbc.SetItem(0x2000, 0x80)
+	assert.Equal(t, uint16(0x01), cart.RomBankSelected, "and 0 still becomes 1")
+	mbc.SetItem(0x4000, 0x06)
+	assert.Equal(t, uint16(0x02), cart.RamBankSelected, "2 RAM bank bits")
+	mbc.SetItem(0x4000, 0x0A)
+	assert.Equal(t, uint16(0x0A), cart.RamBankSelected, "RTC registers untouched")
+}
+
+func TestMBC30(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		romBanks, ramBanks int
+	}{
+		{"256 ROM banks", 256, 4},
+		{"8 RAM banks", 128, 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mbc := mbcNewMBC3(t, tc.romBanks, tc.ramBanks, true)
+			require.NoError(t, mbc.Init())
+			assert.True(t, mbc.mbc30)
+		})
+	}
+
+	cart, mbc := mbcNewMBC3(t, 256, 8, true)
+	require.NoError(t, mbc.Init())
+	mbc.SetItem(0x2000, 0xC5)
+	assert.Equal(t, uint8(0xC5), mbc.GetItem(0x4000), "8 ROM bank bits")
+	mbc.SetItem(0x2000, 0xFF)
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0x7FFF))
+
+	mbc.SetItem(0x0000, 0x0A)
+	for bank := range uint8(8) {
+		mbc.SetItem(0x4000, bank)
+		mbc.SetItem(0xA000, 0x30|bank)
+	}
+	for bank := range uint8(8) {
+		mbc.SetItem(0x4000, bank)
+		assert.Equal(t, 0x30|bank, mbc.GetItem(0xA000), "RAM bank %d", bank)
+		assert.Equal(t, 0x30|bank, cart.RamBanks[bank][0])
+	}
+	mbc.SetItem(0x4000, 0x08)
+	mbc.SetItem(0xA000, 0x2A)
+	assert.Equal(t, uint8(0x2A), cart.Rtc.s, "RTC registers still at 8")
+}
+
 func mbcNewMBC5(t *testing.T, romBanks, ramBanks int) (*Cartridge, *Mbc5Cartridge) {
 	t.Helper()
 	cart := mbcNewTestCart(romBanks, ramBanks)
@@ -544,8 +2158,6 @@ func TestMBC5_RAM_DisabledReadsFF(t *testing.T) {
 	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xBFFF))
 }
 
-// RamBankCount=1 is the smallest non-degenerate setup that keeps the
-// production indexing `RamBankSelected & RamBankCount` inside the array.
 func TestMBC5_RAM_EnabledReadWrite(t *testing.T) {
 	cart, mbc := mbcNewMBC5(t, 16, 1)
 	mbc.SetItem(0x0000, 0x0A)
@@ -555,6 +2167,69 @@ func TestMBC5_RAM_EnabledReadWrite(t *testing.T) {
 	assert.Equal(t, uint8(0xAB), mbc.GetItem(0xA000))
 }
 
+func TestMBC5_RAM_Banks(t *testing.T) {
+	cart, mbc := mbcNewMBC5(t, 16, 16)
+	mbc.SetItem(0x0000, 0x0A)
+	for bank := range uint8(16) {
+		mbc.SetItem(0x4000, bank)
+		mbc.SetItem(0xA000, 0x50|bank)
+	}
+	for bank := range uint8(16) {
+		assert.Equal(t, 0x50|bank, cart.RamBanks[bank][0], "bank %d", bank)
+	}
+
+	cart, mbc = mbcNewMBC5(t, 16, 4)
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0x4000, 0x06)
+	mbc.SetItem(0xA000, 0x66)
+	assert.Equal(t, uint8(0x66), cart.RamBanks[2][0], "mirrored past the last bank")
+}
+
+func TestMBC5_Rumble(t *testing.T) {
+	cart := mbcNewTestCart(16, 4)
+	mbc := &Mbc5Cartridge{parent: cart, hasRumble: true}
+	require.NoError(t, mbc.Init())
+	var events []bool
+	cart.Rumble = func(on bool) { events = append(events, on) }
+
+	mbc.SetItem(0x0000, 0x0A)
+	mbc.SetItem(0x4000, 0x0B)
+	assert.Equal(t, uint16(0x03), cart.RamBankSelected, "bit 3 is the motor")
+	mbc.SetItem(0xA000, 0x33)
+	assert.Equal(t, uint8(0x33), cart.RamBanks[3][0])
+
+	mbc.SetItem(0x4000, 0x0A)
+	mbc.SetItem(0x4000, 0x02)
+	mbc.SetItem(0x4000, 0x00)
+	mbc.SetItem(0x4000, 0x08)
+	assert.Equal(t, []bool{true, false, true}, events, "only changes are heard")
+
+	buf := mbc.Serialize()
+	other := &Mbc5Cartridge{parent: mbcNewTestCart(16, 4)}
+	require.NoError(t, other.Deserialize(buf))
+	assert.True(t, other.motor)
+
+	cart.Rumble = nil
+	assert.NotPanics(t, func() { mbc.SetItem(0x4000, 0x00) })
+}
+
+func TestMBC5_BatteryLoadsSave(t *testing.T) {
+	rom := make([]byte, 2*int(MEMORY_BANK_SIZE))
+	rom[CARTRIDGE_TYPE_ADDR] = 0x1E
+	rom[SRAM_SIZE_ADDR] = 0x03
+	rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+	t.Chdir(t.TempDir())
+
+	cart, err := NewCartridgeFromBytes("rumble", rom)
+	require.NoError(t, err)
+	cart.RamBanks[3][0x1FFF] = 0x77
+	require.NoError(t, cart.Save())
+
+	cart, err = NewCartridgeFromBytes("rumble", rom)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0x77), cart.RamBanks[3][0x1FFF])
+}
+
 func TestMBC5_RAM_DisabledWritesDropped(t *testing.T) {
 	cart, mbc := mbcNewMBC5(t, 16, 1)
 	mbc.SetItem(0xA000, 0x42)
@@ -708,6 +2383,268 @@ func TestRTC_SerializeRoundtrip(t *testing.T) {
 	assert.Equal(t, uint64(9999), other.internalCycleCounter)
 }
 
+func mbcNewUnlicensed(t *testing.T, mapper string, romBanks int) (*Cartridge, CartridgeType) {
+	t.Helper()
+	cart := mbcNewTestCart(romBanks, 0)
+	cart.Mapper = mapper
+	cart.CartType = UNLICENSED_TABLE[mapper](cart)
+	require.NoError(t, cart.CartType.Init())
+	return cart, cart.CartType
+}
+
+func TestWisdomTree_Banking(t *testing.T) {
+	_, mbc := mbcNewUnlicensed(t, "wisdom-tree", 8)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x0000))
+	assert.Equal(t, uint8(1), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x0002, 0xAA) // the address picks the bank, not the value
+	assert.Equal(t, uint8(4), mbc.GetItem(0x0000))
+	assert.Equal(t, uint8(5), mbc.GetItem(0x7FFF))
+
+	mbc.SetItem(0x3F05, 0x00)
+	assert.Equal(t, uint8(2), mbc.GetItem(0x0000), "bank 5 wraps in 128 KiB")
+	assert.Equal(t, uint8(0xFF), mbc.GetItem(0xA000))
+}
+
+func TestM161_Latch(t *testing.T) {
+	_, mbc := mbcNewUnlicensed(t, "m161", 16)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x0000), "menu")
+	assert.Equal(t, uint8(1), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x2000, 0x05) // the games' own bank writes go nowhere
+	assert.Equal(t, uint8(1), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x4000, 0xFB)
+	assert.Equal(t, uint8(6), mbc.GetItem(0x0000))
+	assert.Equal(t, uint8(7), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x4000, 0x01)
+	assert.Equal(t, uint8(6), mbc.GetItem(0x0000), "latched until power-off")
+}
+
+func TestSachen_Banking(t *testing.T) {
+	cart, mbc := mbcNewUnlicensed(t, "sachen-mmc1", 64)
+	cart.SkipBoot()
+	assert.Equal(t, uint8(1), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x2000, 0x05)
+	assert.Equal(t, uint8(5), mbc.GetItem(0x4000))
+	mbc.SetItem(0x2000, 0x00)
+	assert.Equal(t, uint8(1), mbc.GetItem(0x4000), "0 selects 1")
+
+	mbc.SetItem(0x0000, 0x20)
+	mbc.SetItem(0x4000, 0x30)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x0000), "base and mask locked out")
+
+	mbc.SetItem(0x2000, 0x30)
+	mbc.SetItem(0x0000, 0x20)
+	mbc.SetItem(0x4000, 0x30)
+	mbc.SetItem(0x2000, 0x03)
+	assert.Equal(t, uint8(0x20), mbc.GetItem(0x0000))
+	assert.Equal(t, uint8(0x23), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x0000, 0x10)
+	assert.Equal(t, uint8(0x20), mbc.GetItem(0x0000), "locked out again")
+}
+
+func TestSachen_BootLock(t *testing.T) {
+	cart, mbc := mbcNewUnlicensed(t, "sachen-mmc1", 2)
+	cart.RomBanks[0][0x0104] = 0xAA
+	cart.RomBanks[0][0x0184] = 0xBB
+
+	for i := 0; i < sachenStageReads; i++ {
+		require.Equal(t, uint8(0xAA), mbc.GetItem(0x0104), "read %d shows Sachen's logo", i)
+	}
+	assert.Equal(t, uint8(0), mbc.GetItem(0x0004), "reads outside the header don't count")
+	for i := 0; i < sachenStageReads; i++ {
+		require.Equal(t, uint8(0xBB), mbc.GetItem(0x0104), "read %d shows Nintendo's", i)
+	}
+	assert.Equal(t, uint8(0xAA), mbc.GetItem(0x0104), "unlocked")
+}
+
+func TestSachen_PeekLeavesBootLock(t *testing.T) {
+	cart, mbc := mbcNewUnlicensed(t, "sachen-mmc2", 2)
+	cart.RomBanks[0][sachenScramble(0x0184)] = 0xBB
+	for range 2 * sachenStageReads {
+		require.Equal(t, uint8(0), cart.Peek(0x0104))
+	}
+	for range sachenStageReads {
+		mbc.GetItem(0x0104)
+	}
+	assert.Equal(t, uint8(0xBB), cart.Peek(0x0104), "peeks see the logo check")
+	assert.Equal(t, uint8(0xBB), mbc.GetItem(0x0104), "and didn't unlock it")
+}
+
+func TestSachen_MMC2Scramble(t *testing.T) {
+	assert.Equal(t, uint16(0x0140), sachenScramble(0x0101))
+	assert.Equal(t, uint16(0x0101), sachenScramble(0x0140))
+	assert.Equal(t, uint16(0x0110), sachenScramble(0x0102))
+	assert.Equal(t, uint16(0x0102), sachenScramble(0x0110))
+	assert.Equal(t, uint16(0x01AC), sachenScramble(0x01AC))
+
+	cart, mbc := mbcNewUnlicensed(t, "sachen-mmc2", 2)
+	cart.SkipBoot()
+	cart.RomBanks[0][0x0140] = 0xCC
+	assert.Equal(t, uint8(0xCC), mbc.GetItem(0x0101))
+	assert.Equal(t, uint8(0x00), mbc.GetItem(0x0140))
+
+	cart.RomBanks[0][0x0001] = 0xDD
+	assert.Equal(t, uint8(0xDD), mbc.GetItem(0x0001), "only the header is scrambled")
+}
+
+func TestRocket_Banking(t *testing.T) {
+	_, mbc := mbcNewUnlicensed(t, "rocket", 32)
+	mbc.SetItem(0x2000, 0x03)
+	assert.Equal(t, uint8(0), mbc.GetItem(0x0000), "menu")
+	assert.Equal(t, uint8(3), mbc.GetItem(0x4000))
+
+	mbc.SetItem(0x6000, 0x03)
+	mbc.SetItem(0x7000, 0x08)
+	assert.Equal(t, uint8(8), mbc.GetItem(0x0000))
+	mbc.SetItem(0x2000, 0x02)
+	assert.Equal(t, uint8(10), mbc.GetItem(0x4000))
+	mbc.SetItem(0x2000, 0x05)
+	assert.Equal(t, uint8(9), mbc.GetItem(0x4000), "confined to the game's 4 banks")
+
+	mbc.SetItem(0x6000, 0xFF)
+	mbc.SetItem(0x7000, 0x10)
+	assert.Equal(t, uint8(8), mbc.GetItem(0x0000), "locked")
+	assert.Equal(t, uint8(9), mbc.GetItem(0x4000))
+}
+
+func TestUnlicensed_SerializeRoundtrip(t *testing.T) {
+	for _, mapper := range Mappers() {
+		t.Run(mapper, func(t *testing.T) {
+			_, mbc := mbcNewUnlicensed(t, mapper, 32)
+			for _, w := range []struct {
+				addr  uint16
+				value uint8
+			}{{0x2000, 0x30}, {0x0003, 0x12}, {0x4000, 0x32}, {0x6000, 0x07}, {0x7000, 0x04}, {0x2000, 0x35}} {
+				mbc.SetItem(w.addr, w.value)
+			}
+			mbc.GetItem(0x0104)
+
+			buf := mbc.Serialize()
+			want := append([]byte(nil), buf.Bytes()...)
+			_, other := mbcNewUnlicensed(t, mapper, 32)
+			require.NoError(t, other.Deserialize(buf))
+			assert.Equal(t, want, other.Serialize().Bytes())
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
+// mbcUnlicensedROM returns a ROM image of romBanks banks, each filled with
+// its number, for edit to dress up as an unlicensed cartridge.
+func mbcUnlicensedROM(romBanks int, edit func(rom []byte)) []byte {
+	var rom []byte
+	for _, bank := range mbcMakeBankedROM(romBanks) {
+		rom = append(rom, bank...)
+	}
+	if edit != nil {
+		edit(rom)
+	}
+	return rom
+}
+
+func TestDetectMapper(t *testing.T) {
+	cases := []struct {
+		name     string
+		romBanks int
+		edit     func(rom []byte)
+		want     string
+	}{
+		{"licensed", 8, nil, ""},
+		{"Wisdom Tree", 8, func(rom []byte) { copy(rom[0x2000:], "(C) WISDOM TREE") }, "wisdom-tree"},
+		{"Wisdom Tree NUL", 8, func(rom []byte) { copy(rom[0x2000:], "WISDOM\x00TREE") }, "wisdom-tree"},
+		{"Wisdom Tree 32K", 2, func(rom []byte) { copy(rom[0x2000:], "WISDOM TREE") }, ""},
+		{"Sachen MMC1", 8, func(rom []byte) { copy(rom[0x0184:], nintendoLogo[:]) }, "sachen-mmc1"},
+		{"Sachen MMC2", 8, func(rom []byte) {
+			for i, b := range nintendoLogo {
+				rom[sachenScramble(0x0184+uint16(i))] = b
+			}
+		}, "sachen-mmc2"},
+		{"Nintendo logo at both", 8, func(rom []byte) {
+			copy(rom[NINTENDO_LOGO_START_ADDR:], nintendoLogo[:])
+			copy(rom[0x0184:], nintendoLogo[:])
+		}, ""},
+		{"M161", 16, func(rom []byte) {
+			rom[CARTRIDGE_TYPE_ADDR] = 0x10
+			rom[ROM_SIZE_ADDR] = 0x00
+		}, "m161"},
+		{"MBC3 256K", 16, func(rom []byte) {
+			rom[CARTRIDGE_TYPE_ADDR] = 0x10
+			rom[ROM_SIZE_ADDR] = 0x03
+		}, ""},
+		{"Rocket Games", 8, func(rom []byte) { copy(rom[0x3000:], "ROCKET GAMES") }, "rocket"},
+		{"Hong Kong multicart", 32, func(rom []byte) {
+			copy(rom[TITLE_START_ADDR:], "SUPER 4 IN 1")
+			rom[ROM_SIZE_ADDR] = 0x01
+		}, "rocket"},
+		{"MBC1 quoting Rocket Games", 8, func(rom []byte) {
+			rom[CARTRIDGE_TYPE_ADDR], rom[ROM_SIZE_ADDR] = 0x01, 0x02
+			copy(rom[0x3000:], "ROCKET GAMES")
+		}, ""},
+		{"MBC1 quoting Wisdom Tree", 8, func(rom []byte) {
+			rom[CARTRIDGE_TYPE_ADDR], rom[ROM_SIZE_ADDR] = 0x01, 0x02
+			copy(rom[0x2000:], "WISDOM TREE")
+		}, ""},
+		{"Rocket Games in a fitting header", 8, func(rom []byte) {
+			rom[CARTRIDGE_TYPE_ADDR], rom[ROM_SIZE_ADDR] = 0x01, 0x02
+			copy(rom[TITLE_START_ADDR:], "ROCKET GAMES")
+		}, "rocket"},
+		{"N IN 1 of its declared size", 4, func(rom []byte) {
+			copy(rom[TITLE_START_ADDR:], "SUPER 4IN1")
+			rom[ROM_SIZE_ADDR] = 0x01
+		}, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rom := mbcUnlicensedROM(tc.romBanks, tc.edit)
+			assert.Equal(t, tc.want, detectMapper(LoadRomBanks(rom, false)))
+		})
+	}
+}
+
+func TestNewCartridgeFromBytes_LicensedQuotingUnlicensed(t *testing.T) {
+	for _, name := range []string{"ROCKET GAMES", "WISDOM TREE"} {
+		rom := mbcUnlicensedROM(8, func(rom []byte) {
+			rom[CARTRIDGE_TYPE_ADDR], rom[ROM_SIZE_ADDR] = 0x01, 0x02
+			copy(rom[0x3000:], name)
+		})
+		rom[HEADER_CHECKSUM_ADDR] = headerChecksum(rom)
+		cart, err := NewCartridgeFromBytes("game", rom)
+		require.NoError(t, err)
+		assert.IsType(t, &Mbc1Cartridge{}, cart.CartType, name)
+		assert.Empty(t, cart.Mapper)
+	}
+}
+
+func TestNewCartridgeFromBytes_Unlicensed(t *testing.T) {
+	rom := mbcUnlicensedROM(8, func(rom []byte) { copy(rom[0x3000:], "ROCKET GAMES") })
+	cart, err := NewCartridgeFromBytes("multicart", rom)
+	require.NoError(t, err, "no header checks for an unlicensed cartridge")
+	assert.IsType(t, &RocketCartridge{}, cart.CartType)
+	assert.Equal(t, "rocket", cart.Mapper)
+	assert.Equal(t, "ROCKET / HONG KONG MULTICART", cart.GetCartType())
+	assert.Equal(t, uint16(8), cart.RomBanksCount)
+	assert.Equal(t, uint16(0), cart.RamBankCount)
+
+	cart, err = NewCartridgeFromBytesWithMapper("multicart", rom, "wisdom-tree")
+	require.NoError(t, err)
+	assert.IsType(t, &WisdomTreeCartridge{}, cart.CartType, "--mapper beats detection")
+
+	rom = mbcUnlicensedROM(2, nil)
+	cart, err = NewCartridgeFromBytesWithMapper("game", rom, "sachen-mmc2")
+	require.NoError(t, err)
+	assert.IsType(t, &SachenCartridge{}, cart.CartType)
+	assert.True(t, cart.CartType.(*SachenCartridge).mmc2)
+
+	_, err = NewCartridgeFromBytesWithMapper("game", rom, "mbc9")
+	assert.ErrorIs(t, err, ErrUnsupportedMBC)
+	assert.ErrorContains(t, err, `"mbc9"`)
+}
+
 var _ CartridgeType = (*RomOnlyCartridge)(nil)
 var _ CartridgeType = (*Mbc1Cartridge)(nil)
 var _ CartridgeType = (*Mbc3Cartridge)(nil)
@@ -0,0 +1,191 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Mmm01Cartridge is the mapper of multi-game compilations. It starts out
+// unmapped, showing the last 32 KiB of ROM where the menu lives. The menu
+// sets the chosen game's ROM/RAM base and size, then sets the map bit;
+// from then on the base bits and masks are frozen and the game sees an
+// MBC1-like mapper confined to its own slice of the cartridge.
+//
+//	$0000-$1FFF  RAMG  bit 6 map, bits 5-4 RAM bank mask, bits 3-0 RAM enable
+//	$2000-$3FFF  ROMB  bits 6-5 ROM bank mid, bits 4-0 ROM bank low
+//	$4000-$5FFF  RAMB  bit 6 mode lock, bits 5-4 ROM bank high,
+//	                   bits 3-2 RAM bank high, bits 1-0 RAM bank low
+//	$6000-$7FFF  MODE  bit 6 multiplex, bits 5-2 ROM bank mask, bit 0 mode
+//
+// Everything but RAM enable, the low bank bits and the mode is only
+// writable while unmapped. Mask bits mark bank bits as part of the base,
+// which the game can no longer change.
+type Mmm01Cartridge struct {
+	parent     *Cartridge
+	romLow     uint8 // ROM bank bits 4-0
+	romMid     uint8 // ROM bank bits 6-5
+	romHigh    uint8 // ROM bank bits 8-7
+	romMask    uint8 // fixed bits of romLow
+	ramLow     uint8 // RAM bank bits 1-0
+	ramHigh    uint8 // RAM bank bits 3-2
+	ramMask    uint8 // fixed bits of ramLow
+	mode       bool  // MBC1 banking mode
+	modeLocked bool  // mode can no longer be written once mapped
+	multiplex  bool  // swap ramLow and romMid, as MBC1 wires them
+	mapped     bool
+	hasBattery bool
+}
+
+func (c *Mmm01Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romLow)     // ROM Bank Low
+	binary.Write(buf, binary.LittleEndian, c.romMid)     // ROM Bank Mid
+	binary.Write(buf, binary.LittleEndian, c.romHigh)    // ROM Bank High
+	binary.Write(buf, binary.LittleEndian, c.romMask)    // ROM Bank Mask
+	binary.Write(buf, binary.LittleEndian, c.ramLow)     // RAM Bank Low
+	binary.Write(buf, binary.LittleEndian, c.ramHigh)    // RAM Bank High
+	binary.Write(buf, binary.LittleEndian, c.ramMask)    // RAM Bank Mask
+	binary.Write(buf, binary.LittleEndian, c.mode)       // Mode
+	binary.Write(buf, binary.LittleEndian, c.modeLocked) // Mode Locked
+	binary.Write(buf, binary.LittleEndian, c.multiplex)  // Multiplex
+	binary.Write(buf, binary.LittleEndian, c.mapped)     // Mapped
+	binary.Write(buf, binary.LittleEndian, c.hasBattery) // Has Battery
+	logger.Debug("Serialized MMM01 state")
+	return buf
+}
+
+func (c *Mmm01Cartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{
+		&c.romLow, &c.romMid, &c.romHigh, &c.romMask,
+		&c.ramLow, &c.ramHigh, &c.ramMask,
+		&c.mode, &c.modeLocked, &c.multiplex, &c.mapped, &c.hasBattery,
+	} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Mmm01Cartridge) Init() error {
+	if c.hasBattery {
+		return LoadSRAM(c.parent.GetFilename(), &c.parent.RamBanks, c.parent.RamBankCount)
+	}
+	return nil
+}
+
+func (c *Mmm01Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x2000:
+		c.parent.RamBankEnabled = (value & 0x0f) == 0x0a
+		if !c.mapped {
+			c.ramMask = (value >> 4) & 0x03
+			c.mapped = value&0x40 != 0
+		}
+
+	case 0x2000 <= addr && addr < 0x4000:
+		writable := uint8(0x1f)
+		if c.mapped {
+			writable &^= c.romMask
+		} else {
+			c.romMid = (value >> 5) & 0x03
+		}
+		c.romLow = c.romLow&^writable | value&writable
+
+	case 0x4000 <= addr && addr < 0x6000:
+		writable := uint8(0x03)
+		if c.mapped {
+			writable &^= c.ramMask
+		} else {
+			c.ramHigh = (value >> 2) & 0x03
+			c.romHigh = (value >> 4) & 0x03
+			c.modeLocked = value&0x40 != 0
+		}
+		c.ramLow = c.ramLow&^writable | value&writable
+
+	case 0x6000 <= addr && addr < 0x8000:
+		if !c.mapped || !c.modeLocked {
+			c.mode = value&0x01 != 0
+			c.parent.MemoryModel = value & 0x01
+		}
+		if !c.mapped {
+			c.romMask = (value >> 1) & 0x1e
+			c.multiplex = value&0x40 != 0
+		}
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.parent.RamBankEnabled || c.parent.RamBankCount == 0 {
+			return
+		}
+		c.parent.RamBankSelected = c.ramBank()
+		c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000] = value
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *Mmm01Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[c.romBank(false)][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		c.parent.RomBankSelected = c.romBank(true)
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if !c.parent.RamBankEnabled || c.parent.RamBankCount == 0 {
+			return 0xff
+		}
+		c.parent.RamBankSelected = c.ramBank()
+		return c.parent.RamBanks[c.parent.RamBankSelected][addr-0xA000]
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
+
+// romBank returns the bank mapped at $4000-$7FFF when upper is set, and
+// at $0000-$3FFF otherwise.
+func (c *Mmm01Cartridge) romBank(upper bool) uint16 {
+	count := c.parent.RomBanksCount
+	if !c.mapped {
+		// every bank bit but the lowest reads as 1: the last 32 KiB
+		if upper || count < 2 {
+			return count - 1
+		}
+		return count - 2
+	}
+
+	low, mid := c.romLow, c.romMid
+	if c.multiplex {
+		mid = c.ramLow
+	}
+	if upper {
+		// the bank 0 quirk only looks at the bits the game controls
+		if low&^c.romMask == 0 {
+			low |= 0x01
+		}
+	} else {
+		low &= c.romMask
+		if c.multiplex && !c.mode {
+			mid &= c.ramMask
+		}
+	}
+	bank := uint16(c.romHigh)<<7 | uint16(mid)<<5 | uint16(low)
+	return bank % count
+}
+
+func (c *Mmm01Cartridge) ramBank() uint16 {
+	low := c.ramLow
+	if c.multiplex {
+		low = c.romMid
+	} else if !c.mode {
+		low &= c.ramMask
+	}
+	bank := uint16(c.ramHigh)<<2 | uint16(low)
+	return bank % c.parent.RamBankCount
+}
@@ -0,0 +1,97 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// RocketCartridge is the mapper of Rocket Games' multicarts and the Hong
+// Kong multicarts built the same way. A menu in the first 32 KiB picks a
+// game by setting where it starts and how big it is, which locks those
+// registers; the game then sees a plain bank register confined to its
+// own slice of the ROM, bank 0 being its first bank.
+//
+//	$2000-$3FFF  ROM bank within the game; 0 selects 1
+//	$6000-$6FFF  game size, in 16 KiB banks less one, while unlocked
+//	$7000-$7FFF  game's first 16 KiB bank, while unlocked; locks them
+//
+// Until then the menu sees the whole ROM. There is no RAM.
+type RocketCartridge struct {
+	parent  *Cartridge
+	romBank uint8
+	base    uint8 // game's first bank
+	mask    uint8 // game size less one
+	locked  bool
+}
+
+func (c *RocketCartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.romBank) // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.base)    // Game base bank
+	binary.Write(buf, binary.LittleEndian, c.mask)    // Game bank mask
+	binary.Write(buf, binary.LittleEndian, c.locked)  // Locked
+	logger.Debug("Serialized Rocket state")
+	return buf
+}
+
+func (c *RocketCartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&c.romBank, &c.base, &c.mask, &c.locked} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RocketCartridge) Init() error {
+	c.romBank = 1
+	c.mask = 0xff
+	return nil
+}
+
+func (c *RocketCartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case 0x2000 <= addr && addr < 0x4000:
+		if value == 0 {
+			value = 1
+		}
+		c.romBank = value
+
+	case 0x6000 <= addr && addr < 0x7000:
+		if !c.locked {
+			c.mask = value
+		}
+
+	case 0x7000 <= addr && addr < 0x8000:
+		if !c.locked {
+			c.base, c.locked = value, true
+			logger.Debugf("Rocket: game at bank %#x, %d banks", c.base, int(c.mask)+1)
+		}
+
+	case addr < 0x8000, 0xA000 <= addr && addr < 0xC000:
+		// nothing else on the cartridge
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *RocketCartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		c.parent.RomBankSelected = uint16(c.base) % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		c.parent.RomBankSelected = (uint16(c.base) + uint16(c.romBank&c.mask)) % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		return 0xff
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
@@ -6,7 +6,8 @@ type RomOnlyCartridge struct {
 	parent *Cartridge
 }
 
-func (c *RomOnlyCartridge) Init() {
+func (c *RomOnlyCartridge) Init() error {
+	return nil
 }
 
 func (c *RomOnlyCartridge) Serialize() *bytes.Buffer {
@@ -1,7 +1,10 @@
 package cartridge
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -438,38 +441,62 @@ var OldLicenseeCodeMap = map[uint8]string{
 	0xFF: "LJN",
 }
 
-func LoadSRAM(romName string, rambanks *[16][RAM_BANK_SIZE]uint8, ramBankCount uint16) {
+// LoadSRAM fills the first ramBankCount banks from <romName>.sav. A
+// missing save file is not an error; the banks are left untouched.
+func LoadSRAM(romName string, rambanks *[16][RAM_BANK_SIZE]uint8, ramBankCount uint16) error {
+	return loadSRAMSize(romName, rambanks, int(ramBankCount)*int(RAM_BANK_SIZE))
+}
+
+// loadSRAMSize is LoadSRAM for RAM that needn't fill whole banks: it fills
+// the first size bytes of rambanks, bank after bank.
+func loadSRAMSize(romName string, rambanks *[16][RAM_BANK_SIZE]uint8, size int) error {
 	saveName := romName + ".sav"
 	file, err := os.Open(saveName)
-	if err != nil {
+	if errors.Is(err, fs.ErrNotExist) {
 		logger.Info("No save file found")
-		return
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cartridge: opening save file: %w", err)
 	}
 	defer file.Close()
 
-	for i := uint16(0); i < ramBankCount; i++ {
-		if _, err := file.Read(rambanks[i][:]); err != nil {
-			logger.Errorf("Error reading save file: %v", err)
+	for off := 0; off < size; off += int(RAM_BANK_SIZE) {
+		bank := rambanks[off/int(RAM_BANK_SIZE)][:min(size-off, int(RAM_BANK_SIZE))]
+		if n, err := io.ReadFull(file, bank); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("%w: %s holds %d bytes, want %d", ErrSRAMSize, saveName, off+n, size)
+			}
+			return fmt.Errorf("cartridge: reading save file: %w", err)
 		}
 	}
 	absPath, err := filepath.Abs(file.Name())
 	if err != nil {
 		logger.Errorf("Error getting absolute path: %v", err)
 	}
-	logger.Infof("Loaded %d bytes from %s", ramBankCount*RAM_BANK_SIZE, absPath)
+	logger.Infof("Loaded %d bytes from %s", size, absPath)
+	return nil
+}
+
+// SaveSRAM writes the first ramBankCount banks to <romName>.sav.
+func SaveSRAM(romName string, rambanks *[16][RAM_BANK_SIZE]uint8, ramBankCount uint16) error {
+	return saveSRAMSize(romName, rambanks, int(ramBankCount)*int(RAM_BANK_SIZE))
 }
 
-func SaveSRAM(romName string, rambanks *[16][RAM_BANK_SIZE]uint8, ramBankCount uint16) {
+// saveSRAMSize is SaveSRAM for RAM that needn't fill whole banks: it writes
+// the first size bytes of rambanks, bank after bank.
+func saveSRAMSize(romName string, rambanks *[16][RAM_BANK_SIZE]uint8, size int) error {
 	saveName := romName + ".sav"
 	file, err := os.Create(saveName)
 	if err != nil {
-		logger.Errorf("Error creating save file: %v", err)
+		return fmt.Errorf("cartridge: creating save file: %w", err)
 	}
 	defer file.Close()
 
-	for i := uint16(0); i < ramBankCount; i++ {
-		if _, err := file.Write(rambanks[i][:]); err != nil {
-			logger.Errorf("Error writing save file: %v", err)
+	for off := 0; off < size; off += int(RAM_BANK_SIZE) {
+		bank := rambanks[off/int(RAM_BANK_SIZE)][:min(size-off, int(RAM_BANK_SIZE))]
+		if _, err := file.Write(bank); err != nil {
+			return fmt.Errorf("cartridge: writing save file: %w", err)
 		}
 	}
 	absPath, err := filepath.Abs(file.Name())
@@ -477,5 +504,6 @@ func SaveSRAM(romName string, rambanks *[16][RAM_BANK_SIZE]uint8, ramBankCount u
 		logger.Errorf("Error getting absolute path: %v", err)
 	}
 
-	logger.Infof("Saved %d bytes to %s", ramBankCount*RAM_BANK_SIZE, absPath)
+	logger.Infof("Saved %d bytes to %s", size, absPath)
+	return file.Close()
 }
@@ -3,6 +3,7 @@ package cartridge
 import (
 	"bytes"
 	"encoding/binary"
+	"time"
 
 	"github.com/duysqubix/gobc/internal"
 )
@@ -33,6 +34,36 @@ const (
 	DAY
 )
 
+// ClockMode is how the clock of a battery-backed cartridge covers the time
+// between a save and the next load.
+type ClockMode uint8
+
+const (
+	ClockRealTime ClockMode = iota // catch up on the wall-clock time since the save
+	ClockEmulated                  // only move with emulated cycles, for runs that repeat exactly
+	ClockFixed                     // catch up to ClockStart, as though the machine were started then
+)
+
+// clockNow returns the time as the cartridge's clocks see it: what a save
+// is stamped with, and what loading one catches up to.
+func (c *Cartridge) clockNow() time.Time {
+	if c.Clock == ClockFixed {
+		return c.ClockStart
+	}
+	return time.Now()
+}
+
+// clockElapsed returns how far to move on a clock saved at the Unix time
+// saved. Under ClockEmulated, or for a save from the future, that is not
+// at all.
+func (c *Cartridge) clockElapsed(saved uint64) time.Duration {
+	now := c.clockNow().Unix()
+	if c.Clock == ClockEmulated || now <= int64(saved) {
+		return 0
+	}
+	return time.Duration(now-int64(saved)) * time.Second
+}
+
 type RTC struct {
 	internalCycleCounter uint64
 	s                    uint8 // 6-bit seconds counter
@@ -177,6 +208,27 @@ func (r *RTC) SetItem(id uint16, value uint8) {
 	}
 }
 
+// Advance moves the clock on by d, a whole second at a time, as though
+// that much time had passed while the cartridge was on the shelf. A halted
+// clock stays put.
+func (r *RTC) Advance(d time.Duration) {
+	if internal.IsBitSet(r.dh, TIMER_HALT_BIT) || d < time.Second {
+		return
+	}
+	total := uint64(r.s%MAX_SECONDS) + uint64(r.m%MAX_MINUTES)*60 + uint64(r.h%MAX_HOURS)*3600 + uint64(d/time.Second)
+	r.s = uint8(total % 60)
+	r.m = uint8(total / 60 % 60)
+	r.h = uint8(total / 3600 % 24)
+
+	days := uint64(r.internalDayCounter()) + total/86400
+	if days > 0x1FF {
+		internal.SetBit(&r.dh, TIMER_CARRY_BIT)
+		days &= 0x1FF
+	}
+	r.dl = uint8(days)
+	r.dh = r.dh&^0x01 | uint8(days>>8)
+}
+
 func (r *RTC) internalDayCounter() uint16 {
 	return uint16(r.dh&0x1)<<8 | uint16(r.dl)
 }
@@ -185,6 +237,18 @@ func (r *RTC) isDayCounterOverflow() bool {
 	return r.internalDayCounter() > 0x1FF
 }
 
+// NextEvent returns the cycles left until the clock next ticks over a
+// second. ok is false while the clock is halted.
+func (r *RTC) NextEvent() (cycles uint64, ok bool) {
+	if internal.IsBitSet(r.dh, TIMER_HALT_BIT) {
+		return 0, false
+	}
+	if r.internalCycleCounter >= RTCCycles {
+		return 1, true
+	}
+	return RTCCycles - r.internalCycleCounter, true
+}
+
 func (r *RTC) Tick(cycles uint64) {
 	if internal.IsBitSet(r.dh, TIMER_HALT_BIT) {
 		return
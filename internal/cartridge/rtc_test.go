@@ -3,6 +3,7 @@ package cartridge
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/duysqubix/gobc/internal"
 	"github.com/stretchr/testify/assert"
@@ -106,6 +107,45 @@ func TestRTC_Tick_AccumulatesPartialCycles(t *testing.T) {
 	assert.Equal(t, uint8(1), r.s, "the rest of the second should now bump seconds")
 }
 
+func TestRTC_Advance(t *testing.T) {
+	r := NewRTC()
+	r.s, r.m, r.h, r.dl = 50, 59, 23, 0xFF
+	r.Advance(15 * time.Second)
+	assert.Equal(t, [4]uint8{5, 0, 0, 0x00}, [4]uint8{r.s, r.m, r.h, r.dl})
+	assert.Equal(t, uint8(0x01), r.dh, "day 256 sets the day counter's bit 8")
+
+	r.Advance(2*time.Hour + 500*time.Millisecond)
+	assert.Equal(t, uint8(2), r.h, "whole seconds only")
+	assert.Equal(t, uint8(5), r.s)
+
+	r.Advance(256 * 24 * time.Hour)
+	assert.Equal(t, uint8(0x00), r.dl)
+	assert.Equal(t, uint8(0x80), r.dh, "day 512 wraps to 0 and sets the carry")
+	r.Advance(24 * time.Hour)
+	assert.Equal(t, uint8(0x01), r.dl)
+	assert.Equal(t, uint8(0x80), r.dh, "the carry stays until the game clears it")
+
+	internal.SetBit(&r.dh, TIMER_HALT_BIT)
+	r.Advance(time.Hour)
+	assert.Equal(t, uint8(2), r.h, "a halted clock stays put")
+}
+
+func TestRTC_NextEvent(t *testing.T) {
+	r := NewRTC()
+	r.Tick(RTCCycles / 4)
+
+	cycles, ok := r.NextEvent()
+	require.True(t, ok)
+	r.Tick(cycles - 1)
+	assert.Equal(t, uint8(0), r.s)
+	r.Tick(1)
+	assert.Equal(t, uint8(1), r.s, "the second should tick over exactly at the event")
+
+	internal.SetBit(&r.dh, TIMER_HALT_BIT)
+	_, ok = r.NextEvent()
+	assert.False(t, ok, "a halted clock has nothing to schedule")
+}
+
 func TestRTC_InternalDayCounter_CombinesDhAndDl(t *testing.T) {
 	r := NewRTC()
 	r.dh = 0x01
@@ -0,0 +1,174 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// SachenCartridge is the mapper of Sachen's unlicensed games: the MMC1,
+// or the MMC2 of later cartridges.
+//
+//	$0000-$1FFF  base ROM bank
+//	$2000-$3FFF  ROM bank; 0 selects 1
+//	$4000-$5FFF  ROM bank mask
+//
+// The base and mask only take writes while bits 5-4 of the ROM bank are
+// set. $4000-$7FFF shows the bank whose bits come from the base where the
+// mask is set and from the ROM bank elsewhere; $0000-$3FFF the base's
+// masked bits alone.
+//
+// Sachen cartridges carry their own logo at $0104 and Nintendo's at
+// $0184. The mapper starts locked: it passes the first $30 reads of
+// $0100-$01FF through, as the boot ROM draws the logo, then forces A7
+// high for the next $30 so that the boot ROM's check finds Nintendo's,
+// and then unlocks. The MMC2 also swaps address lines A0 with A6 and A1
+// with A4 for every read of $0100-$01FF, locked or not.
+type SachenCartridge struct {
+	parent   *Cartridge
+	mmc2     bool
+	romBank  uint8
+	baseBank uint8
+	mask     uint8
+	lock     uint8 // stage of the boot lock
+	reads    uint8 // reads of $0100-$01FF in this stage
+}
+
+// Stages of the boot lock.
+const (
+	sachenUnlocked  = iota
+	sachenShowLogo  // passing the header through
+	sachenCheckLogo // forcing A7 high
+)
+
+const sachenStageReads = 0x30
+
+func (c *SachenCartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.mmc2)     // MMC2
+	binary.Write(buf, binary.LittleEndian, c.romBank)  // ROM Bank Select
+	binary.Write(buf, binary.LittleEndian, c.baseBank) // Base ROM Bank
+	binary.Write(buf, binary.LittleEndian, c.mask)     // ROM Bank Mask
+	binary.Write(buf, binary.LittleEndian, c.lock)     // Boot lock stage
+	binary.Write(buf, binary.LittleEndian, c.reads)    // Boot lock reads
+	logger.Debug("Serialized Sachen state")
+	return buf
+}
+
+func (c *SachenCartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&c.mmc2, &c.romBank, &c.baseBank, &c.mask, &c.lock, &c.reads} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *SachenCartridge) Init() error {
+	c.romBank = 1
+	c.lock = sachenShowLogo
+	return nil
+}
+
+// skipBoot unlocks the mapper, as the boot ROM leaves it.
+func (c *SachenCartridge) skipBoot() {
+	c.lock, c.reads = sachenUnlocked, 0
+}
+
+func (c *SachenCartridge) SetItem(addr uint16, value uint8) {
+	writable := c.romBank&0x30 == 0x30
+	switch {
+	case addr < 0x2000:
+		if writable {
+			c.baseBank = value
+		}
+
+	case 0x2000 <= addr && addr < 0x4000:
+		if value == 0 {
+			value = 1
+		}
+		c.romBank = value
+
+	case 0x4000 <= addr && addr < 0x6000:
+		if writable {
+			c.mask = value
+		}
+
+	case 0x6000 <= addr && addr < 0x8000, 0xA000 <= addr && addr < 0xC000:
+		// nothing else on the cartridge
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *SachenCartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		if addr&0xff00 == 0x0100 {
+			addr = c.header(addr)
+		}
+		c.parent.RomBankSelected = uint16(c.baseBank&c.mask) % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		bank := c.baseBank&c.mask | c.romBank&^c.mask
+		c.parent.RomBankSelected = uint16(bank) % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		return 0xff
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
+
+// peek reads addr without moving the boot lock on.
+func (c *SachenCartridge) peek(addr uint16) uint8 {
+	if addr&0xff00 == 0x0100 {
+		bank := uint16(c.baseBank&c.mask) % c.parent.RomBanksCount
+		return c.parent.RomBanks[bank][c.headerAt(addr)]
+	}
+	return c.GetItem(addr)
+}
+
+// headerAt returns where a read of addr, in $0100-$01FF, lands in ROM.
+func (c *SachenCartridge) headerAt(addr uint16) uint16 {
+	if c.lock == sachenCheckLogo {
+		addr |= 0x80
+	}
+	if c.mmc2 {
+		addr = sachenScramble(addr)
+	}
+	return addr
+}
+
+// header returns where a read of addr, in $0100-$01FF, lands in ROM, and
+// moves the boot lock on.
+func (c *SachenCartridge) header(addr uint16) uint16 {
+	addr = c.headerAt(addr)
+	if c.lock != sachenUnlocked {
+		if c.reads++; c.reads == sachenStageReads {
+			c.reads = 0
+			if c.lock == sachenShowLogo {
+				c.lock = sachenCheckLogo
+			} else {
+				c.lock = sachenUnlocked
+			}
+		}
+	}
+	return addr
+}
+
+// sachenScramble swaps address lines A0 and A6, and A1 and A4, as the
+// MMC2 does for the header.
+func sachenScramble(addr uint16) uint16 {
+	s := addr & 0xffac
+	s |= (addr & 0x40) >> 6
+	s |= (addr & 0x10) >> 3
+	s |= (addr & 0x02) << 3
+	s |= (addr & 0x01) << 6
+	return s
+}
@@ -0,0 +1,324 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Tama5Cartridge is Bandai's TAMA5 mapper (Tamagotchi 3) with its TAMA6
+// companion: a real-time clock and 32 bytes of battery-backed RAM. It
+// only answers at A000-BFFF, through two ports: odd addresses select one
+// of its 4-bit registers, even ones write the register or read it back.
+//
+//	$0  ROM bank, bits 3-0
+//	$1  ROM bank, bit 4
+//	$4  data, low nibble
+//	$5  data, high nibble
+//	$6  bits 3-1 command, bit 0 address bit 4
+//	$7  address, bits 3-0; writing it runs the command
+//	$A  reads $F1: ready for a command
+//	$C  reads the result's low nibble
+//	$D  reads the result's high nibble
+//
+// The commands are
+//
+//	0  write the data to RAM at the address
+//	1  read RAM at the address
+//	2  TAMA6 control, by address:
+//	     $00/$01 stop/start the clock
+//	     $04/$05 set the minutes/hours to the data, in BCD
+//	     $06/$07 read the minutes/hours, in BCD
+//	     $10/$11 disable/enable the alarm
+//	     $12     read whether the alarm went off, which clears it
+//	4  TAMA6 register pages: the data's low nibble picks the register,
+//	   its high nibble is the value. Address $0 writes the clock page,
+//	   $2 the alarm page, $4 and $6 two pages of free RAM; one more
+//	   reads the register back instead.
+//
+// The clock page holds seconds, minutes, hours, the weekday, the day, the
+// month and the year as BCD nibbles, ones first. The alarm page holds
+// the alarm's minutes and hours at the same registers. The clock runs
+// in 24-hour time, and leap years are those divisible by four.
+type Tama5Cartridge struct {
+	parent     *Cartridge
+	regs       [16]uint8 // one nibble each
+	reg        uint8     // register selected
+	result     uint8
+	pages      [tama6Pages][tama6PageSize]uint8 // clock, alarm and free pages, nibbles
+	cycles     uint64                           // towards the next second
+	running    bool
+	alarmOn    bool
+	alarmFired bool
+	hasBattery bool
+}
+
+const (
+	tama5RamSize = 0x20
+
+	tama6Pages    = 4
+	tama6PageSize = 13
+	tama6Clock    = 0
+	tama6Alarm    = 1
+
+	// registers of the clock and alarm pages
+	tama6Second  = 0x0
+	tama6Minute  = 0x2
+	tama6Hour    = 0x4
+	tama6Weekday = 0x6
+	tama6Day     = 0x7
+	tama6Month   = 0x9
+	tama6Year    = 0xB
+)
+
+func (c *Tama5Cartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.regs)       // Registers
+	binary.Write(buf, binary.LittleEndian, c.reg)        // Register Select
+	binary.Write(buf, binary.LittleEndian, c.result)     // Result
+	binary.Write(buf, binary.LittleEndian, c.pages)      // TAMA6 pages
+	binary.Write(buf, binary.LittleEndian, c.cycles)     // Cycles towards the next second
+	binary.Write(buf, binary.LittleEndian, c.running)    // Clock running
+	binary.Write(buf, binary.LittleEndian, c.alarmOn)    // Alarm enabled
+	binary.Write(buf, binary.LittleEndian, c.alarmFired) // Alarm went off
+	binary.Write(buf, binary.LittleEndian, c.hasBattery) // Has Battery
+	logger.Debug("Serialized TAMA5 state")
+	return buf
+}
+
+func (c *Tama5Cartridge) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{
+		&c.regs, &c.reg, &c.result, &c.pages, &c.cycles,
+		&c.running, &c.alarmOn, &c.alarmFired, &c.hasBattery,
+	} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Tama5Cartridge) Init() error {
+	// the 32 bytes of RAM sit at the start of one bank, and the save
+	// file holds them alone before the clock
+	c.parent.RamBankCount = 1
+	c.parent.RtcEnabled = true
+	if !c.hasBattery {
+		return nil
+	}
+	if err := loadSRAMSize(c.parent.GetFilename(), &c.parent.RamBanks, tama5RamSize); err != nil {
+		return err
+	}
+	extra, err := loadSaveExtra(c.parent.GetFilename(), c.parent.SRAMSize())
+	if err != nil || extra == nil {
+		return err
+	}
+	return c.loadExtra(extra)
+}
+
+func (c *Tama5Cartridge) sramSize() int {
+	return tama5RamSize
+}
+
+// The clock goes in the save file after RAM, in a layout of gobc's own:
+// the Unix time of the save, the TAMA6 pages, then bit 0 running, bit 1
+// alarm enabled and bit 2 alarm gone off.
+const tama5ExtraSize = 8 + tama6Pages*tama6PageSize + 1
+
+func (c *Tama5Cartridge) saveExtra() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, uint64(c.parent.clockNow().Unix()))
+	binary.Write(buf, binary.LittleEndian, c.pages)
+	var flags uint8
+	for i, set := range []bool{c.running, c.alarmOn, c.alarmFired} {
+		if set {
+			flags |= 1 << i
+		}
+	}
+	buf.WriteByte(flags)
+	return buf.Bytes()
+}
+
+func (c *Tama5Cartridge) loadExtra(data []byte) error {
+	if len(data) < tama5ExtraSize {
+		return fmt.Errorf("%w: %d bytes of clock data, want %d", ErrSRAMSize, len(data), tama5ExtraSize)
+	}
+	r := bytes.NewReader(data)
+	var saved uint64
+	var flags uint8
+	for _, v := range []any{&saved, &c.pages, &flags} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	c.running, c.alarmOn, c.alarmFired = flags&0x01 != 0, flags&0x02 != 0, flags&0x04 != 0
+
+	// the clock kept running while the game was off
+	if elapsed := c.parent.clockElapsed(saved); c.running && elapsed > 0 {
+		c.advance(elapsed)
+	}
+	return nil
+}
+
+func (c *Tama5Cartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x8000:
+		// the TAMA5 has no registers here
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if addr&0x01 != 0 {
+			c.reg = value & 0x0f
+			return
+		}
+		c.regs[c.reg] = value & 0x0f
+		if c.reg == 0x7 {
+			c.command()
+		}
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *Tama5Cartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return c.parent.RomBanks[0][addr]
+
+	case 0x4000 <= addr && addr < 0x8000:
+		bank := uint16(c.regs[0x1]&0x01)<<4 | uint16(c.regs[0x0])
+		c.parent.RomBankSelected = bank % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr-0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		if addr&0x01 != 0 {
+			return 0xff
+		}
+		switch c.reg {
+		case 0xc:
+			return 0xf0 | c.result&0x0f
+		case 0xd:
+			return 0xf0 | c.result>>4
+		}
+		return 0xf1
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
+
+func (c *Tama5Cartridge) command() {
+	addr := (c.regs[0x6]&0x01)<<4 | c.regs[0x7]
+	data := c.regs[0x5]<<4 | c.regs[0x4]
+	ram := c.parent.RamBanks[0][:tama5RamSize]
+	switch c.regs[0x6] >> 1 {
+	case 0x0:
+		ram[addr] = data
+	case 0x1:
+		c.result = ram[addr]
+	case 0x2:
+		c.control(addr, data)
+	case 0x4:
+		reg, page := data&0x0f, addr>>1
+		if reg >= tama6PageSize || page >= tama6Pages {
+			return
+		}
+		if addr&0x01 != 0 {
+			c.result = c.pages[page][reg]
+			return
+		}
+		c.pages[page][reg] = data >> 4
+	default:
+		logger.Debugf("TAMA5: unknown command %#x", c.regs[0x6]>>1)
+	}
+}
+
+func (c *Tama5Cartridge) control(addr, data uint8) {
+	clock := &c.pages[tama6Clock]
+	switch addr {
+	case 0x00, 0x01:
+		c.running = addr == 0x01
+	case 0x04:
+		clock[tama6Minute], clock[tama6Minute+1] = data&0x0f, data>>4
+		clock[tama6Second], clock[tama6Second+1] = 0, 0
+		c.cycles = 0
+	case 0x05:
+		clock[tama6Hour], clock[tama6Hour+1] = data&0x0f, data>>4
+	case 0x06:
+		c.result = clock[tama6Minute+1]<<4 | clock[tama6Minute]
+	case 0x07:
+		c.result = clock[tama6Hour+1]<<4 | clock[tama6Hour]
+	case 0x10, 0x11:
+		c.alarmOn = addr == 0x11
+	case 0x12:
+		c.result = 0
+		if c.alarmFired {
+			c.result = 1
+		}
+		c.alarmFired = false
+	default:
+		logger.Debugf("TAMA5: unknown TAMA6 command %#02x", addr)
+	}
+}
+
+// bcd reads the two-nibble BCD number at reg of page.
+func (c *Tama5Cartridge) bcd(page, reg int) int {
+	return int(c.pages[page][reg+1])*10 + int(c.pages[page][reg])
+}
+
+func (c *Tama5Cartridge) setBCD(reg int, v int) {
+	c.pages[tama6Clock][reg], c.pages[tama6Clock][reg+1] = uint8(v%10), uint8(v/10)
+}
+
+// time returns the clock page as a time in 2000-2099. A clock never set
+// starts on the 1st of January.
+func (c *Tama5Cartridge) time() time.Time {
+	return time.Date(2000+c.bcd(tama6Clock, tama6Year), time.Month(max(c.bcd(tama6Clock, tama6Month), 1)),
+		max(c.bcd(tama6Clock, tama6Day), 1), c.bcd(tama6Clock, tama6Hour), c.bcd(tama6Clock, tama6Minute),
+		c.bcd(tama6Clock, tama6Second), 0, time.UTC)
+}
+
+// advance moves the clock d on, the weekday with it.
+func (c *Tama5Cartridge) advance(d time.Duration) {
+	was := c.time()
+	now := was.Add(d)
+	days := now.Unix()/86400 - was.Unix()/86400
+	weekday := &c.pages[tama6Clock][tama6Weekday]
+	*weekday = uint8((int64(*weekday) + days) % 7)
+
+	c.setBCD(tama6Second, now.Second())
+	c.setBCD(tama6Minute, now.Minute())
+	c.setBCD(tama6Hour, now.Hour())
+	c.setBCD(tama6Day, now.Day())
+	c.setBCD(tama6Month, int(now.Month()))
+	c.setBCD(tama6Year, now.Year()%100)
+}
+
+// Tick runs the clock, a second at a time, and sets the alarm off when
+// the clock reaches it.
+func (c *Tama5Cartridge) Tick(cycles uint64) {
+	if !c.running {
+		return
+	}
+	c.cycles += cycles
+	for c.cycles >= RTCCycles {
+		c.cycles -= RTCCycles
+		c.advance(time.Second)
+		if c.alarmOn && c.bcd(tama6Clock, tama6Second) == 0 &&
+			c.bcd(tama6Clock, tama6Minute) == c.bcd(tama6Alarm, tama6Minute) &&
+			c.bcd(tama6Clock, tama6Hour) == c.bcd(tama6Alarm, tama6Hour) {
+			c.alarmFired = true
+		}
+	}
+}
+
+// NextEvent returns the cycles left until the clock's next second.
+func (c *Tama5Cartridge) NextEvent() (cycles uint64, ok bool) {
+	if !c.running {
+		return 0, false
+	}
+	return RTCCycles - c.cycles, true
+}
@@ -0,0 +1,146 @@
+package cartridge
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// UNLICENSED_TABLE holds the mappers of unlicensed cartridges, whose
+// headers can't be trusted to name them, by the names --mapper takes.
+var UNLICENSED_TABLE = map[string]func(*Cartridge) CartridgeType{
+	"wisdom-tree": func(c *Cartridge) CartridgeType {
+		return &WisdomTreeCartridge{parent: c}
+	},
+	"sachen-mmc1": func(c *Cartridge) CartridgeType {
+		return &SachenCartridge{parent: c}
+	},
+	"sachen-mmc2": func(c *Cartridge) CartridgeType {
+		return &SachenCartridge{parent: c, mmc2: true}
+	},
+	"m161": func(c *Cartridge) CartridgeType {
+		return &M161Cartridge{parent: c}
+	},
+	"rocket": func(c *Cartridge) CartridgeType {
+		return &RocketCartridge{parent: c}
+	},
+}
+
+var UnlicensedTypeMap = map[string]string{
+	"wisdom-tree": "WISDOM TREE",
+	"sachen-mmc1": "SACHEN MMC1",
+	"sachen-mmc2": "SACHEN MMC2",
+	"m161":        "M161",
+	"rocket":      "ROCKET / HONG KONG MULTICART",
+}
+
+// Mappers returns the names UNLICENSED_TABLE knows, sorted.
+func Mappers() []string {
+	return slices.Sorted(maps.Keys(UNLICENSED_TABLE))
+}
+
+// detectMapper guesses which unlicensed mapper rom_banks need, or returns
+// "" when nothing gives them away and the header should be believed.
+func detectMapper(rom_banks [][]uint8) string {
+	bank0 := rom_banks[0]
+	// A publisher's name only counts inside the header of a ROM whose
+	// header describes it, lest a licensed game that happens to carry
+	// the bytes be run on the wrong mapper.
+	names := bank0
+	if headerFits(bank0, len(rom_banks)) {
+		names = bank0[HEADER_START_ADDR : HEADER_END_ADDR+1]
+	}
+	switch {
+	case sachenLogo(bank0, false):
+		return "sachen-mmc1"
+	case sachenLogo(bank0, true):
+		return "sachen-mmc2"
+	case len(rom_banks) > 2 &&
+		(bytes.Contains(names, []byte("WISDOM TREE")) || bytes.Contains(names, []byte("WISDOM\x00TREE"))):
+		return "wisdom-tree"
+	case isM161(bank0, len(rom_banks)):
+		return "m161"
+	case isRocket(bank0, names, len(rom_banks)):
+		return "rocket"
+	}
+	return ""
+}
+
+// headerFits reports whether bank0's header names a mapper gobc knows and
+// declares the ROM size the file has.
+func headerFits(bank0 []uint8, banks int) bool {
+	size := bank0[ROM_SIZE_ADDR]
+	return CARTRIDGE_TABLE[bank0[CARTRIDGE_TYPE_ADDR]] != nil && size <= 0x08 && banks == 2<<size
+}
+
+// sachenLogo reports whether bank0 carries Nintendo's logo at $0184 but
+// not at $0104, as the boot ROM sees them through a Sachen mapper; the
+// MMC2 scrambles the header.
+func sachenLogo(bank0 []uint8, mmc2 bool) bool {
+	logoAt := func(addr uint16) bool {
+		for i, b := range nintendoLogo {
+			a := addr + uint16(i)
+			if mmc2 {
+				a = sachenScramble(a)
+			}
+			if bank0[a] != b {
+				return false
+			}
+		}
+		return true
+	}
+	return logoAt(0x0184) && !logoAt(NINTENDO_LOGO_START_ADDR)
+}
+
+// isM161 reports whether bank0 heads an M161 multicart: 256 KiB behind
+// the first game's MBC3+TIMER+RAM+BATTERY header, which declares 32 KiB.
+func isM161(bank0 []uint8, banks int) bool {
+	return banks == 16 && bank0[CARTRIDGE_TYPE_ADDR] == 0x10 && bank0[ROM_SIZE_ADDR] == 0x00
+}
+
+// isRocket reports whether bank0 heads a Rocket Games or Hong Kong
+// multicart: Rocket Games' name in names, the part of bank0 that may name
+// a publisher, or an "N IN 1" title on a ROM bigger than its header
+// declares.
+func isRocket(bank0, names []uint8, banks int) bool {
+	if bytes.Contains(names, []byte("ROCKET GAMES")) {
+		return true
+	}
+	size := bank0[ROM_SIZE_ADDR]
+	if size > 0x08 || banks <= 2<<size {
+		return false
+	}
+	title := bank0[TITLE_START_ADDR : TITLE_END_ADDR+1]
+	return bytes.Contains(title, []byte("IN 1")) || bytes.Contains(title, []byte("IN1"))
+}
+
+// loadUnlicensed builds a cartridge around the unlicensed mapper named
+// mapper. The header is not checked, its sizes being as unreliable as its
+// type: the ROM is as big as the file and there is no RAM.
+func loadUnlicensed(fname string, rom_banks [][]uint8, headerBank int, mapper string) (*Cartridge, error) {
+	newMapper := UNLICENSED_TABLE[mapper]
+	if newMapper == nil {
+		return nil, fmt.Errorf("%w: no mapper called %q; pick one of %v", ErrUnsupportedMBC, mapper, Mappers())
+	}
+
+	cart := Cartridge{
+		RomBanks:        rom_banks,
+		Filename:        fname,
+		RomBanksCount:   uint16(len(rom_banks)),
+		RomBankSelected: 1,
+		Rtc:             NewRTC(),
+		Mapper:          mapper,
+		headerBank:      headerBank,
+	}
+	cart.CartType = newMapper(&cart)
+	if err := cart.CartType.Init(); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("ROM file loaded successfully: %s", fname)
+	logger.Infof("Cartridge Initialized: %s (unlicensed)", reflect.TypeOf(cart.CartType))
+	logger.Infof("ROM Banks: %d, Size: %dKb", cart.RomBanksCount, cart.RomBanksCount*16)
+	return &cart, nil
+}
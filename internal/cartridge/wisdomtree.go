@@ -0,0 +1,59 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// WisdomTreeCartridge is the mapper of Wisdom Tree's unlicensed games.
+// It switches the whole 32 KiB of ROM at once: a write anywhere in
+// $0000-$3FFF selects the 32 KiB bank in the low byte of the address,
+// whatever the value. There is no RAM.
+type WisdomTreeCartridge struct {
+	parent *Cartridge
+	bank   uint8 // 32 KiB bank
+}
+
+func (c *WisdomTreeCartridge) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, c.bank) // ROM Bank Select
+	logger.Debug("Serialized Wisdom Tree state")
+	return buf
+}
+
+func (c *WisdomTreeCartridge) Deserialize(data *bytes.Buffer) error {
+	return binary.Read(data, binary.LittleEndian, &c.bank)
+}
+
+func (c *WisdomTreeCartridge) Init() error {
+	return nil
+}
+
+func (c *WisdomTreeCartridge) SetItem(addr uint16, value uint8) {
+	switch {
+	case addr < 0x4000:
+		c.bank = uint8(addr)
+
+	case 0x4000 <= addr && addr < 0x8000, 0xA000 <= addr && addr < 0xC000:
+		// nothing else on the cartridge
+
+	default:
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+	}
+}
+
+func (c *WisdomTreeCartridge) GetItem(addr uint16) uint8 {
+	switch {
+	case addr < 0x8000:
+		c.parent.RomBankSelected = (2*uint16(c.bank) + addr/0x4000) % c.parent.RomBanksCount
+		return c.parent.RomBanks[c.parent.RomBankSelected][addr%0x4000]
+
+	case 0xA000 <= addr && addr < 0xC000:
+		return 0xff
+
+	default:
+		logger.Errorf("Memory read error! Can't read from %#x\n", addr)
+	}
+
+	return 0
+}
@@ -0,0 +1,347 @@
+// Package fourplayer — adapter.go
+//
+// The DMG-07 four-player adapter. Up to four Game Boys plug into it and
+// it drives all of their serial clocks, so every game waits on the
+// external clock and the adapter sends the same byte to each port at once.
+//
+// It starts out in the ping phase, sending each port a four-byte packet
+// again and again:
+//
+//	adapter:   $FE   STAT  STAT  STAT
+//	Game Boy:  ACK1  ACK2  RATE  SIZE
+//
+// STAT holds a bit per connected player in the high nibble (player 1 is
+// bit 4) and the number of the port it is sent to in the low bits. A
+// Game Boy counts as connected while it answers $88 for ACK1 and ACK2.
+// Player 1 starts the game by answering $AA instead; the adapter then
+// takes the RATE and SIZE player 1 sent, sends four $CC bytes, and moves
+// on to the transmission phase.
+//
+// There, the adapter collects a packet of SIZE bytes from every player
+// in each frame of 4*SIZE bytes and sends all four packets back, player 1
+// first, during the next frame. Each Game Boy sends its packet in the
+// first SIZE bytes of a frame; whatever it sends during the rest is
+// dropped. Player 1 sending four $FF bytes in a row takes the adapter
+// back to the ping phase.
+//
+// References:
+//   - Pan Docs, "4 Player Adapter"
+
+package fourplayer
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/link"
+	"github.com/duysqubix/gobc/internal/motherboard"
+)
+
+var logger = internal.Logger
+
+// Players is the number of ports on the adapter.
+const Players = 4
+
+const (
+	pingHeader = 0xFE
+	ackConnect = 0x88 // ACK1 and ACK2 from a player taking part
+	ackStart   = 0xAA // ACK1 from player 1 to start the game
+	startByte  = 0xCC // sent between the ping and transmission phases
+	startBytes = 4
+	restart    = 0xFF // four in a row from player 1 go back to pinging
+	maxSize    = 4    // longest packet a player may send
+)
+
+const (
+	// Time between bytes in the ping phase, about a millisecond.
+	pingByteCycles motherboard.OpCycles = 4096
+	// Time between bytes in the transmission phase: the same, slowed
+	// down by this much per step of the low nibble of RATE.
+	rateStepCycles motherboard.OpCycles = 512
+)
+
+type phase int
+
+const (
+	phasePing phase = iota
+	phaseStart
+	phaseTransmit
+)
+
+// Port is one of the adapter's sockets, with a Game Boy plugged in.
+type Port interface {
+	// Exchange clocks out into the Game Boy's serial port and returns the
+	// byte shifted out of it.
+	Exchange(out uint8) (in uint8)
+}
+
+// LocalPort returns a Port for a machine in this process. It must be
+// stepped from the same goroutine as the machine the adapter is clocked
+// from.
+func LocalPort(s *motherboard.Serial) Port {
+	return localPort{s}
+}
+
+type localPort struct {
+	serial *motherboard.Serial
+}
+
+func (p localPort) Exchange(out uint8) uint8 {
+	var in uint8
+	for i := 7; i >= 0; i-- {
+		in <<= 1
+		if p.serial.ClockIn(out>>i&1 != 0) {
+			in |= 1
+		}
+	}
+	return in
+}
+
+// Adapter is a DMG-07.
+type Adapter struct {
+	mu    sync.Mutex // guards ports, which Listen fills in from its own goroutine
+	ports [Players]Port
+
+	phase     phase
+	pos       int                      // byte of the current packet or frame
+	wait      motherboard.OpCycles     // cycles until the next byte
+	connected uint8                    // bit per connected player
+	acks      [Players][4]uint8        // what each player answered to the last ping packet
+	rate      uint8                    // RATE from player 1
+	size      int                      // SIZE from player 1
+	sending   [Players * maxSize]uint8 // packets collected in the last frame
+	receiving [Players * maxSize]uint8 // packets being collected in this frame
+	restarts  int                      // $FF bytes in a row from player 1
+
+	listener net.Listener
+}
+
+// New returns an adapter with nothing plugged in.
+func New() *Adapter {
+	return &Adapter{wait: pingByteCycles}
+}
+
+// Connect plugs the machines behind serials into ports 1 onwards of a new
+// adapter, clocked from the first. All of them must be stepped from the
+// same goroutine.
+func Connect(serials ...*motherboard.Serial) *Adapter {
+	a := New()
+	for i, s := range serials {
+		a.Plug(i+1, LocalPort(s))
+	}
+	a.ClockFrom(serials[0])
+	return a
+}
+
+// Plug puts p into port player (1-4), replacing whatever was there. A nil
+// p unplugs it.
+func (a *Adapter) Plug(player int, p Port) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ports[player-1] = p
+}
+
+// ClockFrom runs the adapter on the clock of the machine behind s, by
+// plugging it into s as a poller; s stays free to be one of the adapter's
+// ports. The machines on the other ports wait on the adapter, so it is
+// their only link to each other's time.
+func (a *Adapter) ClockFrom(s *motherboard.Serial) {
+	s.Connect(clock{a})
+}
+
+// clock is what the clocking machine sees plugged into its port. Its own
+// internally clocked transfers go nowhere, as the adapter only ever
+// drives the clock.
+type clock struct {
+	a *Adapter
+}
+
+func (c clock) ExchangeBit(out bool) bool {
+	return true
+}
+
+func (c clock) Poll() {
+	c.a.Tick(motherboard.SerialPollCycles)
+}
+
+// Listen accepts gobc instances connecting to addr as link cables and
+// plugs them into the free ports from player 2 on, in the background,
+// until the adapter is full or closed.
+func (a *Adapter) Listen(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	a.listener = ln
+	logger.Infof("fourplayer: waiting for players on %s", ln.Addr())
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.Warnf("fourplayer: %v", err)
+				}
+				return
+			}
+			p, err := link.NewPort(conn)
+			if err != nil {
+				logger.Warnf("fourplayer: %v", err)
+				continue
+			}
+			player := a.plugFree(p)
+			if player == 0 {
+				logger.Warnf("fourplayer: all ports taken, hanging up on %s", conn.RemoteAddr())
+				p.Close()
+				continue
+			}
+			logger.Infof("fourplayer: player %d joined from %s", player, conn.RemoteAddr())
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address Listen is accepting players on.
+func (a *Adapter) Addr() net.Addr {
+	return a.listener.Addr()
+}
+
+// plugFree plugs p into the first free port and returns its number, or 0
+// if every port is taken.
+func (a *Adapter) plugFree(p Port) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.ports {
+		if a.ports[i] == nil {
+			a.ports[i] = p
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Close stops listening and hangs up on the players connected over the
+// network.
+func (a *Adapter) Close() error {
+	var err error
+	if a.listener != nil {
+		err = a.listener.Close()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range a.ports {
+		if p, ok := p.(*link.Port); ok {
+			p.Close()
+		}
+	}
+	return err
+}
+
+// Tick advances the adapter by the given number of cycles, sending the
+// bytes that fall due.
+func (a *Adapter) Tick(cycles motherboard.OpCycles) {
+	a.wait -= cycles
+	for a.wait <= 0 {
+		a.step()
+		a.wait += a.byteCycles()
+	}
+}
+
+func (a *Adapter) byteCycles() motherboard.OpCycles {
+	if a.phase == phasePing {
+		return pingByteCycles
+	}
+	return pingByteCycles + motherboard.OpCycles(a.rate&0x0F)*rateStepCycles
+}
+
+// step sends the next byte to every port.
+func (a *Adapter) step() {
+	a.mu.Lock()
+	ports := a.ports
+	a.mu.Unlock()
+
+	switch a.phase {
+	case phasePing:
+		for i, p := range ports {
+			out := uint8(pingHeader)
+			if a.pos > 0 {
+				out = a.connected<<4 | uint8(i+1)
+			}
+			a.acks[i][a.pos] = exchange(p, out)
+		}
+		if a.pos++; a.pos == len(a.acks[0]) {
+			a.pos = 0
+			a.endPing()
+		}
+
+	case phaseStart:
+		for _, p := range ports {
+			exchange(p, startByte)
+		}
+		if a.pos++; a.pos == startBytes {
+			a.pos = 0
+			a.phase = phaseTransmit
+		}
+
+	case phaseTransmit:
+		for i, p := range ports {
+			in := exchange(p, a.sending[a.pos])
+			if a.pos >= a.size {
+				continue
+			}
+			if a.connected&(1<<i) != 0 {
+				a.receiving[i*a.size+a.pos] = in
+			}
+			if i == 0 {
+				if in == restart {
+					a.restarts++
+				} else {
+					a.restarts = 0
+				}
+			}
+		}
+		if a.pos++; a.pos == Players*a.size {
+			a.pos = 0
+			a.sending, a.receiving = a.receiving, [Players * maxSize]uint8{}
+			if a.restarts >= 4 {
+				a.reset()
+			}
+		}
+	}
+}
+
+// exchange sends out to p, reading an empty port as an idle line.
+func exchange(p Port, out uint8) uint8 {
+	if p == nil {
+		return 0xFF
+	}
+	return p.Exchange(out)
+}
+
+// endPing takes stock of the answers to a ping packet.
+func (a *Adapter) endPing() {
+	a.connected = 0
+	for i, ack := range a.acks {
+		if ack[0] == ackConnect && ack[1] == ackConnect || i == 0 && ack[0] == ackStart {
+			a.connected |= 1 << i
+		}
+	}
+	if a.acks[0][0] != ackStart {
+		return
+	}
+	a.rate = a.acks[0][2]
+	a.size = min(max(int(a.acks[0][3]), 1), maxSize)
+	a.phase = phaseStart
+	logger.Infof("fourplayer: starting with players %04b, rate $%02X, %d-byte packets", a.connected, a.rate, a.size)
+}
+
+// reset goes back to the ping phase.
+func (a *Adapter) reset() {
+	a.phase = phasePing
+	a.pos = 0
+	a.restarts = 0
+	a.sending = [Players * maxSize]uint8{}
+	a.receiving = [Players * maxSize]uint8{}
+}
@@ -0,0 +1,231 @@
+package fourplayer
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/link"
+	"github.com/duysqubix/gobc/internal/motherboard"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	internal.Logger.SetOutput(io.Discard)
+	internal.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+// scriptedPort answers the adapter's bytes from a script, then with $00,
+// and records what it was sent.
+type scriptedPort struct {
+	script []uint8
+	got    []uint8
+}
+
+func (p *scriptedPort) Exchange(out uint8) uint8 {
+	p.got = append(p.got, out)
+	if len(p.script) == 0 {
+		return 0x00
+	}
+	in := p.script[0]
+	p.script = p.script[1:]
+	return in
+}
+
+func concat(parts ...[]uint8) []uint8 {
+	var all []uint8
+	for _, p := range parts {
+		all = append(all, p...)
+	}
+	return all
+}
+
+func repeat(b uint8, n int) []uint8 {
+	s := make([]uint8, n)
+	for i := range s {
+		s[i] = b
+	}
+	return s
+}
+
+func TestAdapter_Protocol(t *testing.T) {
+	// Two-byte packets. Player 1 and 2 take part, port 3 is empty and the
+	// Game Boy in port 4 never answers the ping.
+	p1 := &scriptedPort{script: concat(
+		[]uint8{0x88, 0x88, 0x00, 0x02},
+		[]uint8{0xAA, 0xAA, 0x00, 0x02},
+		repeat(0x00, startBytes),
+		[]uint8{0x11, 0x12}, repeat(0x00, 6),
+		[]uint8{0x13, 0x14}, repeat(0x00, 6),
+		[]uint8{0xFF, 0xFF}, repeat(0x00, 6),
+		[]uint8{0xFF, 0xFF}, repeat(0x00, 6),
+	)}
+	p2 := &scriptedPort{script: concat(
+		[]uint8{0x88, 0x88, 0x00, 0x00},
+		[]uint8{0x88, 0x88, 0x00, 0x00},
+		repeat(0x00, startBytes),
+		[]uint8{0x21, 0x22}, repeat(0x00, 6),
+		[]uint8{0x23, 0x24}, repeat(0x00, 6),
+		[]uint8{0x25, 0x26}, repeat(0x00, 6),
+		[]uint8{0x27, 0x28}, repeat(0x00, 6),
+	)}
+	p4 := &scriptedPort{}
+
+	a := New()
+	a.Plug(1, p1)
+	a.Plug(2, p2)
+	a.Plug(4, p4)
+	bytes := 4 + 4 + startBytes + 4*8 + 4
+	for range bytes {
+		a.Tick(pingByteCycles)
+	}
+
+	assert.Equal(t, concat(
+		[]uint8{0xFE, 0x02, 0x02, 0x02},
+		[]uint8{0xFE, 0x32, 0x32, 0x32},
+		repeat(startByte, startBytes),
+		repeat(0x00, 8),
+		[]uint8{0x11, 0x12, 0x21, 0x22}, repeat(0x00, 4),
+		[]uint8{0x13, 0x14, 0x23, 0x24}, repeat(0x00, 4),
+		[]uint8{0xFF, 0xFF, 0x25, 0x26}, repeat(0x00, 4),
+		[]uint8{0xFE, 0x32, 0x32, 0x32}, // four $FF from player 1: back to pinging
+	), p2.got)
+	assert.Equal(t, []uint8{0xFE, 0x04, 0x04, 0x04, 0xFE, 0x34, 0x34, 0x34}, p4.got[:8])
+	assert.Equal(t, p2.got[8:len(p2.got)-4], p4.got[8:len(p4.got)-4], "every port gets the same frames")
+}
+
+func TestAdapter_RateSlowsTransmission(t *testing.T) {
+	p1 := &scriptedPort{script: []uint8{0xAA, 0xAA, 0x03, 0x01}}
+	a := New()
+	a.Plug(1, p1)
+	a.Tick(4 * pingByteCycles)
+	require.Equal(t, phaseStart, a.phase)
+
+	a.Tick(pingByteCycles + 3*rateStepCycles - 1)
+	assert.Len(t, p1.got, 4, "rate 3 waits three steps longer")
+	a.Tick(1)
+	assert.Len(t, p1.got, 5)
+}
+
+const echoBytes = 8
+
+// echoProgram returns a program for $0150 that answers every byte the
+// adapter clocks through with ack, logging what it receives to $C000
+// onwards, then spins at the returned address.
+func echoProgram(ack uint8) ([]byte, uint16) {
+	p := []byte{
+		0x31, 0xFE, 0xFF, // LD SP,$FFFE
+		0x21, 0x00, 0xC0, // LD HL,$C000
+		0x06, 0x00, // LD B,0
+		0x3E, ack, // LD A,ack
+		0xE0, 0x01, // LDH (SB),A
+		0x3E, 0x80, // LD A,$80
+		0xE0, 0x02, // LDH (SC),A
+		0xF0, 0x02, // LDH A,(SC)
+		0xCB, 0x7F, // BIT 7,A
+		0x20, 0xFA, // JR NZ,-6
+		0xF0, 0x01, // LDH A,(SB)
+		0x22,            // LD (HL+),A
+		0x04,            // INC B
+		0x78,            // LD A,B
+		0xFE, echoBytes, // CP echoBytes
+		0x20, 0xE9, // JR NZ,-23
+	}
+	done := 0x150 + uint16(len(p))
+	return append(p, 0x18, 0xFE), done // JR -2
+}
+
+func newAdapterTestMb(t *testing.T, program []byte) *motherboard.Motherboard {
+	t.Helper()
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0xC3, 0x50, 0x01}) // JP $0150
+	copy(rom[0x150:], program)
+	copy(rom[0x134:], "DMG07TEST")
+	var checksum uint8
+	for i := 0x134; i <= 0x14C; i++ {
+		checksum -= rom[i] + 1
+	}
+	rom[0x14D] = checksum
+
+	mb, err := motherboard.NewMotherboard(&motherboard.MotherboardParams{Rom: rom, RomName: "dmg07", ForceDmg: true})
+	require.NoError(t, err)
+	mb.SkipBootROM()
+	return mb
+}
+
+func echoLog(mb *motherboard.Motherboard) []byte {
+	var log []byte
+	for a := uint16(0xC000); a < 0xC000+echoBytes; a++ {
+		log = append(log, mb.GetItem(a))
+	}
+	return log
+}
+
+func TestAdapter_InProcess(t *testing.T) {
+	program, done := echoProgram(0x88)
+	var mbs []*motherboard.Motherboard
+	var serials []*motherboard.Serial
+	for range Players {
+		mb := newAdapterTestMb(t, program)
+		mbs = append(mbs, mb)
+		serials = append(serials, mb.Serial)
+	}
+	Connect(serials...)
+
+	for i := 0; i < 1_000_000 && mbs[Players-1].Cpu.Registers.PC != done; i++ {
+		for _, mb := range mbs {
+			mb.Tick()
+		}
+	}
+	for i, mb := range mbs {
+		stat := uint8(i + 1)
+		assert.Equal(t, []byte{0xFE, stat, stat, stat, 0xFE, 0xF0 | stat, 0xF0 | stat, 0xF0 | stat}, echoLog(mb), "player %d", i+1)
+	}
+}
+
+func TestAdapter_RemotePlayer(t *testing.T) {
+	program, done := echoProgram(0x88)
+	local := newAdapterTestMb(t, program)
+	remote := newAdapterTestMb(t, program)
+
+	a := New()
+	a.Plug(1, LocalPort(local.Serial))
+	a.ClockFrom(local.Serial)
+	require.NoError(t, a.Listen("127.0.0.1:0"))
+	defer a.Close()
+
+	l, err := link.Dial(a.Addr().String(), remote.Serial)
+	require.NoError(t, err)
+	defer l.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		a.mu.Lock()
+		joined := a.ports[1] != nil
+		a.mu.Unlock()
+		if joined {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "remote player never joined")
+		time.Sleep(time.Millisecond)
+	}
+
+	var running sync.WaitGroup
+	running.Add(2)
+	run := func(mb *motherboard.Motherboard) {
+		defer running.Done()
+		for deadline := time.Now().Add(20 * time.Second); mb.Cpu.Registers.PC != done && time.Now().Before(deadline); {
+			mb.Tick()
+		}
+	}
+	go run(local)
+	go run(remote)
+	running.Wait()
+
+	assert.Equal(t, []byte{0xFE, 0x01, 0x01, 0x01, 0xFE, 0x31, 0x31, 0x31}, echoLog(local))
+	assert.Equal(t, []byte{0xFE, 0x02, 0x02, 0x02, 0xFE, 0x32, 0x32, 0x32}, echoLog(remote))
+}
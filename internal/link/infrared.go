@@ -0,0 +1,151 @@
+// Package link — infrared.go
+//
+// Two gobc processes' infrared ports pointed at each other over TCP.
+//
+// IR protocols are timed by counting cycles between the partner's LED
+// pulses, and two processes only keep roughly the same pace, a frame at a
+// time. So each LED switch is sent stamped with the sender's port clock,
+// and the receiving end replays the switches with their original spacing,
+// a frame behind, on its own clock. If the sender drifts too far ahead for
+// that, the replay catches up and starts over from the next switch.
+
+package link
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/duysqubix/gobc/internal/motherboard"
+)
+
+// How far behind the sender the replay runs: enough to absorb one
+// process running a frame before the other.
+const infraredDelay motherboard.OpCycles = 70224
+
+// ledSwitch is the far end's LED switching on or off, on its clock.
+type ledSwitch struct {
+	on bool
+	at motherboard.OpCycles
+}
+
+// Infrared is one end of an IR link. It plugs into its machine's IR port
+// as a motherboard.InfraredDevice.
+type Infrared struct {
+	conn net.Conn
+
+	mu       sync.Mutex
+	switches []ledSwitch // received and not replayed yet, oldest first
+
+	// replay state, only touched on the machine's goroutine
+	offset motherboard.OpCycles // our clock minus the far end's, delay included
+	synced bool                 // whether offset has been set
+	on     bool                 // the far end's LED as replayed so far
+	since  motherboard.OpCycles // when it last switched, on our clock
+}
+
+// NewInfrared runs the handshake over conn and points ir at the far end.
+// It must be called from the goroutine that runs ir's machine, before it
+// starts.
+func NewInfrared(conn net.Conn, ir *motherboard.Infrared) (*Infrared, error) {
+	if err := handshake(conn, infraredHello); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	l := &Infrared{conn: conn}
+	go l.read()
+	ir.Connect(l)
+	return l, nil
+}
+
+// ListenInfrared waits for one peer to connect on addr, then points ir at
+// it.
+func ListenInfrared(addr string, ir *motherboard.Infrared) (*Infrared, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	logger.Infof("link: waiting for an IR partner on %s", ln.Addr())
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return NewInfrared(conn, ir)
+}
+
+// DialInfrared connects to a peer listening on addr and points ir at it.
+func DialInfrared(addr string, ir *motherboard.Infrared) (*Infrared, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewInfrared(conn, ir)
+}
+
+// Close hangs up. The peer's receiver sees nothing from then on.
+func (l *Infrared) Close() error {
+	return l.conn.Close()
+}
+
+// read queues the far end's LED switches until the connection drops,
+// leaving its LED off.
+func (l *Infrared) read() {
+	var buf [9]byte
+	for {
+		if _, err := io.ReadFull(l.conn, buf[:]); err != nil {
+			logger.Infof("link: IR partner disconnected: %v", err)
+			l.mu.Lock()
+			l.switches = append(l.switches, ledSwitch{false, -1})
+			l.mu.Unlock()
+			return
+		}
+		at := motherboard.OpCycles(binary.LittleEndian.Uint64(buf[1:]))
+		l.mu.Lock()
+		l.switches = append(l.switches, ledSwitch{buf[0] != 0, at})
+		l.mu.Unlock()
+	}
+}
+
+// LED implements motherboard.InfraredDevice, sending the switch to the
+// far end.
+func (l *Infrared) LED(on bool, now motherboard.OpCycles) {
+	var buf [9]byte
+	if on {
+		buf[0] = 1
+	}
+	binary.LittleEndian.PutUint64(buf[1:], uint64(now))
+	if _, err := l.conn.Write(buf[:]); err != nil {
+		logger.Warnf("link: %v", err)
+	}
+}
+
+// Light implements motherboard.InfraredDevice, replaying the far end's
+// LED switches up to now.
+func (l *Infrared) Light(now motherboard.OpCycles) (bool, motherboard.OpCycles) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.switches) > 0 {
+		s := l.switches[0]
+		if s.at < 0 {
+			// hung up: the light goes out at once
+			l.on, l.since = false, now
+			l.switches = l.switches[1:]
+			continue
+		}
+		if !l.synced || s.at+l.offset < now-infraredDelay {
+			l.offset = now - s.at + infraredDelay
+			l.synced = true
+		}
+		at := s.at + l.offset
+		if at > now {
+			break
+		}
+		l.on, l.since = s.on, at
+		l.switches = l.switches[1:]
+	}
+	return l.on, l.since
+}
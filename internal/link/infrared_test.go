@@ -0,0 +1,117 @@
+package link
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duysqubix/gobc/internal/motherboard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIRTestMb(t *testing.T) *motherboard.Motherboard {
+	t.Helper()
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0x00, 0x18, 0xFD}) // NOP ; JR -3
+	rom[0x143] = 0x80                           // CGB
+	copy(rom[0x134:], "IRTEST")
+	var checksum uint8
+	for i := 0x134; i <= 0x14C; i++ {
+		checksum -= rom[i] + 1
+	}
+	rom[0x14D] = checksum
+
+	mb, err := motherboard.NewMotherboard(&motherboard.MotherboardParams{Rom: rom, RomName: "ir"})
+	require.NoError(t, err)
+	require.True(t, mb.Cgb)
+	mb.SkipBootROM()
+	return mb
+}
+
+func runFor(mb *motherboard.Motherboard, cycles motherboard.OpCycles) {
+	for cycles > 0 {
+		_, n := mb.Tick()
+		cycles -= n
+	}
+}
+
+func TestInfrared_ReplaysPulsesOverTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	sender, receiver := newIRTestMb(t), newIRTestMb(t)
+	var far *Infrared
+	var acceptErr error
+	var accepted sync.WaitGroup
+	accepted.Add(1)
+	go func() {
+		defer accepted.Done()
+		conn, err := ln.Accept()
+		if err != nil {
+			acceptErr = err
+			return
+		}
+		far, acceptErr = NewInfrared(conn, receiver.Infrared)
+	}()
+	near, err := DialInfrared(ln.Addr().String(), sender.Infrared)
+	require.NoError(t, err)
+	defer near.Close()
+	accepted.Wait()
+	require.NoError(t, acceptErr)
+	defer far.Close()
+
+	// a 2000-cycle pulse, then a 500-cycle one 3000 cycles later
+	sender.SetItem(0xFF56, 0x01)
+	runFor(sender, 2000)
+	sender.SetItem(0xFF56, 0x00)
+	runFor(sender, 3000)
+	sender.SetItem(0xFF56, 0x01)
+	runFor(sender, 500)
+	sender.SetItem(0xFF56, 0x00)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		far.mu.Lock()
+		n := len(far.switches)
+		far.mu.Unlock()
+		if n == 4 {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "switches never arrived")
+		time.Sleep(time.Millisecond)
+	}
+
+	receiver.SetItem(0xFF56, 0xC0)
+	var edges []motherboard.OpCycles
+	was := false
+	for elapsed := motherboard.OpCycles(0); elapsed < 2*infraredDelay; {
+		_, n := receiver.Tick()
+		elapsed += n
+		if on := receiver.GetItem(0xFF56)&0x02 == 0; on != was {
+			edges = append(edges, elapsed)
+			was = on
+		}
+	}
+	require.Len(t, edges, 4)
+	assert.InDelta(t, 2000, int(edges[1]-edges[0]), 16)
+	assert.InDelta(t, 3000, int(edges[2]-edges[1]), 16)
+	assert.InDelta(t, 500, int(edges[3]-edges[2]), 16)
+	assert.GreaterOrEqual(t, edges[0], infraredDelay-16, "replayed a frame behind")
+}
+
+func TestInfrared_RejectsLinkCable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			New(conn, newLinkTestMb(t, nil).Serial)
+		}
+	}()
+
+	_, err = DialInfrared(ln.Addr().String(), newIRTestMb(t).Infrared)
+	assert.ErrorIs(t, err, ErrHandshake)
+}
@@ -0,0 +1,302 @@
+// Package link — link.go
+//
+// A link cable between two gobc processes over TCP. Each end plugs into
+// its machine's serial port as a motherboard.SerialPoller.
+//
+// The cable is emulated a byte at a time, in lock-step. When a machine
+// starts a transfer on the internal clock, its end sends the byte in SB
+// and waits for the answer before the first bit is shifted. The far end
+// shifts that byte into a transfer waiting on the external clock and
+// answers with what its SB held, or with $FF if no such transfer was
+// waiting. If both machines start an internal transfer at once, each
+// takes the other's byte, as two Game Boys both driving the clock would.
+// Either way both sides have finished the exchange before either moves
+// on, so they cannot disagree about what was sent.
+//
+// A machine that is slow to answer, because its window is paused say,
+// has the transfer withdrawn: the far end's connection takes it back off
+// the queue unanswered and confirms, and the machine that started it
+// reads $FF as if nothing were plugged in. An answer already on its way
+// by then is taken instead. Only a process that stops reading altogether
+// holds the transfer up, until its connection drops.
+
+package link
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/motherboard"
+)
+
+var logger = internal.Logger
+
+// ErrHandshake is returned when the peer does not speak this version of
+// the protocol.
+var ErrHandshake = errors.New("link: peer is not a compatible gobc link")
+
+const protocolVersion = 1
+
+const (
+	handshakeTimeout = 10 * time.Second
+	// A transfer the peer has not answered in this long, e.g. because it
+	// is paused, is withdrawn and sees $FF as if the cable were pulled.
+	replyTimeout = 2 * time.Second
+)
+
+// Message kinds. Every message is four bytes: kind, data and a 16-bit
+// sequence number.
+const (
+	msgTransfer  uint8 = iota + 1 // the sender started a transfer with data in SB
+	msgReply                      // the answer to the msgTransfer with the same seq
+	msgCancel                     // the sender withdraws the msgTransfer with the same seq
+	msgCancelled                  // that msgTransfer was withdrawn before it was answered
+)
+
+type message struct {
+	kind uint8
+	data uint8
+	seq  uint16
+}
+
+// peer is the connection to the far end of the cable.
+type peer struct {
+	conn    net.Conn
+	replies chan message  // answers to our transfers; closed once the connection drops
+	wake    chan struct{} // signalled when a transfer joins queue
+	seq     uint16        // sequence number of the last transfer we started
+
+	mu    sync.Mutex
+	queue []message // transfers the far end started, not answered yet
+}
+
+// newPeer runs the handshake over conn and starts reading messages.
+func newPeer(conn net.Conn) (*peer, error) {
+	if err := handshake(conn, linkHello); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p := &peer{conn: conn, replies: make(chan message, 16), wake: make(chan struct{}, 1)}
+	go p.read()
+	return p, nil
+}
+
+// Link is one end of the cable.
+type Link struct {
+	*peer
+	serial *motherboard.Serial
+	reply  uint8 // bits of the peer's byte still to be shifted in, MSB first
+}
+
+// New runs the handshake over conn and plugs the link into s. It must be
+// called from the goroutine that runs s's machine, before it starts.
+func New(conn net.Conn, s *motherboard.Serial) (*Link, error) {
+	p, err := newPeer(conn)
+	if err != nil {
+		return nil, err
+	}
+	l := &Link{peer: p, serial: s}
+	s.Connect(l)
+	return l, nil
+}
+
+// Listen waits for one peer to connect on addr, then links it to s.
+func Listen(addr string, s *motherboard.Serial) (*Link, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	logger.Infof("link: waiting for a peer on %s", ln.Addr())
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return New(conn, s)
+}
+
+// Dial connects to a peer listening on addr and links it to s.
+func Dial(addr string, s *motherboard.Serial) (*Link, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return New(conn, s)
+}
+
+// Close hangs up. The peer's transfers see $FF from then on.
+func (l *Link) Close() error {
+	return l.conn.Close()
+}
+
+// Hellos exchanged at the start of each kind of connection.
+var (
+	linkHello     = [5]byte{'G', 'B', 'L', 'K', protocolVersion}
+	infraredHello = [5]byte{'G', 'B', 'I', 'R', protocolVersion}
+)
+
+func handshake(conn net.Conn, hello [5]byte) error {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	// Both ends say hello at once, so send while reading the peer's.
+	sent := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(hello[:])
+		sent <- err
+	}()
+	var got [5]byte
+	if _, err := io.ReadFull(conn, got[:]); err != nil {
+		return err
+	}
+	if err := <-sent; err != nil {
+		return err
+	}
+	if got != hello {
+		return ErrHandshake
+	}
+	return nil
+}
+
+// read handles the far end's messages until the connection drops:
+// transfers are queued for the machine, withdrawals taken back off the
+// queue, and answers to our own transfers passed to p.replies.
+func (p *peer) read() {
+	defer close(p.replies)
+	var buf [4]byte
+	for {
+		if _, err := io.ReadFull(p.conn, buf[:]); err != nil {
+			logger.Infof("link: peer disconnected: %v", err)
+			return
+		}
+		m := message{buf[0], buf[1], binary.LittleEndian.Uint16(buf[2:])}
+		switch m.kind {
+		case msgTransfer:
+			p.mu.Lock()
+			p.queue = append(p.queue, m)
+			p.mu.Unlock()
+			select {
+			case p.wake <- struct{}{}:
+			default:
+			}
+		case msgCancel:
+			if p.withdraw(m.seq) {
+				p.send(message{msgCancelled, 0, m.seq})
+			}
+		default:
+			p.replies <- m
+		}
+	}
+}
+
+// take removes the oldest transfer the far end started from the queue.
+func (p *peer) take() (message, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		return message{}, false
+	}
+	m := p.queue[0]
+	p.queue = p.queue[1:]
+	return m, true
+}
+
+// withdraw removes the far end's transfer seq from the queue, and reports
+// whether it was still there. If not, it was taken and is answered.
+func (p *peer) withdraw(seq uint16) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := slices.IndexFunc(p.queue, func(m message) bool { return m.seq == seq })
+	if i < 0 {
+		return false
+	}
+	p.queue = slices.Delete(p.queue, i, i+1)
+	return true
+}
+
+func (p *peer) send(m message) {
+	buf := [4]byte{m.kind, m.data}
+	binary.LittleEndian.PutUint16(buf[2:], m.seq)
+	if _, err := p.conn.Write(buf[:]); err != nil {
+		logger.Warnf("link: %v", err)
+	}
+}
+
+// ExchangeBit implements motherboard.SerialDevice. The whole byte is
+// exchanged with the peer on the first clock of each transfer.
+func (l *Link) ExchangeBit(out bool) bool {
+	if l.serial.Bits == 0 {
+		l.reply = l.transfer(l.serial.SB)
+	}
+	in := l.reply&0x80 != 0
+	l.reply <<= 1
+	return in
+}
+
+// transfer sends sb to the far end and waits for the byte it answers with.
+// Once replyTimeout has passed it asks for the transfer back, and then
+// waits for either the answer or the far end's word that it was withdrawn.
+func (p *peer) transfer(sb uint8) uint8 {
+	p.seq++
+	p.send(message{msgTransfer, sb, p.seq})
+
+	timeout := time.NewTimer(replyTimeout)
+	defer timeout.Stop()
+	for {
+		if m, ok := p.take(); ok {
+			// The peer is driving the clock too and waits on our
+			// transfer in turn, so each side takes the other's byte.
+			return m.data
+		}
+		select {
+		case m, ok := <-p.replies:
+			switch {
+			case !ok:
+				return 0xFF
+			case m.seq != p.seq:
+				logger.Warnf("link: answer to transfer %d while waiting on %d", m.seq, p.seq)
+			case m.kind == msgReply:
+				return m.data
+			case m.kind == msgCancelled:
+				return 0xFF
+			}
+		case <-p.wake:
+		case <-timeout.C:
+			logger.Warnf("link: peer did not answer within %v, withdrawing the transfer", replyTimeout)
+			p.send(message{msgCancel, 0, p.seq})
+		}
+	}
+}
+
+// Poll implements motherboard.SerialPoller, answering transfers the peer
+// has started.
+func (l *Link) Poll() {
+	for {
+		m, ok := l.take()
+		if !ok {
+			return
+		}
+		l.answer(m)
+	}
+}
+
+// answer clocks the peer's byte through our SB and sends back what comes
+// out. With no transfer waiting on the external clock nothing is shifted
+// and the peer gets $FF.
+func (l *Link) answer(m message) {
+	var out uint8
+	for i := 7; i >= 0; i-- {
+		out <<= 1
+		if l.serial.ClockIn(m.data>>i&1 != 0) {
+			out |= 1
+		}
+	}
+	l.send(message{msgReply, out, m.seq})
+}
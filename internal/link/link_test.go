@@ -0,0 +1,256 @@
+package link
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/motherboard"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	internal.Logger.SetOutput(io.Discard)
+	internal.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+const transfers = 16
+
+// exchangeProgram returns a program for $0150 that makes transfers serial
+// transfers with SC = sc, logging each byte received to $C000 onwards,
+// then spins at the returned address. next computes the byte to send from
+// the transfer count in B. The master waits a while before each transfer
+// so that the slave is always ready for it.
+func exchangeProgram(sc uint8, next []byte) ([]byte, uint16) {
+	p := []byte{
+		0x31, 0xFE, 0xFF, // LD SP,$FFFE
+		0x21, 0x00, 0xC0, // LD HL,$C000
+		0x06, 0x00, // LD B,0
+	}
+	loop := len(p)
+	if sc&0x01 != 0 {
+		p = append(p, 0x0E, 0x00, 0x0D, 0x20, 0xFD) // LD C,0 ; DEC C ; JR NZ,-3
+	}
+	p = append(p, next...)
+	p = append(p,
+		0xE0, 0x01, // LDH (SB),A
+		0x3E, sc, // LD A,sc
+		0xE0, 0x02, // LDH (SC),A
+		0xF0, 0x02, // LDH A,(SC)
+		0xCB, 0x7F, // BIT 7,A
+		0x20, 0xFA, // JR NZ,-6
+		0xF0, 0x01, // LDH A,(SB)
+		0x22,            // LD (HL+),A
+		0x04,            // INC B
+		0x78,            // LD A,B
+		0xFE, transfers, // CP transfers
+		0x20, 0x00, // JR NZ,loop
+	)
+	p[len(p)-1] = byte(loop - len(p))
+	done := 0x150 + uint16(len(p))
+	p = append(p, 0x18, 0xFE) // JR -2
+	return p, done
+}
+
+var (
+	masterNext = []byte{0x78, 0x87, 0x80, 0x3C} // LD A,B ; ADD A,A ; ADD A,B ; INC A  -> 3B+1
+	slaveNext  = []byte{0x3E, 0xF0, 0x90}       // LD A,$F0 ; SUB B                   -> $F0-B
+)
+
+func newLinkTestMb(t *testing.T, program []byte) *motherboard.Motherboard {
+	t.Helper()
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0xC3, 0x50, 0x01}) // JP $0150
+	copy(rom[0x150:], program)
+	copy(rom[0x134:], "LINKTEST")
+	var checksum uint8
+	for i := 0x134; i <= 0x14C; i++ {
+		checksum -= rom[i] + 1
+	}
+	rom[0x14D] = checksum
+
+	mb, err := motherboard.NewMotherboard(&motherboard.MotherboardParams{Rom: rom, RomName: "link", ForceDmg: true})
+	require.NoError(t, err)
+	mb.SkipBootROM()
+	return mb
+}
+
+type linkTestPair struct {
+	master, slave         *motherboard.Motherboard
+	masterDone, slaveDone uint16
+}
+
+func newLinkTestPair(t *testing.T) linkTestPair {
+	t.Helper()
+	mp, md := exchangeProgram(0x81, masterNext)
+	sp, sd := exchangeProgram(0x80, slaveNext)
+	return linkTestPair{newLinkTestMb(t, mp), newLinkTestMb(t, sp), md, sd}
+}
+
+// runUntil ticks mb until its CPU spins at done, and returns the bytes it
+// logged. Linked machines run freely on their own goroutines, so the
+// slave can spin through any number of cycles waiting on the master; only
+// wall-clock time bounds the run.
+func runUntil(mb *motherboard.Motherboard, done uint16) []byte {
+	deadline := time.Now().Add(20 * time.Second)
+	for mb.Cpu.Registers.PC != done && time.Now().Before(deadline) {
+		mb.Tick()
+	}
+	var log []byte
+	for a := uint16(0xC000); a < 0xC000+transfers; a++ {
+		log = append(log, mb.GetItem(a))
+	}
+	return log
+}
+
+func TestLink_MatchesInProcessCable(t *testing.T) {
+	var want []byte
+	for i := range transfers {
+		want = append(want, 0xF0-byte(i))
+	}
+	for i := range transfers {
+		want = append(want, 3*byte(i)+1)
+	}
+
+	local := newLinkTestPair(t)
+	motherboard.ConnectCable(local.master.Serial, local.slave.Serial)
+	for i := 0; i < 1_000_000 && (local.master.Cpu.Registers.PC != local.masterDone || local.slave.Cpu.Registers.PC != local.slaveDone); i++ {
+		local.master.Tick()
+		local.slave.Tick()
+	}
+	inProcess := append(runUntil(local.master, local.masterDone), runUntil(local.slave, local.slaveDone)...)
+	require.Equal(t, want, inProcess)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	remote := newLinkTestPair(t)
+	var slaveLink *Link
+	var acceptErr error
+	var accepted sync.WaitGroup
+	accepted.Add(1)
+	go func() {
+		defer accepted.Done()
+		conn, err := ln.Accept()
+		if err != nil {
+			acceptErr = err
+			return
+		}
+		slaveLink, acceptErr = New(conn, remote.slave.Serial)
+	}()
+	masterLink, err := Dial(ln.Addr().String(), remote.master.Serial)
+	require.NoError(t, err)
+	defer masterLink.Close()
+	accepted.Wait()
+	require.NoError(t, acceptErr)
+	defer slaveLink.Close()
+
+	var masterLog, slaveLog []byte
+	var running sync.WaitGroup
+	running.Add(2)
+	go func() { defer running.Done(); masterLog = runUntil(remote.master, remote.masterDone) }()
+	go func() { defer running.Done(); slaveLog = runUntil(remote.slave, remote.slaveDone) }()
+	running.Wait()
+
+	assert.Equal(t, inProcess, append(masterLog, slaveLog...))
+}
+
+func TestLink_HandshakeRejectsOtherPeers(t *testing.T) {
+	a, b := net.Pipe()
+	defer b.Close()
+	go func() {
+		io.ReadFull(b, make([]byte, 5))
+		b.Write([]byte("HELLO"))
+	}()
+
+	mb := newLinkTestMb(t, nil)
+	_, err := New(a, mb.Serial)
+	assert.ErrorIs(t, err, ErrHandshake)
+}
+
+func TestLink_HungUpPeerReadsFF(t *testing.T) {
+	a, b := net.Pipe()
+	mb := newLinkTestMb(t, nil)
+	peer := newLinkTestMb(t, nil)
+
+	var peerLink *Link
+	var done sync.WaitGroup
+	done.Add(1)
+	go func() { defer done.Done(); peerLink, _ = New(b, peer.Serial) }()
+	l, err := New(a, mb.Serial)
+	require.NoError(t, err)
+	defer l.Close()
+	done.Wait()
+	require.NotNil(t, peerLink)
+	peerLink.Close()
+
+	mb.SetItem(0xFF01, 0x00)
+	mb.SetItem(0xFF02, 0x81)
+	for range 8 * 512 / 4 {
+		mb.Tick()
+	}
+	assert.Zero(t, mb.GetItem(0xFF02)&0x80, "transfer should complete")
+	assert.Equal(t, uint8(0xFF), mb.GetItem(0xFF01))
+}
+
+func TestLink_PausedPeerAgrees(t *testing.T) {
+	a, b := net.Pipe()
+	master := newLinkTestMb(t, nil)
+	slave := newLinkTestMb(t, nil)
+
+	var slaveLink *Link
+	var done sync.WaitGroup
+	done.Add(1)
+	go func() { defer done.Done(); slaveLink, _ = New(b, slave.Serial) }()
+	masterLink, err := New(a, master.Serial)
+	require.NoError(t, err)
+	defer masterLink.Close()
+	done.Wait()
+	require.NotNil(t, slaveLink)
+	defer slaveLink.Close()
+
+	// The slave waits on the external clock, but is paused past the
+	// master's timeout.
+	slave.SetItem(0xFF01, 0x42)
+	slave.SetItem(0xFF02, 0x80)
+	master.SetItem(0xFF01, 0x11)
+	master.SetItem(0xFF02, 0x81)
+	for master.GetItem(0xFF02)&0x80 != 0 {
+		master.Tick()
+	}
+	assert.Equal(t, uint8(0xFF), master.GetItem(0xFF01), "withdrawn, as if unplugged")
+
+	// Resumed, the slave never saw the withdrawn byte.
+	for range 8 * 512 / 4 {
+		slave.Tick()
+	}
+	assert.NotZero(t, slave.GetItem(0xFF02)&0x80, "still waiting")
+	assert.Equal(t, uint8(0x42), slave.GetItem(0xFF01))
+
+	// So the next transfer exchanges both bytes.
+	master.SetItem(0xFF01, 0x22)
+	master.SetItem(0xFF02, 0x81)
+	var running sync.WaitGroup
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		deadline := time.Now().Add(5 * time.Second)
+		for slave.GetItem(0xFF02)&0x80 != 0 && time.Now().Before(deadline) {
+			slave.Tick()
+		}
+	}()
+	for master.GetItem(0xFF02)&0x80 != 0 {
+		master.Tick()
+	}
+	running.Wait()
+	assert.Equal(t, uint8(0x42), master.GetItem(0xFF01))
+	assert.Equal(t, uint8(0x22), slave.GetItem(0xFF01))
+}
@@ -0,0 +1,36 @@
+// Package link — port.go
+//
+// The clock-master end of a link cable, for accessories that drive the
+// clock of every Game Boy plugged into them. The Game Boy at the far end
+// is an ordinary Link and needs nothing special.
+
+package link
+
+import "net"
+
+// Port drives the serial port of the gobc at the far end of a connection,
+// a byte at a time.
+type Port struct {
+	*peer
+}
+
+// NewPort runs the handshake over conn.
+func NewPort(conn net.Conn) (*Port, error) {
+	p, err := newPeer(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &Port{p}, nil
+}
+
+// Exchange clocks out into the far end's transfer waiting on the external
+// clock, and returns the byte shifted out of it. It reads $FF when no
+// transfer was waiting or the far end has hung up.
+func (p *Port) Exchange(out uint8) uint8 {
+	return p.transfer(out)
+}
+
+// Close hangs up.
+func (p *Port) Close() error {
+	return p.conn.Close()
+}
@@ -4,7 +4,8 @@
 // lifecycle and frame-sequencer scheduling.
 //
 // Channel DSP lives in apu_square.go / apu_wave.go / apu_noise.go.
-// beep speaker wiring + sample ring buffer live in apu_streamer.go.
+// Sample ring buffer + AudioOutput interface live in apu_streamer.go;
+// the beep speaker implementation lives in internal/audio.
 //
 // References:
 //   - Pan Docs: https://gbdev.io/pandocs/Audio.html
@@ -17,6 +18,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"os"
 	"time"
 
@@ -28,34 +30,14 @@ const (
 	defaultAudioSampleRate = 32000             // Default host output rate. 32 kHz gives 27% more cycle-budget headroom than 44.1 kHz while staying well above the Nyquist for any GB-audio spectral content.
 	apuDmgClock            = 4194304           // DMG CPU clock (Hz).
 	apuRingBufferCap       = 16384             // ~500 ms of stereo headroom at 32 kHz.
-	apuBufferDuration      = 5                 // speaker.Init period divisor: time.Second/5 = ~200 ms latency.
 	apuFrameSeqPeriod      = apuDmgClock / 512 // CPU cycles per 512 Hz frame-sequencer step.
 	apuCh1Bit              = 0
 	apuCh2Bit              = 1
 	apuCh3Bit              = 2
 	apuCh4Bit              = 3
+	apuCartSpeakerLevel    = 0.25 // as loud as one channel at full volume
 )
 
-// audioSampleRateOverride lets the user pin the audio rate via the
-// --audio-rate CLI flag. Slow CPUs (e.g., WSL2 hosts that can't sustain
-// 60 FPS of gobc emulation) need to lower this to match their actual
-// sample-production rate, otherwise the producer underruns and audio
-// chops. Zero = use defaultAudioSampleRate.
-var audioSampleRateOverride int
-
-// SetAudioSampleRateOverride sets the audio sample rate for newly-
-// created APUs. Must be called BEFORE NewMotherboard. Pass 0 to clear.
-func SetAudioSampleRateOverride(hz int) {
-	audioSampleRateOverride = hz
-}
-
-func effectiveAudioSampleRate() int {
-	if audioSampleRateOverride > 0 {
-		return audioSampleRateOverride
-	}
-	return defaultAudioSampleRate
-}
-
 // APU register read masks. Bits set to 1 are OR'd into the read value
 // (= unused / unreadable bits return 1, per Pan Docs).
 //
@@ -97,6 +79,9 @@ type APU struct {
 	// Wave RAM at 0xFF30..0xFF3F (16 bytes = 32 4-bit samples).
 	waveRAM [16]byte
 
+	// Phase of the cartridge speaker's square wave, 0..1 (HuC3).
+	cartTonePhase float64
+
 	// Frame sequencer state.
 	frameSeqStep    uint8 // 0..7
 	frameSeqCounter int   // CPU cycles toward next 512 Hz tick
@@ -115,7 +100,10 @@ type APU struct {
 	// Audio toggle (--no-audio CLI flag). If false: no speaker, no ring buffer push.
 	audioEnabled bool
 
-	// beep streamer & ring buffer. Allocated in NewAPU when audioEnabled.
+	// Host audio device the ring buffer drains into. nil = headless.
+	output AudioOutput
+
+	// Sample ring buffer. Allocated by startStreamer when audioEnabled.
 	streamer *apuStreamer
 
 	// Smooth-mode lazy init. When --audio-smooth is set, the speaker is
@@ -151,9 +139,15 @@ func (a *APU) AudioQueueFramesBuffered() float64 {
 // (false in --no-audio mode or when audio init failed).
 func (a *APU) AudioEnabled() bool { return a.audioEnabled }
 
-// NewAPU constructs and initializes the APU. Safe to call even when
-// audioEnabled is false — the APU still emulates registers correctly,
-// it just doesn't produce audible output.
+// NewAPU constructs and initializes the APU. Safe to call with a nil
+// output — the APU still emulates registers correctly, it just doesn't
+// produce audible output.
+//
+// sampleRate pins the host output rate (the --audio-rate CLI flag).
+// Slow CPUs (e.g., WSL2 hosts that can't sustain 60 FPS of gobc
+// emulation) need to lower this to match their actual sample-production
+// rate, otherwise the producer underruns and audio chops. Zero = use
+// defaultAudioSampleRate.
 //
 // In default mode the speaker opens at the spec sample rate (32 kHz).
 // In smooth mode (smoothMode=true) the speaker init is deferred until
@@ -162,11 +156,16 @@ func (a *APU) AudioEnabled() bool { return a.audioEnabled }
 // and consumer rates equal each other exactly — no underruns ever, at
 // the cost of a ~1.9 % pitch drop on a 98 %-speed host (one third of a
 // semitone — usually below the user-detectable threshold).
-func NewAPU(mb *Motherboard, audioEnabled bool, smoothMode bool) *APU {
+func NewAPU(mb *Motherboard, output AudioOutput, sampleRate int, smoothMode bool) *APU {
+	if sampleRate <= 0 {
+		sampleRate = defaultAudioSampleRate
+	}
+	audioEnabled := output != nil
 	a := &APU{
 		Mb:           mb,
 		audioEnabled: audioEnabled,
-		sampleRate:   effectiveAudioSampleRate(),
+		output:       output,
+		sampleRate:   sampleRate,
 		smoothMode:   smoothMode,
 	}
 	a.cyclesPerSampleQ16 = (apuDmgClock << 16) / a.sampleRate
@@ -283,10 +282,14 @@ func (a *APU) Close() {
 	if a.streamer != nil {
 		a.streamer.close()
 	}
+	if a.output != nil {
+		a.output.Stop()
+	}
 }
 
-// Tick advances APU state by `cycles` CPU clocks. Called by
-// Motherboard.Tick() every CPU step. In CGB double-speed mode the
+// Tick advances APU state by `cycles` CPU clocks. Called by the
+// motherboard scheduler at each frame-sequencer step and whenever the
+// CPU touches an APU register. In CGB double-speed mode the
 // APU clock stays at DMG rate while the CPU clock doubles, so the
 // APU ticks at half the rate per CPU cycle. We achieve this by
 // halving the input cycles when `Mb.doubleSpeed` is true (Pan Docs
@@ -338,6 +341,24 @@ func (a *APU) Tick(cycles OpCycles) {
 	}
 }
 
+// nextEvent returns the cycles left until the next frame-sequencer step,
+// which is the only thing that changes channel state on its own. While
+// samples are streaming to the host the APU is stepped every instruction
+// instead, so each sample sees the channels as they were at its time.
+func (a *APU) nextEvent() (OpCycles, bool) {
+	if a.audioEnabled && (a.streamer != nil || a.smoothMode && !a.smoothInitDone) {
+		return 1, true
+	}
+	if !a.enabled {
+		return 0, false
+	}
+	cycles := OpCycles(apuFrameSeqPeriod - a.frameSeqCounter)
+	if a.Mb != nil && a.Mb.doubleSpeed {
+		cycles <<= 1
+	}
+	return cycles, true
+}
+
 // stepFrameSequencer advances the 8-step, 512 Hz sequencer one step.
 //
 //	Step | Length | Envelope | Sweep
@@ -411,11 +432,32 @@ func (a *APU) emitSample() {
 	rightMaster := float64(a.nr50&0x07) / 7.0
 	l = (l / 4.0) * leftMaster
 	r = (r / 4.0) * rightMaster
-	a.streamer.push(l, r)
+	speaker := a.cartSpeaker()
+	a.streamer.push(l+speaker, r+speaker)
+}
+
+// cartSpeaker returns the next sample of the cartridge's own speaker. It
+// sits in the cartridge, so NR50/NR51 and the APU power don't reach it.
+func (a *APU) cartSpeaker() float64 {
+	if a.Mb == nil {
+		return 0
+	}
+	hz, on := a.Mb.Cartridge.Tone()
+	if !on {
+		a.cartTonePhase = 0
+		return 0
+	}
+	a.cartTonePhase += hz / float64(a.sampleRate)
+	a.cartTonePhase -= math.Floor(a.cartTonePhase)
+	if a.cartTonePhase < 0.5 {
+		return apuCartSpeakerLevel
+	}
+	return -apuCartSpeakerLevel
 }
 
 // emitSilence pushes silent samples to keep the ring buffer fed when
-// the APU is disabled. Avoids underrun-induced crackle.
+// the APU is disabled. Avoids underrun-induced crackle. Only the
+// cartridge speaker can still be heard.
 func (a *APU) emitSilence(cycles int) {
 	if !a.audioEnabled || a.streamer == nil {
 		return
@@ -423,7 +465,8 @@ func (a *APU) emitSilence(cycles int) {
 	a.sampleClockQ16 += cycles << 16
 	for a.sampleClockQ16 >= a.cyclesPerSampleQ16 {
 		a.sampleClockQ16 -= a.cyclesPerSampleQ16
-		a.streamer.push(0, 0)
+		speaker := a.cartSpeaker()
+		a.streamer.push(speaker, speaker)
 	}
 }
 
@@ -26,7 +26,7 @@ import (
 // the channel's actual output.
 func captureAPU(t *testing.T) (*APU, *apuStreamer) {
 	t.Helper()
-	a := NewAPU(nil, false, false) // headless, no beep
+	a := NewAPU(nil, nil, 0, false) // headless, no beep
 	cap := 1 << 18
 	streamer := &apuStreamer{
 		bufferL: make([]float64, cap),
@@ -262,3 +262,52 @@ func TestAPU_Capture_Noise_NonZero(t *testing.T) {
 	rms := math.Sqrt(sumSq / float64(len(samples)))
 	assert.Greater(t, rms, 0.05, "noise channel must produce non-trivial amplitude")
 }
+
+// TestAPU_Capture_HuC3Speaker plays a tone on a HuC3 cartridge's speaker
+// with the APU powered off and confirms it still comes through at the
+// tone's pitch.
+func TestAPU_Capture_HuC3Speaker(t *testing.T) {
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0xC3, 0x50, 0x01}) // JP $0150
+	copy(rom[0x150:], []byte{0x00, 0x18, 0xFD}) // NOP; JR -3
+	copy(rom[0x134:], "HUC3TEST")
+	rom[0x147] = 0xFE
+	var checksum uint8
+	for i := 0x134; i <= 0x14C; i++ {
+		checksum -= rom[i] + 1
+	}
+	rom[0x14D] = checksum
+	t.Chdir(t.TempDir()) // HuC3 saves carry a battery
+
+	mb, err := NewMotherboard(&MotherboardParams{Rom: rom, RomName: "huc3test"})
+	require.NoError(t, err)
+	mb.SkipBootROM()
+	a, s := captureAPU(t)
+	a.Mb = mb
+
+	// scratch $27 = $1 (C6), then command $6E
+	for _, cmd := range []uint16{0x47, 0x52, 0x21, 0x6E} {
+		mb.SetItem(0x0000, 0x0B)
+		mb.SetItem(0xA000, cmd)
+	}
+	a.enabled = false // NR52 off
+
+	for i := 0; i < apuDmgClock/5; i += 16 {
+		a.Tick(16)
+	}
+	samples := drainSamples(s)
+	require.GreaterOrEqual(t, len(samples), 4096)
+	window := samples[len(samples)-4096:]
+	peak := dftPeakHz(window, defaultAudioSampleRate)
+	assert.InDelta(t, 1046.5, peak, 20, "speaker tone should peak near C6")
+
+	mb.SetItem(0xA000, 0x20) // scratch $27 = 0
+	mb.SetItem(0xA000, 0x6E)
+	drainSamples(s)
+	for i := 0; i < apuDmgClock/100; i += 16 {
+		a.Tick(16)
+	}
+	for _, v := range drainSamples(s) {
+		require.Equal(t, 0.0, v, "tone 0 silences the speaker")
+	}
+}
@@ -12,10 +12,12 @@ package motherboard
 import (
 	"bytes"
 	"encoding/binary"
-	"errors"
+	"fmt"
+
+	"github.com/duysqubix/gobc/internal"
 )
 
-var errAPUUnknownVersion = errors.New("apu: unknown save-state version")
+var errAPUUnknownVersion = fmt.Errorf("%w: unknown APU section version", internal.ErrStateVersion)
 
 // 4 duty patterns × 8 phase steps. 1 = high, 0 = low (Pan Docs).
 var dutyTable = [4][8]byte{
@@ -1,11 +1,13 @@
 // Package motherboard — apu_streamer.go
 //
-// beep wiring: implements beep.Streamer over a lock-protected stereo
-// ring buffer. The APU pushes samples (one stereo pair per output rate
-// tick); beep's audio goroutine drains them via Stream().
+// Sample ring buffer: implements SampleStream (method-compatible with
+// beep.Streamer) over a lock-protected stereo ring buffer. The APU
+// pushes samples (one stereo pair per output rate tick); the host audio
+// goroutine drains them via Stream(). The device itself is wired up
+// through the AudioOutput interface so this package never imports beep.
 //
 // References:
-//   - github.com/gopxl/beep/v2 — Streamer interface, speaker.Init/Play
+//   - github.com/gopxl/beep/v2 — Streamer interface
 //   - HFO4/gameboy.live — pattern of "one Streamer per APU" (we simplified to one Streamer with internal mixing)
 
 package motherboard
@@ -13,17 +15,32 @@ package motherboard
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/duysqubix/gobc/internal"
-	"github.com/gopxl/beep/v2"
-	"github.com/gopxl/beep/v2/speaker"
 )
 
-// apuStreamer is the bridge from APU-generated samples into beep.
+// SampleStream is the pull side of the APU sample ring. Its method set
+// matches beep.Streamer so an AudioOutput can hand it straight to the
+// beep speaker.
+type SampleStream interface {
+	Stream(out [][2]float64) (int, bool)
+	Err() error
+}
+
+// AudioOutput is a host audio device the APU can play into. Play opens
+// the device at sampleRate and starts draining src; Stop silences it.
+// Implementations live outside this package (see internal/audio) so the
+// core emulator stays free of audio-library dependencies.
+type AudioOutput interface {
+	Play(sampleRate int, src SampleStream) error
+	Stop()
+}
+
+// apuStreamer is the bridge from APU-generated samples into the host
+// audio output.
 //
 // Mutex-protected SPSC ring. Producer (APU.Tick on the emulator's
 // main goroutine) calls push() ~44100×/sec; consumer (beep's audio
@@ -184,7 +201,7 @@ func (s *apuStreamer) Stream(out [][2]float64) (int, bool) {
 	return len(out), true
 }
 
-// Err is beep.Streamer.Err: never errors (infinite stream).
+// Err satisfies SampleStream: never errors (infinite stream).
 func (s *apuStreamer) Err() error { return nil }
 
 // flush drops any buffered samples and re-primes silence. Called on
@@ -196,82 +213,27 @@ func (s *apuStreamer) flush() {
 	s.prefillSilence(s.cap / 2)
 }
 
-// close stops audio playback. Idempotent.
+// close marks the ring as finished so the diagnostics goroutine exits.
+// Idempotent.
 func (s *apuStreamer) close() {
 	s.closed.Store(true)
-	speaker.Clear()
 }
 
-// startStreamer brings the beep speaker up and connects the APU ring
-// buffer to it. Idempotent across emulator resets (beep's speaker is a
-// process-global singleton that rejects double-Init).
-//
-// All failure modes degrade gracefully to silent operation:
-//
-//   - No audio device present (audioAvailable() == false) → skip the
-//     speaker.Init call entirely, log an info line, leave the emulator
-//     running silent. This is the common case on headless servers and
-//     on WSL without libasound2-plugins installed.
-//   - speaker.Init returns an error → log warning, run silent.
-//
-// libasound itself writes config-parse diagnostics directly to fd 2
-// before returning the error code. We wrap speaker.Init in
-// silenceStderr() so end users don't see the ALSA spew when their
-// system has a partial audio config.
+// startStreamer allocates the ring buffer and hands it to the configured
+// AudioOutput. With no output configured, or when the output fails to
+// open, the APU degrades to silent operation.
 func (a *APU) startStreamer() error {
-	if !audioAvailable() {
-		internal.Logger.Info("APU: no audio device detected; running silent. " +
-			"On WSL install `libasound2-plugins` and add an `~/.asoundrc` pulse PCM; " +
-			"on bare Linux install pulseaudio or pipewire-pulse.")
+	if a.output == nil {
+		internal.Logger.Info("APU: no audio output configured; running silent.")
 		a.audioEnabled = false
 		return nil
 	}
 
-	sr := beep.SampleRate(a.sampleRate)
 	a.streamer = newAPUStreamer(apuRingBufferCap)
-
-	if !speakerInitialized {
-		restore := silenceStderr()
-		err := speaker.Init(sr, sr.N(time.Second/apuBufferDuration))
-		restore()
-		if err != nil {
-			internal.Logger.Warnf("APU: speaker init failed (%v); running silent.", err)
-			a.audioEnabled = false
-			a.streamer = nil
-			return nil
-		}
-		speakerInitialized = true
+	if err := a.output.Play(a.sampleRate, a.streamer); err != nil {
+		internal.Logger.Warnf("APU: audio output unavailable (%v); running silent.", err)
+		a.audioEnabled = false
+		a.streamer = nil
 	}
-	speaker.Clear()
-	speaker.Play(a.streamer)
 	return nil
 }
-
-// audioAvailable reports whether the host has SOME plausible audio
-// sink we can target. Returns true when any of:
-//
-//   - /dev/snd exists and contains entries (real ALSA cards)
-//   - $PULSE_SERVER points at a PulseAudio socket (covers WSLg via
-//     /mnt/wslg/PulseServer)
-//   - $XDG_RUNTIME_DIR/pulse/native exists (standard Linux PulseAudio)
-//
-// Returning true does NOT guarantee audio will work — libasound may
-// still fail to open the PCM — but returning false reliably avoids
-// the ALSA error spam on hosts with no audio path at all.
-func audioAvailable() bool {
-	if entries, err := os.ReadDir("/dev/snd"); err == nil && len(entries) > 0 {
-		return true
-	}
-	if os.Getenv("PULSE_SERVER") != "" {
-		return true
-	}
-	if r := os.Getenv("XDG_RUNTIME_DIR"); r != "" {
-		if _, err := os.Stat(filepath.Join(r, "pulse", "native")); err == nil {
-			return true
-		}
-	}
-	return false
-}
-
-// Process-wide flag because beep's speaker is a global singleton.
-var speakerInitialized bool
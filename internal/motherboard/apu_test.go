@@ -270,7 +270,7 @@ func TestAPU_Tick_NoAudio(t *testing.T) {
 // TestAPU_PanningMixesIntoCorrectChannel uses NR51 to route channel 1
 // to LEFT only, then confirms emitSample produces left-only output.
 func TestAPU_PanningMixesIntoCorrectChannel(t *testing.T) {
-	a := NewAPU(nil, false, false) // headless
+	a := NewAPU(nil, nil, 0, false) // headless
 
 	// Force a deterministic full-volume ch1 output.
 	a.enabled = true
@@ -20,8 +20,6 @@ const (
 	PC_HISTORY_COUNT_MAX = 6
 )
 
-var tickCycles OpCycles
-
 // Registers is a struct that represents the CPU registers
 type Registers struct {
 	A  uint8  // Accumulator
@@ -45,6 +43,8 @@ type CPU struct {
 	IsStuck    bool         // CPU is stuck
 	Stopped    bool         // CPU is stopped
 	PcHist     *list.List   // records last 16 PC values
+	RecordHist bool         // fill PcHist; off unless a debug view reads it
+	Trace      bool         // append a register trace line per instruction to the log file
 	lastOpCode OpCode       // last opcode executed
 }
 
@@ -127,6 +127,7 @@ func NewCpu(mb *Motherboard) *CPU {
 		},
 		Mb:     mb,
 		PcHist: list.New(),
+		Trace:  os.Getenv("PC_DUMP") == "true",
 	}
 
 	cpu.initRegisters(mb.Cgb)
@@ -174,11 +175,9 @@ func (c *CPU) Reset() {
 }
 
 func (c *CPU) Tick() OpCycles {
-	tickCycles = 0
-
 	old_pc := c.Registers.PC
 	old_sp := c.Registers.SP
-	tickCycles = c.ExecuteInstruction()
+	tickCycles := c.ExecuteInstruction()
 
 	if !c.Halted && (old_pc == c.Registers.PC) && (old_sp == c.Registers.SP) && !c.IsStuck {
 		logger.Warnf("CPU is stuck at PC: %#x SP: %#x", c.Registers.PC, c.Registers.SP)
@@ -208,7 +207,7 @@ func (c *CPU) addToPCHistory(addr uint16, opCode OpCode, isOpCode bool) {
 }
 
 func (c *CPU) ExecuteInstruction() OpCycles {
-	if os.Getenv("PC_DUMP") == "true" {
+	if c.Trace {
 		pc0 := c.Mb.GetItem(c.Registers.PC)
 		pc1 := c.Mb.GetItem(c.Registers.PC + 1)
 		pc2 := c.Mb.GetItem(c.Registers.PC + 2)
@@ -223,16 +222,18 @@ func (c *CPU) ExecuteInstruction() OpCycles {
 
 	var value uint16
 
-	opcode := OpCode(c.Mb.GetItem(c.Registers.PC))
+	opcode := OpCode(c.Mb.cpuRead(c.Registers.PC))
 
 	// fmt.Printf("Pre-Execution :Opcode: %s [%#x] | PC: %#x | SP: %#x\n", internal.OPCODE_NAMES[opcode], opcode, c.Registers.PC, c.Registers.SP)
 	if opcode.CBPrefix() {
 		pc := c.Registers.PC + 1
-		opcode = OpCode(c.Mb.GetItem(pc))
+		opcode = OpCode(c.Mb.cpuRead(pc))
 		opcode = opcode.Shift()
 
 	}
-	c.addToPCHistory(c.Registers.PC, opcode, true)
+	if c.RecordHist {
+		c.addToPCHistory(c.Registers.PC, opcode, true)
+	}
 
 	// Pan Docs "HALT bug": when HALT executed with IME=0 and a pending
 	// interrupt, the byte after HALT is read as the next opcode but PC
@@ -251,18 +252,21 @@ func (c *CPU) ExecuteInstruction() OpCycles {
 	// 8 bit immediate
 	case 2:
 		pc++
-		value = uint16(c.Mb.GetItem(pc))
-		c.addToPCHistory(pc, opcode, false)
+		value = uint16(c.Mb.cpuRead(pc))
+		if c.RecordHist {
+			c.addToPCHistory(pc, opcode, false)
+		}
 
 	// 16 bit immediate
 	case 3:
 		pc++
-		b := uint16(c.Mb.GetItem(pc))
-		c.addToPCHistory(pc, opcode, false)
-
+		b := uint16(c.Mb.cpuRead(pc))
 		pc++
-		a := uint16(c.Mb.GetItem(pc))
-		c.addToPCHistory(pc, opcode, false)
+		a := uint16(c.Mb.cpuRead(pc))
+		if c.RecordHist {
+			c.addToPCHistory(pc-1, opcode, false)
+			c.addToPCHistory(pc, opcode, false)
+		}
 
 		value = (a << 8) | b
 
@@ -14,11 +14,9 @@ import (
 // Test harness
 // ---------------------------------------------------------------------------
 //
-// The motherboard package does not (today) expose a clean way to construct a
-// CPU in isolation: `NewMotherboard` requires a real cartridge file on disk
-// and the alternative "dummy ROM" path inside `cartridge.NewCartridge` panics
-// because the synthesised header has SRAM_SIZE_ADDR == 0xFF, which falls into
-// the `default` branch of the RAM-size switch and calls `logger.Panicf`.
+// The motherboard package does not expose a way to construct a CPU in
+// isolation: `NewMotherboard` always loads a cartridge and brings up every
+// peripheral, including the APU and boot ROM.
 //
 // To keep these tests fast and hermetic we build a *Motherboard* manually,
 // wiring up only the components the CPU under test actually touches:
@@ -49,6 +47,7 @@ func newTestCPU(t *testing.T) (*CPU, *Motherboard) {
 		Timer:         NewTimer(),
 		BGPalette:     NewPalette(),
 		SpritePalette: NewPalette(),
+		fastCPU:       true, // no peripherals to step between bus accesses
 	}
 	mb.Input = NewInput(mb)
 	mb.Cpu = NewCpu(mb)
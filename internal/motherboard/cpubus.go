@@ -0,0 +1,92 @@
+/*
+* CPU bus timing.
+*
+* The SM83 spends one M-cycle (4 clocks) on every memory access an
+* instruction makes, plus whatever internal cycles it needs in between.
+* By default the CPU core runs on that M-cycle grid: each access happens
+* at the start of its own M-cycle, with the peripherals caught up to that
+* exact point, so a timer or PPU register read mid-instruction sees the
+* value it has on hardware and a write lands on the right cycle. Internal
+* cycles that come before an access are spent with cpuIdle; any left at
+* the end of an instruction are spent once the handler returns.
+*
+* With MotherboardParams.FastCPU each instruction instead runs atomically
+* and the peripherals only catch up once it has finished. That is quicker,
+* but every access appears to happen on the instruction's first cycle.
+ */
+
+package motherboard
+
+// cpuRead is a CPU bus read occupying one M-cycle.
+func (m *Motherboard) cpuRead(addr uint16) uint8 {
+	m.cpuCycle()
+	if m.dmaBlocks(addr) || m.ppuBlocks(addr) {
+		return 0xFF
+	}
+	return m.GetItem(addr)
+}
+
+// cpuWrite is a CPU bus write occupying one M-cycle.
+func (m *Motherboard) cpuWrite(addr uint16, value uint16) {
+	m.cpuCycle()
+	if m.dmaBlocks(addr) || m.ppuBlocks(addr) {
+		return
+	}
+	m.SetItem(addr, value)
+}
+
+// cpuIdle is an internal M-cycle that does not touch the bus.
+func (m *Motherboard) cpuIdle() {
+	m.cpuCycle()
+}
+
+// cpuCycle starts the next M-cycle of the current instruction, first
+// running the previous one to completion.
+func (m *Motherboard) cpuCycle() {
+	if m.fastCPU {
+		return
+	}
+	if m.busCycles > 0 {
+		m.advance(4)
+	}
+	m.busCycles += 4
+}
+
+// spendCycles advances the peripherals over the part of an instruction's
+// cycles that its bus accesses have not already covered.
+func (m *Motherboard) spendCycles(cycles OpCycles) {
+	if m.busCycles > 0 {
+		cycles -= m.busCycles - 4
+		m.busCycles = 0
+	}
+	if cycles > 0 {
+		m.advance(cycles)
+	}
+}
+
+// dmaBlocks reports whether a running OAM DMA keeps the CPU off addr.
+func (m *Motherboard) dmaBlocks(addr uint16) bool {
+	if addr < 0xFE00 || addr >= 0xFEA0 {
+		return false
+	}
+	m.sync(evDMA)
+	return m.Dma.Active
+}
+
+// ppuBlocks reports whether the PPU is holding the VRAM or OAM bus at
+// addr. Only the M-cycle CPU is locked out: under FastCPU an access can
+// land far enough from its real cycle to hit a mode it would have missed.
+func (m *Motherboard) ppuBlocks(addr uint16) bool {
+	if m.fastCPU {
+		return false
+	}
+	switch {
+	case addr >= 0x8000 && addr < 0xA000:
+		m.sync(evLCD)
+		return m.Lcd.vramLocked()
+	case addr >= 0xFE00 && addr < 0xFEA0:
+		m.sync(evLCD)
+		return m.Lcd.oamLocked()
+	}
+	return false
+}
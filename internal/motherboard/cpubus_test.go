@@ -0,0 +1,112 @@
+package motherboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runUntilPC ticks mb until the CPU is about to execute pc.
+func runUntilPC(t *testing.T, mb *Motherboard, pc uint16) {
+	t.Helper()
+	for i := 0; mb.Cpu.Registers.PC != pc; i++ {
+		require.Less(t, i, 10000, "CPU never reached $%04X", pc)
+		ok, _ := mb.Tick()
+		require.True(t, ok)
+	}
+}
+
+func TestCPUBus_AccessesLandOnTheirOwnMCycle(t *testing.T) {
+	program := []byte{
+		0x21, 0x04, 0xFF, // LD HL,DIV
+		0x3E, 0x05, 0xE0, 0x07, // TAC = enabled, 16 cycles
+		0x77,       // LD (HL),A  ; resets DIV on its 2nd M-cycle
+		0x00,       // NOP
+		0xF0, 0x05, // LDH A,(TIMA) ; reads on its 3rd M-cycle
+	}
+	end := uint16(0x150 + len(program))
+
+	for _, tc := range []struct {
+		name    string
+		fastCPU bool
+		tima    uint8
+	}{
+		// The read lands 16 cycles after the reset, as on hardware.
+		{"m-cycle", false, 1},
+		// Both accesses happen on their instruction's first cycle, 12
+		// cycles apart.
+		{"fast", true, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mb := newSchedulerTestMb(t, program...)
+			mb.fastCPU = tc.fastCPU
+			mb.SetItem(0xFF05, 0x00)
+			runUntilPC(t, mb, end)
+			assert.Equal(t, tc.tima, mb.Cpu.Registers.A)
+		})
+	}
+}
+
+func TestCPUBus_OAMDMABlocksOAM(t *testing.T) {
+	program := []byte{
+		0x3E, 0xC0, 0xE0, 0x46, // LDH (DMA),$C0
+		0xFA, 0x10, 0xFE, // LD A,($FE10)
+		0x47,       // LD B,A
+		0x0E, 0x30, // LD C,48
+		0x0D, 0x20, 0xFD, // wait: DEC C ; JR NZ,wait
+		0xFA, 0x10, 0xFE, // LD A,($FE10)
+	}
+	end := uint16(0x150 + len(program))
+
+	for _, tc := range []struct {
+		name    string
+		fastCPU bool
+		during  uint8
+	}{
+		{"m-cycle", false, 0xFF},
+		{"fast", true, 0xA5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mb := newSchedulerTestMb(t, program...)
+			mb.fastCPU = tc.fastCPU
+			mb.SetItem(0xFF40, 0x00) // LCD off, so the PPU leaves OAM alone
+			mb.Memory.Wram[0][0x10] = 0xA5
+			mb.Memory.Oam[0x10] = 0x11
+
+			runUntilPC(t, mb, end)
+			assert.Equal(t, tc.during, mb.Cpu.Registers.B, "OAM read while the transfer runs")
+			assert.Equal(t, uint8(0xA5), mb.Cpu.Registers.A, "OAM read once the transfer is done")
+			assert.False(t, mb.Dma.Active)
+		})
+	}
+}
+
+func TestOamDMA_CopiesOneBytePerMCycle(t *testing.T) {
+	mb := newSchedulerTestMb(t)
+	for i := range oamDMALength {
+		mb.Memory.Wram[0][i] = uint8(i + 1)
+	}
+
+	mb.Dma.Start(0xC0)
+	mb.Dma.Tick(oamDMASetup)
+	assert.True(t, mb.Dma.Active)
+	assert.Equal(t, uint16(1), mb.Dma.Pos, "first byte is copied as the transfer takes over")
+
+	mb.Dma.Tick(10 * oamDMAByteCycles)
+	assert.Equal(t, uint16(11), mb.Dma.Pos)
+	assert.Equal(t, uint8(11), mb.Memory.Oam[10])
+	assert.NotEqual(t, uint8(12), mb.Memory.Oam[11])
+
+	// Restarting keeps the old transfer running through the setup M-cycle.
+	mb.Dma.Start(0xC0)
+	mb.Dma.Tick(oamDMASetup - 1)
+	assert.True(t, mb.Dma.Active)
+	assert.Equal(t, uint16(12), mb.Dma.Pos)
+	mb.Dma.Tick(1)
+	assert.Equal(t, uint16(1), mb.Dma.Pos)
+
+	mb.Dma.Tick(oamDMALength * oamDMAByteCycles)
+	assert.False(t, mb.Dma.Active)
+	assert.Equal(t, mb.Memory.Wram[0][:oamDMALength], mb.Memory.Oam[:oamDMALength])
+}
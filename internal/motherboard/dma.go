@@ -0,0 +1,127 @@
+/*
+* Implements timed OAM DMA (writes to $FF46) for the M-cycle CPU core.
+*
+* After the M-cycle of the write and one setup M-cycle, the transfer copies
+* one byte per M-cycle for 160 M-cycles. While it runs the CPU reads $FF
+* from OAM and its OAM writes are dropped. Restarting a transfer lets the
+* old one run on through the new one's setup M-cycle, so OAM never becomes
+* accessible in between.
+*
+* With MotherboardParams.FastCPU the copy is still done instantly by
+* doDMATransfer, as the atomic core has no cycles to spread it over.
+ */
+
+package motherboard
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	oamDMALength     = 0xA0 // bytes copied per transfer
+	oamDMASetup      = 8    // cycles from the write to the first byte copied
+	oamDMAByteCycles = 4    // cycles per byte copied
+)
+
+type OamDMA struct {
+	Active     bool     // a transfer owns OAM
+	Src        uint16   // source address of the active transfer
+	Pos        uint16   // bytes the active transfer has copied
+	Elapsed    OpCycles // cycles since the active transfer copied its first byte
+	Pending    bool     // a transfer has been requested and is being set up
+	PendingSrc uint16   // source address of the pending transfer
+	PendingIn  OpCycles // cycles until the pending transfer takes over
+	mb         *Motherboard
+}
+
+func NewOamDMA(mb *Motherboard) *OamDMA {
+	return &OamDMA{mb: mb}
+}
+
+func (d *OamDMA) Reset() {
+	*d = OamDMA{mb: d.mb}
+}
+
+func (d *OamDMA) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, d.Active)     // Transfer active
+	binary.Write(buf, binary.LittleEndian, d.Src)        // Source address
+	binary.Write(buf, binary.LittleEndian, d.Pos)        // Bytes copied
+	binary.Write(buf, binary.LittleEndian, d.Elapsed)    // Cycles since first byte
+	binary.Write(buf, binary.LittleEndian, d.Pending)    // Transfer pending
+	binary.Write(buf, binary.LittleEndian, d.PendingSrc) // Pending source address
+	binary.Write(buf, binary.LittleEndian, d.PendingIn)  // Cycles until pending takes over
+	return buf
+}
+
+func (d *OamDMA) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&d.Active, &d.Src, &d.Pos, &d.Elapsed, &d.Pending, &d.PendingSrc, &d.PendingIn} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start requests a transfer from page value, as a write to $FF46 does.
+func (d *OamDMA) Start(value uint8) {
+	d.Pending = true
+	d.PendingSrc = uint16(value) << 8
+	d.PendingIn = oamDMASetup
+}
+
+// Tick advances the transfer by the given number of cycles.
+func (d *OamDMA) Tick(cycles OpCycles) {
+	for cycles > 0 {
+		n := cycles
+		if d.Pending {
+			n = min(n, d.PendingIn)
+		}
+		d.run(n)
+		cycles -= n
+
+		if d.Pending {
+			d.PendingIn -= n
+			if d.PendingIn == 0 {
+				d.Pending = false
+				d.Active = true
+				d.Src = d.PendingSrc
+				d.Pos = 0
+				d.Elapsed = 0
+				d.run(0)
+			}
+		}
+	}
+}
+
+// run advances the active transfer, copying every byte that falls due.
+func (d *OamDMA) run(cycles OpCycles) {
+	if !d.Active {
+		return
+	}
+	d.Elapsed += cycles
+	for d.Pos < oamDMALength && OpCycles(d.Pos)*oamDMAByteCycles <= d.Elapsed {
+		d.mb.Memory.Oam[d.Pos] = d.mb.GetItem(d.Src + d.Pos)
+		d.Pos++
+	}
+	if d.Elapsed >= oamDMALength*oamDMAByteCycles {
+		d.Active = false
+	}
+}
+
+// nextEvent reports the cycles until the next byte is copied or the
+// transfer state changes.
+func (d *OamDMA) nextEvent() (OpCycles, bool) {
+	next, ok := evNever, false
+	if d.Active {
+		next, ok = oamDMALength*oamDMAByteCycles-d.Elapsed, true
+		if d.Pos < oamDMALength {
+			next = OpCycles(d.Pos)*oamDMAByteCycles - d.Elapsed
+		}
+	}
+	if d.Pending {
+		next, ok = min(next, d.PendingIn), true
+	}
+	return next, ok
+}
@@ -0,0 +1,220 @@
+/*
+* Implements the CGB infrared port (RP $FF56).
+*
+* Bit 0 switches the IR LED on. Bit 1 reads 0 while the receiver sees IR
+* light, but only with both read enable bits (6-7) set; otherwise it reads
+* 1, as it does with nothing there to see. The receiver adapts to the level
+* of IR in the room, so light that stays on fades back to "no signal" after
+* a few milliseconds; senders have to pulse the LED.
+*
+* What the port faces is an InfraredDevice: another Game Boy's port, light
+* from the room, or nothing at all. The port keeps its own clock for timing
+* the light, which runs at the normal rate in double speed mode like the
+* other peripherals.
+*
+* HuC cartridges carry an LED and receiver of their own. They are plugged
+* into this port, sharing its device, so they reach the same partners
+* whether or not the console is a CGB.
+ */
+
+package motherboard
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/duysqubix/gobc/internal/cartridge"
+)
+
+// Light on the receiver for longer than this stops registering.
+const irFadeCycles OpCycles = 12288 // about 3 ms
+
+// InfraredDevice is whatever the CGB's IR port is pointed at.
+type InfraredDevice interface {
+	// LED is called whenever the port's LED switches on or off. now is the
+	// port's clock.
+	LED(on bool, now OpCycles)
+	// Light reports whether IR light is reaching the receiver at now, on
+	// the port's clock, and if so since when.
+	Light(now OpCycles) (on bool, since OpCycles)
+}
+
+// ConnectInfrared points two machines' IR ports at each other. Both
+// machines must be stepped from the same goroutine.
+func ConnectInfrared(a, b *Infrared) {
+	a.Connect(irFacing{b})
+	b.Connect(irFacing{a})
+}
+
+// irFacing sees the LED of the port across from it.
+type irFacing struct {
+	peer *Infrared
+}
+
+func (f irFacing) LED(on bool, now OpCycles) {}
+
+func (f irFacing) Light(now OpCycles) (bool, OpCycles) {
+	f.peer.mb.sync(evInfrared)
+	// the peer's clock need not match ours, but the time since its LED
+	// last switched does
+	return f.peer.led(), now - (f.peer.clock - f.peer.ledAt)
+}
+
+// IdleNoise returns a device that stands for an empty room: the receiver
+// picks up a brief flicker of stray light every few milliseconds, as a
+// real one does with no partner in front of it.
+func IdleNoise() InfraredDevice {
+	return &idleNoise{seed: 0x2545F491}
+}
+
+type idleNoise struct {
+	seed       uint32
+	start, end OpCycles // the flicker in progress or coming up next
+}
+
+func (n *idleNoise) LED(on bool, now OpCycles) {}
+
+func (n *idleNoise) Light(now OpCycles) (bool, OpCycles) {
+	for now >= n.end {
+		n.start = n.end + 2048 + OpCycles(n.next()%30720) // 0.5-8 ms apart
+		n.end = n.start + 8 + OpCycles(n.next()%120)      // 2-30 µs long
+	}
+	return now >= n.start, n.start
+}
+
+// next steps a xorshift generator, so a run is the same every time.
+func (n *idleNoise) next() uint32 {
+	n.seed ^= n.seed << 13
+	n.seed ^= n.seed >> 17
+	n.seed ^= n.seed << 5
+	return n.seed
+}
+
+type Infrared struct {
+	RP      uint8    // LED and read enable bits (0xFF56)
+	cartLED bool     // LED of the cartridge's transceiver
+	clock   OpCycles // cycles the port has run, at the normal rate
+	ledAt   OpCycles // clock when the LED last switched
+	device  InfraredDevice
+	mb      *Motherboard
+}
+
+func NewInfrared(mb *Motherboard) *Infrared {
+	return &Infrared{mb: mb}
+}
+
+// Reset clears the port, switching the LED off. The device stays
+// connected.
+func (i *Infrared) Reset() {
+	if i.led() && i.device != nil {
+		i.device.LED(false, i.clock)
+	}
+	*i = Infrared{clock: i.clock, ledAt: i.clock, device: i.device, mb: i.mb}
+}
+
+func (i *Infrared) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, i.RP)      // RP
+	binary.Write(buf, binary.LittleEndian, i.cartLED) // Cartridge LED
+	binary.Write(buf, binary.LittleEndian, i.clock)   // Port clock
+	binary.Write(buf, binary.LittleEndian, i.ledAt)   // Clock when the LED last switched
+	return buf
+}
+
+func (i *Infrared) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&i.RP, &i.cartLED, &i.clock, &i.ledAt} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect points the port at dev, replacing whatever was there. A nil dev
+// leaves it facing nothing.
+func (i *Infrared) Connect(dev InfraredDevice) {
+	i.mb.sync(evInfrared)
+	i.device = dev
+}
+
+// Read returns RP as the CPU sees it.
+func (i *Infrared) Read() uint8 {
+	if !i.mb.Cgb {
+		return 0xFF
+	}
+	v := i.RP | 0x3E
+	if i.RP&0xC0 == 0xC0 && i.receiving() {
+		v &^= 0x02
+	}
+	return v
+}
+
+// Write stores a CPU write to RP, switching the LED.
+func (i *Infrared) Write(v uint8) {
+	if !i.mb.Cgb {
+		return
+	}
+	was := i.led()
+	i.RP = v & 0xC1
+	i.switched(was)
+}
+
+// switched tells the device about the LED if it differs from was.
+func (i *Infrared) switched(was bool) {
+	if i.led() != was {
+		i.ledAt = i.clock
+		if i.device != nil {
+			i.device.LED(i.led(), i.clock)
+		}
+	}
+}
+
+// led reports whether either LED, the console's or the cartridge's, is on.
+func (i *Infrared) led() bool {
+	return i.RP&0x01 != 0 || i.cartLED
+}
+
+// receiving reports whether the receiver registers light right now.
+func (i *Infrared) receiving() bool {
+	if i.device == nil {
+		return false
+	}
+	on, since := i.device.Light(i.clock)
+	return on && i.clock-since < irFadeCycles
+}
+
+// Transceiver returns the port as a cartridge's IR LED and receiver see
+// it. The receiver reads without RP's enable bits.
+func (i *Infrared) Transceiver() cartridge.Transceiver {
+	return cartTransceiver{i}
+}
+
+type cartTransceiver struct {
+	port *Infrared
+}
+
+func (t cartTransceiver) SetLED(on bool) {
+	t.port.mb.sync(evInfrared)
+	was := t.port.led()
+	t.port.cartLED = on
+	t.port.switched(was)
+}
+
+func (t cartTransceiver) Light() bool {
+	t.port.mb.sync(evInfrared)
+	return t.port.receiving()
+}
+
+// Tick advances the port's clock.
+func (i *Infrared) Tick(cycles OpCycles) {
+	if i.mb.doubleSpeed {
+		cycles >>= 1
+	}
+	i.clock += cycles
+}
+
+// nextEvent reports that the port never needs stepping on its own: the
+// light is only ever looked at when RP is read.
+func (i *Infrared) nextEvent() (OpCycles, bool) {
+	return 0, false
+}
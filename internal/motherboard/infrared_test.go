@@ -0,0 +1,165 @@
+package motherboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// irSignal reports whether mb's receiver sees light, with reading enabled.
+func irSignal(mb *Motherboard) bool {
+	return mb.GetItem(0xFF56)&0x02 == 0
+}
+
+func runCycles(mbs []*Motherboard, cycles OpCycles) {
+	for range cycles / 4 {
+		for _, mb := range mbs {
+			mb.advance(4)
+		}
+	}
+}
+
+func TestInfrared_RegisterBits(t *testing.T) {
+	dmg := newMbForSubsysTest(t)
+	dmg.SetItem(0xFF56, 0xC1)
+	assert.Equal(t, uint8(0xFF), dmg.GetItem(0xFF56), "no IR port on DMG")
+
+	cgb := newCGBMbForSubsysTest(t)
+	assert.Equal(t, uint8(0x3E), cgb.GetItem(0xFF56))
+	cgb.SetItem(0xFF56, 0xFF)
+	assert.Equal(t, uint8(0xFF), cgb.GetItem(0xFF56), "no light")
+	cgb.SetItem(0xFF56, 0x40)
+	assert.Equal(t, uint8(0x7E), cgb.GetItem(0xFF56))
+}
+
+// ledRecorder is an InfraredDevice that records LED switches and never
+// shines.
+type ledRecorder struct {
+	switches []bool
+	at       []OpCycles
+}
+
+func (r *ledRecorder) LED(on bool, now OpCycles) {
+	r.switches = append(r.switches, on)
+	r.at = append(r.at, now)
+}
+
+func (r *ledRecorder) Light(now OpCycles) (bool, OpCycles) {
+	return false, 0
+}
+
+func TestInfrared_LEDSwitchesAtNormalSpeed(t *testing.T) {
+	mb := newCGBMbForSubsysTest(t)
+	dev := &ledRecorder{}
+	mb.Infrared.Connect(dev)
+
+	mb.SetItem(0xFF56, 0x01)
+	mb.SetItem(0xFF56, 0xC1) // LED already on
+	runCycles([]*Motherboard{mb}, 400)
+	mb.SetItem(0xFF56, 0x00)
+	mb.doubleSpeed = true
+	runCycles([]*Motherboard{mb}, 400)
+	mb.SetItem(0xFF56, 0x01)
+
+	assert.Equal(t, []bool{true, false, true}, dev.switches)
+	require.Len(t, dev.at, 3)
+	assert.Equal(t, OpCycles(400), dev.at[1]-dev.at[0])
+	assert.Equal(t, OpCycles(200), dev.at[2]-dev.at[1], "the port clock runs at the normal rate in double speed")
+}
+
+func TestInfrared_Partner(t *testing.T) {
+	a, b := newCGBMbForSubsysTest(t), newCGBMbForSubsysTest(t)
+	both := []*Motherboard{a, b}
+	ConnectInfrared(a.Infrared, b.Infrared)
+	runCycles(both, 1000)
+
+	b.SetItem(0xFF56, 0xC0)
+	assert.False(t, irSignal(b))
+
+	a.SetItem(0xFF56, 0x01)
+	assert.True(t, irSignal(b), "a's LED reaches b")
+	assert.False(t, irSignal(a), "a does not see its own LED")
+	b.SetItem(0xFF56, 0x00)
+	assert.Equal(t, uint8(0x3E), b.GetItem(0xFF56), "reading disabled")
+	b.SetItem(0xFF56, 0xC0)
+
+	runCycles(both, irFadeCycles-8)
+	assert.True(t, irSignal(b))
+	runCycles(both, 8)
+	assert.False(t, irSignal(b), "steady light fades")
+
+	a.SetItem(0xFF56, 0x00)
+	assert.False(t, irSignal(b))
+	a.SetItem(0xFF56, 0x01)
+	assert.True(t, irSignal(b), "a fresh pulse registers again")
+}
+
+func TestInfrared_IdleNoise(t *testing.T) {
+	mb := newCGBMbForSubsysTest(t)
+	mb.Infrared.Connect(IdleNoise())
+	mb.SetItem(0xFF56, 0xC0)
+
+	const samples = 1_000_000
+	var lit, flickers int
+	was := false
+	for range samples {
+		mb.advance(4)
+		on := irSignal(mb)
+		if on {
+			lit++
+			if !was {
+				flickers++
+			}
+		}
+		was = on
+	}
+	// about 4M cycles, a second of it: some stray light, but short and rare
+	assert.Greater(t, flickers, 20)
+	assert.Less(t, flickers, 2000)
+	assert.Less(t, lit, samples/100)
+}
+
+func newHuC1Mb(t *testing.T) *Motherboard {
+	t.Helper()
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0xC3, 0x50, 0x01}) // JP $0150
+	copy(rom[0x150:], []byte{0x00, 0x18, 0xFD}) // NOP; JR -3
+	copy(rom[0x134:], "HUC1TEST")
+	rom[0x147] = 0xFF
+	var checksum uint8
+	for i := 0x134; i <= 0x14C; i++ {
+		checksum -= rom[i] + 1
+	}
+	rom[0x14D] = checksum
+
+	mb, err := NewMotherboard(&MotherboardParams{Rom: rom, RomName: "huc1test"})
+	require.NoError(t, err)
+	mb.SkipBootROM()
+	return mb
+}
+
+func TestInfrared_HuC1Cartridge(t *testing.T) {
+	a, b := newHuC1Mb(t), newCGBMbForSubsysTest(t)
+	require.False(t, a.Cgb)
+	both := []*Motherboard{a, b}
+	ConnectInfrared(a.Infrared, b.Infrared)
+	runCycles(both, 1000)
+	b.SetItem(0xFF56, 0xC0)
+
+	a.SetItem(0x0000, 0x0E) // A000-BFFF is the transceiver
+	assert.Equal(t, uint8(0xC0), a.GetItem(0xA000))
+	a.SetItem(0xA000, 0x01)
+	assert.True(t, irSignal(b), "the cartridge LED reaches b")
+	assert.Equal(t, uint8(0xC0), a.GetItem(0xA000), "a does not see its own LED")
+	a.SetItem(0xA000, 0x00)
+	assert.False(t, irSignal(b))
+
+	b.SetItem(0xFF56, 0xC1)
+	assert.Equal(t, uint8(0xC1), a.GetItem(0xBFFF), "b's LED reaches the cartridge")
+
+	a.SetItem(0x0000, 0x00) // back to RAM
+	a.SetItem(0xA000, 0x5A)
+	assert.Equal(t, uint8(0x5A), a.GetItem(0xA000))
+	assert.False(t, a.Infrared.cartLED, "RAM writes leave the LED alone")
+}
@@ -34,10 +34,17 @@ const (
 	SelectRelease
 )
 
-type Input struct {
+// joypad is the state of one controller's buttons, a bit per button,
+// clear while held.
+type joypad struct {
 	directional uint8
 	standard    uint8
-	Mb          *Motherboard
+}
+
+type Input struct {
+	joypad           // player 1
+	others [3]joypad // players 2-4, read on a Super Game Boy after MLT_REQ
+	Mb     *Motherboard
 }
 
 func (i *Input) Serialize() *bytes.Buffer {
@@ -60,14 +67,28 @@ func (i *Input) Deserialize(data *bytes.Buffer) error {
 }
 
 func NewInput(mb *Motherboard) *Input {
-	return &Input{
-		directional: 0x0F,
-		standard:    0x0F,
-		Mb:          mb,
+	i := &Input{Mb: mb}
+	for _, pad := range i.pads() {
+		*pad = joypad{directional: 0x0F, standard: 0x0F}
 	}
+	return i
+}
+
+// pads returns every player's joypad, player 1 first.
+func (i *Input) pads() []*joypad {
+	return []*joypad{&i.joypad, &i.others[0], &i.others[1], &i.others[2]}
 }
 
 func (i *Input) KeyEvent(key Key) uint8 {
+	return i.joypad.keyEvent(key)
+}
+
+// PlayerKeyEvent is KeyEvent for the joypad of player (1-4).
+func (i *Input) PlayerKeyEvent(player int, key Key) uint8 {
+	return i.pads()[player-1].keyEvent(key)
+}
+
+func (i *joypad) keyEvent(key Key) uint8 {
 	prevDirectional := i.directional
 	prevStandard := i.standard
 
@@ -127,13 +148,24 @@ func (i *Input) Pull(joystickbyte uint8) uint8 {
 	// # Bit 1 - P11 in port
 	// # Bit 0 - P10 in port
 
+	pad := &i.joypad
+	if i.Mb.Sgb {
+		if player, multi := i.Mb.Super.Joypad(); multi {
+			if P14 == 1 && P15 == 1 {
+				// with neither row selected, the low bits read the joypad ID
+				return 0xF0 | (0x0F - player)
+			}
+			pad = i.pads()[player]
+		}
+	}
+
 	joystickByte := 0xFF & (joystickbyte | 0b11001111)
 	if P14 == 0 {
-		joystickByte &= i.directional
+		joystickByte &= pad.directional
 	}
 
 	if P15 == 0 {
-		joystickByte &= i.standard
+		joystickByte &= pad.standard
 	}
 
 	return joystickByte
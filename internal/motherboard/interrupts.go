@@ -98,8 +98,11 @@ func (c *CPU) ServiceInterrupt(interrupt uint8) {
 	sp := c.Registers.SP
 	pc := c.Registers.PC
 
-	c.Mb.SetItem(sp-1, (pc&0xff00)>>8)
-	c.Mb.SetItem(sp-2, pc&0xFF)
+	// Two internal M-cycles, then PC is pushed high byte first.
+	c.Mb.cpuIdle()
+	c.Mb.cpuIdle()
+	c.Mb.cpuWrite(sp-1, (pc&0xff00)>>8)
+	c.Mb.cpuWrite(sp-2, pc&0xFF)
 	c.Registers.SP -= 2
 	c.Registers.PC = interruptAddresses[interrupt]
 }
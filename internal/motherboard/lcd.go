@@ -8,35 +8,47 @@ import (
 )
 
 type ScreenData [internal.GB_SCREEN_WIDTH][internal.GB_SCREEN_HEIGHT][3]uint8
-type ScreenPriority [internal.GB_SCREEN_WIDTH][internal.GB_SCREEN_HEIGHT]bool
 
 const (
-	lcdMode2Bounds       = 456 - 80
-	lcdMode3Bounds       = lcdMode2Bounds - 172
-	spritePriorityOffset = 100
-)
+	lineDots = 456 // dots per scanline
+
+	// LY moves on as each line starts, but for its first lineStartDots
+	// dots STAT still shows mode 0 and LYC is not compared.
+	lineStartDots = 4
+
+	lcdMode2Bounds = lineDots - 80 // mode 2 (OAM scan) is the first 80 dots of a line
 
-var (
-	prevLY = uint8(0)
+	// The mode 0 interrupt fires a few dots after STAT shows mode 0.
+	hblankIntrDelay = 3
 )
 
 type LCD struct {
 
 	// Matrix of pixel data which is used while the screen is rendering. When the screen is done rendering, this data is copied to the PreparedData matrix.
 	screenData ScreenData
-	bgPriority ScreenPriority
+	fifo       pixelFIFO // mode-3 state of the line being drawn
+	windowYHit bool      // LY has matched WY this frame, so the window may be drawn
 
-	tileScanline    [internal.GB_SCREEN_WIDTH]uint8
 	scanlineCounter OpCycles
 	screenCleared   bool
 
 	// PreparedData is a matrix of screen pixel data for a single frame which has been fully rendered
 	PreparedData         ScreenData
 	Mb                   *Motherboard
-	CurrentPixelPosition uint8 // current pixel position in the scanline
-	CurrentScanline      uint8 // current scanline being rendered
-	WindowLY             uint8 // current window scanline being rendered
-	lastEnabled          bool  // PPU enable state from the previous tick
+	CurrentPixelPosition uint8  // current pixel position in the scanline
+	CurrentScanline      uint8  // current scanline being rendered
+	WindowLY             uint8  // current window scanline being rendered
+	lastEnabled          bool   // PPU enable state from the previous tick
+	FrameCount           uint64 // frames completed (entries into vblank) since power-on
+
+	// STAT interrupt line. Every enabled source is ORed onto one line and
+	// only its rising edge requests an interrupt, so a source that comes
+	// on while another holds the line high is lost ("STAT blocking").
+	lcdOnLine bool  // first line after the LCD was switched on; it has no mode 2
+	intrMode  uint8 // mode whose STAT source drives the line; can lead the mode STAT shows
+	lyCompare int16 // line LYC is being compared against, or -1 between lines
+	lycLine   bool  // LY=LYC source; holds its value while no comparison is made
+	statLine  bool  // level of the STAT interrupt line
 }
 
 func (l *LCD) Serialize() *bytes.Buffer {
@@ -45,11 +57,18 @@ func (l *LCD) Serialize() *bytes.Buffer {
 
 	binary.Write(buf, binary.LittleEndian, l.PreparedData)         // PreparedData
 	binary.Write(buf, binary.LittleEndian, l.scanlineCounter)      // scanlineCounter
-	binary.Write(buf, binary.LittleEndian, l.bgPriority)           // screenCleared
+	binary.Write(buf, binary.LittleEndian, l.fifo)                 // fifo
+	binary.Write(buf, binary.LittleEndian, l.windowYHit)           // windowYHit
 	binary.Write(buf, binary.LittleEndian, l.screenCleared)        // screenCleared
 	binary.Write(buf, binary.LittleEndian, l.WindowLY)             // WindowLY
 	binary.Write(buf, binary.LittleEndian, l.CurrentScanline)      // CurrentScanline
 	binary.Write(buf, binary.LittleEndian, l.CurrentPixelPosition) // CurrentPixelPosition
+	binary.Write(buf, binary.LittleEndian, l.lastEnabled)          // lastEnabled
+	binary.Write(buf, binary.LittleEndian, l.lcdOnLine)            // lcdOnLine
+	binary.Write(buf, binary.LittleEndian, l.intrMode)             // intrMode
+	binary.Write(buf, binary.LittleEndian, l.lyCompare)            // lyCompare
+	binary.Write(buf, binary.LittleEndian, l.lycLine)              // lycLine
+	binary.Write(buf, binary.LittleEndian, l.statLine)             // statLine
 
 	logger.Debug("Serialized LCD state")
 	return buf
@@ -63,7 +82,10 @@ func (l *LCD) Deserialize(data *bytes.Buffer) error {
 	if err := binary.Read(data, binary.LittleEndian, &l.scanlineCounter); err != nil {
 		return err
 	}
-	if err := binary.Read(data, binary.LittleEndian, &l.bgPriority); err != nil {
+	if err := binary.Read(data, binary.LittleEndian, &l.fifo); err != nil {
+		return err
+	}
+	if err := binary.Read(data, binary.LittleEndian, &l.windowYHit); err != nil {
 		return err
 	}
 	if err := binary.Read(data, binary.LittleEndian, &l.screenCleared); err != nil {
@@ -79,22 +101,49 @@ func (l *LCD) Deserialize(data *bytes.Buffer) error {
 	if err := binary.Read(data, binary.LittleEndian, &l.CurrentPixelPosition); err != nil {
 		return err
 	}
+	if err := binary.Read(data, binary.LittleEndian, &l.lastEnabled); err != nil {
+		return err
+	}
+	if err := binary.Read(data, binary.LittleEndian, &l.lcdOnLine); err != nil {
+		return err
+	}
+	if err := binary.Read(data, binary.LittleEndian, &l.intrMode); err != nil {
+		return err
+	}
+	if err := binary.Read(data, binary.LittleEndian, &l.lyCompare); err != nil {
+		return err
+	}
+	if err := binary.Read(data, binary.LittleEndian, &l.lycLine); err != nil {
+		return err
+	}
+	if err := binary.Read(data, binary.LittleEndian, &l.statLine); err != nil {
+		return err
+	}
 
 	return nil
 }
 
 func NewLCD(mb *Motherboard) *LCD {
 	return &LCD{
-		Mb: mb,
+		Mb:        mb,
+		lyCompare: -1,
 	}
 }
 
 func (l *LCD) Reset() {
 	l.screenData = ScreenData{}
-	l.bgPriority = ScreenPriority{}
+	l.fifo = pixelFIFO{}
+	l.windowYHit = false
 	l.clearScreen()
 	l.scanlineCounter = 0
 	l.screenCleared = false
+	l.CurrentScanline = 0
+	l.lastEnabled = false
+	l.lcdOnLine = false
+	l.intrMode = STAT_MODE_HBLANK
+	l.lyCompare = -1
+	l.lycLine = false
+	l.statLine = false
 }
 
 func (l *LCD) Tick(cycles OpCycles) {
@@ -129,37 +178,75 @@ func (l *LCD) updateGraphics(cycles OpCycles) {
 	if !enabled {
 		if l.lastEnabled {
 			// Detected off transition this tick. Reset PPU state once so the
-			// next enable starts cleanly at LY=0 sc=456. Pan Docs: LCD
-			// enable restarts the PPU "from the very beginning of OAM
-			// search". Without this, gobc carries over whatever LY/sc
-			// disable_lcd left behind (LY=144 mid-vblank) and Blargg's
-			// oam_bug timing tests calibrate against the wrong moment.
+			// next enable starts cleanly at LY=0. Pan Docs: LCD enable
+			// restarts the PPU "from the very beginning of OAM search".
+			// Without this, gobc carries over whatever LY disable_lcd left
+			// behind (LY=144 mid-vblank) and Blargg's oam_bug timing tests
+			// calibrate against the wrong moment.
 			l.setLCDStatus()
 			l.lastEnabled = false
 		}
 		return
 	}
-	l.lastEnabled = true
+	if !l.lastEnabled {
+		// The first line after switching on skips LY's early step and has
+		// no mode 2: the PPU starts at dot 4 in mode 0, with OAM left
+		// open, and goes straight into mode 3 at the usual dot.
+		l.lastEnabled = true
+		l.lcdOnLine = true
+		l.CurrentScanline = 0
+		l.scanlineCounter = lineDots - lineStartDots
+	}
 	l.scanlineCounter -= cycles
+	if l.fifo.Active {
+		l.renderDots()
+	}
 
-	l.setLCDStatus()
 	if l.scanlineCounter <= 0 {
-		l.Mb.Memory.IO[IO_LY-IO_START_ADDR]++ // directly change for optimized performance
-		if l.Mb.Memory.GetIO(IO_LY) > 153 {
-			// l.PreparedData = ScreenData{}
-			// l.screenData = ScreenData{}
-			// l.clearScreen()
-			l.bgPriority = ScreenPriority{}
-			l.Mb.Memory.SetIO(IO_LY, 0)
+		l.scanlineCounter += lineDots * 1 // change 1 to 2 for double speed
+		l.lcdOnLine = false
+		l.CurrentScanline++
+		if l.CurrentScanline > 153 {
+			l.CurrentScanline = 0
 		}
+		l.fifo.Started, l.fifo.Active = false, false
+	}
+	l.setLCDStatus()
+}
 
-		l.scanlineCounter += (456 * 1) // change 1 to 2 for double speed
-
-		if l.Mb.Memory.GetIO(IO_LY) == internal.GB_SCREEN_HEIGHT {
-			l.Mb.Cpu.SetInterruptFlag(INTR_VBLANK)
-			l.PreparedData = l.screenData
-		}
+// nextEvent returns the cycles left until setLCDStatus would next change
+// anything: LY's early step at the start of a line, a mode boundary, a
+// step of line 153's LY quirk, or the end of the line. How long mode 3
+// lasts is only known once the pixel FIFO has drawn the line, so while it
+// runs the PPU is stepped at the earliest dot it could end.
+func (l *LCD) nextEvent() (OpCycles, bool) {
+	if !l.isLCDEnabled() {
+		return 1, l.lastEnabled
 	}
+	if !l.lastEnabled {
+		return 1, true
+	}
+
+	sc := l.scanlineCounter
+	switch {
+	case sc > lineDots-lineStartDots:
+		return sc - (lineDots - lineStartDots), true
+	case l.CurrentScanline == 153 && sc > lineDots-8:
+		return sc - (lineDots - 8), true
+	case l.CurrentScanline == 153 && sc > lineDots-12:
+		return sc - (lineDots - 12), true
+	case l.CurrentScanline >= internal.GB_SCREEN_HEIGHT:
+	case sc >= lcdMode2Bounds:
+		return sc - lcdMode2Bounds + 1, true
+	case l.fifo.Active:
+		return l.fifo.remainingDots() + 1, true
+	case l.drawing():
+		return 1, true
+	case l.intrMode == STAT_MODE_TRANS:
+		// mode 0 is showing, but its interrupt is still to come
+		return OpCycles(l.fifo.Dots+hblankIntrDelay) - (lcdMode2Bounds - sc) + 1, true
+	}
+	return sc, true
 }
 
 func (l *LCD) setLCDStatus() {
@@ -168,11 +255,14 @@ func (l *LCD) setLCDStatus() {
 	if !l.isLCDEnabled() {
 		// clear the screen
 		l.clearScreen()
-		l.scanlineCounter = 456 // total cycles per scanline
+		l.scanlineCounter = lineDots // total cycles per scanline
+		l.fifo = pixelFIFO{}
+		l.CurrentScanline = 0
 
 		l.Mb.Memory.SetIO(IO_LY, 0)
 
-		// reset status
+		// reset status; the LYC flag and the interrupt line keep their
+		// values until the LCD is switched back on
 		status &= 252
 		internal.ResetBit(&status, 0)
 		internal.ResetBit(&status, 1)
@@ -184,65 +274,162 @@ func (l *LCD) setLCDStatus() {
 
 	l.screenCleared = false
 
-	l.CurrentScanline = l.Mb.Memory.GetIO(IO_LY)
 	currentMode := status & 0b11
+	line := l.CurrentScanline
+	ly := line
+	l.lyCompare = int16(line)
 
-	var mode uint8
-	rqstInterrupt := false
+	if line < internal.GB_SCREEN_HEIGHT && l.scanlineCounter < lcdMode2Bounds && !l.fifo.Started {
+		l.startLine()
+		l.renderDots()
+	}
 
+	var mode uint8
 	switch {
 
-	case l.CurrentScanline >= 144:
-		mode = STAT_MODE_VBLANK
-		internal.SetBit(&status, STAT_MODE0)
-		internal.ResetBit(&status, STAT_MODE1)
-		rqstInterrupt = internal.IsBitSet(status, STAT_VBLINT)
+	case l.scanlineCounter > lineDots-lineStartDots:
+		// LY has moved on but the line has not started yet
+		mode, l.intrMode = STAT_MODE_HBLANK, STAT_MODE_HBLANK
+		switch {
+		case line == 0:
+			// LY=0 has been compared since line 153, and vblank holds on
+			if l.Mb.Cgb {
+				mode = STAT_MODE_VBLANK
+			}
+			l.intrMode = STAT_MODE_VBLANK
+		case line == internal.GB_SCREEN_HEIGHT:
+			// the mode 2 interrupt still fires on the line vblank starts
+			l.intrMode = STAT_MODE_OAM
+			l.lyCompare = -1
+		case line > internal.GB_SCREEN_HEIGHT:
+			mode, l.intrMode = STAT_MODE_VBLANK, STAT_MODE_VBLANK
+			l.lyCompare = -1
+		default:
+			l.lyCompare = -1
+		}
+
+	case line >= internal.GB_SCREEN_HEIGHT:
+		mode, l.intrMode = STAT_MODE_VBLANK, STAT_MODE_VBLANK
+		if mode != currentMode {
+			l.Mb.Cpu.SetInterruptFlag(INTR_VBLANK)
+			if l.Mb.Sgb {
+				l.Mb.Super.endFrame(&l.PreparedData)
+			} else {
+				l.PreparedData = l.screenData
+			}
+			l.FrameCount++
+		}
 		l.WindowLY = 0
+		l.windowYHit = false
+
+		if line == 153 {
+			// LY reads 0 for all but the start of line 153; LYC sees 153
+			// for a moment, then nothing, then 0
+			ly = 0
+			switch {
+			case l.scanlineCounter > lineDots-8:
+			case l.scanlineCounter > lineDots-12:
+				l.lyCompare = -1
+			default:
+				l.lyCompare = 0
+			}
+		}
 
 	case l.scanlineCounter >= lcdMode2Bounds:
-		mode = STAT_MODE_OAM
-		internal.ResetBit(&status, STAT_MODE0)
-		internal.SetBit(&status, STAT_MODE1)
-		rqstInterrupt = internal.IsBitSet(status, STAT_OAMINT)
-
-	case l.scanlineCounter >= lcdMode3Bounds:
-		mode = STAT_MODE_TRANS
-		internal.SetBit(&status, STAT_MODE0)
-		internal.SetBit(&status, STAT_MODE1)
-		if mode != currentMode {
-			// draw scanline when we start mode 3. In the real gameboy
-			// this would be done through mode 3 by readong OAM and VRAM
-			// to generate the picture
-			l.drawScanline()
+		mode, l.intrMode = STAT_MODE_OAM, STAT_MODE_OAM
+		if l.lcdOnLine {
+			mode, l.intrMode = STAT_MODE_HBLANK, STAT_MODE_HBLANK
 		}
+
+	case l.drawing():
+		mode, l.intrMode = STAT_MODE_TRANS, STAT_MODE_TRANS
+
 	default:
-		mode = STAT_MODE_HBLANK
-		internal.ResetBit(&status, STAT_MODE1)
-		internal.ResetBit(&status, STAT_MODE0)
-		rqstInterrupt = internal.IsBitSet(status, STAT_HBLINT)
-		if mode != currentMode {
+		mode, l.intrMode = STAT_MODE_HBLANK, STAT_MODE_HBLANK
+		if l.fifo.Dots+hblankIntrDelay >= uint16(lcdMode2Bounds-l.scanlineCounter) {
+			l.intrMode = STAT_MODE_TRANS
+		}
+		if currentMode == STAT_MODE_TRANS {
 			l.Mb.DoHDMATransfer() // do HDMATransfer when we start mode 0
 		}
 	}
 
-	if rqstInterrupt && mode != currentMode && prevLY != l.CurrentScanline {
+	l.Mb.Memory.SetIO(IO_LY, ly)
+	l.Mb.Memory.SetIO(IO_STAT, status&^0b11|mode)
+	l.updateSTAT()
+}
+
+// updateSTAT refreshes the LYC flag and the STAT interrupt line, and
+// requests the interrupt on the line's rising edge. It runs after every
+// PPU step and every write to STAT or LYC. With the LCD off, the flag and
+// the line are left as they were.
+func (l *LCD) updateSTAT() {
+	if !l.isLCDEnabled() {
+		return
+	}
+	status := l.Mb.Memory.GetIO(IO_STAT)
+	internal.ResetBit(&status, STAT_LYC)
+	if l.lyCompare >= 0 {
+		l.lycLine = uint8(l.lyCompare) == l.Mb.Memory.GetIO(IO_LYC)
+		if l.lycLine {
+			internal.SetBit(&status, STAT_LYC)
+		}
+	}
+	l.Mb.Memory.SetIO(IO_STAT, status)
+
+	line := l.lycLine && internal.IsBitSet(status, STAT_LYCINT)
+	switch l.intrMode {
+	case STAT_MODE_HBLANK:
+		line = line || internal.IsBitSet(status, STAT_HBLINT)
+	case STAT_MODE_VBLANK:
+		line = line || internal.IsBitSet(status, STAT_VBLINT)
+	case STAT_MODE_OAM:
+		line = line || internal.IsBitSet(status, STAT_OAMINT)
+	}
+	if line && !l.statLine {
 		l.Mb.Cpu.SetInterruptFlag(INTR_LCDSTAT)
 	}
+	l.statLine = line
+}
 
-	// // check if LYC == LY (coincedence flag)
-	if l.CurrentScanline == l.Mb.Memory.GetIO(IO_LYC) {
-		internal.SetBit(&status, STAT_LYC)
-		if internal.IsBitSet(status, STAT_LYCINT) && prevLY != l.CurrentScanline {
-			l.Mb.Cpu.SetInterruptFlag(INTR_LCDSTAT)
-		}
-	} else {
-		internal.ResetBit(&status, STAT_LYC)
+// writeSTAT stores a CPU write to STAT. The DMG briefly sees every
+// interrupt source enabled while the write lands, so writing STAT in
+// any mode but 3, or while LY=LYC, can raise a spurious interrupt.
+func (l *LCD) writeSTAT(v uint8) {
+	status := l.Mb.Memory.GetIO(IO_STAT) & 0x07
+	if !l.Mb.Cgb {
+		l.Mb.Memory.SetIO(IO_STAT, 0xF8|status)
+		l.updateSTAT()
+	}
+	l.Mb.Memory.SetIO(IO_STAT, 0x80|v&0x78|status)
+	l.updateSTAT()
+}
+
+// oamLocked reports whether the PPU is using OAM, so that the CPU reads
+// $FF from it and its writes are dropped: from the start of the line's
+// mode 2 until mode 3 ends. The CPU is locked out before STAT shows mode
+// 2, as soon as LY moves on.
+func (l *LCD) oamLocked() bool {
+	if !l.isLCDEnabled() || l.CurrentScanline >= internal.GB_SCREEN_HEIGHT {
+		return false
 	}
-	if prevLY != l.CurrentScanline {
-		prevLY = l.CurrentScanline
+	if l.scanlineCounter >= lcdMode2Bounds {
+		return !l.lcdOnLine
 	}
-	// write status to memory
-	l.Mb.Memory.SetIO(IO_STAT, status)
+	return l.drawing()
+}
+
+// vramLocked reports whether the PPU is using VRAM: during mode 3, and on
+// all but the first line after the LCD is switched on, the M-cycle before
+// it as well.
+func (l *LCD) vramLocked() bool {
+	if !l.isLCDEnabled() || l.CurrentScanline >= internal.GB_SCREEN_HEIGHT {
+		return false
+	}
+	if l.scanlineCounter >= lcdMode2Bounds {
+		return !l.lcdOnLine && l.scanlineCounter < lcdMode2Bounds+4
+	}
+	return l.drawing()
 }
 
 func (l *LCD) isLCDEnabled() bool {
@@ -264,13 +451,9 @@ func (l *LCD) isLCDEnabled() bool {
 //     sequence is 8, 8, 16, 16, ... 152, 152, 160, 160.
 //   - Mode 3 starts at elapsed=80 and the latch becomes 0xFF.
 //
-// The +cycleOffset parameter advances the virtual scanline counter to
-// model where the address bus operation actually lands inside a multi-
-// cycle opcode. Game Boy SM83 puts the inc/dec unit operand on the bus
-// during the second M-cycle of the instruction, so the natural offset
-// is +4 T-cycles past the start of the opcode handler. The caller picks
-// the offset; see OAMBugTrigger.
-func (l *LCD) OAMBugRowAt(cycleOffset OpCycles) uint8 {
+// The LCD must already be caught up to the M-cycle in which the address
+// is on the bus; see OAMBugTrigger.
+func (l *LCD) OAMBugRow() uint8 {
 	if l.Mb.Cgb {
 		return 0xFF
 	}
@@ -278,8 +461,8 @@ func (l *LCD) OAMBugRowAt(cycleOffset OpCycles) uint8 {
 		return 0xFF
 	}
 
-	virtSc := l.scanlineCounter - cycleOffset
-	virtLY := int(l.Mb.Memory.GetIO(IO_LY))
+	virtSc := l.scanlineCounter
+	virtLY := int(l.CurrentScanline)
 	for virtSc <= 0 {
 		virtSc += 456
 		virtLY++
@@ -291,7 +474,7 @@ func (l *LCD) OAMBugRowAt(cycleOffset OpCycles) uint8 {
 		return 0xFF
 	}
 
-	elapsed := int(456) - int(virtSc)
+	elapsed := lineDots - lineStartDots - int(virtSc)
 	if elapsed < 2 || elapsed >= 80 {
 		return 0xFF
 	}
@@ -301,65 +484,6 @@ func (l *LCD) OAMBugRowAt(cycleOffset OpCycles) uint8 {
 	return row
 }
 
-func (l *LCD) OAMBugRow() uint8 { return l.OAMBugRowAt(0) }
-
-func (l *LCD) drawScanline() {
-	control := l.Mb.Memory.GetIO(IO_LCDC)
-
-	// LCDC bit 0 clears tiles on DMG but controls priority on CBG
-	if l.Mb.Cgb || internal.IsBitSet(control, LCDC_BGEN) {
-		l.renderTiles(control)
-	}
-
-	if internal.IsBitSet(control, LCDC_OBJEN) {
-		l.renderSprites(control)
-	}
-}
-
-type tileSettings struct {
-	UsingWindow bool   // true if window is enabled
-	Unsigned    bool   // true if using unsigned tile numbers
-	TileData    uint16 // address of tile data
-	BgMemory    uint16 // address of background tile map
-	WinMemory   uint16 // address of window tile map
-}
-
-func (l *LCD) getTileSettings(lcdControl uint8, windowY uint8) tileSettings {
-	var usingWindow bool = false
-	var tileData uint16 = uint16(0x8800)
-	var bgMemory uint16 = uint16(0x9800)
-	var winMemory uint16 = uint16(0x9800)
-	var unsigned bool = false
-
-	if internal.IsBitSet(lcdControl, LCDC_WINEN) {
-		// is current scanline we are drawing within the window?
-		if windowY <= l.Mb.Memory.GetIO(IO_LY) {
-			usingWindow = true
-		}
-	}
-	// test if we are using unsigned bytes
-	if internal.IsBitSet(lcdControl, LCDC_BGMAP) {
-		tileData = 0x8000
-		unsigned = true
-	}
-
-	if internal.IsBitSet(lcdControl, LCDC_BGWIN) {
-		bgMemory = 0x9C00
-	}
-
-	if internal.IsBitSet(lcdControl, LCDC_WINMAP) {
-		winMemory = 0x9C00
-	}
-
-	return tileSettings{
-		TileData:    tileData,
-		BgMemory:    bgMemory,
-		WinMemory:   winMemory,
-		UsingWindow: usingWindow,
-		Unsigned:    unsigned,
-	}
-}
-
 func (l *LCD) FindTileLocation(tileAddress uint16, tileData uint16, unsigned bool) (uint16, int16) {
 	var tileNum int16
 	var tileLocation uint16 = tileData
@@ -373,268 +497,15 @@ func (l *LCD) FindTileLocation(tileAddress uint16, tileData uint16, unsigned boo
 	return tileLocation, tileNum
 }
 
-func (l *LCD) renderTiles(lcdControl uint8) {
-	scrollY := l.Mb.Memory.GetIO(IO_SCY)
-	scrollX := l.Mb.Memory.GetIO(IO_SCX)
-	windowY := l.Mb.Memory.GetIO(IO_WY)
-	windowX := l.Mb.Memory.GetIO(IO_WX) - 7
-
-	ts := l.getTileSettings(lcdControl, windowY)
-
-	if ts.UsingWindow && windowY < l.CurrentScanline && windowX <= internal.GB_SCREEN_WIDTH {
-		l.WindowLY++
-	}
-
-	var (
-		yPos, xPos       uint8
-		tileRow, tileCol uint16
-	)
-
-	yPos = scrollY + l.CurrentScanline
-	if ts.UsingWindow {
-		yPos = l.CurrentScanline - windowY
-	}
-
-	palette := l.Mb.Memory.GetIO(IO_BGP)
-	l.tileScanline = [internal.GB_SCREEN_WIDTH]uint8{}
-
-	for pixel := uint8(0); pixel < internal.GB_SCREEN_WIDTH; pixel++ {
-
-		xPos = pixel + scrollX
-		if ts.UsingWindow {
-			xPos = pixel - windowX
-		}
-
-		tileCol = (uint16(pixel) + uint16(scrollX)) / 8 % 32
-		tileRow = (uint16(l.CurrentScanline) + uint16(scrollY)) / 8 * 32 % 0x400
-		if ts.UsingWindow && pixel >= windowX && l.CurrentScanline >= windowY {
-			tileCol = (uint16(pixel) - uint16(windowX)) / 8 % 32
-			tileRow = (uint16(l.WindowLY)) / 8 * 32 % 0x400
-
-		}
-
-		var tileAddress uint16
-		if ts.UsingWindow && pixel >= windowX && l.CurrentScanline >= windowY {
-			tileAddress = ts.WinMemory + tileRow + tileCol
-		} else {
-			tileAddress = ts.BgMemory + tileRow + tileCol
-		}
-
-		// //deduce tile id in memory
-		tileLocation, _ := l.FindTileLocation(tileAddress, ts.TileData, ts.Unsigned)
-
-		// Attributes used in CGB mode TODO: check in CGB mode
-		//
-		//    Bit 0-2  Background Palette number  (BGP0-7)
-		//    Bit 3    Tile VRAM Bank number      (0=Bank 0, 1=Bank 1)
-		//    Bit 5    Horizontal Flip            (0=Normal, 1=Mirror horizontally)
-		//    Bit 6    Vertical Flip              (0=Normal, 1=Mirror vertically)
-		//    Bit 7    BG-to-OAM Priority         (0=Use OAM priority bit, 1=BG Priority)
-		//
-		tileAttr := l.Mb.Memory.Vram[1][tileAddress-0x8000]
-
-		bank := 0
-		if l.Mb.Cgb && internal.IsBitSet(tileAttr, 3) {
-			bank = 1
-		}
-
-		priority := internal.IsBitSet(tileAttr, 7)
-
-		var line uint16
-		if l.Mb.Cgb && internal.IsBitSet(tileAttr, 6) {
-			// vertical flip
-			line = uint16((7-yPos)%8) * 2
-		} else {
-			line = uint16(yPos%8) * 2
-		}
-
-		data1 := l.Mb.Memory.Vram[bank][tileLocation+uint16(line)-0x8000]
-		data2 := l.Mb.Memory.Vram[bank][tileLocation+uint16(line)+1-0x8000]
-
-		if l.Mb.Cgb && internal.IsBitSet(tileAttr, 5) {
-			// horizontal flip
-			xPos = (7 - (pixel+(scrollX&0b111))%8)
-		}
-
-		colorBit := uint8(int8((xPos%8)-7) * -1)
-		colorNum := (internal.BitValue(data2, colorBit) << 1) | internal.BitValue(data1, colorBit)
-
-		// if (l.CurrentScanline == 112) && (pixel == 88) {
-		// Map Address: 0x9945
-		// Tile Address: 0:8090
-		// tileNum: 0x09
-		// logger.Debugf("Map Address: %#x, Tile Address: %#x, tileNum: %#x, unsignedTile: %t, LCDC: %08b", tileAddress, tileLocation, tileNum, ts.Unsigned, lcdControl)
-
-		// logger.Debugf("Y: %d, X: %d, wx: %d, wy: %d, tileNum: %#x, tileLocation: %#x, tileData: %#x, tileAddress: %#x, xPos: %d, yPos: %d, bgMemory: %#x, winMemory: %#x, WindowLY: %d",
-		// 	scanline, pixel, windowX, windowY, tileNum, tileLocation, ts.TileData, tileAddress, xPos, yPos, ts.BgMemory, ts.WinMemory, l.WindowLY)
-		// logger.Debugf("Scanline: %d, Pixel: %d, xPos: %d, yPos: %d, tileNum: %#x, tileLocation: %#x, tileData: %#x,  tileAddress: %#x, tileAttr: %#x, data1: %#x, data2: %#x, LCDC: %08b, STAT: %08b, IE: %08b, IF: %08b: BGMem: %#x, Unsigned: %t\n", scanline, pixel, xPos, yPos, tileNum, tileLocation, ts.TileData, tileAddress, tileAttr, data1, data2, lcdControl, l.Mb.Memory.IO[IO_STAT-IO_START_ADDR], l.Mb.Cpu.Interrupts.IE, l.Mb.Cpu.Interrupts.IF, ts.BgMemory, ts.Unsigned)
-		// logger.Debugf("Cycles: %d)", l.scanlineCounter)
-		// }
-
-		// if data1 != 0x00 || data2 != 0x00 {
-		// 	fmt.Printf("-----------------\n"+
-		// 		"data1: %#x, data2: %#x, tileLocation: %#x, line: %#x, tileAddress: %#x, tileAttr: %#x\n"+
-		// 		"Unsigned: %t, tileNum: %#x, tileData: %#x, yPos: %#x, xPos: %#x, tileCol: %#x\n"+
-		// 		"tileRow: %#x, scrollX: %#x, scrollY: %#x, windowX: %#x, windowY: %#x, pixel: %#x, colorBit: %#x, colorNum: %#x\n",
-		// 		data1, data2, tileLocation, line, tileAddress, tileAttr, ts.Unsigned, tileNum, ts.TileData, yPos, xPos, tileCol,
-		// 		tileRow, scrollX, scrollY, windowX, windowY, pixel, colorBit, colorNum,
-		// 	)
-		// 	l.setTilePixel(pixel, scanline, tileAttr, colorNum, palette, priority)
-
-		// }
-
-		if l.Mb.Cgb && !internal.IsBitSet(lcdControl, LCDC_BGEN) {
-			priority = false
-		}
-		l.setTilePixel(pixel, l.CurrentScanline, tileAttr, colorNum, palette, priority)
-
-	}
-}
-
-func (l *LCD) setTilePixel(x, y, tileAttr, colorNum, palette uint8, priority bool) {
-	l.tileScanline[x] = colorNum
-
-	if l.Mb.Cgb {
-		cgbPalette := tileAttr & 0x7
-		r, g, b := l.Mb.BGPalette.get(cgbPalette, colorNum)
-		l.setPixel(x, y, r, g, b, true)
-		l.bgPriority[x][y] = priority
-	} else {
-		r, g, b := l.getColour(colorNum, palette)
-		l.setPixel(x, y, r, g, b, true)
-	}
-
-}
-
-func (l *LCD) setPixel(x, y, r, g, b uint8, priority bool) {
-	if (priority && !l.bgPriority[x][y]) || l.tileScanline[x] == 0 {
-		l.screenData[x][y][0] = r
-		l.screenData[x][y][1] = g
-		l.screenData[x][y][2] = b
-	}
-}
-
 // Get the RGB colour value for a colour num at an address using the current palette.
 func (l *LCD) getColour(colourNum byte, palette byte) (uint8, uint8, uint8) {
 	hi := colourNum<<1 | 1
 	lo := colourNum << 1
 	index := (internal.BitValue(palette, hi) << 1) | internal.BitValue(palette, lo)
-	r, g, b := GetPaletteColour(index)
+	r, g, b := l.Mb.GetPaletteColour(index)
 	return r, g, b
 }
 
-func (l *LCD) renderSprites(lcdControl uint8) {
-	var ySize int32 = 8
-	scanline := int32(l.CurrentScanline)
-	if internal.IsBitSet(lcdControl, LCDC_OBJSZ) {
-		ySize = 16
-
-	}
-
-	// Load the two palettes which sprites can be drawn in
-
-	var palette1 = l.Mb.Memory.GetIO(IO_OBP0)
-	var palette2 = l.Mb.Memory.GetIO(IO_OBP1)
-
-	var minx [internal.GB_SCREEN_WIDTH]int32
-	var lineSprites = 0
-	for sprite := uint16(0); sprite < 40; sprite++ {
-		// Load sprite data from memory.
-		index := sprite * 4
-
-		// If this is true the scanline is out of the area we care about
-		yPos := int32(l.Mb.Memory.Oam[index]) - 16
-		if scanline < yPos || scanline >= (yPos+ySize) {
-			continue
-		}
-
-		// Only 10 sprites are allowed to be displayed on each line
-		if lineSprites >= 10 {
-			break
-		}
-		lineSprites++
-
-		xPos := int32(l.Mb.Memory.Oam[index+1]) - 8
-		tileLocation := l.Mb.Memory.Oam[index+2]
-		if ySize == 16 {
-			tileLocation &= 0b11111110
-		}
-
-		attributes := l.Mb.Memory.Oam[index+3]
-
-		yFlip := internal.IsBitSet(attributes, 6)
-		xFlip := internal.IsBitSet(attributes, 5)
-		priority := !internal.IsBitSet(attributes, 7)
-
-		// Bank the sprite data in is (CGB only)
-		var bank uint16 = 0
-		if l.Mb.Cgb && internal.IsBitSet(attributes, 3) {
-			bank = 1
-		}
-
-		// Set the line to draw based on if the sprite is flipped on the y
-		line := scanline - yPos
-		if yFlip {
-			line = ySize - line - 1
-		}
-
-		// Load the data containing the sprite data for this line
-		dataAddress := (uint16(tileLocation) * 16) + (uint16(line * 2))
-
-		data1 := l.Mb.Memory.Vram[bank][dataAddress]
-		data2 := l.Mb.Memory.Vram[bank][dataAddress+1]
-
-		// Draw the line of the sprite
-		for tilePixel := byte(0); tilePixel < 8; tilePixel++ {
-			pixel := int16(xPos) + int16(7-tilePixel)
-			if pixel < 0 || pixel >= internal.GB_SCREEN_WIDTH {
-				continue
-			}
-
-			// Check if the pixel has priority.
-			//  - In DMG this is determined by the sprite with the smallest X coordinate,
-			//    then the first sprite in the OAM.
-			//  - In CGB this is determined by the first sprite appearing in the OAM.
-			// We add a fixed 100 to the xPos so we can use the 0 value as the absence of a sprite.
-			if minx[pixel] != 0 && (l.Mb.Cgb || minx[pixel] <= xPos+spritePriorityOffset) {
-				continue
-			}
-
-			colourBit := tilePixel
-			if xFlip {
-				colourBit = byte(int8(colourBit-7) * -1)
-			}
-
-			// Find the colour value by combining the data bits
-			// colourNum := (bits.Val(data2, colourBit) << 1) | bits.Val(data1, colourBit)
-			colourNum := (internal.BitValue(data2, colourBit) << 1) | internal.BitValue(data1, colourBit)
-
-			// Colour 0 is transparent for sprites
-			if colourNum == 0 {
-				continue
-			}
-
-			if l.Mb.Cgb {
-				cgbPalette := attributes & 0x7
-				red, green, blue := l.Mb.SpritePalette.get(cgbPalette, colourNum)
-				l.setPixel(byte(pixel), byte(scanline), red, green, blue, priority)
-			} else {
-				// Determine the colour palette to use
-				var palette = palette1
-				if internal.IsBitSet(attributes, 4) {
-					palette = palette2
-				}
-				red, green, blue := l.getColour(colourNum, palette)
-				l.setPixel(byte(pixel), byte(scanline), red, green, blue, priority)
-			}
-
-			// Store the xpos of the sprite for this pixel for priority resolution
-			minx[pixel] = xPos + spritePriorityOffset
-		}
-	}
-
-}
-
 func (l *LCD) clearScreen() {
 
 	for x := 0; x < len(l.PreparedData); x++ {
@@ -0,0 +1,431 @@
+/*
+* Pixel FIFO: draws a scanline one dot at a time during mode 3.
+*
+* A background fetcher reads a tile number and two bytes of tile data, two
+* dots each, then pushes the tile's 8 pixels into the BG FIFO as soon as it
+* has drained. Every dot the FIFO holds a pixel, one is shifted out to the
+* LCD, mixed with whatever the OBJ FIFO holds for that column. Mode 3 ends
+* once the 160th pixel has been shifted out, so its length falls out of the
+* pipeline rather than being fixed (Pan Docs "Rendering", "Pixel FIFO"):
+*
+*   - 12 dots before the first pixel: a discarded tile fetch, then the real
+*     one, for 172 dots in all when nothing else gets in the way.
+*   - SCX & 7 pixels are shifted out and dropped at the start of the line.
+*   - Reaching WX restarts the fetcher on the window tile map, 6 dots.
+*   - Each sprite stalls the shifter while its tile is fetched, 6 dots plus
+*     up to 5 more waiting for the BG fetch it interrupted.
+*
+* Registers are read when the hardware reads them: tile maps, SCX (coarse)
+* and SCY by the fetcher, BGP/OBP and LCDC bits 0/1 when a pixel is shifted
+* out. Writes to them, and to VRAM, OAM and palette RAM, sync the PPU first
+* (Motherboard.syncPPU), so a mid-line write takes effect from the pixel
+* being drawn at that moment.
+ */
+
+package motherboard
+
+import (
+	"math"
+	"sort"
+
+	"github.com/duysqubix/gobc/internal"
+)
+
+const (
+	fifoWarmupDots  = 6  // dots spent on the discarded first tile fetch
+	fetchPushStep   = 6  // fetcher step at which a fetched tile waits to be pushed
+	spriteFetchDots = 6  // flat cost of fetching a sprite's tile data
+	maxLineSprites  = 10 // sprites OAM scan selects per line
+	noPenaltyTile   = math.MinInt16
+)
+
+// fifoPixel is one pixel in the BG or OBJ FIFO.
+type fifoPixel struct {
+	Color uint8 // colour number 0-3; 0 is transparent in the OBJ FIFO
+	Attr  uint8 // CGB BG map attributes, or OAM attributes for sprites
+	Oam   uint8 // OAM index of the sprite the pixel came from
+}
+
+// lineSprite is a sprite picked by OAM scan for the current line.
+type lineSprite struct {
+	Y, X, Tile, Attr, Index uint8
+}
+
+// pixelFIFO is the mode-3 state of the current line. Every field is
+// exported and fixed-size so it can be saved with binary.Write.
+type pixelFIFO struct {
+	Started bool   // mode 3 has begun on this line
+	Active  bool   // mode 3 is in progress
+	Dots    uint16 // dots spent in mode 3
+	X       uint8  // next LCD column
+	Discard uint8  // pixels still to drop before the first visible one
+	Stall   uint8  // dots left in the current sprite fetch
+
+	Bg     [8]fifoPixel
+	BgPos  uint8
+	BgLen  uint8
+	Obj    [8]fifoPixel // indexed relative to X through ObjPos
+	ObjPos uint8
+
+	FetchStep uint8 // dots into the current tile fetch
+	FetchX    uint8 // tile column of the current fetch
+	TileNo    uint8
+	TileAttr  uint8
+	TileLine  uint8
+	TileLo    uint8
+	TileHi    uint8
+
+	Window      bool  // the fetcher has switched to the window
+	PenaltyTile int16 // BG/window tile that last made a sprite wait for its fetch
+
+	Sprites    [maxLineSprites]lineSprite // sorted by X, then OAM index
+	NumSprites uint8
+	NextSprite uint8 // first sprite not yet fetched
+}
+
+// startLine enters mode 3: OAM scan picks the line's sprites and the
+// fetcher starts on the first tile.
+func (l *LCD) startLine() {
+	f := &l.fifo
+	*f = pixelFIFO{
+		Started:     true,
+		Active:      true,
+		Discard:     l.Mb.Memory.GetIO(IO_SCX) & 7,
+		PenaltyTile: noPenaltyTile,
+	}
+	if l.Mb.Memory.GetIO(IO_WY) == l.CurrentScanline {
+		l.windowYHit = true
+	}
+	l.scanOAM()
+}
+
+// scanOAM selects the first 10 sprites in OAM that overlap the current
+// line.
+func (l *LCD) scanOAM() {
+	f := &l.fifo
+	height := 8
+	if internal.IsBitSet(l.Mb.Memory.GetIO(IO_LCDC), LCDC_OBJSZ) {
+		height = 16
+	}
+	line := int(l.CurrentScanline) + 16
+	oam := &l.Mb.Memory.Oam
+	for i := 0; i < 40 && f.NumSprites < maxLineSprites; i++ {
+		y := int(oam[i*4])
+		if line < y || line >= y+height {
+			continue
+		}
+		f.Sprites[f.NumSprites] = lineSprite{
+			Y:     oam[i*4],
+			X:     oam[i*4+1],
+			Tile:  oam[i*4+2],
+			Attr:  oam[i*4+3],
+			Index: uint8(i),
+		}
+		f.NumSprites++
+	}
+	sprites := f.Sprites[:f.NumSprites]
+	sort.SliceStable(sprites, func(i, j int) bool { return sprites[i].X < sprites[j].X })
+}
+
+// renderDots runs mode 3 up to the dot the LCD has reached.
+func (l *LCD) renderDots() {
+	f := &l.fifo
+	target := uint16(lcdMode2Bounds - l.scanlineCounter)
+	for f.Active && f.Dots < target {
+		l.fifoDot()
+	}
+	l.CurrentPixelPosition = f.X
+}
+
+// drawing reports whether the line is still in mode 3. The dot that shifts
+// out the last pixel is part of mode 3; mode 0 starts on the next one.
+func (l *LCD) drawing() bool {
+	return l.fifo.Active || l.fifo.Dots >= uint16(lcdMode2Bounds-l.scanlineCounter)
+}
+
+// fifoDot advances mode 3 by one dot.
+func (l *LCD) fifoDot() {
+	f := &l.fifo
+	f.Dots++
+	if f.Dots <= fifoWarmupDots {
+		return
+	}
+
+	if f.Stall == 0 {
+		l.fetchStep()
+		if f.BgLen == 0 {
+			return
+		}
+		if !f.Window && l.windowYHit && l.windowStarts() {
+			f.Window = true
+			f.FetchStep, f.FetchX = 0, 0
+			f.BgLen, f.BgPos = 0, 0
+			if wx := l.Mb.Memory.GetIO(IO_WX); wx < 7 {
+				f.Discard = 7 - wx
+			} else {
+				f.Discard = 0
+			}
+			l.fetchStep()
+			return
+		}
+		if f.Discard == 0 && f.NextSprite < f.NumSprites {
+			l.nextSpriteFetch()
+		}
+	}
+
+	if f.Stall > 0 {
+		f.Stall--
+		if f.Stall == 0 {
+			l.fetchSprite()
+		}
+		return
+	}
+	l.shiftPixel()
+}
+
+// windowStarts reports whether the pixel about to be shifted out is the
+// first one covered by the window, once WY has matched LY this frame.
+func (l *LCD) windowStarts() bool {
+	f := &l.fifo
+	if !internal.IsBitSet(l.Mb.Memory.GetIO(IO_LCDC), LCDC_WINEN) {
+		return false
+	}
+	wx := l.Mb.Memory.GetIO(IO_WX)
+	if wx > 166 {
+		return false
+	}
+	return int(f.X)+7 == int(wx) || (f.X == 0 && wx < 7)
+}
+
+// fetchStep advances the background fetcher by one dot.
+func (l *LCD) fetchStep() {
+	f := &l.fifo
+	switch f.FetchStep {
+	case 1:
+		l.fetchTileNo()
+	case 3:
+		f.TileLo = l.tileData(0)
+	case 5:
+		f.TileHi = l.tileData(1)
+	case fetchPushStep:
+		if f.BgLen == 0 {
+			l.pushTile()
+			f.FetchStep = 0
+		}
+		return
+	}
+	f.FetchStep++
+}
+
+func (l *LCD) fetchTileNo() {
+	f := &l.fifo
+	lcdc := l.Mb.Memory.GetIO(IO_LCDC)
+
+	var mapAddr uint16 = 0x9800
+	var col, row uint8
+	if f.Window {
+		if internal.IsBitSet(lcdc, LCDC_WINMAP) {
+			mapAddr = 0x9C00
+		}
+		col = f.FetchX
+		row = l.WindowLY
+	} else {
+		if internal.IsBitSet(lcdc, LCDC_BGWIN) {
+			mapAddr = 0x9C00
+		}
+		col = l.Mb.Memory.GetIO(IO_SCX)>>3 + f.FetchX
+		row = l.CurrentScanline + l.Mb.Memory.GetIO(IO_SCY)
+	}
+	mapAddr += uint16(row/8)*32 + uint16(col&31) - 0x8000
+
+	f.TileNo = l.Mb.Memory.Vram[0][mapAddr]
+	f.TileAttr = 0
+	if l.Mb.Cgb {
+		f.TileAttr = l.Mb.Memory.Vram[1][mapAddr]
+	}
+	f.TileLine = row & 7
+	if internal.IsBitSet(f.TileAttr, 6) {
+		f.TileLine = 7 - f.TileLine
+	}
+}
+
+// tileData reads the low (plane 0) or high (plane 1) byte of the fetched
+// tile's current line.
+func (l *LCD) tileData(plane uint16) uint8 {
+	f := &l.fifo
+	addr := uint16(0x1000 + int(int8(f.TileNo))*16)
+	if internal.IsBitSet(l.Mb.Memory.GetIO(IO_LCDC), LCDC_BGMAP) {
+		addr = uint16(f.TileNo) * 16
+	}
+	bank := 0
+	if internal.IsBitSet(f.TileAttr, 3) {
+		bank = 1
+	}
+	return l.Mb.Memory.Vram[bank][addr+uint16(f.TileLine)*2+plane]
+}
+
+func (l *LCD) pushTile() {
+	f := &l.fifo
+	xFlip := internal.IsBitSet(f.TileAttr, 5)
+	for i := uint8(0); i < 8; i++ {
+		bit := 7 - i
+		if xFlip {
+			bit = i
+		}
+		f.Bg[i] = fifoPixel{
+			Color: (f.TileHi>>bit&1)<<1 | f.TileLo>>bit&1,
+			Attr:  f.TileAttr,
+		}
+	}
+	f.BgPos, f.BgLen = 0, 8
+	f.FetchX++
+}
+
+// nextSpriteFetch stalls the shifter if a sprite starts at the current
+// column, charging the dots the fetch takes.
+func (l *LCD) nextSpriteFetch() {
+	f := &l.fifo
+	if int(f.Sprites[f.NextSprite].X) > int(f.X)+8 {
+		return
+	}
+	if !internal.IsBitSet(l.Mb.Memory.GetIO(IO_LCDC), LCDC_OBJEN) {
+		f.NextSprite++
+		return
+	}
+
+	// The fetch first waits for the BG fetch under the sprite's leftmost
+	// pixel to finish, unless an earlier sprite already waited for it.
+	left := int(f.Sprites[f.NextSprite].X) - 8
+	var tile, offset int
+	if f.Window {
+		p := left - (int(l.Mb.Memory.GetIO(IO_WX)) - 7)
+		tile, offset = 1000+p>>3, p&7
+	} else {
+		p := left + int(l.Mb.Memory.GetIO(IO_SCX))
+		tile, offset = p>>3, p&7
+	}
+	f.Stall = spriteFetchDots
+	if int16(tile) != f.PenaltyTile {
+		f.PenaltyTile = int16(tile)
+		f.Stall += uint8(max(0, 5-offset))
+	}
+}
+
+// fetchSprite reads the pending sprite's tile data and merges it into the
+// OBJ FIFO.
+func (l *LCD) fetchSprite() {
+	f := &l.fifo
+	s := f.Sprites[f.NextSprite]
+	f.NextSprite++
+
+	height := uint8(8)
+	tile := s.Tile
+	if internal.IsBitSet(l.Mb.Memory.GetIO(IO_LCDC), LCDC_OBJSZ) {
+		height = 16
+		tile &^= 1
+	}
+	line := (l.CurrentScanline + 16 - s.Y) & (height - 1)
+	if internal.IsBitSet(s.Attr, 6) {
+		line = height - 1 - line
+	}
+	bank := 0
+	if l.Mb.Cgb && internal.IsBitSet(s.Attr, 3) {
+		bank = 1
+	}
+	addr := uint16(tile)*16 + uint16(line)*2
+	lo := l.Mb.Memory.Vram[bank][addr]
+	hi := l.Mb.Memory.Vram[bank][addr+1]
+
+	xFlip := internal.IsBitSet(s.Attr, 5)
+	for i := 0; i < 8; i++ {
+		col := int(s.X) - 8 + i
+		if col < int(f.X) {
+			continue
+		}
+		bit := uint8(7 - i)
+		if xFlip {
+			bit = uint8(i)
+		}
+		color := (hi>>bit&1)<<1 | lo>>bit&1
+		if color == 0 {
+			continue
+		}
+		// DMG: the first sprite fetched (smallest X) wins; CGB: the one
+		// earliest in OAM.
+		slot := &f.Obj[(int(f.ObjPos)+col-int(f.X))&7]
+		if slot.Color == 0 || (l.Mb.Cgb && s.Index < slot.Oam) {
+			*slot = fifoPixel{Color: color, Attr: s.Attr, Oam: s.Index}
+		}
+	}
+}
+
+// shiftPixel shifts one pixel out of the FIFOs and onto the LCD.
+func (l *LCD) shiftPixel() {
+	f := &l.fifo
+	bg := f.Bg[f.BgPos]
+	f.BgPos++
+	f.BgLen--
+	if f.Discard > 0 {
+		f.Discard--
+		return
+	}
+	obj := f.Obj[f.ObjPos]
+	f.Obj[f.ObjPos] = fifoPixel{}
+	f.ObjPos = (f.ObjPos + 1) & 7
+
+	l.drawPixel(f.X, bg, obj)
+	f.X++
+	if f.X == internal.GB_SCREEN_WIDTH {
+		f.Active = false
+		if f.Window {
+			l.WindowLY++
+		}
+	}
+}
+
+// drawPixel resolves BG/OBJ priority for one pixel and writes its colour
+// to the frame being drawn.
+func (l *LCD) drawPixel(x uint8, bg, obj fifoPixel) {
+	lcdc := l.Mb.Memory.GetIO(IO_LCDC)
+	bgOn := internal.IsBitSet(lcdc, LCDC_BGEN)
+	if !internal.IsBitSet(lcdc, LCDC_OBJEN) {
+		obj.Color = 0
+	}
+
+	var r, g, b uint8
+	if l.Mb.Cgb {
+		// LCDC bit 0 is the CGB master priority: clear, sprites always win.
+		useObj := obj.Color != 0 &&
+			(!bgOn || bg.Color == 0 || !internal.IsBitSet(bg.Attr, 7) && !internal.IsBitSet(obj.Attr, 7))
+		if useObj {
+			r, g, b = l.Mb.SpritePalette.get(obj.Attr&7, obj.Color)
+		} else {
+			r, g, b = l.Mb.BGPalette.get(bg.Attr&7, bg.Color)
+		}
+	} else {
+		palette, color := l.Mb.Memory.GetIO(IO_BGP), bg.Color
+		if !bgOn {
+			palette, color = 0, 0 // BG and window blank to white
+		}
+		if obj.Color != 0 && (!internal.IsBitSet(obj.Attr, 7) || color == 0) {
+			palette, color = l.Mb.Memory.GetIO(IO_OBP0), obj.Color
+			if internal.IsBitSet(obj.Attr, 4) {
+				palette = l.Mb.Memory.GetIO(IO_OBP1)
+			}
+		}
+		shade := palette >> (color * 2) & 3
+		if l.Mb.Sgb {
+			l.Mb.Super.setShade(x, l.CurrentScanline, shade)
+		}
+		r, g, b = l.Mb.GetPaletteColour(shade)
+	}
+
+	px := &l.screenData[x][l.CurrentScanline]
+	px[0], px[1], px[2] = r, g, b
+}
+
+// remainingDots is a lower bound on the dots left in mode 3: every pixel
+// still to be shifted out takes at least one.
+func (f *pixelFIFO) remainingDots() OpCycles {
+	return OpCycles(internal.GB_SCREEN_WIDTH-int(f.X)) + OpCycles(f.Discard)
+}
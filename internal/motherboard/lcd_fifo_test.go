@@ -0,0 +1,137 @@
+package motherboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFifoTestMb builds a DMG machine with the LCD on (BG, sprites and
+// window tile data at $8000) and returns it.
+func newFifoTestMb(t *testing.T) *Motherboard {
+	t.Helper()
+	mb := newSchedulerTestMb(t)
+	mb.Memory.SetIO(IO_LCDC, 0x93) // LCD, BG, OBJ on; tile data at $8000
+	mb.Memory.SetIO(IO_BGP, 0xE4)
+	mb.Memory.SetIO(IO_WX, 0xFF)
+	return mb
+}
+
+func lcdMode(mb *Motherboard) uint8 {
+	return mb.Memory.GetIO(IO_STAT) & 0b11
+}
+
+// mode3Length steps the LCD a dot at a time and returns how many dots
+// line 1 spends in mode 3.
+func mode3Length(t *testing.T, mb *Motherboard) int {
+	t.Helper()
+	for i := 0; mb.Memory.GetIO(IO_LY) != 1 || lcdMode(mb) != STAT_MODE_TRANS; i++ {
+		require.Less(t, i, 2*456, "line 1 never reached mode 3")
+		mb.Lcd.Tick(1)
+	}
+	dots := 0
+	for lcdMode(mb) == STAT_MODE_TRANS {
+		dots++
+		mb.Lcd.Tick(1)
+	}
+	return dots
+}
+
+func TestLCD_Mode3Length(t *testing.T) {
+	sprite := func(i int, x uint8) func(*Motherboard) {
+		return func(mb *Motherboard) {
+			copy(mb.Memory.Oam[i*4:], []byte{17, x, 0, 0}) // covers lines 1-8
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		setup []func(*Motherboard)
+		dots  int
+	}{
+		{"plain", nil, 172},
+		{"scx fine scroll", []func(*Motherboard){func(mb *Motherboard) { mb.Memory.SetIO(IO_SCX, 0x13) }}, 172 + 3},
+		{"sprite at x=0", []func(*Motherboard){sprite(0, 0)}, 172 + 11},
+		{"tile-aligned sprite", []func(*Motherboard){sprite(0, 24)}, 172 + 11},
+		{"unaligned sprite", []func(*Motherboard){sprite(0, 29)}, 172 + 6},
+		{"two sprites in one tile", []func(*Motherboard){sprite(0, 24), sprite(1, 25)}, 172 + 11 + 6},
+		{"sprites disabled", []func(*Motherboard){sprite(0, 24), func(mb *Motherboard) { mb.Memory.SetIO(IO_LCDC, 0x91) }}, 172},
+		{"offscreen sprite", []func(*Motherboard){sprite(0, 168)}, 172},
+		{"window", []func(*Motherboard){func(mb *Motherboard) {
+			mb.Memory.SetIO(IO_LCDC, 0xB3)
+			mb.Memory.SetIO(IO_WY, 0)
+			mb.Memory.SetIO(IO_WX, 87)
+		}}, 172 + 6},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mb := newFifoTestMb(t)
+			for _, setup := range tc.setup {
+				setup(mb)
+			}
+			assert.Equal(t, tc.dots, mode3Length(t, mb))
+		})
+	}
+}
+
+func TestLCD_MidLineWritesTakeEffectAtCurrentPixel(t *testing.T) {
+	mb := newFifoTestMb(t)
+	for i := range 16 {
+		mb.Memory.Vram[0][i] = 0xFF // tile 0 is solid colour 3
+	}
+
+	// Catch the PPU up to the start of line 1, then run it lazily, an
+	// M-cycle at a time as the CPU does, until pixel 80 is next to be
+	// shifted out: 12 + 80 dots into mode 3.
+	for mb.Memory.GetIO(IO_LY) != 1 {
+		mb.advance(4)
+		mb.sync(evLCD)
+	}
+	dots := int(mb.Lcd.scanlineCounter) - lcdMode2Bounds + 12 + 80
+	for range dots / 4 {
+		mb.advance(4)
+	}
+	mb.advance(OpCycles(dots % 4))
+	mb.SetItem(0xFF47, 0x00) // BGP: every colour white
+	require.Equal(t, uint8(80), mb.Lcd.fifo.X, "write should have synced the PPU")
+	for range 456 / 4 {
+		mb.advance(4)
+	}
+
+	black := [3]uint8{}
+	black[0], black[1], black[2] = mb.GetPaletteColour(3)
+	white := [3]uint8{}
+	white[0], white[1], white[2] = mb.GetPaletteColour(0)
+	for x := 0; x < 80; x++ {
+		require.Equal(t, black, mb.Lcd.screenData[x][1], "pixel %d drawn before the write", x)
+	}
+	for x := 80; x < 160; x++ {
+		require.Equal(t, white, mb.Lcd.screenData[x][1], "pixel %d drawn after the write", x)
+	}
+}
+
+func TestLCD_SpritePriority(t *testing.T) {
+	mb := newFifoTestMb(t)
+	// Tile 1: colour 1 on the left half; tile 2: colour 2 everywhere.
+	for row := range 8 {
+		mb.Memory.Vram[0][16+row*2] = 0xF0
+		mb.Memory.Vram[0][32+row*2+1] = 0xFF
+	}
+	mb.Memory.SetIO(IO_OBP0, 0xE4)
+	copy(mb.Memory.Oam[0:], []byte{17, 20, 2, 0}) // OAM 0 at X=20, behind OAM 1 on DMG
+	copy(mb.Memory.Oam[4:], []byte{17, 16, 1, 0}) // OAM 1 at X=16
+
+	mode3Length(t, mb)
+	colour := func(c uint8) [3]uint8 {
+		r, g, b := mb.GetPaletteColour(c)
+		return [3]uint8{r, g, b}
+	}
+
+	// Columns 8-11: OAM 1 (smaller X wins); 12-15: OAM 1 is transparent
+	// there, so OAM 0 shows through.
+	assert.Equal(t, colour(1), mb.Lcd.screenData[8][1])
+	assert.Equal(t, colour(1), mb.Lcd.screenData[11][1])
+	assert.Equal(t, colour(2), mb.Lcd.screenData[12][1])
+	assert.Equal(t, colour(2), mb.Lcd.screenData[19][1])
+	assert.Equal(t, colour(0), mb.Lcd.screenData[20][1])
+}
@@ -0,0 +1,92 @@
+package motherboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stepUntil runs the machine an M-cycle at a time, keeping the PPU synced,
+// until done reports true. It returns how many LCD STAT interrupts were
+// raised on the way.
+func stepUntil(t *testing.T, mb *Motherboard, done func() bool) int {
+	t.Helper()
+	irqs := 0
+	for i := 0; !done(); i++ {
+		require.Less(t, i, 2*154*456/4, "condition never met")
+		mb.advance(4)
+		mb.sync(evLCD)
+		if mb.Cpu.Interrupts.IF&(1<<INTR_LCDSTAT) != 0 {
+			irqs++
+			mb.Cpu.Interrupts.IF = 0
+		}
+	}
+	return irqs
+}
+
+func atLine(mb *Motherboard, ly, mode uint8) func() bool {
+	return func() bool {
+		return mb.Memory.GetIO(IO_LY) == ly && lcdMode(mb) == mode
+	}
+}
+
+func TestLCD_STATSourcesShareOneLine(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		stat uint8
+		irqs int
+	}{
+		{"hblank", 1 << STAT_HBLINT, 1},
+		{"lyc", 1 << STAT_LYCINT, 1},
+		{"hblank then oam", 1<<STAT_HBLINT | 1<<STAT_OAMINT, 1},
+		{"hblank then lyc", 1<<STAT_HBLINT | 1<<STAT_LYCINT, 1},
+		{"oam and lyc", 1<<STAT_OAMINT | 1<<STAT_LYCINT, 1},
+		{"oam", 1 << STAT_OAMINT, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mb := newFifoTestMb(t)
+			mb.Memory.SetIO(IO_LYC, 2)
+			stepUntil(t, mb, atLine(mb, 1, STAT_MODE_TRANS))
+			mb.SetItem(0xFF41, uint16(tc.stat))
+			mb.Cpu.Interrupts.IF = 0
+
+			// From line 1's mode 3 to line 2's: every enabled source
+			// rises once, but back to back they keep the line high.
+			irqs := stepUntil(t, mb, atLine(mb, 2, STAT_MODE_TRANS))
+			assert.Equal(t, tc.irqs, irqs)
+		})
+	}
+}
+
+func TestLCD_STATWriteRaisesSpuriousIRQOnDMG(t *testing.T) {
+	for _, cgb := range []bool{false, true} {
+		mb := newMbForSubsysTestMode(t, cgb)
+		stepUntil(t, mb, atLine(mb, 1, STAT_MODE_HBLANK))
+		mb.Cpu.Interrupts.IF = 0
+
+		mb.SetItem(0xFF41, 0x00)
+		assert.Equal(t, !cgb, mb.Cpu.Interrupts.IF&(1<<INTR_LCDSTAT) != 0, "cgb=%v", cgb)
+		assert.Equal(t, STAT_MODE_HBLANK, lcdMode(mb), "cgb=%v: mode bits are read only", cgb)
+	}
+}
+
+func TestLCD_LYReadsZeroEarlyOnLine153(t *testing.T) {
+	mb := newFifoTestMb(t)
+	mb.SetItem(0xFF45, 0)              // LYC
+	mb.SetItem(0xFF41, 1<<STAT_LYCINT) // STAT
+	stepUntil(t, mb, atLine(mb, 153, STAT_MODE_VBLANK))
+	mb.Cpu.Interrupts.IF = 0
+
+	// LY drops to 0 a few M-cycles into line 153, and LY=LYC matches
+	// there rather than at the start of the next frame.
+	irqs := stepUntil(t, mb, func() bool { return mb.Memory.GetIO(IO_LY) == 0 })
+	assert.Equal(t, STAT_MODE_VBLANK, lcdMode(mb))
+	assert.Less(t, lineDots-int(mb.Lcd.scanlineCounter), 16)
+	irqs += stepUntil(t, mb, func() bool { return mb.Memory.GetIO(IO_STAT)&(1<<STAT_LYC) != 0 })
+	assert.Equal(t, 1, irqs)
+	assert.Equal(t, STAT_MODE_VBLANK, lcdMode(mb), "LYC matches LY=0 during line 153")
+
+	irqs = stepUntil(t, mb, atLine(mb, 0, STAT_MODE_OAM))
+	assert.Zero(t, irqs, "line 0 keeps the line high")
+}
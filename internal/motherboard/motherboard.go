@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"time"
 
 	"github.com/chigopher/pathlib"
 	"github.com/duysqubix/gobc/internal"
@@ -25,18 +26,27 @@ type Motherboard struct {
 	Memory        *Memory              // Internal RAM
 	BootRom       *bootrom.BootRom     // Boot ROM
 	Timer         *Timer               // Timer
+	Dma           *OamDMA              // OAM DMA
+	Serial        *Serial              // Serial port
+	Infrared      *Infrared            // CGB and cartridge infrared port
+	Super         *SGB                 // Super Game Boy packets, palettes and border
 	Lcd           *LCD                 // LCD
 	Sound         *APU                 // APU (audio)
 	Input         *Input               // Input
 	Cgb           bool                 // Color Gameboy
+	Sgb           bool                 // Super Game Boy
 	CpuFreq       uint32               // CPU frequency
 	Randomize     bool                 // Randomize RAM on startup
 	BGPalette     *cgbPalette          // Background palette
 	SpritePalette *cgbPalette          // Sprite palette
+	DmgPalette    byte                 // Selected DMG colour palette (index into Palettes)
 
-	HdmaActive  bool  // HDMA active
-	HdmaLength  uint8 // HDMA length
-	doubleSpeed bool  // Double speed mode
+	HdmaActive  bool      // HDMA active
+	HdmaLength  uint8     // HDMA length
+	doubleSpeed bool      // Double speed mode
+	sched       scheduler // When each peripheral next needs stepping
+	fastCPU     bool      // Run instructions atomically instead of M-cycle by M-cycle
+	busCycles   OpCycles  // M-cycles the current instruction has started, in clocks
 
 	// debugging
 	Decouple     bool         // Decouple Motherboard from other components, and all calls to read/write memory will be mocked
@@ -46,8 +56,10 @@ type Motherboard struct {
 }
 
 func (m *Motherboard) Serialize() *bytes.Buffer {
+	m.syncAll()
 	buf := new(bytes.Buffer)
 
+	internal.WriteStateHeader(buf)
 	binary.Write(buf, binary.LittleEndian, m.HdmaActive)                        // HDMA active
 	binary.Write(buf, binary.LittleEndian, m.HdmaLength)                        // HDMA length
 	binary.Write(buf, binary.LittleEndian, m.doubleSpeed)                       // Double speed mode
@@ -60,12 +72,20 @@ func (m *Motherboard) Serialize() *bytes.Buffer {
 	binary.Write(buf, binary.LittleEndian, m.SpritePalette.Serialize().Bytes()) // Sprite Palette
 	binary.Write(buf, binary.LittleEndian, m.Cartridge.Serialize().Bytes())     // Cartridge
 	binary.Write(buf, binary.LittleEndian, m.Sound.Serialize().Bytes())         // APU
+	binary.Write(buf, binary.LittleEndian, m.Dma.Serialize().Bytes())           // OAM DMA
+	binary.Write(buf, binary.LittleEndian, m.Serial.Serialize().Bytes())        // Serial port
+	binary.Write(buf, binary.LittleEndian, m.Infrared.Serialize().Bytes())      // Infrared port
+	binary.Write(buf, binary.LittleEndian, m.Super.Serialize().Bytes())         // Super Game Boy
 
 	return buf
 }
 
 func (m *Motherboard) Deserialize(data *bytes.Buffer) error {
-	// Read the data from the buffer
+	defer m.rescheduleAll()
+
+	if err := internal.ReadStateHeader(data); err != nil {
+		return err
+	}
 	if err := binary.Read(data, binary.LittleEndian, &m.HdmaActive); err != nil {
 		return err
 	}
@@ -102,25 +122,57 @@ func (m *Motherboard) Deserialize(data *bytes.Buffer) error {
 	if err := m.Sound.Deserialize(data); err != nil {
 		return err
 	}
+	if err := m.Dma.Deserialize(data); err != nil {
+		return err
+	}
+	if err := m.Serial.Deserialize(data); err != nil {
+		return err
+	}
+	if err := m.Infrared.Deserialize(data); err != nil {
+		return err
+	}
+	if err := m.Super.Deserialize(data); err != nil {
+		return err
+	}
 
 	return nil
 }
 
 type MotherboardParams struct {
-	Filename     *pathlib.Path
-	Randomize    bool
-	ForceCgb     bool
-	ForceDmg     bool
-	Breakpoints  []uint16
-	Decouple     bool
-	PanicOnStuck bool
-	AudioEnabled bool
-	AudioSmooth  bool
+	Filename        *pathlib.Path
+	Rom             []byte              // in-memory ROM image; used instead of Filename when set
+	RomName         string              // base name for SRAM / state files when loading from Rom
+	Mapper          string              // unlicensed mapper to use instead of the header's; empty guesses
+	Clock           cartridge.ClockMode // how the cartridge's clock catches up on a save
+	ClockStart      time.Time           // when the machine is started, for cartridge.ClockFixed
+	Randomize       bool
+	ForceCgb        bool
+	ForceDmg        bool
+	Sgb             bool // run as a Super Game Boy: DMG mode, with SGB packets and the border
+	Breakpoints     []uint16
+	Decouple        bool
+	PanicOnStuck    bool
+	AudioOutput     AudioOutput // host audio device; nil runs the APU silent
+	AudioSampleRate int         // host audio rate in Hz; 0 = default
+	AudioSmooth     bool
+	FastCPU         bool // run each instruction atomically; faster, but not M-cycle accurate
 }
 
-func NewMotherboard(params *MotherboardParams) *Motherboard {
-
-	var cart *cartridge.Cartridge = cartridge.NewCartridge(params.Filename)
+// NewMotherboard loads the cartridge described by params and wires up a
+// powered-on machine. Cartridge load failures are returned unchanged so
+// callers can match them against the cartridge.Err* values.
+func NewMotherboard(params *MotherboardParams) (*Motherboard, error) {
+	var cart *cartridge.Cartridge
+	var err error
+	opts := cartridge.LoadOptions{Mapper: params.Mapper, Clock: params.Clock, ClockStart: params.ClockStart}
+	if params.Rom != nil {
+		cart, err = cartridge.NewCartridgeFromBytesWithOptions(params.RomName, params.Rom, opts)
+	} else {
+		cart, err = cartridge.NewCartridgeWithOptions(params.Filename, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	var bp *Breakpoints
 	if len(params.Breakpoints) > 0 {
@@ -148,11 +200,14 @@ func NewMotherboard(params *MotherboardParams) *Motherboard {
 		PanicOnStuck:  params.PanicOnStuck,
 		BGPalette:     NewPalette(),
 		SpritePalette: NewPalette(),
+		DmgPalette:    DefaultPalette,
+		fastCPU:       params.FastCPU,
 	}
 
 	mb.Cgb = mb.Cartridge.CgbModeEnabled() || params.ForceCgb
 
-	if mb.Cgb && params.ForceDmg {
+	mb.Sgb = params.Sgb
+	if mb.Cgb && (params.ForceDmg || mb.Sgb) {
 		mb.Cgb = false
 	}
 
@@ -165,10 +220,19 @@ func NewMotherboard(params *MotherboardParams) *Motherboard {
 	mb.Cpu = NewCpu(mb)
 	mb.Memory = NewInternalRAM(mb, params.Randomize)
 	mb.Lcd = NewLCD(mb)
-	mb.Sound = NewAPU(mb, params.AudioEnabled, params.AudioSmooth)
+	mb.Dma = NewOamDMA(mb)
+	mb.Serial = NewSerial(mb)
+	mb.Infrared = NewInfrared(mb)
+	mb.Cartridge.IR = mb.Infrared.Transceiver()
+	mb.Super = NewSGB(mb)
+	if mb.Sgb && !mb.Cartridge.SgbFunctionsEnabled() {
+		logger.Warn("Super Game Boy: the cartridge header does not enable SGB functions; its commands will be ignored")
+	}
+	mb.Sound = NewAPU(mb, params.AudioOutput, params.AudioSampleRate, params.AudioSmooth)
 	mb.BootRom = bootrom.NewBootRom(mb.Cgb)
 	mb.BootRom.Enable()
 	// mb.BootRom.Disable()
+	mb.Memory.SetIO(IO_LCDC, 0x00) // the boot ROM starts with the LCD off
 
 	if !mb.BootRomEnabled() {
 		logger.Info("Boot ROM not enabled. Jumping to 0x100")
@@ -177,8 +241,9 @@ func NewMotherboard(params *MotherboardParams) *Motherboard {
 		logger.Info("Boot ROM enabled. Jumping to 0x0")
 		mb.Cpu.Registers.PC = BOOTROM_START_ADDR
 	}
+	mb.rescheduleAll()
 
-	return mb
+	return mb, nil
 }
 
 func (m *Motherboard) Reset() {
@@ -188,7 +253,12 @@ func (m *Motherboard) Reset() {
 	m.Sound.Reset()
 	m.BootRom.Enable()
 	// m.BootRom.Disable()
+	m.Memory.SetIO(IO_LCDC, 0x00) // the boot ROM starts with the LCD off
 	m.Timer.Reset()
+	m.Dma.Reset()
+	m.Serial.Reset()
+	m.Infrared.Reset()
+	m.Super.Reset()
 
 	if !m.BootRomEnabled() {
 		logger.Info("Boot ROM not enabled. Jumping to 0x100")
@@ -197,53 +267,86 @@ func (m *Motherboard) Reset() {
 		logger.Info("Boot ROM enabled. Jumping to 0x0")
 		m.Cpu.Registers.PC = BOOTROM_START_ADDR
 	}
+	m.rescheduleAll()
+}
+
+// DoubleSpeed reports whether the CGB CPU is running in double-speed mode.
+func (m *Motherboard) DoubleSpeed() bool {
+	return m.doubleSpeed
 }
 
 func (m *Motherboard) BootRomEnabled() bool {
 	return m.BootRom.IsEnabled
 }
 
-func (m *Motherboard) Tick() (bool, OpCycles) {
-	defer func() {
-		if r := recover(); r != nil {
-			df := internal.StateDumpFile()
-			logger.SetOutput(df)
-			// dump CPU State
-			fmt.Fprintln(df, "-----CRASH DETECTED-----")
-			fmt.Fprintln(df, "----- CPU STATE -----")
-			m.Cpu.DumpState(df)
-			fmt.Fprintln(df, "----- MEMORY STATE -----")
-			m.Memory.DumpState(df)
-			fmt.Fprintln(df, "-----END CRASH-----")
-			fmt.Println("Crash detected.. check log file for more details")
-			logger.Panic()
+// SkipBootROM unmaps the boot ROM and leaves the machine where the boot ROM
+// would hand over: PC at $0100 with the LCD on and the cartridge unlocked.
+func (m *Motherboard) SkipBootROM() {
+	m.BootRom.Disable()
+	m.Cartridge.SkipBoot()
+	m.Cpu.Registers.PC = ROM_START_ADDR
+	m.Memory.SetIO(IO_LCDC, 0x91)
+	m.rescheduleAll()
+}
+
+// RunCycles executes instructions until at least n cycles have elapsed or
+// the CPU can no longer run. It returns the cycles actually run and whether
+// the CPU is still running. A panic anywhere in the batch dumps the CPU and
+// memory state to the crash log before it is re-raised.
+func (m *Motherboard) RunCycles(n OpCycles) (OpCycles, bool) {
+	defer m.dumpOnCrash()
+
+	var ran OpCycles
+	for ran < n {
+		ok, cycles := m.Tick()
+		if !ok {
+			return ran, false
 		}
-	}()
+		ran += cycles
+	}
+	return ran, true
+}
+
+func (m *Motherboard) dumpOnCrash() {
+	if r := recover(); r != nil {
+		df := internal.StateDumpFile()
+		logger.SetOutput(df)
+		// dump CPU State
+		fmt.Fprintln(df, "-----CRASH DETECTED-----")
+		fmt.Fprintln(df, "----- CPU STATE -----")
+		m.Cpu.DumpState(df)
+		fmt.Fprintln(df, "----- MEMORY STATE -----")
+		m.Memory.DumpState(df)
+		fmt.Fprintln(df, "-----END CRASH-----")
+		fmt.Println("Crash detected.. check log file for more details")
+		logger.Panic()
+	}
+}
+
+// Tick executes a single instruction, or idles while halted, and steps
+// any peripherals that fall due. Callers running many instructions should
+// prefer RunCycles.
+func (m *Motherboard) Tick() (bool, OpCycles) {
 	var cycles OpCycles = 4
 
 	if m.Cpu.Stopped || m.Cpu.IsStuck || m.GuiPause {
 		return false, cycles
 	}
 
-	if !m.Cpu.Halted {
+	if m.Cpu.Halted {
+		cycles = m.haltCycles()
+	} else {
 		cycles = m.Cpu.Tick()
 	}
-	m.Cartridge.Tick(uint64(cycles))
-
-	m.Cpu.Mb.Timer.Tick(cycles, m.Cpu)
-	m.Lcd.Tick(cycles)
-	m.Sound.Tick(cycles)
+	m.spendCycles(cycles)
 
 	// Interrupt servicing consumes real wall-clock cycles too (5 M-cycles
-	// per Pan Docs "Interrupt Service Routine"). Without ticking the
+	// per Pan Docs "Interrupt Service Routine"). Without advancing the
 	// peripherals during that window TIMA/DIV/LCD/APU lag behind the
 	// CPU, and Blargg's interrupt_time test sees an 8-cycle interrupt
 	// (JP+RET only) instead of the expected 13.
 	if irq := m.Cpu.handleInterrupts(); irq > 0 {
-		m.Cartridge.Tick(uint64(irq))
-		m.Cpu.Mb.Timer.Tick(irq, m.Cpu)
-		m.Lcd.Tick(irq)
-		m.Sound.Tick(irq)
+		m.spendCycles(irq)
 		cycles += irq
 	}
 	return true, cycles
@@ -256,18 +359,43 @@ func (m *Motherboard) ButtonEvent(key Key) {
 	}
 }
 
+// PlayerButtonEvent is ButtonEvent for the joypad of player (1-4). Only
+// a Super Game Boy reads players 2-4, once the game asks for them.
+func (m *Motherboard) PlayerButtonEvent(player int, key Key) {
+	if m.Input.PlayerKeyEvent(player, key) != 0 {
+		m.Cpu.SetInterruptFlag(INTR_HIGHTOLOW)
+	}
+}
+
+// TiltEvent tilts the cartridge x g to the right and y g towards the
+// player. Only cartridges with an accelerometer (MBC7) notice.
+func (m *Motherboard) TiltEvent(x, y float64) {
+	m.Cartridge.Tilt(x, y)
+}
+
+// syncPPU catches the PPU up before a write to anything it reads while
+// drawing a line, so the write takes effect from the pixel being drawn at
+// that moment. Outside mode 3 the write cannot affect the picture until
+// the PPU is next stepped anyway.
+func (m *Motherboard) syncPPU() {
+	if m.Lcd.fifo.Active {
+		m.sync(evLCD)
+	}
+}
+
 // resolveOAMBugRow shares the gating logic for both write- and read-style
 // OAM corruption: DMG-only, address in OAM range, PPU latched a row in mode 2.
 // Returns the row offset (always a multiple of 8 in [8, 152]) or 0xFF when
 // no corruption should fire.
-func (m *Motherboard) resolveOAMBugRow(addr uint16, cycleOffset OpCycles) uint8 {
+func (m *Motherboard) resolveOAMBugRow(addr uint16) uint8 {
 	if m.Cgb {
 		return 0xFF
 	}
 	if addr < 0xFE00 || addr >= 0xFF00 {
 		return 0xFF
 	}
-	row := m.Lcd.OAMBugRowAt(cycleOffset)
+	m.sync(evLCD)
+	row := m.Lcd.OAMBugRow()
 	if row == 0xFF || row < 8 {
 		return 0xFF
 	}
@@ -280,8 +408,9 @@ func (m *Motherboard) resolveOAMBugRow(addr uint16, cycleOffset OpCycles) uint8
 // OAMBugTrigger models the DMG OAM corruption bug for opcodes whose
 // inc/dec unit puts a $FE00-$FEFF address on the bus during PPU mode 2.
 // Pan Docs "OAM Corruption Bug"; formulas from SameBoy memory.c
-// bitwise_glitch / GB_trigger_oam_bug. cycleOffset is the T-cycle offset
-// from the start of the opcode handler to the bus driving moment.
+// bitwise_glitch / GB_trigger_oam_bug. Handlers call it in the M-cycle
+// where the address is driven, so the PPU is already caught up to that
+// point in M-cycle mode.
 //
 // Write-style formula (INC/DEC rr, PUSH, LD (HL+/-),A):
 //
@@ -290,8 +419,8 @@ func (m *Motherboard) resolveOAMBugRow(addr uint16, cycleOffset OpCycles) uint8
 //	OAM[row+2..row+7] := OAM[row-6..row-1]
 //
 // where a=OAM[row..row+1], b=OAM[row-8..row-7], c=OAM[row-4..row-3].
-func (m *Motherboard) OAMBugTrigger(addr uint16, cycleOffset OpCycles) {
-	row := m.resolveOAMBugRow(addr, cycleOffset)
+func (m *Motherboard) OAMBugTrigger(addr uint16) {
+	row := m.resolveOAMBugRow(addr)
 	if row == 0xFF {
 		return
 	}
@@ -326,8 +455,8 @@ func (m *Motherboard) OAMBugTrigger(addr uint16, cycleOffset OpCycles) {
 //     Blargg's deterministic CRC tests on row=0x30 / 0x50 / 0x70 etc.
 //
 // All three variants then run OAM[row..row+7] := OAM[row-8..row-1].
-func (m *Motherboard) OAMBugTriggerRead(addr uint16, cycleOffset OpCycles) {
-	row := m.resolveOAMBugRow(addr, cycleOffset)
+func (m *Motherboard) OAMBugTriggerRead(addr uint16) {
+	row := m.resolveOAMBugRow(addr)
 	if row == 0xFF {
 		return
 	}
@@ -1,5 +1,16 @@
 package motherboard
 
+// Peek returns the byte GetItem would, for debuggers and embedders, but
+// without moving on the cartridges whose ROM reads do more than read,
+// such as a Sachen mapper counting its way through the boot lock.
+func (m *Motherboard) Peek(addr uint16) uint8 {
+	bootRom := m.BootRomEnabled() && (addr < 0x100 || (m.Cgb && 0x200 <= addr && addr < 0x900))
+	if addr < 0x8000 && !bootRom {
+		return m.Cartridge.Peek(addr)
+	}
+	return m.GetItem(addr)
+}
+
 func (m *Motherboard) GetItem(addr uint16) uint8 {
 
 	// debugging
@@ -44,6 +55,7 @@ func (m *Motherboard) GetItem(addr uint16) uint8 {
 	*
 	 */
 	case 0xA000 <= addr && addr < 0xC000: // 8K External RAM (Cartridge)
+		m.sync(evCartridge)
 		return m.Cartridge.CartType.GetItem(addr)
 
 	/*
@@ -106,6 +118,7 @@ func (m *Motherboard) GetItem(addr uint16) uint8 {
 	case 0xFF00 <= addr && addr < 0xFF80:
 
 		if 0xFF10 <= addr && addr <= 0xFF3F {
+			m.sync(evAPU)
 			return m.Sound.Read(addr)
 		}
 
@@ -114,16 +127,28 @@ func (m *Motherboard) GetItem(addr uint16) uint8 {
 		case 0xFF00: /* P1 */
 			return m.Memory.GetIO(IO_P1_JOYP)
 
+		case 0xFF01: /* SB */
+			m.sync(evSerial)
+			return m.Serial.SB
+
+		case 0xFF02: /* SC */
+			m.sync(evSerial)
+			return m.Serial.ReadSC()
+
 		case 0xFF04: /* DIV */
+			m.sync(evTimer)
 			return uint8(m.Timer.DIV)
 
 		case 0xFF05: /* TIMA */
+			m.sync(evTimer)
 			return uint8(m.Timer.TIMA)
 
 		case 0xFF06: /* TMA */
+			m.sync(evTimer)
 			return uint8(m.Timer.TMA)
 
 		case 0xFF07: /* TAC */
+			m.sync(evTimer)
 			return uint8(m.Timer.TAC)
 
 		case 0xFF0F: /* IF */
@@ -133,9 +158,11 @@ func (m *Motherboard) GetItem(addr uint16) uint8 {
 			return m.Memory.GetIO(IO_LCDC)
 
 		case 0xFF41: /* STAT */
+			m.sync(evLCD)
 			return m.Memory.GetIO(IO_STAT)
 
 		case 0xFF44: /* LY */
+			m.sync(evLCD)
 			return m.Memory.GetIO(IO_LY)
 
 		case 0xFF46: /* DMA */
@@ -155,6 +182,11 @@ func (m *Motherboard) GetItem(addr uint16) uint8 {
 
 		case 0xFF50: /* Disable Boot ROM */
 			return 0xFF
+
+		case 0xFF56: /* RP */
+			m.sync(evInfrared)
+			return m.Infrared.Read()
+
 		case 0xFF68: /* BG Palette Index */
 			if m.Cgb {
 				return m.BGPalette.readIndex()
@@ -200,7 +232,7 @@ func (m *Motherboard) GetItem(addr uint16) uint8 {
 		return m.Cpu.Interrupts.IE
 
 	default:
-		logger.Panicf("Memory read error! Can't read from %#x\n", addr)
+		logger.Errorf("Memory read error! Can't read from %#x", addr)
 	}
 
 	return 0xFF
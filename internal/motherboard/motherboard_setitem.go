@@ -1,14 +1,9 @@
 package motherboard
 
-import (
-	"fmt"
-
-	"github.com/duysqubix/gobc/internal"
-)
-
 func (m *Motherboard) SetItem(addr uint16, value uint16) {
 	if value >= 0x100 {
-		logger.Fatalf("Memory write error! Can't write %#x to %#x\n", value, addr)
+		logger.Errorf("Memory write error! Can't write %#x to %#x", value, addr)
+		return
 	}
 
 	if m.Decouple {
@@ -29,7 +24,9 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 			return
 		}
 
+		m.sync(evCartridge)
 		m.Cartridge.CartType.SetItem(addr, v)
+		m.reschedule(evCartridge)
 
 	/*
 	*
@@ -38,7 +35,9 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 	 */
 	case 0x4000 <= addr && addr < 0x8000:
 		// logger.Debugf("Setting Ram Bank: %#x, PC: %#x", v, m.Cpu.Registers.PC)
+		m.sync(evCartridge)
 		m.Cartridge.CartType.SetItem(addr, v)
+		m.reschedule(evCartridge)
 
 	/*
 	*
@@ -46,6 +45,7 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 	*
 	 */
 	case 0x8000 <= addr && addr < 0xA000:
+		m.syncPPU()
 		if m.Cgb {
 			bank := m.Memory.GetIO(IO_VBK) & 0x01
 
@@ -60,7 +60,9 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 	*
 	 */
 	case 0xA000 <= addr && addr < 0xC000:
+		m.sync(evCartridge)
 		m.Cartridge.CartType.SetItem(addr, v)
+		m.reschedule(evCartridge)
 
 	/*
 	*
@@ -104,6 +106,7 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 	*
 	 */
 	case 0xFE00 <= addr && addr < 0xFEA0:
+		m.syncPPU()
 		m.Memory.Oam[addr-0xFE00] = v
 	/*
 	*
@@ -120,50 +123,96 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 	case 0xFF00 <= addr && addr < 0xFF80:
 
 		if 0xFF10 <= addr && addr <= 0xFF3F {
+			m.sync(evAPU)
 			m.Sound.Write(addr, v)
+			m.reschedule(evAPU)
 			return
 		}
 
 		switch addr {
 		case 0xFF00: /* P1 */
+			if m.Sgb {
+				m.Super.WriteP1(v)
+			}
 			m.Memory.SetIO(IO_P1_JOYP, m.Input.Pull(v))
 
+		case 0xFF01: /* SB */
+			m.sync(evSerial)
+			m.Serial.SB = v
+			return
+
+		case 0xFF02: /* SC */
+			m.sync(evSerial)
+			m.Serial.WriteSC(v)
+			m.reschedule(evSerial)
+			return
+
 		case 0xFF04: /* DIV */
+			m.sync(evTimer)
 			m.Timer.TimaCounter = 0
 			m.Timer.DivCounter = 0
 			m.Timer.DIV = 0
+			m.reschedule(evTimer)
 			return
 
 		case 0xFF05: /* TIMA */
+			m.sync(evTimer)
 			m.Timer.TIMA = uint32(v)
+			m.reschedule(evTimer)
 			return
 
 		case 0xFF06: /* TMA */
+			m.sync(evTimer)
 			m.Timer.TMA = uint32(v)
+			m.reschedule(evTimer)
 			return
 
 		case 0xFF07: /* TAC */
+			m.sync(evTimer)
 			currentFreq := m.Timer.TAC & 0x03
 			m.Timer.TAC = uint32(v) | 0xF8
 			newFreq := m.Timer.TAC & 0x03
 			if currentFreq != newFreq {
 				m.Timer.TimaCounter = 0
 			}
+			m.reschedule(evTimer)
 			return
 
 		case 0xFF0F: /* IF */
 			m.Cpu.Interrupts.IF = v
 			return
 
+		case 0xFF40: /* LCDC */
+			// the PPU picks this up the next time it is stepped
+			m.sync(evLCD)
+			m.Memory.SetIO(addr, v)
+			m.scheduleNext(evLCD)
+
 		case 0xFF41: /* STAT */
-			// do not set bits 0-1, they are read_only bits, bit 7 always reads 1
-			m.Memory.SetIO(IO_STAT, (m.Memory.GetIO(IO_STAT)&0x83)|(v&0xFC))
+			// do not set bits 0-2, they are read_only bits, bit 7 always reads 1
+			m.sync(evLCD)
+			m.Lcd.writeSTAT(v)
+
+		case 0xFF45: /* LYC */
+			m.sync(evLCD)
+			m.Memory.SetIO(IO_LYC, v)
+			m.Lcd.updateSTAT()
+
+		case 0xFF42, 0xFF43, 0xFF47, 0xFF48, 0xFF49, 0xFF4A, 0xFF4B: /* SCY, SCX, BGP, OBP0, OBP1, WY, WX */
+			m.syncPPU()
+			m.Memory.SetIO(addr, v)
 
 		case 0xFF44: /* LY */
 			// m.Memory.SetIO(IO_LY, 0)
 
 		case 0xFF46: /* DMA */
-			m.doDMATransfer(v)
+			if m.fastCPU {
+				m.doDMATransfer(v)
+			} else {
+				m.sync(evDMA)
+				m.Dma.Start(v)
+				m.reschedule(evDMA)
+			}
 
 		case 0xFF4D: /* KEY1 */
 			if m.Cgb {
@@ -191,6 +240,11 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 			}
 			return
 
+		case 0xFF56: /* RP */
+			m.sync(evInfrared)
+			m.Infrared.Write(v)
+			return
+
 		case 0xFF55: /* HDMA5 */
 			if m.Cgb {
 				m.doNewDMATransfer(v)
@@ -204,6 +258,7 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 
 		case 0xFF69: /* BG Palette Data */
 			if m.Cgb {
+				m.syncPPU()
 				m.BGPalette.write(v)
 			}
 
@@ -217,6 +272,7 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 
 		case 0xFF6B: /* Sprite Palette Data */
 			if m.Cgb {
+				m.syncPPU()
 				m.SpritePalette.write(v)
 			}
 			return
@@ -230,12 +286,6 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 			m.Memory.SetIO(addr, v)
 		}
 
-		/// prints serial output to terminal ///
-		if v == 0x81 && addr == IO_SC {
-			fmt.Printf("%c", m.Memory.GetIO(IO_SB))
-		}
-		////////////////////////////////////
-
 	/*
 	*
 	* WRITE: HIGH RAM
@@ -252,7 +302,7 @@ func (m *Motherboard) SetItem(addr uint16, value uint16) {
 	case addr == IE:
 		m.Cpu.Interrupts.IE = v
 	default:
-		internal.Logger.Panicf("Memory write error! Can't write `%#x` to `%#x`\n", value, addr)
+		logger.Errorf("Memory write error! Can't write `%#x` to `%#x`", value, addr)
 	}
 
 }
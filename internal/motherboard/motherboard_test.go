@@ -0,0 +1,141 @@
+package motherboard
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/cartridge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMbFromBytes(t testing.TB, rom []byte) *Motherboard {
+	t.Helper()
+	mb, err := NewMotherboard(&MotherboardParams{Rom: rom, ForceDmg: true})
+	require.NoError(t, err)
+	return mb
+}
+
+func TestNewMotherboard_ReturnsCartridgeErrors(t *testing.T) {
+	rom := subsysFakeROMBytes()
+	rom[0x014D]++
+
+	mb, err := NewMotherboard(&MotherboardParams{Rom: rom})
+	assert.ErrorIs(t, err, cartridge.ErrChecksum)
+	assert.Nil(t, mb)
+
+	_, err = NewMotherboard(&MotherboardParams{Rom: rom[:0x100]})
+	assert.ErrorIs(t, err, cartridge.ErrTruncatedROM)
+}
+
+func TestMotherboard_SerializeStartsWithHeader(t *testing.T) {
+	mb := newMbFromBytes(t, subsysFakeROMBytes())
+	state := mb.Serialize().Bytes()
+
+	require.NoError(t, internal.ReadStateHeader(bytes.NewBuffer(state)))
+	require.NoError(t, mb.Deserialize(bytes.NewBuffer(state)))
+}
+
+func TestMotherboard_DeserializeRejectsOtherVersions(t *testing.T) {
+	mb := newMbFromBytes(t, subsysFakeROMBytes())
+	state := mb.Serialize().Bytes()
+	state[len(internal.STATE_MAGIC)] ^= 0xFF
+
+	assert.ErrorIs(t, mb.Deserialize(bytes.NewBuffer(state)), internal.ErrStateVersion)
+}
+
+// FuzzLoadState checks that arbitrary save-state blobs are either
+// restored or rejected, but never panic.
+func FuzzLoadState(f *testing.F) {
+	valid := newMbFromBytes(f, subsysFakeROMBytes()).Serialize().Bytes()
+	f.Add(valid)
+	f.Add(valid[:len(valid)/2])
+	f.Add(valid[:len(internal.STATE_MAGIC)+2])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		mb := newMbFromBytes(t, subsysFakeROMBytes())
+		if err := internal.RestoreState(mb, data); err != nil {
+			return
+		}
+		mb.Serialize()
+	})
+}
+
+func TestMotherboard_RunCyclesRunsWholeInstructions(t *testing.T) {
+	mb := newSchedulerTestMb(t, 0x21, 0x00, 0xC0, 0x34, 0x18, 0xFD) // LD HL,$C000 ; loop: INC (HL) ; JR loop
+
+	ran, ok := mb.RunCycles(1)
+	require.True(t, ok)
+	assert.Equal(t, OpCycles(16), ran, "a batch always finishes the instruction it started (JP a16)")
+
+	ran, ok = mb.RunCycles(100)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, ran, OpCycles(100))
+	assert.Less(t, ran, OpCycles(100+16))
+
+	mb.Cpu.Stopped = true
+	ran, ok = mb.RunCycles(100)
+	assert.False(t, ok)
+	assert.Zero(t, ran)
+}
+
+// benchLoopProgram is a loop of 8 instructions taking 64 cycles, mixing
+// register, memory, CB-prefixed and I/O accesses. With interrupts off every
+// Tick runs exactly one instruction.
+var benchLoopProgram = []byte{
+	0x21, 0x00, 0xC0, // LD HL,$C000
+	// loop:
+	0x7E,       // LD A,(HL)
+	0x3C,       // INC A
+	0x77,       // LD (HL),A
+	0xCB, 0x37, // SWAP A
+	0xF0, 0x44, // LDH A,(LY)
+	0x80,             // ADD A,B
+	0x47,             // LD B,A
+	0xC3, 0x53, 0x01, // JP loop
+}
+
+const benchLoopCyclesPerInstr = 64 / 8
+
+func newBenchMb(b *testing.B) *Motherboard {
+	mb := newSchedulerTestMb(b, benchLoopProgram...)
+	mb.RunCycles(16 + 12) // JP $0150 ; LD HL,$C000
+	b.ResetTimer()
+	return mb
+}
+
+func reportInstrPerSec(b *testing.B, instrs int) {
+	b.ReportMetric(float64(instrs)/b.Elapsed().Seconds(), "instr/s")
+}
+
+// BenchmarkMotherboard_Tick measures one instruction per call, as when
+// single-stepping through the emulator package.
+func BenchmarkMotherboard_Tick(b *testing.B) {
+	mb := newBenchMb(b)
+	for i := 0; i < b.N; i++ {
+		mb.Tick()
+	}
+	reportInstrPerSec(b, b.N)
+}
+
+// BenchmarkMotherboard_RunCycles measures b.N instructions run in
+// frame-sized batches, as the GUI loop does, with and without the PC
+// history the CPU debug view needs.
+func BenchmarkMotherboard_RunCycles(b *testing.B) {
+	for _, hist := range []bool{false, true} {
+		b.Run(fmt.Sprintf("history=%t", hist), func(b *testing.B) {
+			mb := newBenchMb(b)
+			mb.Cpu.RecordHist = hist
+
+			var ran OpCycles
+			for total := OpCycles(b.N) * benchLoopCyclesPerInstr; ran < total; {
+				cycles, _ := mb.RunCycles(min(total-ran, 154*456))
+				ran += cycles
+			}
+			reportInstrPerSec(b, int(ran/benchLoopCyclesPerInstr))
+		})
+	}
+}
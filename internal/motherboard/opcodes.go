@@ -16,17 +16,21 @@ func (o *OpCode) Shift() OpCode {
 
 var ILLEGAL_OPCODES = []OpCode{0xd3, 0xdb, 0xdd, 0xe3, 0xe4, 0xeb, 0xec, 0xed, 0xf4, 0xfc, 0xfd}
 
-func (o *OpCode) IsIllegal() bool {
+// illegalOpcodes flags every entry of ILLEGAL_OPCODES so the check in
+// executeOpcode is a single index instead of a scan.
+var illegalOpcodes = func() (flags [len(OPCODES)]bool) {
 	for _, opcode := range ILLEGAL_OPCODES {
-		if *o == opcode {
-			return true
-		}
+		flags[opcode] = true
 	}
-	return false
+	return flags
+}()
+
+func (o *OpCode) IsIllegal() bool {
+	return int(*o) < len(illegalOpcodes) && illegalOpcodes[*o]
 }
 
 func executeOpcode(opcode OpCode, mb *Motherboard, value uint16) OpCycles {
-	if opcode.IsIllegal() {
+	if illegalOpcodes[opcode] {
 		mb.Cpu.DumpState(os.Stdout)
 		logger.Errorf("Illegal opcode %#x", opcode)
 		mb.Cpu.IsStuck = true
@@ -36,8 +40,8 @@ func executeOpcode(opcode OpCode, mb *Motherboard, value uint16) OpCycles {
 	return OPCODES[opcode](mb, value)
 }
 
-// OPCODES is a map of opcodes to their logic
-var OPCODES = OpCodeMap{
+// OPCODES is a table of opcodes to their logic
+var OPCODES = OpCodeTable{
 
 	/****************************** 0xn0 **********************/
 	// NOP - No operation (0)
@@ -49,12 +53,16 @@ var OPCODES = OpCodeMap{
 	// STOP 0 - Stop CPU & LCD display until button pressed (16)
 	0x10: func(mb *Motherboard, value uint16) OpCycles {
 		if mb.Cgb {
+			// every peripheral's clock rate may change; catch them up
+			// at the old speed first
+			mb.syncAll()
 			key1 := mb.Memory.GetIO(IO_KEY1)
 			if internal.IsBitSet(key1, 0) {
 				mb.doubleSpeed = !mb.doubleSpeed
 				mb.Memory.SetIO(IO_KEY1, key1^0x81)
 			}
 			mb.Cpu.Mb.Timer.DIV = 0x00
+			mb.rescheduleAll()
 		}
 		mb.Cpu.Registers.PC += 2
 		return 4
@@ -111,7 +119,7 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		b := uint16(mb.Cpu.Registers.B)
-		mb.SetItem(hl, b)
+		mb.cpuWrite(hl, b)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -152,10 +160,11 @@ var OPCODES = OpCodeMap{
 	0xc0: func(mb *Motherboard, value uint16) OpCycles {
 
 		var pch, pcl uint8
+		mb.cpuIdle() // condition check
 		if !mb.Cpu.IsFlagZSet() {
 			spadd1 := mb.Cpu.Registers.SP + 1
-			pch = mb.GetItem(spadd1)
-			pcl = mb.GetItem(mb.Cpu.Registers.SP)
+			pcl = mb.cpuRead(mb.Cpu.Registers.SP)
+			pch = mb.cpuRead(spadd1)
 			mb.Cpu.Registers.PC = (uint16(pch) << 8) | uint16(pcl)
 
 			mb.Cpu.Registers.SP += 2
@@ -170,10 +179,11 @@ var OPCODES = OpCodeMap{
 	0xd0: func(mb *Motherboard, value uint16) OpCycles {
 
 		var pch, pcl uint8
+		mb.cpuIdle() // condition check
 		if !mb.Cpu.IsFlagCSet() {
 			spadd1 := mb.Cpu.Registers.SP + 1
-			pch = mb.GetItem(spadd1)
-			pcl = mb.GetItem(mb.Cpu.Registers.SP)
+			pcl = mb.cpuRead(mb.Cpu.Registers.SP)
+			pch = mb.cpuRead(spadd1)
 
 			mb.Cpu.Registers.PC = (uint16(pch) << 8) | uint16(pcl)
 
@@ -190,7 +200,7 @@ var OPCODES = OpCodeMap{
 
 		var addr uint16 = 0xff00 + value
 		a := uint16(mb.Cpu.Registers.A)
-		mb.SetItem(addr, a)
+		mb.cpuWrite(addr, a)
 		mb.Cpu.Registers.PC += 2
 		return 12
 	},
@@ -199,7 +209,7 @@ var OPCODES = OpCodeMap{
 	0xf0: func(mb *Motherboard, value uint16) OpCycles {
 
 		var addr uint16 = 0xff00 + value
-		a := mb.GetItem(addr)
+		a := mb.cpuRead(addr)
 		mb.Cpu.Registers.A = a
 		mb.Cpu.Registers.PC += 2
 		return 12
@@ -267,7 +277,7 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		cr := uint16(mb.Cpu.Registers.C)
-		mb.SetItem(hl, cr)
+		mb.cpuWrite(hl, cr)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -310,10 +320,10 @@ var OPCODES = OpCodeMap{
 		var pch, pcl uint8
 		sp := mb.Cpu.Registers.SP
 		spadd1 := sp + 1
-		mb.OAMBugTriggerRead(sp, 0)
-		mb.OAMBugTriggerRead(spadd1, 0)
-		pch = mb.GetItem(spadd1)
-		pcl = mb.GetItem(sp)
+		pcl = mb.cpuRead(sp)
+		mb.OAMBugTriggerRead(sp)
+		pch = mb.cpuRead(spadd1)
+		mb.OAMBugTriggerRead(spadd1)
 
 		mb.Cpu.SetBC((uint16(pch) << 8) | uint16(pcl))
 
@@ -328,10 +338,10 @@ var OPCODES = OpCodeMap{
 		var pch, pcl uint8
 		sp := mb.Cpu.Registers.SP
 		spadd1 := sp + 1
-		mb.OAMBugTriggerRead(sp, 0)
-		mb.OAMBugTriggerRead(spadd1, 0)
-		pch = mb.GetItem(spadd1)
-		pcl = mb.GetItem(sp)
+		pcl = mb.cpuRead(sp)
+		mb.OAMBugTriggerRead(sp)
+		pch = mb.cpuRead(spadd1)
+		mb.OAMBugTriggerRead(spadd1)
 
 		mb.Cpu.SetDE((uint16(pch) << 8) | uint16(pcl))
 
@@ -346,10 +356,10 @@ var OPCODES = OpCodeMap{
 		var pch, pcl uint8
 		sp := mb.Cpu.Registers.SP
 		spadd1 := sp + 1
-		mb.OAMBugTriggerRead(sp, 0)
-		mb.OAMBugTriggerRead(spadd1, 0)
-		pch = mb.GetItem(spadd1)
-		pcl = mb.GetItem(sp)
+		pcl = mb.cpuRead(sp)
+		mb.OAMBugTriggerRead(sp)
+		pch = mb.cpuRead(spadd1)
+		mb.OAMBugTriggerRead(spadd1)
 
 		mb.Cpu.SetHL((uint16(pch) << 8) | uint16(pcl))
 
@@ -363,10 +373,10 @@ var OPCODES = OpCodeMap{
 
 		sp := mb.Cpu.Registers.SP
 		spadd1 := sp + 1
-		mb.OAMBugTriggerRead(sp, 0)
-		mb.OAMBugTriggerRead(spadd1, 0)
-		mb.Cpu.Registers.A = mb.GetItem(spadd1)
-		mb.Cpu.Registers.F = mb.GetItem(sp) & 0xF0 & 0xF0
+		mb.Cpu.Registers.F = mb.cpuRead(sp) & 0xF0
+		mb.OAMBugTriggerRead(sp)
+		mb.Cpu.Registers.A = mb.cpuRead(spadd1)
+		mb.OAMBugTriggerRead(spadd1)
 
 		mb.Cpu.Registers.SP += 2
 		mb.Cpu.Registers.PC += 1
@@ -379,7 +389,7 @@ var OPCODES = OpCodeMap{
 
 		bc := mb.Cpu.BC()
 		a := (uint16)(mb.Cpu.Registers.A)
-		mb.SetItem(bc, a)
+		mb.cpuWrite(bc, a)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -389,7 +399,7 @@ var OPCODES = OpCodeMap{
 
 		de := mb.Cpu.DE()
 		a := uint16(mb.Cpu.Registers.A)
-		mb.SetItem(de, a)
+		mb.cpuWrite(de, a)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -399,8 +409,8 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		a := uint16(mb.Cpu.Registers.A)
-		mb.SetItem(hl, a)
-		mb.OAMBugTrigger(hl, 0)
+		mb.cpuWrite(hl, a)
+		mb.OAMBugTrigger(hl)
 		hl += 1
 		mb.Cpu.SetHL(hl)
 		mb.Cpu.Registers.PC += 1
@@ -412,8 +422,8 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		a := uint16(mb.Cpu.Registers.A)
-		mb.SetItem(hl, a)
-		mb.OAMBugTrigger(hl, 0)
+		mb.cpuWrite(hl, a)
+		mb.OAMBugTrigger(hl)
 		hl -= 1
 		mb.Cpu.SetHL(hl)
 		mb.Cpu.Registers.PC += 1
@@ -449,7 +459,7 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		d := uint16(mb.Cpu.Registers.D)
-		mb.SetItem(hl, d)
+		mb.cpuWrite(hl, d)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -513,7 +523,7 @@ var OPCODES = OpCodeMap{
 
 		var addr uint16 = 0xff00 + uint16(mb.Cpu.Registers.C)
 		a := uint16(mb.Cpu.Registers.A)
-		mb.SetItem(addr, a)
+		mb.cpuWrite(addr, a)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -522,7 +532,7 @@ var OPCODES = OpCodeMap{
 	0xf2: func(mb *Motherboard, value uint16) OpCycles {
 
 		var addr uint16 = 0xff00 + uint16(mb.Cpu.Registers.C)
-		a := mb.GetItem(addr)
+		a := mb.cpuRead(addr)
 		mb.Cpu.Registers.A = a
 		mb.Cpu.Registers.PC += 1
 		return 8
@@ -533,7 +543,8 @@ var OPCODES = OpCodeMap{
 	0x03: func(mb *Motherboard, value uint16) OpCycles {
 
 		bc := mb.Cpu.BC()
-		mb.OAMBugTrigger(bc, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(bc)
 		bc += 1
 		mb.Cpu.SetBC(bc)
 		mb.Cpu.Registers.PC += 1
@@ -544,7 +555,8 @@ var OPCODES = OpCodeMap{
 	0x13: func(mb *Motherboard, value uint16) OpCycles {
 
 		de := mb.Cpu.DE()
-		mb.OAMBugTrigger(de, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(de)
 		de += 1
 		mb.Cpu.SetDE(de)
 		mb.Cpu.Registers.PC += 1
@@ -555,7 +567,8 @@ var OPCODES = OpCodeMap{
 	0x23: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.OAMBugTrigger(hl, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(hl)
 		hl += 1
 		mb.Cpu.SetHL(hl)
 		mb.Cpu.Registers.PC += 1
@@ -565,7 +578,8 @@ var OPCODES = OpCodeMap{
 	// INC SP - Increment SP (51)
 	0x33: func(mb *Motherboard, value uint16) OpCycles {
 
-		mb.OAMBugTrigger(mb.Cpu.Registers.SP, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(mb.Cpu.Registers.SP)
 		mb.Cpu.Registers.SP += 1
 		mb.Cpu.Registers.PC += 1
 		return 8
@@ -599,7 +613,7 @@ var OPCODES = OpCodeMap{
 	0x73: func(mb *Motherboard, value uint16) OpCycles {
 		hl := mb.Cpu.HL()
 		e := uint16(mb.Cpu.Registers.E)
-		mb.SetItem(hl, e)
+		mb.cpuWrite(hl, e)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -684,11 +698,11 @@ var OPCODES = OpCodeMap{
 	0x34: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		v := mb.GetItem(hl)
+		v := mb.cpuRead(hl)
 		v = mb.Cpu.Inc(v)
 
 		v16 := uint16(v)
-		mb.SetItem(hl, v16)
+		mb.cpuWrite(hl, v16)
 		mb.Cpu.Registers.PC += 1
 		return 12
 	},
@@ -722,7 +736,7 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		h := uint16(mb.Cpu.Registers.H)
-		mb.SetItem(hl, h)
+		mb.cpuWrite(hl, h)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -769,8 +783,9 @@ var OPCODES = OpCodeMap{
 
 			pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 			pcl := mb.Cpu.Registers.PC & 0xff
-			mb.SetItem(sp1, pch)
-			mb.SetItem(sp2, pcl)
+			mb.cpuIdle()
+			mb.cpuWrite(sp1, pch)
+			mb.cpuWrite(sp2, pcl)
 			mb.Cpu.Registers.SP -= 2
 			mb.Cpu.Registers.PC = value
 			return 24
@@ -789,8 +804,9 @@ var OPCODES = OpCodeMap{
 
 			pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 			pcl := mb.Cpu.Registers.PC & 0xff
-			mb.SetItem(sp1, pch)
-			mb.SetItem(sp2, pcl)
+			mb.cpuIdle()
+			mb.cpuWrite(sp1, pch)
+			mb.cpuWrite(sp2, pcl)
 			mb.Cpu.Registers.SP -= 2
 			mb.Cpu.Registers.PC = value
 			return 24
@@ -831,11 +847,11 @@ var OPCODES = OpCodeMap{
 	0x35: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		v := mb.GetItem(hl)
+		v := mb.cpuRead(hl)
 		v = mb.Cpu.Dec(v)
 
 		v16 := uint16(v)
-		mb.SetItem(hl, v16)
+		mb.cpuWrite(hl, v16)
 		mb.Cpu.Registers.PC += 1
 		return 12
 	},
@@ -869,7 +885,7 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		l := uint16(mb.Cpu.Registers.L)
-		mb.SetItem(hl, l)
+		mb.cpuWrite(hl, l)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -912,13 +928,14 @@ var OPCODES = OpCodeMap{
 		sp := mb.Cpu.Registers.SP
 		sp1 := sp - 1
 		sp2 := sp - 2
-		mb.OAMBugTrigger(sp, 0)
-		mb.OAMBugTrigger(sp1, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(sp)
+		mb.OAMBugTrigger(sp1)
 
 		br := uint16(mb.Cpu.Registers.B)
 		cr := uint16(mb.Cpu.Registers.C)
-		mb.SetItem(sp1, br)
-		mb.SetItem(sp2, cr)
+		mb.cpuWrite(sp1, br)
+		mb.cpuWrite(sp2, cr)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC += 1
 		return 16
@@ -930,13 +947,14 @@ var OPCODES = OpCodeMap{
 		sp := mb.Cpu.Registers.SP
 		sp1 := sp - 1
 		sp2 := sp - 2
-		mb.OAMBugTrigger(sp, 0)
-		mb.OAMBugTrigger(sp1, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(sp)
+		mb.OAMBugTrigger(sp1)
 
 		dr := uint16(mb.Cpu.Registers.D)
 		er := uint16(mb.Cpu.Registers.E)
-		mb.SetItem(sp1, dr)
-		mb.SetItem(sp2, er)
+		mb.cpuWrite(sp1, dr)
+		mb.cpuWrite(sp2, er)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC += 1
 		return 16
@@ -948,13 +966,14 @@ var OPCODES = OpCodeMap{
 		sp := mb.Cpu.Registers.SP
 		sp1 := sp - 1
 		sp2 := sp - 2
-		mb.OAMBugTrigger(sp, 0)
-		mb.OAMBugTrigger(sp1, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(sp)
+		mb.OAMBugTrigger(sp1)
 
 		hr := uint16(mb.Cpu.Registers.H)
 		lr := uint16(mb.Cpu.Registers.L)
-		mb.SetItem(sp1, hr)
-		mb.SetItem(sp2, lr)
+		mb.cpuWrite(sp1, hr)
+		mb.cpuWrite(sp2, lr)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC += 1
 		return 16
@@ -966,13 +985,14 @@ var OPCODES = OpCodeMap{
 		sp := mb.Cpu.Registers.SP
 		sp1 := sp - 1
 		sp2 := sp - 2
-		mb.OAMBugTrigger(sp, 0)
-		mb.OAMBugTrigger(sp1, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(sp)
+		mb.OAMBugTrigger(sp1)
 
 		ar := uint16(mb.Cpu.Registers.A)
 		fr := uint16(mb.Cpu.Registers.F)
-		mb.SetItem(sp1, ar)
-		mb.SetItem(sp2, fr)
+		mb.cpuWrite(sp1, ar)
+		mb.cpuWrite(sp2, fr)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC += 1
 		return 16
@@ -1008,7 +1028,7 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		value &= 0xff
-		mb.SetItem(hl, value)
+		mb.cpuWrite(hl, value)
 		mb.Cpu.Registers.PC += 2
 		return 12
 	},
@@ -1017,7 +1037,7 @@ var OPCODES = OpCodeMap{
 	0x46: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.B = mb.GetItem(hl)
+		mb.Cpu.Registers.B = mb.cpuRead(hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1026,7 +1046,7 @@ var OPCODES = OpCodeMap{
 	0x56: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.D = mb.GetItem(hl)
+		mb.Cpu.Registers.D = mb.cpuRead(hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1035,7 +1055,7 @@ var OPCODES = OpCodeMap{
 	0x66: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.H = mb.GetItem(hl)
+		mb.Cpu.Registers.H = mb.cpuRead(hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1058,7 +1078,7 @@ var OPCODES = OpCodeMap{
 	0x86: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.Cpu.AddSetFlags8(mb.Cpu.Registers.A, mb.GetItem(hl))
+		mb.Cpu.Registers.A = mb.Cpu.AddSetFlags8(mb.Cpu.Registers.A, mb.cpuRead(hl))
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1067,7 +1087,7 @@ var OPCODES = OpCodeMap{
 	0x96: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.Cpu.SubSetFlags8(mb.Cpu.Registers.A, mb.GetItem(hl))
+		mb.Cpu.Registers.A = mb.Cpu.SubSetFlags8(mb.Cpu.Registers.A, mb.cpuRead(hl))
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1076,7 +1096,7 @@ var OPCODES = OpCodeMap{
 	0xa6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.Cpu.AndSetFlags(mb.Cpu.Registers.A, mb.GetItem(hl))
+		mb.Cpu.Registers.A = mb.Cpu.AndSetFlags(mb.Cpu.Registers.A, mb.cpuRead(hl))
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1085,7 +1105,7 @@ var OPCODES = OpCodeMap{
 	0xb6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.Cpu.OrSetFlags(mb.Cpu.Registers.A, mb.GetItem(hl))
+		mb.Cpu.Registers.A = mb.Cpu.OrSetFlags(mb.Cpu.Registers.A, mb.cpuRead(hl))
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1258,7 +1278,7 @@ var OPCODES = OpCodeMap{
 
 		hl := mb.Cpu.HL()
 		a := uint16(mb.Cpu.Registers.A)
-		mb.SetItem(hl, a)
+		mb.cpuWrite(hl, a)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -1304,8 +1324,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC = 0x00
 		return 16
@@ -1320,8 +1341,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC = 0x10
 		return 16
@@ -1336,8 +1358,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC = 0x20
 		return 16
@@ -1352,8 +1375,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 		mb.Cpu.Registers.PC = 0x30
 		return 16
@@ -1370,8 +1394,8 @@ var OPCODES = OpCodeMap{
 		addr2 := value + 1
 		value2 := (mb.Cpu.Registers.SP >> 8) & 0xFF
 
-		mb.SetItem(addr1, value1)
-		mb.SetItem(addr2, value2)
+		mb.cpuWrite(addr1, value1)
+		mb.cpuWrite(addr2, value2)
 
 		mb.Cpu.Registers.PC += 3
 		return 20
@@ -1481,10 +1505,11 @@ var OPCODES = OpCodeMap{
 	0xc8: func(mb *Motherboard, value uint16) OpCycles {
 
 		mb.Cpu.Registers.PC += 1
+		mb.cpuIdle() // condition check
 		if mb.Cpu.IsFlagZSet() {
 			nsp := mb.Cpu.Registers.SP + 1
-			pcl := mb.GetItem(mb.Cpu.Registers.SP)
-			pch := mb.GetItem(nsp)
+			pcl := mb.cpuRead(mb.Cpu.Registers.SP)
+			pch := mb.cpuRead(nsp)
 			mb.Cpu.Registers.SP += 2
 			mb.Cpu.Registers.PC = uint16(pch)<<8 | uint16(pcl)
 			return 20
@@ -1497,10 +1522,11 @@ var OPCODES = OpCodeMap{
 	0xd8: func(mb *Motherboard, value uint16) OpCycles {
 
 		mb.Cpu.Registers.PC += 1
+		mb.cpuIdle() // condition check
 		if mb.Cpu.IsFlagCSet() {
 			nsp := mb.Cpu.Registers.SP + 1
-			pcl := mb.GetItem(mb.Cpu.Registers.SP)
-			pch := mb.GetItem(nsp)
+			pcl := mb.cpuRead(mb.Cpu.Registers.SP)
+			pch := mb.cpuRead(nsp)
 			mb.Cpu.Registers.SP += 2
 			mb.Cpu.Registers.PC = uint16(pch)<<8 | uint16(pcl)
 			return 20
@@ -1663,8 +1689,8 @@ var OPCODES = OpCodeMap{
 	0xc9: func(mb *Motherboard, value uint16) OpCycles {
 
 		sp2 := mb.Cpu.Registers.SP + 1
-		pcl := mb.GetItem(mb.Cpu.Registers.SP)
-		pch := mb.GetItem(sp2)
+		pcl := mb.cpuRead(mb.Cpu.Registers.SP)
+		pch := mb.cpuRead(sp2)
 		mb.Cpu.Registers.SP += 2
 		mb.Cpu.Registers.PC = uint16(pch)<<8 | uint16(pcl)
 		return 16
@@ -1675,8 +1701,8 @@ var OPCODES = OpCodeMap{
 
 		// mb.Cpu.Interrupts.Master_Enable = true
 		mb.Cpu.Interrupts.InterruptsEnabling = true
-		pcl := mb.GetItem(mb.Cpu.Registers.SP)
-		pch := mb.GetItem(mb.Cpu.Registers.SP + 1)
+		pcl := mb.cpuRead(mb.Cpu.Registers.SP)
+		pch := mb.cpuRead(mb.Cpu.Registers.SP + 1)
 		mb.Cpu.Registers.SP += 2
 		mb.Cpu.Registers.PC = uint16(pch)<<8 | uint16(pcl)
 		return 16
@@ -1701,7 +1727,7 @@ var OPCODES = OpCodeMap{
 	0x0A: func(mb *Motherboard, value uint16) OpCycles {
 
 		bc := mb.Cpu.BC()
-		a := mb.GetItem(bc)
+		a := mb.cpuRead(bc)
 		mb.Cpu.Registers.A = uint8(a)
 		mb.Cpu.Registers.PC += 1
 		return 8
@@ -1711,7 +1737,7 @@ var OPCODES = OpCodeMap{
 	0x1A: func(mb *Motherboard, value uint16) OpCycles {
 
 		de := mb.Cpu.DE()
-		a := mb.GetItem(de)
+		a := mb.cpuRead(de)
 		mb.Cpu.Registers.A = uint8(a)
 		mb.Cpu.Registers.PC += 1
 		return 8
@@ -1721,9 +1747,9 @@ var OPCODES = OpCodeMap{
 	0x2A: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		a := mb.GetItem(hl)
+		a := mb.cpuRead(hl)
 		mb.Cpu.Registers.A = uint8(a)
-		mb.OAMBugTrigger(hl, 0)
+		mb.OAMBugTrigger(hl)
 		hl += 1
 		mb.Cpu.SetHL(hl)
 		mb.Cpu.Registers.PC += 1
@@ -1734,9 +1760,9 @@ var OPCODES = OpCodeMap{
 	0x3A: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		a := mb.GetItem(hl)
+		a := mb.cpuRead(hl)
 		mb.Cpu.Registers.A = uint8(a)
-		mb.OAMBugTrigger(hl, 0)
+		mb.OAMBugTrigger(hl)
 		hl -= 1
 		mb.Cpu.SetHL(hl)
 		mb.Cpu.Registers.PC += 1
@@ -1833,7 +1859,7 @@ var OPCODES = OpCodeMap{
 	0xea: func(mb *Motherboard, value uint16) OpCycles {
 
 		a := uint16(mb.Cpu.Registers.A)
-		mb.SetItem(value, a)
+		mb.cpuWrite(value, a)
 		mb.Cpu.Registers.PC += 3
 		return 16
 	},
@@ -1841,7 +1867,7 @@ var OPCODES = OpCodeMap{
 	// LD A, (a16) - Load A from given address (250)
 	0xfa: func(mb *Motherboard, value uint16) OpCycles {
 
-		a := mb.GetItem(value)
+		a := mb.cpuRead(value)
 		mb.Cpu.Registers.A = uint8(a)
 		mb.Cpu.Registers.PC += 3
 		return 16
@@ -1852,7 +1878,8 @@ var OPCODES = OpCodeMap{
 	0x0B: func(mb *Motherboard, value uint16) OpCycles {
 
 		bc := mb.Cpu.BC()
-		mb.OAMBugTrigger(bc, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(bc)
 		bc -= 1
 		mb.Cpu.SetBC(bc)
 		mb.Cpu.Registers.PC += 1
@@ -1863,7 +1890,8 @@ var OPCODES = OpCodeMap{
 	0x1B: func(mb *Motherboard, value uint16) OpCycles {
 
 		de := mb.Cpu.DE()
-		mb.OAMBugTrigger(de, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(de)
 		de -= 1
 		mb.Cpu.SetDE(de)
 		mb.Cpu.Registers.PC += 1
@@ -1874,7 +1902,8 @@ var OPCODES = OpCodeMap{
 	0x2B: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.OAMBugTrigger(hl, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(hl)
 		hl -= 1
 		mb.Cpu.SetHL(hl)
 		mb.Cpu.Registers.PC += 1
@@ -1884,7 +1913,8 @@ var OPCODES = OpCodeMap{
 	// DEC SP - Decrement SP (59)
 	0x3B: func(mb *Motherboard, value uint16) OpCycles {
 
-		mb.OAMBugTrigger(mb.Cpu.Registers.SP, 0)
+		mb.cpuIdle()
+		mb.OAMBugTrigger(mb.Cpu.Registers.SP)
 		mb.Cpu.Registers.SP -= 1
 		mb.Cpu.Registers.PC += 1
 		return 8
@@ -2081,8 +2111,9 @@ var OPCODES = OpCodeMap{
 
 			pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 			pcl := mb.Cpu.Registers.PC & 0xff
-			mb.SetItem(sp1, pch)
-			mb.SetItem(sp2, pcl)
+			mb.cpuIdle()
+			mb.cpuWrite(sp1, pch)
+			mb.cpuWrite(sp2, pcl)
 			mb.Cpu.Registers.SP -= 2
 
 			mb.Cpu.Registers.PC = value
@@ -2105,8 +2136,9 @@ var OPCODES = OpCodeMap{
 
 			pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 			pcl := mb.Cpu.Registers.PC & 0xff
-			mb.SetItem(sp1, pch)
-			mb.SetItem(sp2, pcl)
+			mb.cpuIdle()
+			mb.cpuWrite(sp1, pch)
+			mb.cpuWrite(sp2, pcl)
 			mb.Cpu.Registers.SP -= 2
 
 			mb.Cpu.Registers.PC = value
@@ -2224,8 +2256,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 
 		mb.Cpu.Registers.PC = value
@@ -2271,7 +2304,7 @@ var OPCODES = OpCodeMap{
 	0x4E: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.C = mb.GetItem(hl)
+		mb.Cpu.Registers.C = mb.cpuRead(hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -2280,7 +2313,7 @@ var OPCODES = OpCodeMap{
 	0x5E: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.E = mb.GetItem(hl)
+		mb.Cpu.Registers.E = mb.cpuRead(hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -2289,7 +2322,7 @@ var OPCODES = OpCodeMap{
 	0x6E: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.L = mb.GetItem(hl)
+		mb.Cpu.Registers.L = mb.cpuRead(hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -2298,7 +2331,7 @@ var OPCODES = OpCodeMap{
 	0x7E: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.GetItem(hl)
+		mb.Cpu.Registers.A = mb.cpuRead(hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -2307,7 +2340,7 @@ var OPCODES = OpCodeMap{
 	0x8E: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.Cpu.AdcSetFlags8(mb.Cpu.Registers.A, mb.GetItem(hl))
+		mb.Cpu.Registers.A = mb.Cpu.AdcSetFlags8(mb.Cpu.Registers.A, mb.cpuRead(hl))
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -2316,7 +2349,7 @@ var OPCODES = OpCodeMap{
 	0x9E: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.Cpu.SbcSetFlags8(mb.Cpu.Registers.A, mb.GetItem(hl))
+		mb.Cpu.Registers.A = mb.Cpu.SbcSetFlags8(mb.Cpu.Registers.A, mb.cpuRead(hl))
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -2325,7 +2358,7 @@ var OPCODES = OpCodeMap{
 	0xae: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		mb.Cpu.Registers.A = mb.Cpu.XorSetFlags(mb.Cpu.Registers.A, mb.GetItem(hl))
+		mb.Cpu.Registers.A = mb.Cpu.XorSetFlags(mb.Cpu.Registers.A, mb.cpuRead(hl))
 		mb.Cpu.Registers.PC += 1
 		return 8
 	},
@@ -2334,7 +2367,7 @@ var OPCODES = OpCodeMap{
 	0xbe: func(mb *Motherboard, value uint16) OpCycles {
 
 		addr := mb.Cpu.HL()
-		hl := mb.GetItem(addr)
+		hl := mb.cpuRead(addr)
 		mb.Cpu.CpSetFlags(mb.Cpu.Registers.A, hl)
 		mb.Cpu.Registers.PC += 1
 		return 8
@@ -2517,8 +2550,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 
 		mb.Cpu.Registers.PC = 0x08
@@ -2534,8 +2568,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 
 		mb.Cpu.Registers.PC = 0x18
@@ -2551,8 +2586,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 
 		mb.Cpu.Registers.PC = 0x28
@@ -2568,8 +2604,9 @@ var OPCODES = OpCodeMap{
 
 		pch := (mb.Cpu.Registers.PC >> 8) & 0xff
 		pcl := mb.Cpu.Registers.PC & 0xff
-		mb.SetItem(sp1, pch)
-		mb.SetItem(sp2, pcl)
+		mb.cpuIdle()
+		mb.cpuWrite(sp1, pch)
+		mb.cpuWrite(sp2, pcl)
 		mb.Cpu.Registers.SP -= 2
 
 		mb.Cpu.Registers.PC = 0x38
@@ -3907,7 +3944,7 @@ var OPCODES = OpCodeMap{
 	0x106: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagZ()
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.ResetFlagH()
@@ -3923,7 +3960,7 @@ var OPCODES = OpCodeMap{
 			mb.Cpu.SetFlagZ()
 		}
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -3932,7 +3969,7 @@ var OPCODES = OpCodeMap{
 	0x116: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagZ()
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.ResetFlagH()
@@ -3954,7 +3991,7 @@ var OPCODES = OpCodeMap{
 			mb.Cpu.SetFlagZ()
 		}
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -3963,7 +4000,7 @@ var OPCODES = OpCodeMap{
 	0x126: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagZ()
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.ResetFlagH()
@@ -3979,7 +4016,7 @@ var OPCODES = OpCodeMap{
 			mb.Cpu.SetFlagZ()
 		}
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -3988,7 +4025,7 @@ var OPCODES = OpCodeMap{
 	0x136: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagZ()
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.ResetFlagH()
@@ -4001,7 +4038,7 @@ var OPCODES = OpCodeMap{
 		}
 
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4010,7 +4047,7 @@ var OPCODES = OpCodeMap{
 	0x146: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 
@@ -4026,7 +4063,7 @@ var OPCODES = OpCodeMap{
 	0x156: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 
@@ -4042,7 +4079,7 @@ var OPCODES = OpCodeMap{
 	0x166: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 
@@ -4058,7 +4095,7 @@ var OPCODES = OpCodeMap{
 	0x176: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 		mb.Cpu.SetFlagZ()
@@ -4073,10 +4110,10 @@ var OPCODES = OpCodeMap{
 	0x186: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 0)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4085,10 +4122,10 @@ var OPCODES = OpCodeMap{
 	0x196: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 2)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4097,10 +4134,10 @@ var OPCODES = OpCodeMap{
 	0x1A6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 4)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4109,10 +4146,10 @@ var OPCODES = OpCodeMap{
 	0x1B6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 6)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4121,10 +4158,10 @@ var OPCODES = OpCodeMap{
 	0x1C6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 0)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4133,10 +4170,10 @@ var OPCODES = OpCodeMap{
 	0x1D6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 2)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4145,10 +4182,10 @@ var OPCODES = OpCodeMap{
 	0x1E6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 4)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -4157,10 +4194,10 @@ var OPCODES = OpCodeMap{
 	0x1F6: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 6)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5757,7 +5794,7 @@ var OPCODES = OpCodeMap{
 	0x10e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagZ()
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.ResetFlagH()
@@ -5775,7 +5812,7 @@ var OPCODES = OpCodeMap{
 		}
 
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5784,7 +5821,7 @@ var OPCODES = OpCodeMap{
 	0x11e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagZ()
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.ResetFlagH()
@@ -5808,7 +5845,7 @@ var OPCODES = OpCodeMap{
 		}
 
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5817,7 +5854,7 @@ var OPCODES = OpCodeMap{
 	0x12e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetAllFlags()
 
 		if b&0x01 != 0 {
@@ -5836,7 +5873,7 @@ var OPCODES = OpCodeMap{
 		}
 
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5845,7 +5882,7 @@ var OPCODES = OpCodeMap{
 	0x13e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagZ()
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.ResetFlagH()
@@ -5864,7 +5901,7 @@ var OPCODES = OpCodeMap{
 		}
 
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5873,7 +5910,7 @@ var OPCODES = OpCodeMap{
 	0x14e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 
@@ -5889,7 +5926,7 @@ var OPCODES = OpCodeMap{
 	0x15e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 
@@ -5905,7 +5942,7 @@ var OPCODES = OpCodeMap{
 	0x16e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 		mb.Cpu.SetFlagZ()
@@ -5921,7 +5958,7 @@ var OPCODES = OpCodeMap{
 	0x17e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		mb.Cpu.ResetFlagN()
 		mb.Cpu.SetFlagH()
 		mb.Cpu.SetFlagZ()
@@ -5937,10 +5974,10 @@ var OPCODES = OpCodeMap{
 	0x18e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 1)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5949,10 +5986,10 @@ var OPCODES = OpCodeMap{
 	0x19e: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 3)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5961,10 +5998,10 @@ var OPCODES = OpCodeMap{
 	0x1AE: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 5)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5973,10 +6010,10 @@ var OPCODES = OpCodeMap{
 	0x1BE: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.ResetBit(&b, 7)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5985,10 +6022,10 @@ var OPCODES = OpCodeMap{
 	0x1CE: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 1)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -5997,10 +6034,10 @@ var OPCODES = OpCodeMap{
 	0x1DE: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 3)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -6009,10 +6046,10 @@ var OPCODES = OpCodeMap{
 	0x1EE: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 5)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -6021,10 +6058,10 @@ var OPCODES = OpCodeMap{
 	0x1FE: func(mb *Motherboard, value uint16) OpCycles {
 
 		hl := mb.Cpu.HL()
-		b := mb.GetItem(hl)
+		b := mb.cpuRead(hl)
 		internal.SetBit(&b, 7)
 		b16 := uint16(b)
-		mb.SetItem(hl, b16)
+		mb.cpuWrite(hl, b16)
 		mb.Cpu.Registers.PC += 2
 		return 16
 	},
@@ -734,8 +734,7 @@ func TestCB_SET(t *testing.T) {
 func TestCB_AllOpcodesPresentInMap(t *testing.T) {
 	missing := []OpCode{}
 	for op := OpCode(0x100); op <= 0x1FF; op++ {
-		fn, ok := OPCODES[op]
-		if !ok || fn == nil {
+		if OPCODES[op] == nil {
 			missing = append(missing, op)
 		}
 	}
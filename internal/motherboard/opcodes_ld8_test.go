@@ -775,7 +775,6 @@ func TestLD8_CoverageCensus(t *testing.T) {
 
 	// Every claimed opcode must actually exist in OPCODES (catches typos).
 	for op := range covered {
-		_, ok := OPCODES[op]
-		assert.Truef(t, ok, "claimed opcode %#x not present in OPCODES", op)
+		assert.NotNilf(t, OPCODES[op], "claimed opcode %#x not present in OPCODES", op)
 	}
 }
@@ -10,9 +10,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestOpcodeTable_AllEntriesNonNil(t *testing.T) {
-	require.NotEmpty(t, OPCODES, "OPCODES map must not be empty")
+func TestOpcodeTable_OnlyIllegalEntriesNil(t *testing.T) {
+	require.Len(t, OPCODES, 0x200, "OPCODES covers base and CB-prefixed opcodes")
 	for op, fn := range OPCODES {
+		if illegalOpcodes[op] {
+			continue
+		}
 		assert.NotNil(t, fn, "opcode %#x has nil handler", op)
 	}
 }
@@ -21,8 +24,11 @@ func TestOpcodeTable_TotalCount(t *testing.T) {
 	// 256 base opcodes minus 11 illegal opcodes = 245 base handlers, plus
 	// 256 CB-prefixed handlers (keyed at CB_SHIFT + n) = 501 total.
 	base, cb := 0, 0
-	for op := range OPCODES {
-		if op >= CB_SHIFT {
+	for op, fn := range OPCODES {
+		if fn == nil {
+			continue
+		}
+		if OpCode(op) >= CB_SHIFT {
 			cb++
 		} else {
 			base++
@@ -30,22 +36,28 @@ func TestOpcodeTable_TotalCount(t *testing.T) {
 	}
 	assert.Equal(t, 245, base, "base opcode count (256 - 11 illegal)")
 	assert.Equal(t, 256, cb, "CB-prefixed opcode count")
-	assert.Equal(t, 501, len(OPCODES), "total OPCODES entries")
+	assert.Equal(t, 501, base+cb, "total OPCODES handlers")
 }
 
 func TestOpcodeTable_IllegalOpcodesAreAbsent(t *testing.T) {
 	for _, op := range ILLEGAL_OPCODES {
-		_, ok := OPCODES[op]
-		assert.False(t, ok, "illegal opcode %#x must not have a handler", op)
+		assert.Nil(t, OPCODES[op], "illegal opcode %#x must not have a handler", op)
 		assert.True(t, op.IsIllegal(), "IsIllegal() must return true for %#x", op)
 	}
 }
 
-func TestOpcodeTable_AllKeysWithinValidRange(t *testing.T) {
-	// Base opcodes occupy 0x00..0xFF; CB-prefixed live at 0x100..0x1FF.
-	for op := range OPCODES {
-		assert.LessOrEqual(t, op, OpCode(0x1FF), "opcode %#x out of range", op)
+func TestOpcodeTable_IllegalFlagsMatchList(t *testing.T) {
+	// Only the listed base opcodes are illegal; every CB-prefixed one is valid.
+	illegal := 0
+	for op := OpCode(0); op <= 0x1FF; op++ {
+		if op.IsIllegal() {
+			illegal++
+		}
 	}
+	assert.Equal(t, len(ILLEGAL_OPCODES), illegal)
+
+	outOfRange := OpCode(0x200)
+	assert.False(t, outOfRange.IsIllegal(), "opcodes past the table are not flagged")
 }
 
 func TestOpcodeTable_CBPrefixDetection(t *testing.T) {
@@ -68,7 +80,7 @@ func TestOpcodeTable_NonIllegalBaseOpcodesAllPresent(t *testing.T) {
 		if _, isBad := illegal[op]; isBad {
 			continue
 		}
-		if _, ok := OPCODES[op]; !ok {
+		if OPCODES[op] == nil {
 			missing = append(missing, op)
 		}
 	}
@@ -79,7 +91,7 @@ func TestOpcodeTable_NonIllegalBaseOpcodesAllPresent(t *testing.T) {
 func TestOpcodeTable_CBOpcodesContiguous(t *testing.T) {
 	missing := []OpCode{}
 	for op := CB_SHIFT; op <= CB_SHIFT+0xFF; op++ {
-		if _, ok := OPCODES[op]; !ok {
+		if OPCODES[op] == nil {
 			missing = append(missing, op)
 		}
 	}
@@ -213,9 +225,19 @@ func TestCPU_ExecuteInstruction_LDB_d8_FromWRAM(t *testing.T) {
 	assert.Equal(t, uint16(0xC002), cpu.Registers.PC)
 }
 
+func TestCPU_ExecuteInstruction_PCHistoryOffByDefault(t *testing.T) {
+	cpu, mb := newTestCPU(t)
+	cpu.Registers.PC = 0xC000
+	mb.SetItem(0xC000, 0x00)
+
+	cpu.ExecuteInstruction()
+	assert.Zero(t, cpu.PcHist.Len(), "PC history is only recorded when RecordHist is set")
+}
+
 func TestCPU_ExecuteInstruction_PCHistoryGrowsThenCaps(t *testing.T) {
 	cpu, mb := newTestCPU(t)
 	cpu.Registers.PC = 0xC000
+	cpu.RecordHist = true
 
 	// Lay down a long ribbon of NOPs.
 	for addr := uint16(0xC000); addr < 0xC020; addr++ {
@@ -224,8 +246,8 @@ func TestCPU_ExecuteInstruction_PCHistoryGrowsThenCaps(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		cpu.ExecuteInstruction()
 	}
-	assert.LessOrEqual(t, cpu.PcHist.Len(), PC_HISTORY_COUNT_MAX,
-		"PC history must never exceed PC_HISTORY_COUNT_MAX (%d)", PC_HISTORY_COUNT_MAX)
+	assert.Equal(t, PC_HISTORY_COUNT_MAX, cpu.PcHist.Len(),
+		"PC history must fill up to, and never exceed, PC_HISTORY_COUNT_MAX (%d)", PC_HISTORY_COUNT_MAX)
 }
 
 // Smoke-tests every OPCODES entry: invoke once with a deterministic setup
@@ -237,10 +259,11 @@ func TestOpcodeTable_AllHandlersExecutable(t *testing.T) {
 	cpu := mb.Cpu
 
 	keys := make([]OpCode, 0, len(OPCODES))
-	for k := range OPCODES {
-		keys = append(keys, k)
+	for k, fn := range OPCODES {
+		if fn != nil {
+			keys = append(keys, OpCode(k))
+		}
 	}
-	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
 
 	resetCPU := func() {
 		cpu.Registers.A = 0x12
@@ -23,8 +23,8 @@ const (
 	PaletteCrimson
 )
 
-// CurrentPalette is the global current DMG palette.
-var CurrentPalette = PaletteBGB
+// DefaultPalette is the DMG palette a new Motherboard starts with.
+const DefaultPalette = PaletteBGB
 
 // Palettes is an mapping from colour palettes to their colour values
 // to be used by the emulator.
@@ -73,10 +73,10 @@ var Palettes = [][][]byte{
 	},
 }
 
-// GetPaletteColour returns the colour based on the colour index and the currently
-// selected palette.
-func GetPaletteColour(index byte) (uint8, uint8, uint8) {
-	col := Palettes[CurrentPalette][index]
+// GetPaletteColour returns the colour based on the colour index and the
+// DMG palette currently selected on this motherboard.
+func (m *Motherboard) GetPaletteColour(index byte) (uint8, uint8, uint8) {
+	col := Palettes[m.DmgPalette][index]
 	return col[0], col[1], col[2]
 }
 
@@ -90,8 +90,9 @@ func NewPalette() *cgbPalette {
 	return &cgbPalette{Palette: pal}
 }
 
-func ChangePallete() {
-	CurrentPalette = (CurrentPalette + 1) % byte(len(Palettes))
+// ChangePalette cycles this motherboard to the next DMG palette.
+func (m *Motherboard) ChangePalette() {
+	m.DmgPalette = (m.DmgPalette + 1) % byte(len(Palettes))
 }
 
 // Palette for cgb containing information tracking the palette colour info.
@@ -165,7 +166,7 @@ func (pal *cgbPalette) get(palette byte, num byte) (uint8, uint8, uint8) {
 }
 
 // Mapping of the 5 bit colour value to a 8 bit value.
-var colArr = []uint8{
+var colArr = [32]uint8{
 	0x0, // 0
 	0x8,
 	0x10,
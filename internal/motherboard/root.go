@@ -140,7 +140,7 @@ const (
 type OpCode uint16                                        // 16-bit opcodes
 type OpCycles int64                                       // Number of cycles an operation takes
 type OpLogic func(mb *Motherboard, value uint16) OpCycles // Operation logic
-type OpCodeMap map[OpCode]OpLogic                         // Map of opcodes to their logic
+type OpCodeTable [2 * CB_SHIFT]OpLogic                    // Opcode logic indexed by opcode, CB-prefixed ones at CB_SHIFT+n
 const (
 	// ButtonA is the A button on the GameBoy.
 	ButtonA = 0
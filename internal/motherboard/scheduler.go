@@ -0,0 +1,172 @@
+/*
+* Event scheduler that drives the peripherals between CPU instructions.
+*
+* Stepping every peripheral after every instruction is the simplest way to
+* keep them in lock-step with the CPU, but almost all of those steps change
+* nothing a program can observe. Instead each peripheral reports how many
+* cycles remain until its next visible state change (a PPU mode or line
+* change, a TIMA overflow, a frame-sequencer step, an RTC second) and is
+* only stepped once that many cycles have passed. Everything in between is
+* caught up lazily, in a single step, when the CPU touches one of the
+* peripheral's registers.
+*
+* Peripherals are still only ever stepped up to an instruction boundary, and
+* at a boundary they are stepped in the same order Motherboard.Tick has
+* always used, so a lazily driven machine is indistinguishable from an
+* eagerly driven one.
+ */
+
+package motherboard
+
+import "math"
+
+// Peripherals driven by the scheduler, in the order they are stepped when
+// several fall due at the same instruction boundary.
+const (
+	evCartridge = iota // cartridge RTC
+	evTimer            // DIV / TIMA
+	evLCD              // PPU
+	evAPU              // APU
+	evDMA              // OAM DMA
+	evSerial           // serial transfer
+	evInfrared         // CGB infrared port clock
+	evCount
+)
+
+// evNever marks a peripheral with no pending event.
+const evNever = OpCycles(math.MaxInt64)
+
+// maxHaltSkip bounds how far a halted CPU is fast-forwarded in one Tick, so
+// host-side input still lands within a scanline of when it was pressed.
+const maxHaltSkip OpCycles = 456
+
+type scheduler struct {
+	now  OpCycles          // cycles elapsed up to the current instruction boundary
+	last [evCount]OpCycles // boundary each peripheral has been stepped up to
+	next [evCount]OpCycles // boundary at which each peripheral must be stepped again
+	due  OpCycles          // earliest entry in next
+}
+
+// step advances peripheral id by the given number of cycles.
+func (m *Motherboard) step(id int, cycles OpCycles) {
+	switch id {
+	case evCartridge:
+		m.Cartridge.Tick(uint64(cycles))
+	case evTimer:
+		m.Timer.Tick(cycles, m.Cpu)
+	case evLCD:
+		m.Lcd.Tick(cycles)
+	case evAPU:
+		m.Sound.Tick(cycles)
+	case evDMA:
+		m.Dma.Tick(cycles)
+	case evSerial:
+		m.Serial.Tick(cycles)
+	case evInfrared:
+		m.Infrared.Tick(cycles)
+	}
+}
+
+// cyclesToEvent reports how many cycles peripheral id can run before it
+// must be stepped again. ok is false when it has nothing pending.
+func (m *Motherboard) cyclesToEvent(id int) (cycles OpCycles, ok bool) {
+	switch id {
+	case evCartridge:
+		c, ok := m.Cartridge.NextEvent()
+		return OpCycles(c), ok
+	case evTimer:
+		return m.Timer.nextEvent()
+	case evLCD:
+		return m.Lcd.nextEvent()
+	case evAPU:
+		return m.Sound.nextEvent()
+	case evDMA:
+		return m.Dma.nextEvent()
+	case evSerial:
+		return m.Serial.nextEvent()
+	case evInfrared:
+		return m.Infrared.nextEvent()
+	}
+	return 0, false
+}
+
+// sync catches peripheral id up to the current instruction boundary. It is
+// called before the CPU reads or writes one of the peripheral's registers.
+func (m *Motherboard) sync(id int) {
+	s := &m.sched
+	if delta := s.now - s.last[id]; delta > 0 {
+		s.last[id] = s.now
+		m.step(id, delta)
+	}
+}
+
+// reschedule recomputes when peripheral id next needs stepping. It is
+// called after anything that may have moved its next event, such as a
+// register write.
+func (m *Motherboard) reschedule(id int) {
+	s := &m.sched
+	s.next[id] = evNever
+	if cycles, ok := m.cyclesToEvent(id); ok {
+		s.next[id] = s.last[id] + max(cycles, 1)
+	}
+	s.due = evNever
+	for _, next := range s.next {
+		s.due = min(s.due, next)
+	}
+}
+
+// scheduleNext makes peripheral id due at the next instruction boundary,
+// for register writes whose effect the peripheral only picks up when it is
+// next stepped.
+func (m *Motherboard) scheduleNext(id int) {
+	s := &m.sched
+	s.next[id] = s.now + 1
+	s.due = min(s.due, s.next[id])
+}
+
+// syncAll catches every peripheral up to the current instruction boundary.
+func (m *Motherboard) syncAll() {
+	for id := range evCount {
+		m.sync(id)
+	}
+}
+
+// rescheduleAll recomputes every peripheral's next event. Peripherals must
+// already be in sync.
+func (m *Motherboard) rescheduleAll() {
+	for id := range evCount {
+		m.sched.last[id] = m.sched.now
+		m.reschedule(id)
+	}
+}
+
+// advance moves the current instruction boundary forward and steps every
+// peripheral whose next event has been reached.
+func (m *Motherboard) advance(cycles OpCycles) {
+	s := &m.sched
+	s.now += cycles
+	if s.now < s.due {
+		return
+	}
+	for id := range evCount {
+		if s.next[id] <= s.now {
+			m.sync(id)
+			m.reschedule(id)
+		}
+	}
+}
+
+// haltCycles returns how long a halted CPU idles this Tick. The CPU only
+// wakes for an interrupt, and interrupts are only raised by peripheral
+// events, so it skips straight to the boundary of the next event in the
+// same 4-cycle steps it would otherwise have taken one by one.
+func (m *Motherboard) haltCycles() OpCycles {
+	const idle OpCycles = 4
+
+	intr := m.Cpu.Interrupts
+	if intr.InterruptsEnabling || intr.IF&intr.IE&0x1F != 0 {
+		return idle
+	}
+	wait := min(max(m.sched.due-m.sched.now, idle), maxHaltSkip)
+	return (wait + idle - 1) / idle * idle
+}
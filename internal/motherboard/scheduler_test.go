@@ -0,0 +1,101 @@
+package motherboard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSchedulerTestMb builds a DMG machine past the boot ROM, with program
+// placed at $0150 (jumped to from the $0100 entry point) and RETI on the
+// VBlank, STAT and timer vectors.
+func newSchedulerTestMb(t testing.TB, program ...byte) *Motherboard {
+	t.Helper()
+
+	rom := subsysFakeROMBytes()
+	copy(rom[0x100:], []byte{0xC3, 0x50, 0x01}) // JP $0150
+	copy(rom[0x150:], program)
+	for _, vec := range []int{0x40, 0x48, 0x50} {
+		rom[vec] = 0xD9 // RETI
+	}
+
+	mb := newMbFromBytes(t, rom)
+	mb.SkipBootROM()
+	return mb
+}
+
+// schedulerStressProgram keeps the timer, PPU and APU all raising events
+// while it sleeps in HALT. On every wake-up it logs DIV, TIMA and NR52 to
+// WRAM, then polls STAT for a while to catch PPU mode changes in the act.
+var schedulerStressProgram = []byte{
+	0x31, 0xFE, 0xFF, // LD SP,$FFFE
+	0x21, 0x00, 0xC0, // LD HL,$C000
+	0x3E, 0x05, 0xE0, 0x07, // TAC = enabled, 16 cycles
+	0x3E, 0xF0, 0xE0, 0x06, // TMA = $F0
+	0x3E, 0x40, 0xE0, 0x45, // LYC = 64
+	0x3E, 0x68, 0xE0, 0x41, // STAT = LYC, mode 2 and mode 0 interrupts
+	0x3E, 0x3F, 0xE0, 0x11, // NR11 = shortest length
+	0x3E, 0xF0, 0xE0, 0x12, // NR12 = DAC on
+	0x3E, 0xC0, 0xE0, 0x14, // NR14 = trigger with length enabled
+	0x3E, 0x91, 0xE0, 0x40, // LCDC = on
+	0x3E, 0x07, 0xE0, 0xFF, // IE = VBlank, STAT, timer
+	0xFB, // EI
+	// loop:
+	0x76, 0x00, // HALT ; NOP
+	0xF0, 0x04, 0x22, // LDH A,(DIV) ; LD (HL+),A
+	0xF0, 0x05, 0x22, // LDH A,(TIMA) ; LD (HL+),A
+	0xF0, 0x26, 0x22, // LDH A,(NR52) ; LD (HL+),A
+	0x06, 0x08, // LD B,8
+	// poll:
+	0xF0, 0x41, 0x22, // LDH A,(STAT) ; LD (HL+),A
+	0x05, 0x20, 0xFA, // DEC B ; JR NZ,poll
+	0xCB, 0xA4, // RES 4,H
+	0x18, 0xE9, // JR loop
+}
+
+func TestScheduler_LazyMatchesEagerStepping(t *testing.T) {
+	lazy := newSchedulerTestMb(t, schedulerStressProgram...)
+	eager := newSchedulerTestMb(t, schedulerStressProgram...)
+
+	for frame := uint64(1); frame <= 10; frame++ {
+		for lazy.Lcd.FrameCount < frame {
+			okLazy, lazyCycles := lazy.Tick()
+			okEager, eagerCycles := eager.Tick()
+			eager.syncAll() // step every peripheral at every boundary, as before the scheduler
+			require.True(t, okLazy && okEager)
+			require.Equal(t, eagerCycles, lazyCycles)
+		}
+	}
+
+	assert.Equal(t, eager.Memory.Wram, lazy.Memory.Wram)
+	assert.True(t, bytes.Equal(eager.Serialize().Bytes(), lazy.Serialize().Bytes()),
+		"lazily stepped peripherals diverged from eagerly stepped ones")
+}
+
+func TestMotherboard_HaltSkipsToNextEvent(t *testing.T) {
+	mb := newSchedulerTestMb(t, 0x76, 0x18, 0xFD) // loop: HALT ; JR loop
+	mb.Cpu.Interrupts.InterruptsOn = false
+	mb.SetItem(0xFF40, 0x00) // LCD off, so only the timer is pending
+	mb.SetItem(0xFF04, 0x00)
+	mb.SetItem(0xFF07, 0x04) // enabled, 1024 cycles per increment
+	mb.SetItem(0xFF05, 0xFE)
+	mb.SetItem(0xFFFF, 1<<INTR_TIMER)
+
+	mb.Tick() // JP $0150
+	ticks, elapsed := 0, OpCycles(0)
+	for ticks == 0 || mb.Cpu.Halted {
+		ok, cycles := mb.Tick()
+		require.True(t, ok)
+		assert.LessOrEqual(t, cycles, maxHaltSkip+20)
+		ticks++
+		elapsed += cycles
+		require.Less(t, ticks, 100, "CPU never woke up")
+	}
+
+	// HALT itself, then idle up to the overflow 2×1024 cycles after the
+	// timer was started, then the 20-cycle wake-up.
+	assert.Equal(t, OpCycles(2*1024-16+20), elapsed)
+	assert.Less(t, ticks, 10, "halted CPU should skip ahead instead of idling 4 cycles at a time")
+}
@@ -0,0 +1,253 @@
+/*
+* Implements the serial port (SB $FF01, SC $FF02).
+*
+* Writing SC with bit 7 set starts a transfer: SB is shifted out MSB first,
+* one bit per shift clock, while the bit on the other end of the cable is
+* shifted in at the bottom. After eight clocks SC bit 7 clears and the
+* serial interrupt is requested.
+*
+* With the internal clock (SC bit 0) the Game Boy drives the shift clock
+* itself at 8192 Hz, or at 262144 Hz when the CGB fast-clock bit (SC bit 1)
+* is set. With the external clock the transfer only moves when whatever is
+* on the other end clocks it through ClockIn; with nothing attached it
+* never completes, just as on hardware.
+ */
+
+package motherboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	serialBitCycles     = 512 // cycles per shift clock at 8192 Hz
+	serialFastBitCycles = 16  // cycles per shift clock at 262144 Hz (CGB)
+	SerialPollCycles    = 456 // cycles between polls of a SerialPoller
+)
+
+// SerialDevice is a peripheral plugged into the link port.
+type SerialDevice interface {
+	// ExchangeBit is called on every shift clock the Game Boy drives. out
+	// is the bit leaving SB; the result is the bit shifted into it.
+	ExchangeBit(out bool) (in bool)
+}
+
+// SerialPoller is a SerialDevice that can also clock transfers itself,
+// such as another machine at the far end of a link cable. Poll is called
+// every SerialPollCycles cycles while it is plugged in, on the goroutine
+// running the machine, and may call ClockIn.
+type SerialPoller interface {
+	SerialDevice
+	Poll()
+}
+
+// ConnectCable links two machines' serial ports directly. Each side's
+// internally clocked transfers clock the other side's external-clock
+// transfer bit by bit, so both machines must be stepped from the same
+// goroutine.
+func ConnectCable(a, b *Serial) {
+	a.Connect(cableEnd{b})
+	b.Connect(cableEnd{a})
+}
+
+type cableEnd struct {
+	peer *Serial
+}
+
+func (c cableEnd) ExchangeBit(out bool) bool {
+	return c.peer.ClockIn(out)
+}
+
+// ByteDevice adapts a peripheral that deals in whole bytes to a
+// SerialDevice. exchange is called with each byte the Game Boy sends once
+// its last bit is out, and returns the byte the peripheral shifts back
+// during the next transfer, as a real peripheral preloads its own shift
+// register. Until the first byte arrives it answers with $FF.
+func ByteDevice(exchange func(out uint8) uint8) SerialDevice {
+	return &byteDevice{exchange: exchange, reply: 0xFF}
+}
+
+type byteDevice struct {
+	exchange func(uint8) uint8
+	sent     uint8 // bits of the byte being received, MSB first
+	reply    uint8 // bits still to be shifted back, MSB first
+	bits     uint8 // bits of the current byte exchanged so far
+}
+
+func (d *byteDevice) ExchangeBit(out bool) bool {
+	in := d.reply&0x80 != 0
+	d.reply = d.reply<<1 | 1
+	d.sent <<= 1
+	if out {
+		d.sent |= 1
+	}
+	if d.bits++; d.bits == 8 {
+		d.bits = 0
+		d.reply = d.exchange(d.sent)
+	}
+	return in
+}
+
+// NewSerialWriter returns a device that copies every byte the Game Boy
+// sends to w, as test ROMs use the link port to report their results. It
+// answers with $FF, like an unplugged cable.
+func NewSerialWriter(w io.Writer) SerialDevice {
+	return ByteDevice(func(out uint8) uint8 {
+		w.Write([]byte{out})
+		return 0xFF
+	})
+}
+
+type Serial struct {
+	SB      uint8    // Serial transfer data (0xFF01)
+	SC      uint8    // Serial transfer control (0xFF02), writable bits only
+	Bits    uint8    // bits shifted in the current transfer
+	Counter OpCycles // cycles until the next internal shift clock
+	device  SerialDevice
+	poller  SerialPoller // device, if it needs polling
+	pollIn  OpCycles     // cycles until poller is next polled
+	mb      *Motherboard
+}
+
+func NewSerial(mb *Motherboard) *Serial {
+	return &Serial{mb: mb}
+}
+
+// Reset clears the port. The attached device stays connected.
+func (s *Serial) Reset() {
+	*s = Serial{device: s.device, poller: s.poller, pollIn: SerialPollCycles, mb: s.mb}
+}
+
+func (s *Serial) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, s.SB)      // Serial transfer data
+	binary.Write(buf, binary.LittleEndian, s.SC)      // Serial transfer control
+	binary.Write(buf, binary.LittleEndian, s.Bits)    // Bits shifted
+	binary.Write(buf, binary.LittleEndian, s.Counter) // Cycles until the next shift clock
+	return buf
+}
+
+func (s *Serial) Deserialize(data *bytes.Buffer) error {
+	for _, v := range []any{&s.SB, &s.SC, &s.Bits, &s.Counter} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect plugs dev into the link port, replacing whatever was there. A
+// nil dev unplugs it.
+func (s *Serial) Connect(dev SerialDevice) {
+	s.mb.sync(evSerial)
+	s.device = dev
+	s.poller, _ = dev.(SerialPoller)
+	s.pollIn = SerialPollCycles
+	s.mb.reschedule(evSerial)
+}
+
+// ReadSC returns SC as the CPU sees it, unused bits reading 1.
+func (s *Serial) ReadSC() uint8 {
+	if s.mb.Cgb {
+		return s.SC | 0x7C
+	}
+	return s.SC | 0x7E
+}
+
+// WriteSC stores a CPU write to SC, starting a transfer if bit 7 is set.
+func (s *Serial) WriteSC(v uint8) {
+	if s.mb.Cgb {
+		s.SC = v & 0x83
+	} else {
+		s.SC = v & 0x81
+	}
+	s.Bits = 0
+	s.Counter = s.bitCycles()
+}
+
+// ClockIn shifts one bit through SB on a clock driven from the other end
+// of the cable, and returns the bit shifted out. It does nothing, and
+// reads as an idle line, unless a transfer is waiting on the external
+// clock.
+func (s *Serial) ClockIn(in bool) (out bool) {
+	if s.SC&0x81 != 0x80 {
+		return true
+	}
+	return s.shift(in)
+}
+
+// Tick advances an internally clocked transfer by the given number of
+// cycles, polling the device on the way if it asks for it.
+func (s *Serial) Tick(cycles OpCycles) {
+	for cycles > 0 {
+		n := cycles
+		if s.internalTransfer() {
+			n = min(n, s.Counter)
+		}
+		if s.poller != nil {
+			n = min(n, s.pollIn)
+		}
+		cycles -= n
+
+		if s.internalTransfer() {
+			if s.Counter -= n; s.Counter == 0 {
+				s.Counter = s.bitCycles()
+				in := true
+				if s.device != nil {
+					in = s.device.ExchangeBit(s.SB&0x80 != 0)
+				}
+				s.shift(in)
+			}
+		}
+		if s.poller != nil {
+			if s.pollIn -= n; s.pollIn == 0 {
+				s.pollIn = SerialPollCycles
+				s.poller.Poll()
+			}
+		}
+	}
+}
+
+// nextEvent returns the cycles left until the running transfer completes
+// and raises the serial interrupt, or until the device is next polled.
+// The clocks before a transfer completes are only seen through SB reads
+// and the attached device, so they are caught up lazily.
+func (s *Serial) nextEvent() (OpCycles, bool) {
+	cycles, ok := OpCycles(0), false
+	if s.internalTransfer() {
+		cycles, ok = s.Counter+OpCycles(7-s.Bits)*s.bitCycles(), true
+	}
+	if s.poller != nil && (!ok || s.pollIn < cycles) {
+		cycles, ok = s.pollIn, true
+	}
+	return cycles, ok
+}
+
+func (s *Serial) internalTransfer() bool {
+	return s.SC&0x81 == 0x81
+}
+
+func (s *Serial) bitCycles() OpCycles {
+	if s.mb.Cgb && s.SC&0x02 != 0 {
+		return serialFastBitCycles
+	}
+	return serialBitCycles
+}
+
+// shift moves SB along by one clock and finishes the transfer after the
+// eighth.
+func (s *Serial) shift(in bool) (out bool) {
+	out = s.SB&0x80 != 0
+	s.SB <<= 1
+	if in {
+		s.SB |= 1
+	}
+	if s.Bits++; s.Bits == 8 {
+		s.Bits = 0
+		s.SC &^= 0x80
+		s.mb.Cpu.SetInterruptFlag(INTR_SERIAL)
+	}
+	return out
+}
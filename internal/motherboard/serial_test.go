@@ -0,0 +1,149 @@
+package motherboard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bitRecorder is a SerialDevice that records the bits it is clocked and
+// answers with the bits of reply, MSB first.
+type bitRecorder struct {
+	got   []bool
+	reply uint8
+}
+
+func (r *bitRecorder) ExchangeBit(out bool) bool {
+	r.got = append(r.got, out)
+	in := r.reply&0x80 != 0
+	r.reply <<= 1
+	return in
+}
+
+func serialIRQ(mb *Motherboard) bool {
+	return mb.Cpu.Interrupts.IF&(1<<INTR_SERIAL) != 0
+}
+
+func TestSerial_InternalClockTransfer(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cgb    bool
+		sc     uint16
+		cycles int
+	}{
+		{"8192 Hz", false, 0x81, 8 * serialBitCycles},
+		{"fast clock ignored on DMG", false, 0x83, 8 * serialBitCycles},
+		{"CGB fast clock", true, 0x83, 8 * serialFastBitCycles},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mb := newMbForSubsysTestMode(t, tc.cgb)
+			dev := &bitRecorder{reply: 0xC3}
+			mb.Serial.Connect(dev)
+
+			mb.SetItem(0xFF01, 0x5A)
+			mb.SetItem(0xFF02, tc.sc)
+			for range tc.cycles/4 - 1 {
+				mb.advance(4)
+			}
+			assert.False(t, serialIRQ(mb), "transfer finished early")
+			assert.NotZero(t, mb.GetItem(0xFF02)&0x80, "SC bit 7 cleared early")
+
+			mb.advance(4)
+			assert.True(t, serialIRQ(mb))
+			assert.Zero(t, mb.GetItem(0xFF02)&0x80)
+			assert.Equal(t, uint8(0xC3), mb.GetItem(0xFF01))
+			assert.Equal(t, []bool{false, true, false, true, true, false, true, false}, dev.got)
+		})
+	}
+}
+
+func TestSerial_UnpluggedReadsFF(t *testing.T) {
+	mb := newMbForSubsysTest(t)
+	mb.SetItem(0xFF01, 0x00)
+	mb.SetItem(0xFF02, 0x81)
+	for range 8 * serialBitCycles / 4 {
+		mb.advance(4)
+	}
+	assert.True(t, serialIRQ(mb))
+	assert.Equal(t, uint8(0xFF), mb.GetItem(0xFF01))
+}
+
+func TestSerial_ExternalClock(t *testing.T) {
+	mb := newMbForSubsysTest(t)
+	mb.SetItem(0xFF01, 0x81)
+	mb.SetItem(0xFF02, 0x80)
+	for range 16 * serialBitCycles / 4 {
+		mb.advance(4)
+	}
+	require.False(t, serialIRQ(mb), "nothing drives the clock")
+
+	var out uint8
+	for i := range 8 {
+		bit := mb.Serial.ClockIn(i%2 == 0)
+		out <<= 1
+		if bit {
+			out |= 1
+		}
+	}
+	assert.Equal(t, uint8(0x81), out)
+	assert.Equal(t, uint8(0xAA), mb.GetItem(0xFF01))
+	assert.True(t, serialIRQ(mb))
+	assert.True(t, mb.Serial.ClockIn(false), "idle once the transfer is done")
+}
+
+func TestSerial_ReadSCUnusedBits(t *testing.T) {
+	dmg := newMbForSubsysTest(t)
+	dmg.SetItem(0xFF02, 0x00)
+	assert.Equal(t, uint8(0x7E), dmg.GetItem(0xFF02))
+
+	cgb := newCGBMbForSubsysTest(t)
+	cgb.SetItem(0xFF02, 0x02)
+	assert.Equal(t, uint8(0x7E), cgb.GetItem(0xFF02))
+	cgb.SetItem(0xFF02, 0x00)
+	assert.Equal(t, uint8(0x7C), cgb.GetItem(0xFF02))
+}
+
+func TestSerial_ByteDeviceRepliesOnNextTransfer(t *testing.T) {
+	var got []uint8
+	dev := ByteDevice(func(out uint8) uint8 {
+		got = append(got, out)
+		return ^out
+	})
+	exchange := func(out uint8) (in uint8) {
+		for i := 7; i >= 0; i-- {
+			in <<= 1
+			if dev.ExchangeBit(out>>i&1 != 0) {
+				in |= 1
+			}
+		}
+		return in
+	}
+
+	assert.Equal(t, uint8(0xFF), exchange(0x12))
+	assert.Equal(t, uint8(0xED), exchange(0x34))
+	assert.Equal(t, []uint8{0x12, 0x34}, got)
+}
+
+func TestSerial_WriterCapturesTestROMOutput(t *testing.T) {
+	// LD A,c : LDH (SB),A : LD A,$81 : LDH (SC),A, then wait for SC bit 7
+	// to clear, for each character, as Blargg's test ROMs print.
+	var program []byte
+	for _, c := range []byte("ok\n") {
+		program = append(program,
+			0x3E, c, 0xE0, 0x01,
+			0x3E, 0x81, 0xE0, 0x02,
+			0xF0, 0x02, 0xCB, 0x7F, 0x20, 0xFA, // LDH A,(SC) : BIT 7,A : JR NZ
+		)
+	}
+	program = append(program, 0x18, 0xFE) // JR -2
+	mb := newSchedulerTestMb(t, program...)
+	var buf bytes.Buffer
+	mb.Serial.Connect(NewSerialWriter(&buf))
+
+	for range 4 * 8 * serialBitCycles {
+		mb.Tick()
+	}
+	assert.Equal(t, "ok\n", buf.String())
+}
@@ -0,0 +1,518 @@
+/*
+* Implements the Super Game Boy side of the machine.
+*
+* The SGB is a DMG wired to a SNES. The game talks to the SNES by sending
+* 16-byte packets through the joypad register: a reset pulse (P14 and P15
+* both low), 128 bits least significant first, each a pulse of P14 low for
+* a 0 or P15 low for a 1 with both lines going high in between, and a 0
+* stop bit. The first byte holds the command in its top five bits and the
+* number of packets the command takes in the low three.
+*
+* The SNES colours the 160x144 picture through four 4-colour palettes,
+* picked per 8x8 cell by the attribute map, and draws a 256x224 border
+* around it. Bulk data (border tiles and map, the system palettes and
+* attribute files) is sent by showing it on screen: the *_TRN commands
+* read the next frame back as 4KB of tile data, tiles $00-$FF laid out
+* 20 to a row.
+*
+* Only what the game can do through packets is emulated; the SGB BIOS's
+* own border, menus and built-in palettes are not, so until a game sets
+* them the screen is grey and the border is blank.
+*
+* References:
+*   - Pan Docs, "Super Game Boy"
+ */
+
+package motherboard
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/duysqubix/gobc/internal"
+)
+
+// SGB commands, by the number in the top five bits of the first byte.
+const (
+	sgbPAL01   = 0x00
+	sgbPAL23   = 0x01
+	sgbPAL03   = 0x02
+	sgbPAL12   = 0x03
+	sgbATTRBLK = 0x04
+	sgbATTRLIN = 0x05
+	sgbATTRDIV = 0x06
+	sgbATTRCHR = 0x07
+	sgbPALSET  = 0x0A
+	sgbPALTRN  = 0x0B
+	sgbMLTREQ  = 0x11
+	sgbCHRTRN  = 0x13
+	sgbPCTTRN  = 0x14
+	sgbATTRTRN = 0x15
+	sgbATTRSET = 0x16
+	sgbMASKEN  = 0x17
+)
+
+// MASK_EN modes.
+const (
+	sgbMaskOff    = 0 // show the picture
+	sgbMaskFreeze = 1 // keep showing the last frame
+	sgbMaskBlack  = 2 // black screen
+	sgbMaskColor0 = 3 // screen filled with colour 0
+)
+
+const (
+	sgbPacketSize = 16
+	sgbMaxPackets = 7
+	sgbCellsX     = internal.GB_SCREEN_WIDTH / 8
+	sgbCellsY     = internal.GB_SCREEN_HEIGHT / 8
+	sgbATFs       = 45                        // attribute files ATTR_TRN sends
+	sgbATFSize    = sgbCellsX * sgbCellsY / 4 // two bits per cell
+	sgbBorderX    = (internal.SGB_SCREEN_WIDTH - internal.GB_SCREEN_WIDTH) / 2
+	sgbBorderY    = (internal.SGB_SCREEN_HEIGHT - internal.GB_SCREEN_HEIGHT) / 2
+)
+
+// SGBFrame is a composed SGB picture: the border with the Game Boy
+// screen in the middle.
+type SGBFrame [internal.SGB_SCREEN_WIDTH][internal.SGB_SCREEN_HEIGHT][3]uint8
+
+// sgbGrey is what the screen shows in before the game sets any palettes.
+var sgbGrey = [4]uint16{0x7FFF, 0x5294, 0x294A, 0x0000}
+
+type SGB struct {
+	enabled bool // the cartridge header lets the game send commands
+
+	// packet reception
+	p1        uint8 // P14 and P15 as last written, in bits 0-1
+	receiving bool  // between a reset pulse and the stop bit
+	bit       int   // bits of the current packet received
+	packet    [sgbPacketSize]uint8
+	command   [sgbMaxPackets * sgbPacketSize]uint8
+	packets   int   // packets of command received
+	transfer  uint8 // *_TRN command waiting for the next frame, or 0
+	trnArg    uint8 // its first parameter byte
+
+	// multiplayer (MLT_REQ)
+	players uint8 // joypads the game reads: 1, 2 or 4
+	player  uint8 // joypad P1 reads, from 0
+
+	// picture
+	palettes  [4][4]uint16 // SGB palettes 0-3, RGB555; colour 0 is shared
+	attrs     [sgbCellsY][sgbCellsX]uint8
+	mask      uint8
+	sysPals   [512][4]uint16 // from PAL_TRN, for PAL_SET
+	atfs      [sgbATFs][sgbATFSize]uint8
+	tiles     [256][32]uint8                                             // border tiles, SNES 4bpp
+	tilemap   [32 * 28]uint16                                            // border map
+	borderPal [4][16]uint16                                              // border palettes 4-7
+	shades    [internal.GB_SCREEN_WIDTH][internal.GB_SCREEN_HEIGHT]uint8 // frame being drawn, as BGP/OBP shades
+
+	mb *Motherboard
+}
+
+func NewSGB(mb *Motherboard) *SGB {
+	s := &SGB{mb: mb}
+	s.Reset()
+	return s
+}
+
+func (s *SGB) Reset() {
+	*s = SGB{
+		enabled: s.mb.Sgb && s.mb.Cartridge.SgbFunctionsEnabled(),
+		p1:      3, // both lines idle high
+		players: 1,
+		mb:      s.mb,
+	}
+	for i := range s.palettes {
+		s.palettes[i] = sgbGrey
+	}
+}
+
+func (s *SGB) Serialize() *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, s.p1)             // P14/P15
+	binary.Write(buf, binary.LittleEndian, s.receiving)      // Receiving a packet
+	binary.Write(buf, binary.LittleEndian, int32(s.bit))     // Bits received
+	binary.Write(buf, binary.LittleEndian, s.packet)         // Packet
+	binary.Write(buf, binary.LittleEndian, s.command)        // Command so far
+	binary.Write(buf, binary.LittleEndian, int32(s.packets)) // Packets received
+	binary.Write(buf, binary.LittleEndian, s.transfer)       // Pending transfer
+	binary.Write(buf, binary.LittleEndian, s.trnArg)         // Its parameter
+	binary.Write(buf, binary.LittleEndian, s.players)        // Joypads
+	binary.Write(buf, binary.LittleEndian, s.player)         // Selected joypad
+	binary.Write(buf, binary.LittleEndian, s.palettes)       // Palettes 0-3
+	binary.Write(buf, binary.LittleEndian, s.attrs)          // Attribute map
+	binary.Write(buf, binary.LittleEndian, s.mask)           // MASK_EN
+	binary.Write(buf, binary.LittleEndian, s.sysPals)        // System palettes
+	binary.Write(buf, binary.LittleEndian, s.atfs)           // Attribute files
+	binary.Write(buf, binary.LittleEndian, s.tiles)          // Border tiles
+	binary.Write(buf, binary.LittleEndian, s.tilemap)        // Border map
+	binary.Write(buf, binary.LittleEndian, s.borderPal)      // Border palettes
+	return buf
+}
+
+func (s *SGB) Deserialize(data *bytes.Buffer) error {
+	var bit, packets int32
+	for _, v := range []any{
+		&s.p1, &s.receiving, &bit, &s.packet, &s.command, &packets, &s.transfer, &s.trnArg,
+		&s.players, &s.player, &s.palettes, &s.attrs, &s.mask, &s.sysPals, &s.atfs,
+		&s.tiles, &s.tilemap, &s.borderPal,
+	} {
+		if err := binary.Read(data, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	s.bit, s.packets = int(bit), int(packets)
+	return nil
+}
+
+// WriteP1 watches a CPU write to P1 for packet bits and for the joypad
+// switches of multiplayer polling.
+func (s *SGB) WriteP1(v uint8) {
+	prev, p := s.p1, v>>4&3
+	s.p1 = p
+
+	// P15 going high moves on to the next joypad
+	if s.players > 1 && prev&2 == 0 && p&2 != 0 {
+		s.player = (s.player + 1) % s.players
+	}
+
+	if !s.enabled || p == prev {
+		return
+	}
+	switch p {
+	case 0:
+		s.receiving, s.bit = true, 0
+		s.packet = [sgbPacketSize]uint8{}
+	case 1, 2:
+		if !s.receiving || prev != 3 {
+			return
+		}
+		one := p == 1 // P15 low
+		if s.bit == sgbPacketSize*8 {
+			s.receiving = false
+			if !one {
+				s.endPacket()
+			}
+			return
+		}
+		if one {
+			s.packet[s.bit/8] |= 1 << (s.bit % 8)
+		}
+		s.bit++
+	}
+}
+
+// Joypad returns the joypad P1 reads, from 0, and whether a game has asked
+// for more than one.
+func (s *SGB) Joypad() (uint8, bool) {
+	return s.player, s.players > 1
+}
+
+// endPacket adds a whole packet to the command, running it once the last
+// of its packets is in.
+func (s *SGB) endPacket() {
+	copy(s.command[s.packets*sgbPacketSize:], s.packet[:])
+	s.packets++
+	length := max(int(s.command[0]&7), 1)
+	if s.packets < length {
+		return
+	}
+	s.packets = 0
+	s.run(s.command[:length*sgbPacketSize])
+}
+
+func (s *SGB) run(cmd []uint8) {
+	switch cmd[0] >> 3 {
+	case sgbPAL01:
+		s.setPalettes(0, 1, cmd)
+	case sgbPAL23:
+		s.setPalettes(2, 3, cmd)
+	case sgbPAL03:
+		s.setPalettes(0, 3, cmd)
+	case sgbPAL12:
+		s.setPalettes(1, 2, cmd)
+	case sgbATTRBLK:
+		s.attrBlock(cmd)
+	case sgbATTRLIN:
+		s.attrLines(cmd)
+	case sgbATTRDIV:
+		s.attrDivide(cmd)
+	case sgbATTRCHR:
+		s.attrCells(cmd)
+	case sgbPALSET:
+		for i := range s.palettes {
+			s.palettes[i] = s.sysPals[binary.LittleEndian.Uint16(cmd[1+i*2:])&0x1FF]
+			s.palettes[i][0] = s.palettes[0][0]
+		}
+		s.applyATF(cmd[9])
+	case sgbATTRSET:
+		s.applyATF(cmd[1] | 0x80)
+	case sgbMLTREQ:
+		s.players = [4]uint8{1, 2, 1, 4}[cmd[1]&3]
+		s.player = 0
+	case sgbMASKEN:
+		s.mask = cmd[1] & 3
+	case sgbPALTRN, sgbCHRTRN, sgbPCTTRN, sgbATTRTRN:
+		s.transfer, s.trnArg = cmd[0]>>3, cmd[1]
+	default:
+		logger.Debugf("sgb: ignoring command $%02X", cmd[0]>>3)
+	}
+}
+
+// setPalettes handles PAL01 and friends: colour 0 for every palette, then
+// colours 1-3 of palettes a and b.
+func (s *SGB) setPalettes(a, b int, cmd []uint8) {
+	colour := func(i int) uint16 {
+		return binary.LittleEndian.Uint16(cmd[1+i*2:]) & 0x7FFF
+	}
+	for i := range s.palettes {
+		s.palettes[i][0] = colour(0)
+	}
+	for c := 1; c < 4; c++ {
+		s.palettes[a][c] = colour(c)
+		s.palettes[b][c] = colour(c + 3)
+	}
+}
+
+// attrBlock handles ATTR_BLK: up to 18 rectangles, each colouring its
+// inside, its edge and everything outside it.
+func (s *SGB) attrBlock(cmd []uint8) {
+	for n, set := 0, cmd[2:]; n < int(cmd[1]) && len(set) >= 6; n, set = n+1, set[6:] {
+		ctrl := set[0] & 7
+		in, edge, out := set[1]&3, set[1]>>2&3, set[1]>>4&3
+		// colouring only the inside or only the outside takes the edge along
+		switch ctrl {
+		case 1:
+			ctrl, edge = 3, in
+		case 4:
+			ctrl, edge = 6, out
+		}
+		x1, y1, x2, y2 := set[2]&0x1F, set[3]&0x1F, set[4]&0x1F, set[5]&0x1F
+		for y := range uint8(sgbCellsY) {
+			for x := range uint8(sgbCellsX) {
+				switch {
+				case x > x1 && x < x2 && y > y1 && y < y2:
+					if ctrl&1 != 0 {
+						s.attrs[y][x] = in
+					}
+				case x >= x1 && x <= x2 && y >= y1 && y <= y2:
+					if ctrl&2 != 0 {
+						s.attrs[y][x] = edge
+					}
+				default:
+					if ctrl&4 != 0 {
+						s.attrs[y][x] = out
+					}
+				}
+			}
+		}
+	}
+}
+
+// attrLines handles ATTR_LIN: whole rows or columns of cells.
+func (s *SGB) attrLines(cmd []uint8) {
+	for n := 0; n < int(cmd[1]) && 2+n < len(cmd); n++ {
+		b := cmd[2+n]
+		line, pal := int(b&0x1F), b>>5&3
+		if b&0x80 != 0 {
+			if line < sgbCellsY {
+				for x := range sgbCellsX {
+					s.attrs[line][x] = pal
+				}
+			}
+		} else if line < sgbCellsX {
+			for y := range sgbCellsY {
+				s.attrs[y][line] = pal
+			}
+		}
+	}
+}
+
+// attrDivide handles ATTR_DIV: the screen split in two by a row or column
+// of cells.
+func (s *SGB) attrDivide(cmd []uint8) {
+	after, before, on := cmd[1]&3, cmd[1]>>2&3, cmd[1]>>4&3
+	rows := cmd[1]&0x40 != 0
+	at := int(cmd[2] & 0x1F)
+	for y := range sgbCellsY {
+		for x := range sgbCellsX {
+			pos := x
+			if rows {
+				pos = y
+			}
+			switch {
+			case pos < at:
+				s.attrs[y][x] = before
+			case pos == at:
+				s.attrs[y][x] = on
+			default:
+				s.attrs[y][x] = after
+			}
+		}
+	}
+}
+
+// attrCells handles ATTR_CHR: a palette for each cell in turn, from a
+// starting cell, across rows or down columns.
+func (s *SGB) attrCells(cmd []uint8) {
+	x, y := int(cmd[1]&0x1F), int(cmd[2]&0x1F)
+	count := min(int(binary.LittleEndian.Uint16(cmd[3:])), sgbCellsX*sgbCellsY)
+	down := cmd[5]&1 != 0
+	for i := 0; i < count && 6+i/4 < len(cmd) && x < sgbCellsX && y < sgbCellsY; i++ {
+		s.attrs[y][x] = cmd[6+i/4] >> (6 - 2*(i%4)) & 3
+		if down {
+			if y++; y == sgbCellsY {
+				y, x = 0, x+1
+			}
+		} else if x++; x == sgbCellsX {
+			x, y = 0, y+1
+		}
+	}
+}
+
+// applyATF loads attribute file flags&0x3F into the attribute map if bit 7
+// is set, and lifts the mask if bit 6 is.
+func (s *SGB) applyATF(flags uint8) {
+	if flags&0x80 != 0 {
+		atf := &s.atfs[min(int(flags&0x3F), sgbATFs-1)]
+		for i := range sgbCellsX * sgbCellsY {
+			s.attrs[i/sgbCellsX][i%sgbCellsX] = atf[i/4] >> (6 - 2*(i%4)) & 3
+		}
+	}
+	if flags&0x40 != 0 {
+		s.mask = sgbMaskOff
+	}
+}
+
+// setShade records the shade drawn at a pixel of the frame in progress.
+func (s *SGB) setShade(x, y, shade uint8) {
+	s.shades[x][y] = shade
+}
+
+// vramData reads the frame just drawn back as the 4KB a *_TRN command
+// sends: tiles $00-$FF, 20 to a row, as 2bpp tile data.
+func (s *SGB) vramData() []uint8 {
+	data := make([]uint8, 256*16)
+	for t := range 256 {
+		tx, ty := t%sgbCellsX*8, t/sgbCellsX*8
+		for row := range 8 {
+			var lo, hi uint8
+			for col := range 8 {
+				shade := s.shades[tx+col][ty+row]
+				lo |= shade & 1 << (7 - col)
+				hi |= shade >> 1 << (7 - col)
+			}
+			data[t*16+row*2], data[t*16+row*2+1] = lo, hi
+		}
+	}
+	return data
+}
+
+// finishTransfer takes in the data for the pending *_TRN command.
+func (s *SGB) finishTransfer() {
+	data := s.vramData()
+	switch s.transfer {
+	case sgbPALTRN:
+		for i := range s.sysPals {
+			for c := range 4 {
+				s.sysPals[i][c] = binary.LittleEndian.Uint16(data[i*8+c*2:]) & 0x7FFF
+			}
+		}
+	case sgbCHRTRN:
+		bank := int(s.trnArg&1) * 128
+		for i := range 128 {
+			copy(s.tiles[bank+i][:], data[i*32:])
+		}
+	case sgbPCTTRN:
+		for i := range s.tilemap {
+			s.tilemap[i] = binary.LittleEndian.Uint16(data[i*2:])
+		}
+		for p := range s.borderPal {
+			for c := range 16 {
+				s.borderPal[p][c] = binary.LittleEndian.Uint16(data[0x800+p*32+c*2:]) & 0x7FFF
+			}
+		}
+	case sgbATTRTRN:
+		for i := range s.atfs {
+			copy(s.atfs[i][:], data[i*sgbATFSize:])
+		}
+	}
+	s.transfer = 0
+}
+
+// endFrame runs at vblank in place of handing the frame straight to the
+// screen: it takes in any pending transfer and colours the frame, unless
+// the screen is masked.
+func (s *SGB) endFrame(out *ScreenData) {
+	if s.transfer != 0 {
+		s.finishTransfer()
+	}
+	switch s.mask {
+	case sgbMaskFreeze:
+	case sgbMaskBlack:
+		s.fill(out, 0x0000)
+	case sgbMaskColor0:
+		s.fill(out, s.palettes[0][0])
+	default:
+		for x := range internal.GB_SCREEN_WIDTH {
+			for y := range internal.GB_SCREEN_HEIGHT {
+				pal := s.attrs[y/8][x/8]
+				out[x][y] = rgb555(s.palettes[pal][s.shades[x][y]])
+			}
+		}
+	}
+}
+
+func (s *SGB) fill(out *ScreenData, colour uint16) {
+	c := rgb555(colour)
+	for x := range out {
+		for y := range out[x] {
+			out[x][y] = c
+		}
+	}
+}
+
+// Compose draws the border with the last frame in the middle. Border
+// colour 0 is see-through: outside the screen it shows SGB colour 0.
+func (s *SGB) Compose(out *SGBFrame) {
+	screen := &s.mb.Lcd.PreparedData
+	backdrop := rgb555(s.palettes[0][0])
+	for ty := range 28 {
+		for tx := range 32 {
+			entry := s.tilemap[ty*32+tx]
+			tile := &s.tiles[entry&0xFF]
+			pal := &s.borderPal[entry>>10&3]
+			for py := range 8 {
+				row := py
+				if entry&0x8000 != 0 {
+					row = 7 - py
+				}
+				for px := range 8 {
+					col := px
+					if entry&0x4000 == 0 {
+						col = 7 - px
+					}
+					c := tile[row*2]>>col&1 | tile[row*2+1]>>col&1<<1 |
+						tile[16+row*2]>>col&1<<2 | tile[16+row*2+1]>>col&1<<3
+
+					x, y := tx*8+px, ty*8+py
+					sx, sy := x-sgbBorderX, y-sgbBorderY
+					switch {
+					case c != 0:
+						out[x][y] = rgb555(pal[c])
+					case sx >= 0 && sx < internal.GB_SCREEN_WIDTH && sy >= 0 && sy < internal.GB_SCREEN_HEIGHT:
+						out[x][y] = screen[sx][sy]
+					default:
+						out[x][y] = backdrop
+					}
+				}
+			}
+		}
+	}
+}
+
+// rgb555 converts a SNES colour to 8 bits a channel, the same way as CGB
+// palette colours.
+func rgb555(c uint16) [3]uint8 {
+	return [3]uint8{colArr[c&0x1F], colArr[c>>5&0x1F], colArr[c>>10&0x1F]}
+}
@@ -0,0 +1,428 @@
+package motherboard
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden PNGs in testdata")
+
+// newSGBTestMb returns a Super Game Boy running an idle loop, with the
+// LCD on. sgbHeader sets the header bytes that let the game send
+// commands.
+func newSGBTestMb(t *testing.T, sgbHeader bool) *Motherboard {
+	t.Helper()
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0xC3, 0x50, 0x01}) // JP $0150
+	copy(rom[0x150:], []byte{0x00, 0x18, 0xFD}) // NOP; JR -3
+	copy(rom[0x134:], "SGBTEST")
+	if sgbHeader {
+		rom[0x146] = 0x03
+		rom[0x14B] = 0x33
+	}
+	var checksum uint8
+	for i := 0x134; i <= 0x14C; i++ {
+		checksum -= rom[i] + 1
+	}
+	rom[0x14D] = checksum
+
+	mb, err := NewMotherboard(&MotherboardParams{Rom: rom, RomName: "sgbtest", Sgb: true})
+	require.NoError(t, err)
+	mb.SkipBootROM()
+	return mb
+}
+
+// sgbSend sends a command through P1 the way the SGB BIOS expects,
+// splitting it over as many packets as it takes.
+func sgbSend(mb *Motherboard, command uint8, data ...uint8) {
+	packets := (len(data) + sgbPacketSize) / sgbPacketSize
+	cmd := make([]uint8, packets*sgbPacketSize)
+	cmd[0] = command<<3 | uint8(packets)
+	copy(cmd[1:], data)
+
+	for p := range packets {
+		mb.SetItem(0xFF00, 0x00) // reset pulse
+		mb.SetItem(0xFF00, 0x30)
+		for _, b := range cmd[p*sgbPacketSize : (p+1)*sgbPacketSize] {
+			for bit := range 8 {
+				if b>>bit&1 != 0 {
+					mb.SetItem(0xFF00, 0x10)
+				} else {
+					mb.SetItem(0xFF00, 0x20)
+				}
+				mb.SetItem(0xFF00, 0x30)
+			}
+		}
+		mb.SetItem(0xFF00, 0x20) // stop bit
+		mb.SetItem(0xFF00, 0x30)
+	}
+}
+
+func runFrames(mb *Motherboard, n int) {
+	for end := mb.Lcd.FrameCount + uint64(n); mb.Lcd.FrameCount < end; {
+		mb.Tick()
+	}
+}
+
+// showScreen switches the LCD off, loads tile data and a tile map for the
+// BG, and switches it back on with the plain palette.
+func showScreen(mb *Motherboard, tiles []uint8, tilemap func(x, y int) uint8) {
+	mb.SetItem(0xFF40, 0x00)
+	for i, b := range tiles {
+		mb.SetItem(0x8000+uint16(i), uint16(b))
+	}
+	for y := range 32 {
+		for x := range 32 {
+			mb.SetItem(0x9800+uint16(y*32+x), uint16(tilemap(x, y)))
+		}
+	}
+	mb.SetItem(0xFF47, 0xE4)
+	mb.SetItem(0xFF40, 0x91)
+}
+
+// sgbTransfer sends 4KB with one of the *_TRN commands, showing it on
+// screen as tiles $00-$FF for the SGB to read back.
+func sgbTransfer(mb *Motherboard, command uint8, data []uint8, params ...uint8) {
+	showScreen(mb, data, func(x, y int) uint8 {
+		if x >= sgbCellsX {
+			return 0
+		}
+		return uint8(y*sgbCellsX + x)
+	})
+	sgbSend(mb, command, params...)
+	runFrames(mb, 2)
+}
+
+// shadeTiles returns tiles 0-3, solid in shades 0-3.
+func shadeTiles() []uint8 {
+	tiles := make([]uint8, 4*16)
+	for t := range 4 {
+		for row := range 8 {
+			tiles[t*16+row*2] = uint8(-(t & 1))
+			tiles[t*16+row*2+1] = uint8(-(t >> 1))
+		}
+	}
+	return tiles
+}
+
+// showShades fills the screen with diagonal stripes of all four shades.
+func showShades(mb *Motherboard) {
+	showScreen(mb, shadeTiles(), func(x, y int) uint8 { return uint8((x + y) % 4) })
+}
+
+func rgb(r, g, b uint16) []uint8 {
+	c := r | g<<5 | b<<10
+	return []uint8{uint8(c), uint8(c >> 8)}
+}
+
+func concatBytes(parts ...[]uint8) []uint8 {
+	var all []uint8
+	for _, p := range parts {
+		all = append(all, p...)
+	}
+	return all
+}
+
+func TestSGB_Palettes(t *testing.T) {
+	mb := newSGBTestMb(t, true)
+	sgbSend(mb, sgbPAL01, concatBytes(
+		rgb(31, 31, 31),
+		rgb(1, 0, 0), rgb(2, 0, 0), rgb(3, 0, 0),
+		rgb(0, 1, 0), rgb(0, 2, 0), rgb(0, 3, 0),
+	)...)
+	sgbSend(mb, sgbPAL23, concatBytes(
+		rgb(0, 0, 31),
+		rgb(0, 0, 1), rgb(0, 0, 2), rgb(0, 0, 3),
+		rgb(1, 1, 1), rgb(2, 2, 2), rgb(3, 3, 3),
+	)...)
+
+	blue := uint16(31 << 10)
+	assert.Equal(t, [4]uint16{blue, 1, 2, 3}, mb.Super.palettes[0])
+	assert.Equal(t, [4]uint16{blue, 1 << 5, 2 << 5, 3 << 5}, mb.Super.palettes[1])
+	assert.Equal(t, [4]uint16{blue, 1 << 10, 2 << 10, 3 << 10}, mb.Super.palettes[2])
+	assert.Equal(t, [4]uint16{blue, 0x0421, 0x0842, 0x0C63}, mb.Super.palettes[3], "colour 0 is shared")
+
+	off := newSGBTestMb(t, false)
+	sgbSend(off, sgbPAL01, rgb(31, 31, 31)...)
+	assert.Equal(t, sgbGrey, off.Super.palettes[0], "the header does not enable SGB functions")
+}
+
+func TestSGB_Attributes(t *testing.T) {
+	mb := newSGBTestMb(t, true)
+	attrs := &mb.Super.attrs
+
+	// the screen split at column 5: 1 to the left, 2 on it, 3 to the right
+	sgbSend(mb, sgbATTRDIV, 0x01<<2|0x02<<4|0x03, 5)
+	assert.Equal(t, uint8(1), attrs[10][4])
+	assert.Equal(t, uint8(2), attrs[10][5])
+	assert.Equal(t, uint8(3), attrs[10][6])
+
+	// only the inside of a box: its edge goes along
+	sgbSend(mb, sgbATTRBLK, 1, 0x01, 0x00, 2, 2, 4, 4)
+	assert.Equal(t, uint8(0), attrs[3][3], "inside")
+	assert.Equal(t, uint8(0), attrs[2][4], "edge")
+	assert.Equal(t, uint8(1), attrs[5][4], "outside is untouched")
+
+	// row 17 and column 19
+	sgbSend(mb, sgbATTRLIN, 2, 0x80|2<<5|17, 1<<5|19)
+	assert.Equal(t, uint8(2), attrs[17][0])
+	assert.Equal(t, uint8(1), attrs[0][19])
+	assert.Equal(t, uint8(1), attrs[17][19], "the later line wins")
+
+	// a run of cells down column 0 from row 16, wrapping to column 1; this
+	// takes two packets
+	data := []uint8{0, 16, 4, 0, 1, 0b00_01_10_11}
+	sgbSend(mb, sgbATTRCHR, append(data, make([]uint8, 16)...)...)
+	assert.Equal(t, uint8(0), attrs[16][0])
+	assert.Equal(t, uint8(1), attrs[17][0])
+	assert.Equal(t, uint8(2), attrs[0][1])
+	assert.Equal(t, uint8(3), attrs[1][1])
+}
+
+func TestSGB_Multiplayer(t *testing.T) {
+	mb := newSGBTestMb(t, true)
+	joypadID := func() uint8 {
+		mb.SetItem(0xFF00, 0x30)
+		return mb.GetItem(0xFF00) & 0x0F
+	}
+	assert.Equal(t, uint8(0x0F), joypadID())
+	mb.SetItem(0xFF00, 0x10)
+	assert.Equal(t, uint8(0x0F), joypadID(), "a single joypad reads no ID")
+
+	sgbSend(mb, sgbMLTREQ, 0x01)
+	mb.PlayerButtonEvent(2, APress)
+	for _, want := range []uint8{0x0F, 0x0E, 0x0F} {
+		// reading the buttons and letting P15 go moves on to the next joypad
+		assert.Equal(t, want, joypadID())
+		mb.SetItem(0xFF00, 0x10)
+		pressed := mb.GetItem(0xFF00)&0x01 == 0
+		assert.Equal(t, want == 0x0E, pressed, "A is down on joypad 2 only")
+	}
+}
+
+func TestSGB_Mask(t *testing.T) {
+	mb := newSGBTestMb(t, true)
+	showShades(mb)
+	runFrames(mb, 2)
+	frame := mb.Lcd.PreparedData
+
+	sgbSend(mb, sgbMASKEN, sgbMaskFreeze)
+	mb.SetItem(0xFF47, 0x1B) // inverted
+	runFrames(mb, 2)
+	assert.Equal(t, frame, mb.Lcd.PreparedData, "frozen")
+
+	sgbSend(mb, sgbMASKEN, sgbMaskBlack)
+	runFrames(mb, 1)
+	assert.Equal(t, [3]uint8{}, mb.Lcd.PreparedData[80][72])
+
+	sgbSend(mb, sgbMASKEN, sgbMaskOff)
+	runFrames(mb, 1)
+	assert.NotEqual(t, frame, mb.Lcd.PreparedData)
+	assert.Equal(t, rgb555(sgbGrey[3]), mb.Lcd.PreparedData[0][0], "BGP now maps colour 0 to shade 3")
+}
+
+// borderTiles returns 4KB of border tiles in SNES 4bpp format, from first
+// on: tile 0 is see-through, the rest are wedges and checks in all
+// fifteen other colours.
+func borderTiles(first int) []uint8 {
+	data := make([]uint8, 128*32)
+	for i := range 128 {
+		tile := first + i
+		if tile == 0 {
+			continue
+		}
+		for y := range 8 {
+			for x := range 8 {
+				c := uint8(1 + (x+y+tile)%15)
+				if tile%2 == 0 && (x^y)&1 != 0 || tile%2 == 1 && x < y {
+					c = 0
+				}
+				for plane := range 4 {
+					data[i*32+plane/2*16+y*2+plane%2] |= c >> plane & 1 << (7 - x)
+				}
+			}
+		}
+	}
+	return data
+}
+
+// borderMap returns the PCT_TRN data: a map framing the screen in tiles
+// 1-5, flipped in the lower and right halves, and palettes 4-7 ramping up
+// in red, green, blue and grey.
+func borderMap() []uint8 {
+	data := make([]uint8, 4096)
+	for ty := range 28 {
+		for tx := range 32 {
+			var entry uint16
+			if tx < 6 || tx >= 26 || ty < 5 || ty >= 23 || tx == 6 && ty == 5 {
+				entry = uint16(1+(tx+ty)%5) | uint16(4+tx/8)<<10
+			}
+			if tx >= 16 {
+				entry |= 0x4000
+			}
+			if ty >= 14 {
+				entry |= 0x8000
+			}
+			data[(ty*32+tx)*2], data[(ty*32+tx)*2+1] = uint8(entry), uint8(entry>>8)
+		}
+	}
+	for c := range uint16(16) {
+		l := c * 2
+		copy(data[0x800+c*2:], rgb(l, 0, 0))
+		copy(data[0x820+c*2:], rgb(0, l, 0))
+		copy(data[0x840+c*2:], rgb(0, 0, l))
+		copy(data[0x860+c*2:], rgb(l, l, l))
+	}
+	return data
+}
+
+// systemPalettes returns PAL_TRN data, every palette different.
+func systemPalettes() []uint8 {
+	var data []uint8
+	for i := range uint16(512) {
+		for c := range uint16(4) {
+			data = append(data, rgb((i*7+c*9)%32, (i*3+c*5)%32, (i+c*11)%32)...)
+		}
+	}
+	return data
+}
+
+// attributeFiles returns ATTR_TRN data, every file a different pattern of
+// diagonal bands.
+func attributeFiles() []uint8 {
+	data := make([]uint8, 4096)
+	for n := range sgbATFs {
+		for i := range sgbCellsX * sgbCellsY {
+			pal := uint8(i/sgbCellsX+n+i%sgbCellsX/5) % 4
+			data[n*sgbATFSize+i/4] |= pal << (6 - 2*(i%4))
+		}
+	}
+	return data
+}
+
+var sgbScenes = map[string]func(mb *Motherboard){
+	// colours from PAL01 and PAL23, laid out with each ATTR_ command
+	"attributes": func(mb *Motherboard) {
+		showShades(mb)
+		sgbSend(mb, sgbPAL01, concatBytes(
+			rgb(31, 31, 24),
+			rgb(31, 20, 0), rgb(20, 8, 0), rgb(8, 0, 0),
+			rgb(16, 31, 16), rgb(4, 20, 4), rgb(0, 8, 0),
+		)...)
+		sgbSend(mb, sgbPAL23, concatBytes(
+			rgb(31, 31, 24),
+			rgb(16, 16, 31), rgb(4, 4, 20), rgb(0, 0, 8),
+			rgb(24, 24, 24), rgb(12, 12, 12), rgb(2, 2, 2),
+		)...)
+		sgbSend(mb, sgbATTRDIV, 0x40|0x01<<2|0x02<<4|0x00, 9)
+		sgbSend(mb, sgbATTRBLK, 2,
+			0x03, 0x03|0x01<<2, 3, 2, 8, 6,
+			0x01, 0x00, 12, 11, 17, 15)
+		sgbSend(mb, sgbATTRLIN, 1, 3<<5|18)
+		sgbSend(mb, sgbATTRCHR, 1, 16, 8, 0, 0, 0b00_01_10_11, 0b11_10_01_00)
+		runFrames(mb, 2)
+	},
+	// a border, with palettes and attributes picked from the ones sent
+	// with PAL_TRN and ATTR_TRN
+	"border": func(mb *Motherboard) {
+		sgbSend(mb, sgbMASKEN, sgbMaskFreeze)
+		sgbTransfer(mb, sgbCHRTRN, borderTiles(0), 0)
+		sgbTransfer(mb, sgbCHRTRN, borderTiles(128), 1)
+		sgbTransfer(mb, sgbPCTTRN, borderMap())
+		sgbTransfer(mb, sgbPALTRN, systemPalettes())
+		sgbTransfer(mb, sgbATTRTRN, attributeFiles())
+		showShades(mb)
+		sgbSend(mb, sgbPALSET, 5, 0, 100, 0, 200, 0, 0xFF, 0x01, 0xC0|2)
+		runFrames(mb, 2)
+	},
+}
+
+// TestSGB_BorderByHand checks pixels of the border scene worked out by
+// hand rather than against the golden PNG. PAL_SET picks system palette
+// 5 for palette 0, so SGB colour 0 is $15E3 (R 3, G 15, B 5); ATF 2 puts
+// the screen's top-left cells in palette 2, system palette 200. Border
+// palettes 4-7 hold colour c at level 2c in red, green, blue and grey.
+func TestSGB_BorderByHand(t *testing.T) {
+	mb := newSGBTestMb(t, true)
+	sgbScenes["border"](mb)
+	var frame SGBFrame
+	mb.Super.Compose(&frame)
+
+	snes := func(r, g, b uint16) uint16 { return r | g<<5 | b<<10 }
+	for _, tc := range []struct {
+		name string
+		x, y int
+		want uint16
+	}{
+		// tile 1 at (0, 0), palette 4, not flipped: its pixel (0, 0) is
+		// colour 1+(0+0+1)%15 = 2
+		{"palette 4", 0, 0, snes(4, 0, 0)},
+		// its pixel (0, 1) has x < y, colour 0, and is outside the screen
+		{"see-through outside the screen", 0, 1, snes(3, 15, 5)},
+		// tile 1 at (20, 0), palette 6, flipped across: its pixel (7, 0)
+		// is colour 1+(7+0+1)%15 = 9, where unflipped would give 2
+		{"flipped across", 160, 0, snes(0, 0, 18)},
+		// tile 4 at (31, 27), palette 7, flipped both ways: its pixel
+		// (0, 0) is colour 1+(0+0+4)%15 = 5, where unflipped would give 4
+		{"flipped both ways", 255, 223, snes(10, 10, 10)},
+		// tile 2 at (6, 5), over the screen: its pixel (1, 0) has x^y
+		// odd, colour 0, and shows screen pixel (1, 0), shade 0 in
+		// palette 2, whose colour 0 is SGB colour 0
+		{"see-through over screen colour 0", 49, 40, snes(3, 15, 5)},
+		// tile 0 at (7, 5) shows screen pixel (8, 0): shade 1 in palette
+		// 2, colour 1 of system palette 200, (1400+9, 600+5, 200+11) mod 32
+		{"see-through over screen colour 1", 56, 40, snes(1, 29, 19)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, rgb555(tc.want), frame[tc.x][tc.y])
+		})
+	}
+
+	// the map only uses bank 0; the second CHR_TRN filled tiles 128-255
+	assert.Equal(t, borderTiles(128)[32:64], mb.Super.tiles[129][:], "tile 129")
+}
+
+func sgbImage(mb *Motherboard) *image.RGBA {
+	var frame SGBFrame
+	mb.Super.Compose(&frame)
+	img := image.NewRGBA(image.Rect(0, 0, internal.SGB_SCREEN_WIDTH, internal.SGB_SCREEN_HEIGHT))
+	for x := range frame {
+		for y, px := range frame[x] {
+			img.Set(x, y, color.RGBA{px[0], px[1], px[2], 0xFF})
+		}
+	}
+	return img
+}
+
+func TestSGB_GoldenFrames(t *testing.T) {
+	for name, scene := range sgbScenes {
+		t.Run(name, func(t *testing.T) {
+			mb := newSGBTestMb(t, true)
+			scene(mb)
+			img := sgbImage(mb)
+
+			file := filepath.Join("testdata", "sgb-"+name+".png")
+			if *update {
+				var buf bytes.Buffer
+				require.NoError(t, png.Encode(&buf, img))
+				require.NoError(t, os.MkdirAll("testdata", 0o755))
+				require.NoError(t, os.WriteFile(file, buf.Bytes(), 0o644))
+			}
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			want, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, want.(*image.RGBA).Pix, img.Pix, file)
+		})
+	}
+}
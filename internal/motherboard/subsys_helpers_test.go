@@ -1,9 +1,9 @@
 // Test helpers for the timer, interrupts and memory subsystem tests.
 //
 // init() below performs load-bearing test infrastructure that MUST stay in
-// place: internal.Logger.ExitFunc is set to a no-op so that logger.Fatalf
-// calls inside the cartridge loader and the memory dispatchers do not tear
-// down the test binary. The logger output writer and level are also pinned
+// place: internal.Logger.ExitFunc is set to a no-op so that the remaining
+// logger.Fatal calls (unknown key events, panic-on-stuck) do not tear down
+// the test binary. The logger output writer and level are also pinned
 // so the verbose chatter from NewMotherboard does not crowd test output.
 //
 // stdout redirection is intentionally NOT done globally - that would silence
@@ -51,6 +51,14 @@ func withSilencedStdout(fn func()) {
 func subsysFakeROM(t *testing.T) *pathlib.Path {
 	t.Helper()
 
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "subsys.gb")
+	require.NoError(t, os.WriteFile(fp, subsysFakeROMBytes(), 0o644))
+	return pathlib.NewPath(fp)
+}
+
+// subsysFakeROMBytes returns a minimal valid 32 KiB ROM_ONLY image.
+func subsysFakeROMBytes() []byte {
 	// Game Boy ROM header layout (Pan Docs):
 	//   0x0134..0x0142  title (ASCII)
 	//   0x0143          CGB flag           (0x00 = DMG only)
@@ -99,11 +107,7 @@ func subsysFakeROM(t *testing.T) *pathlib.Path {
 		checksum -= rom[i] + 1
 	}
 	rom[hdrChecksum] = checksum
-
-	dir := t.TempDir()
-	fp := filepath.Join(dir, "subsys.gb")
-	require.NoError(t, os.WriteFile(fp, rom, 0o644))
-	return pathlib.NewPath(fp)
+	return rom
 }
 
 func newMbForSubsysTest(t *testing.T) *Motherboard {
@@ -120,8 +124,9 @@ func newMbForSubsysTestMode(t *testing.T, cgb bool) *Motherboard {
 	t.Helper()
 
 	var mb *Motherboard
+	var err error
 	withSilencedStdout(func() {
-		mb = NewMotherboard(&MotherboardParams{
+		mb, err = NewMotherboard(&MotherboardParams{
 			Filename:     subsysFakeROM(t),
 			Randomize:    false,
 			ForceCgb:     cgb,
@@ -131,11 +136,11 @@ func newMbForSubsysTestMode(t *testing.T, cgb bool) *Motherboard {
 			PanicOnStuck: false,
 		})
 	})
+	require.NoError(t, err)
 
 	// Bring the motherboard to a deterministic post-bootrom state so reads
 	// against 0x0000..0x00FF route to the cartridge rather than the boot ROM.
-	mb.BootRom.Disable()
-	mb.Cpu.Registers.PC = ROM_START_ADDR
+	mb.SkipBootROM()
 	mb.Cpu.Registers.SP = 0xFFFE
 	mb.Cpu.Interrupts.IE = 0
 	mb.Cpu.Interrupts.IF = 0
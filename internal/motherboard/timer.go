@@ -105,7 +105,7 @@ func (t *Timer) updateDividerRegister(cycles OpCycles, doubleSpeedMode bool) {
 
 	t.DivCounter += cycles
 
-	if t.DivCounter >= maxDivCycles {
+	for t.DivCounter >= maxDivCycles {
 		t.DivCounter -= maxDivCycles
 		t.DIV++
 
@@ -135,3 +135,13 @@ func (t *Timer) Tick(cycles OpCycles, c *CPU) {
 	}
 
 }
+
+// nextEvent returns the cycles left until TIMA next overflows and raises
+// the timer interrupt. DIV is never scheduled; it is only observable
+// through reads, which catch the timer up first.
+func (t *Timer) nextEvent() (OpCycles, bool) {
+	if !t.Enabled() {
+		return 0, false
+	}
+	return OpCycles(0x100-(t.TIMA&0xFF))*t.getClockFreqCount() - t.TimaCounter, true
+}
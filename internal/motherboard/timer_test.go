@@ -279,6 +279,39 @@ func TestTimer_TIMAAndTMAWritesPersist(t *testing.T) {
 	assert.Equal(t, uint8(0x88), mb.GetItem(0xFF06))
 }
 
+func TestTimer_NextEventIsOverflow(t *testing.T) {
+	for _, bits := range []uint32{0b00, 0b01, 0b10, 0b11} {
+		timer := NewTimer()
+		timer.Reset()
+		cpu, _ := minTimerCPU(false)
+		timer.TAC = uint32(TAC_ENABLE) | bits
+		timer.TIMA = 0xF0
+		timer.TimaCounter = 3
+
+		cycles, ok := timer.nextEvent()
+		require.True(t, ok)
+
+		timer.Tick(cycles-1, cpu)
+		assert.Zero(t, cpu.Interrupts.IF&(1<<INTR_TIMER), "TAC=%02b: overflow one cycle early", bits)
+		timer.Tick(1, cpu)
+		assert.NotZero(t, cpu.Interrupts.IF&(1<<INTR_TIMER), "TAC=%02b: no overflow at the event", bits)
+	}
+
+	timer := NewTimer()
+	timer.Reset()
+	_, ok := timer.nextEvent()
+	assert.False(t, ok, "a stopped timer has nothing to schedule")
+}
+
+func TestTimer_DivCatchesUpOverLongSpans(t *testing.T) {
+	timer := NewTimer()
+	timer.Reset()
+
+	timer.updateDividerRegister(256*5+10, false)
+	assert.Equal(t, uint32(5), timer.DIV)
+	assert.Equal(t, OpCycles(10), timer.DivCounter)
+}
+
 func TestTimer_TickViaMotherboardSetsTimerInterrupt(t *testing.T) {
 	mb := newMbForSubsysTest(t)
 	mb.Timer.TAC = uint32(TAC_ENABLE)
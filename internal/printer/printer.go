@@ -0,0 +1,331 @@
+// Package printer — printer.go
+//
+// Game Boy Printer emulation. The printer plugs into the serial port and
+// speaks a packet protocol over it; each finished print job is written
+// out as a PNG.
+//
+// Every packet the Game Boy sends looks like
+//
+//	$88 $33  command  compression  length (LE16)  data...  checksum (LE16)  $00 $00
+//
+// where the checksum is the 16-bit sum of everything from the command
+// byte to the end of the data. The printer answers $81 while the first
+// trailing $00 is shifted, to say it is there, and its status byte while
+// the second one is.
+//
+// References:
+//   - Pan Docs, "Game Boy Printer"
+
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/motherboard"
+)
+
+var logger = internal.Logger
+
+// Commands.
+const (
+	cmdInit   uint8 = 0x01 // clear the image buffer
+	cmdPrint  uint8 = 0x02 // print the image buffer
+	cmdData   uint8 = 0x04 // append image data to the buffer
+	cmdBreak  uint8 = 0x08 // abandon the image buffer
+	cmdStatus uint8 = 0x0F // just report status
+)
+
+// Status bits.
+const (
+	statusChecksumError uint8 = 1 << 0
+	statusPrinting      uint8 = 1 << 1
+	statusFull          uint8 = 1 << 2 // the image buffer holds a full screen
+	statusUnprocessed   uint8 = 1 << 3 // the image buffer holds data not yet printed
+	statusPacketError   uint8 = 1 << 4
+)
+
+const (
+	// Width of the paper in pixels. Image data comes as rows of 20 tiles
+	// in the usual 2bpp format.
+	Width          = 160
+	tileRowBytes   = Width / 8 * 16
+	bufferSize     = tileRowBytes * 18 // a full 160x144 screen
+	defaultPalette = 0xE4
+	// The printer reports it is busy in this many status replies after
+	// each PRINT, which is what games wait on before sending more.
+	printBusyReplies = 4
+)
+
+// Paper shades for colours 0-3 once the palette has been applied.
+var shades = [4]uint8{0xFF, 0xAA, 0x55, 0x00}
+
+// Packet receiver states, in the order a packet's bytes arrive.
+const (
+	stateMagic1 = iota
+	stateMagic2
+	stateCommand
+	stateCompression
+	stateLengthLo
+	stateLengthHi
+	stateData
+	stateChecksumLo
+	stateChecksumHi
+	stateAlive  // first trailing $00; the printer answers $81
+	stateStatus // second trailing $00; the printer answers its status
+)
+
+// Printer is a Game Boy Printer. Plug it into a machine's serial port
+// with Serial.Connect.
+type Printer struct {
+	motherboard.SerialDevice
+
+	dir     string // where PNGs are written
+	printed int    // number of the last PNG written
+
+	state      int
+	command    uint8
+	compressed bool
+	length     uint16
+	data       []byte
+	sum        uint16 // checksum of the packet so far
+	checksum   uint16 // checksum the Game Boy sent
+
+	status uint8
+	busy   int     // status replies left that report printing
+	buffer []byte  // image data waiting for PRINT
+	job    []uint8 // shades of the rows printed so far in this job, Width per row
+}
+
+// New returns a printer that saves each print job as a PNG in dir,
+// creating it if needed.
+func New(dir string) *Printer {
+	p := &Printer{dir: dir}
+	p.SerialDevice = motherboard.ByteDevice(p.receive)
+	return p
+}
+
+// Close writes out a print job the game left unfinished, without feeding
+// the paper afterwards.
+func (p *Printer) Close() error {
+	return p.finishJob()
+}
+
+// receive takes the next byte of a packet and returns the byte the
+// printer answers with during the next transfer.
+func (p *Printer) receive(b uint8) uint8 {
+	switch p.state {
+	case stateMagic1:
+		if b == 0x88 {
+			p.state = stateMagic2
+		}
+	case stateMagic2:
+		switch b {
+		case 0x33:
+			p.state = stateCommand
+		case 0x88:
+		default:
+			p.state = stateMagic1
+		}
+	case stateCommand:
+		p.command, p.sum = b, uint16(b)
+		p.state = stateCompression
+	case stateCompression:
+		p.compressed = b&0x01 != 0
+		p.sum += uint16(b)
+		p.state = stateLengthLo
+	case stateLengthLo:
+		p.length = uint16(b)
+		p.sum += uint16(b)
+		p.state = stateLengthHi
+	case stateLengthHi:
+		p.length |= uint16(b) << 8
+		p.sum += uint16(b)
+		p.data = p.data[:0]
+		p.state = stateData
+		if p.length == 0 {
+			p.state = stateChecksumLo
+		}
+	case stateData:
+		p.data = append(p.data, b)
+		p.sum += uint16(b)
+		if len(p.data) == int(p.length) {
+			p.state = stateChecksumLo
+		}
+	case stateChecksumLo:
+		p.checksum = uint16(b)
+		p.state = stateChecksumHi
+	case stateChecksumHi:
+		p.checksum |= uint16(b) << 8
+		p.state = stateAlive
+		return 0x81
+	case stateAlive:
+		p.execute()
+		p.state = stateStatus
+		return p.reportStatus()
+	case stateStatus:
+		p.state = stateMagic1
+	}
+	return 0x00
+}
+
+func (p *Printer) reportStatus() uint8 {
+	status := p.status
+	if p.busy > 0 {
+		p.busy--
+		status |= statusPrinting
+	}
+	return status
+}
+
+// execute carries out the packet just received.
+func (p *Printer) execute() {
+	p.status &^= statusChecksumError | statusPacketError
+	if p.sum != p.checksum {
+		p.status |= statusChecksumError
+		return
+	}
+
+	switch p.command {
+	case cmdInit:
+		p.buffer = p.buffer[:0]
+		p.status = 0
+		p.busy = 0
+
+	case cmdData:
+		// an empty DATA packet just marks the end of the image
+		data := p.data
+		if p.compressed {
+			data = decompress(data)
+		}
+		p.buffer = append(p.buffer, data[:min(len(data), bufferSize-len(p.buffer))]...)
+		if len(p.buffer) > 0 {
+			p.status |= statusUnprocessed
+		}
+		if len(p.buffer) == bufferSize {
+			p.status |= statusFull
+		}
+
+	case cmdPrint:
+		if len(p.data) != 4 {
+			p.status |= statusPacketError
+			return
+		}
+		p.print(p.data[0], p.data[1], p.data[2])
+
+	case cmdBreak:
+		p.buffer = p.buffer[:0]
+		p.status &^= statusUnprocessed | statusFull
+		p.busy = 0
+
+	case cmdStatus:
+
+	default:
+		p.status |= statusPacketError
+	}
+}
+
+// print prints the image buffer sheets times. The high nibble of margins
+// is the paper fed before printing and the low nibble the paper fed
+// after: rows printed with no feed in between end up on one strip of
+// paper, saved as one PNG. The exposure byte only sets how dark the ink
+// burns, so it is ignored.
+func (p *Printer) print(sheets, margins, palette uint8) {
+	if palette == 0 {
+		palette = defaultPalette
+	}
+	if margins>>4 != 0 {
+		p.finishJob()
+	}
+
+	rows := len(p.buffer) / tileRowBytes * 8
+	for range sheets {
+		for y := range rows {
+			for x := range Width {
+				p.job = append(p.job, shades[palette>>(2*pixel(p.buffer, x, y))&0x03])
+			}
+		}
+	}
+	p.buffer = p.buffer[:0]
+	p.status &^= statusUnprocessed | statusFull
+	p.busy = printBusyReplies
+
+	if sheets == 0 || margins&0x0F != 0 {
+		p.finishJob()
+	}
+}
+
+// pixel returns the colour number of pixel (x, y) of 2bpp tile data laid
+// out Width pixels wide.
+func pixel(tiles []byte, x, y int) uint8 {
+	i := y/8*tileRowBytes + x/8*16 + y%8*2
+	bit := 7 - x%8
+	return tiles[i]>>bit&1 | tiles[i+1]>>bit&1<<1
+}
+
+// decompress expands RLE image data. A byte with bit 7 set repeats the
+// next byte (its low 7 bits + 2) times; otherwise it is followed by (its
+// value + 1) literal bytes.
+func decompress(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		c := data[i]
+		i++
+		if c&0x80 != 0 {
+			if i < len(data) {
+				for range int(c&0x7F) + 2 {
+					out = append(out, data[i])
+				}
+				i++
+			}
+			continue
+		}
+		end := min(i+int(c)+1, len(data))
+		out = append(out, data[i:end]...)
+		i = end
+	}
+	return out
+}
+
+// finishJob saves the rows printed so far as the next PNG in the output
+// directory, never overwriting an earlier one.
+func (p *Printer) finishJob() error {
+	if len(p.job) == 0 {
+		return nil
+	}
+	img := &image.Gray{Pix: p.job, Stride: Width, Rect: image.Rect(0, 0, Width, len(p.job)/Width)}
+	p.job = nil
+
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		logger.Warnf("printer: %v", err)
+		return err
+	}
+	for {
+		p.printed++
+		name := filepath.Join(p.dir, fmt.Sprintf("print-%04d.png", p.printed))
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			logger.Warnf("printer: %v", err)
+			return err
+		}
+		err = png.Encode(f, img)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			logger.Warnf("printer: %v", err)
+			return err
+		}
+		logger.Infof("printer: printed %s", name)
+		return nil
+	}
+}
@@ -0,0 +1,395 @@
+package printer
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duysqubix/gobc/internal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "regenerate the packet streams and golden PNGs in testdata")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	internal.Logger.SetOutput(io.Discard)
+	internal.Logger.SetLevel(logrus.PanicLevel)
+	os.Exit(m.Run())
+}
+
+// send shifts b out to p a bit at a time, as the Game Boy's serial port
+// does, and returns the byte shifted back.
+func send(p *Printer, b uint8) uint8 {
+	var in uint8
+	for i := 7; i >= 0; i-- {
+		in <<= 1
+		if p.ExchangeBit(b>>i&1 != 0) {
+			in |= 1
+		}
+	}
+	return in
+}
+
+// packet builds a complete packet, trailing $00s included.
+func packet(command uint8, compressed bool, data []byte) []byte {
+	var compression uint8
+	if compressed {
+		compression = 1
+	}
+	pkt := []byte{0x88, 0x33, command, compression, uint8(len(data)), uint8(len(data) >> 8)}
+	pkt = append(pkt, data...)
+	var sum uint16
+	for _, b := range pkt[2:] {
+		sum += uint16(b)
+	}
+	return append(pkt, uint8(sum), uint8(sum>>8), 0x00, 0x00)
+}
+
+// sendPacket sends pkt and returns the printer's last two answers: $81
+// and its status.
+func sendPacket(p *Printer, pkt []byte) (alive, status uint8) {
+	for _, b := range pkt[:len(pkt)-2] {
+		send(p, b)
+	}
+	return send(p, 0x00), send(p, 0x00)
+}
+
+func TestPrinter_Status(t *testing.T) {
+	p := New(t.TempDir())
+	send(p, 0x00) // past the $FF of a device that has not heard anything yet
+
+	alive, status := sendPacket(p, packet(cmdInit, false, nil))
+	assert.Equal(t, uint8(0x81), alive)
+	assert.Equal(t, uint8(0x00), status)
+
+	_, status = sendPacket(p, packet(cmdData, false, make([]byte, tileRowBytes)))
+	assert.Equal(t, statusUnprocessed, status)
+
+	bad := packet(cmdStatus, false, nil)
+	bad[len(bad)-4]++
+	_, status = sendPacket(p, bad)
+	assert.Equal(t, statusUnprocessed|statusChecksumError, status)
+
+	_, status = sendPacket(p, packet(0x7F, false, nil))
+	assert.Equal(t, statusUnprocessed|statusPacketError, status, "unknown command")
+
+	_, status = sendPacket(p, packet(cmdPrint, false, []byte{1, 0x00, 0xE4}))
+	assert.Equal(t, statusUnprocessed|statusPacketError, status, "short PRINT")
+
+	_, status = sendPacket(p, packet(cmdPrint, false, []byte{1, 0x00, 0xE4, 0x40}))
+	assert.Equal(t, statusPrinting, status)
+	for i := 1; i < printBusyReplies; i++ {
+		_, status = sendPacket(p, packet(cmdStatus, false, nil))
+		assert.Equal(t, statusPrinting, status)
+	}
+	_, status = sendPacket(p, packet(cmdStatus, false, nil))
+	assert.Equal(t, uint8(0x00), status, "done printing")
+
+	for range bufferSize / tileRowBytes {
+		_, status = sendPacket(p, packet(cmdData, false, make([]byte, tileRowBytes)))
+	}
+	assert.Equal(t, statusUnprocessed|statusFull, status)
+
+	_, status = sendPacket(p, packet(cmdBreak, false, nil))
+	assert.Equal(t, uint8(0x00), status)
+	assert.Empty(t, p.buffer)
+}
+
+// TestPrinter_WrittenOutPackets checks packet against packets written out
+// byte by byte from the protocol: $88 $33, command, compression, length
+// and checksum low byte first, and two $00s for the printer's answers.
+func TestPrinter_WrittenOutPackets(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		pkt  []byte
+		want []byte
+	}{
+		{"INIT", packet(cmdInit, false, nil),
+			[]byte{0x88, 0x33, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}},
+		{"STATUS", packet(cmdStatus, false, nil),
+			[]byte{0x88, 0x33, 0x0F, 0x00, 0x00, 0x00, 0x0F, 0x00, 0x00, 0x00}},
+		// one sheet, 1 line before and 3 after, palette $E4, default
+		// exposure; $02+$04+$01+$13+$E4+$40 = $013E
+		{"PRINT", packet(cmdPrint, false, []byte{0x01, 0x13, 0xE4, 0x40}),
+			[]byte{0x88, 0x33, 0x02, 0x00, 0x04, 0x00, 0x01, 0x13, 0xE4, 0x40, 0x3E, 0x01, 0x00, 0x00}},
+		// a run of 3 $FF and 2 literal bytes, compressed;
+		// $04+$01+$05+$81+$FF+$01+$12+$34 = $01D1
+		{"DATA", packet(cmdData, true, []byte{0x81, 0xFF, 0x01, 0x12, 0x34}),
+			[]byte{0x88, 0x33, 0x04, 0x01, 0x05, 0x00, 0x81, 0xFF, 0x01, 0x12, 0x34, 0xD1, 0x01, 0x00, 0x00}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.pkt)
+		})
+	}
+
+	p := New(t.TempDir())
+	send(p, 0x00)
+	alive, status := sendPacket(p, []byte{0x88, 0x33, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00})
+	assert.Equal(t, uint8(0x81), alive)
+	assert.Equal(t, uint8(0x00), status)
+}
+
+func TestPrinter_IgnoresNoiseBetweenPackets(t *testing.T) {
+	p := New(t.TempDir())
+	for _, b := range []byte{0x00, 0x88, 0x88, 0x12, 0x33} {
+		send(p, b)
+	}
+	alive, _ := sendPacket(p, packet(cmdStatus, false, nil))
+	assert.Equal(t, uint8(0x81), alive)
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"literal", []byte{0x02, 1, 2, 3}, []byte{1, 2, 3}},
+		{"run", []byte{0x81, 0xAA}, []byte{0xAA, 0xAA, 0xAA}},
+		{"mixed", []byte{0x00, 7, 0x80, 0xFF, 0x01, 4, 5}, []byte{7, 0xFF, 0xFF, 4, 5}},
+		{"truncated", []byte{0x05, 1, 2}, []byte{1, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, decompress(tc.in))
+		})
+	}
+}
+
+// compress is the inverse of decompress, used to build compressed
+// streams.
+func compress(data []byte) []byte {
+	var out, lit []byte
+	flush := func() {
+		if len(lit) > 0 {
+			out = append(append(out, uint8(len(lit)-1)), lit...)
+			lit = lit[:0]
+		}
+	}
+	for i := 0; i < len(data); {
+		n := 1
+		for i+n < len(data) && data[i+n] == data[i] && n < 0x7F+2 {
+			n++
+		}
+		if n >= 2 {
+			flush()
+			out = append(out, 0x80|uint8(n-2), data[i])
+		} else {
+			if lit = append(lit, data[i]); len(lit) == 0x80 {
+				flush()
+			}
+		}
+		i += n
+	}
+	flush()
+	return out
+}
+
+// testImage returns tileRows rows of 2bpp tiles: diagonal bands of all
+// four colours with a black line across them.
+func testImage(tileRows int) []byte {
+	tiles := make([]byte, tileRows*tileRowBytes)
+	for y := range tileRows * 8 {
+		for x := range Width {
+			c := uint8(x/16+y/16) % 4
+			if x == y {
+				c = 3
+			}
+			i := y/8*tileRowBytes + x/8*16 + y%8*2
+			bit := uint8(7 - x%8)
+			tiles[i] |= c & 1 << bit
+			tiles[i+1] |= c >> 1 & 1 << bit
+		}
+	}
+	return tiles
+}
+
+// sendImage appends the DATA packets for tiles, two tile rows each as
+// games send them, and the empty DATA that ends the image.
+func sendImage(stream []byte, tiles []byte, compressed bool) []byte {
+	for len(tiles) > 0 {
+		chunk := tiles[:min(len(tiles), 2*tileRowBytes)]
+		tiles = tiles[len(chunk):]
+		if compressed {
+			chunk = compress(chunk)
+		}
+		stream = append(stream, packet(cmdData, compressed, chunk)...)
+	}
+	return append(stream, packet(cmdData, false, nil)...)
+}
+
+func sendPrint(stream []byte, sheets, margins, palette uint8) []byte {
+	stream = append(stream, packet(cmdPrint, false, []byte{sheets, margins, palette, 0x40})...)
+	for range printBusyReplies {
+		stream = append(stream, packet(cmdStatus, false, nil)...)
+	}
+	return stream
+}
+
+// The packet streams in testdata are synthesized, not captured from a
+// game: these build them from the same helpers the tests use, in the
+// order games send packets. TestPrinter_WrittenOutPackets checks those
+// helpers against packets written out by hand, and
+// TestPrinter_HandWorkedPixels the PNGs against pixels worked out by hand.
+var goldenStreams = map[string]func() []byte{
+	// A whole screen, sent uncompressed.
+	"screen": func() []byte {
+		s := packet(cmdInit, false, nil)
+		s = sendImage(s, testImage(18), false)
+		return sendPrint(s, 1, 0x13, 0xE4)
+	},
+	// The same screen compressed, printed with an inverted palette.
+	"compressed": func() []byte {
+		s := packet(cmdInit, false, nil)
+		s = sendImage(s, testImage(18), true)
+		return sendPrint(s, 1, 0x13, 0x1B)
+	},
+	// Two bands printed with no feed in between land on one strip;
+	// another band printed twice after a feed is a second print.
+	"strip": func() []byte {
+		s := packet(cmdInit, false, nil)
+		s = sendImage(s, testImage(2), false)
+		s = sendPrint(s, 1, 0x10, 0xE4)
+		s = sendImage(s, testImage(4)[2*tileRowBytes:], true)
+		s = sendPrint(s, 1, 0x03, 0xE4)
+		s = sendImage(s, testImage(1), false)
+		return sendPrint(s, 2, 0x11, 0x00)
+	},
+}
+
+func TestPrinter_GoldenPrints(t *testing.T) {
+	for name, build := range goldenStreams {
+		t.Run(name, func(t *testing.T) {
+			streamFile := filepath.Join("testdata", name+".bin")
+			if *update {
+				require.NoError(t, os.WriteFile(streamFile, build(), 0o644))
+			}
+			stream, err := os.ReadFile(streamFile)
+			require.NoError(t, err)
+
+			dir := t.TempDir()
+			p := New(dir)
+			for _, b := range stream {
+				send(p, b)
+			}
+			require.NoError(t, p.Close())
+
+			prints, err := filepath.Glob(filepath.Join(dir, "print-*.png"))
+			require.NoError(t, err)
+			if *update {
+				old, _ := filepath.Glob(filepath.Join("testdata", name+"-*.png"))
+				for _, f := range old {
+					os.Remove(f)
+				}
+			}
+			golden, err := filepath.Glob(filepath.Join("testdata", name+"-*.png"))
+			require.NoError(t, err)
+			if !*update {
+				require.Len(t, prints, len(golden))
+			}
+
+			for i, f := range prints {
+				data, err := os.ReadFile(f)
+				require.NoError(t, err)
+				goldenFile := filepath.Join("testdata", name+"-"+filepath.Base(f)[len("print-"):])
+				if *update {
+					require.NoError(t, os.WriteFile(goldenFile, data, 0o644))
+					continue
+				}
+				assert.Equal(t, golden[i], goldenFile)
+				want, err := os.ReadFile(goldenFile)
+				require.NoError(t, err)
+				assert.Equal(t, decodePNG(t, want), decodePNG(t, data), goldenFile)
+			}
+		})
+	}
+}
+
+// TestPrinter_HandWorkedPixels checks a print against pixels worked out
+// by hand rather than a golden PNG. Tile 0 of a single tile row has every
+// line $F0 $CC, colours 3 3 1 1 2 2 0 0 from the left, and the rest of
+// the row is colour 0. Palette $E4 maps colour n to shade n, paper
+// $FF $AA $55 $00, and $1B inverts it. The row is sent RLE-compressed:
+// 16 literal bytes, then runs of 129, 129 and 46 $00s.
+func TestPrinter_HandWorkedPixels(t *testing.T) {
+	row := []byte{0x0F}
+	for range 8 {
+		row = append(row, 0xF0, 0xCC)
+	}
+	row = append(row, 0xFF, 0x00, 0xFF, 0x00, 0xAC, 0x00)
+	require.Len(t, decompress(row), tileRowBytes)
+
+	dir := t.TempDir()
+	p := New(dir)
+	stream := packet(cmdInit, false, nil)
+	// a feed before the first band, none between the two: one strip
+	for _, args := range [][]byte{{1, 0x10, 0xE4, 0x40}, {1, 0x03, 0x1B, 0x40}} {
+		stream = append(stream, packet(cmdData, true, row)...)
+		stream = append(stream, packet(cmdData, false, nil)...)
+		stream = append(stream, packet(cmdPrint, false, args)...)
+	}
+	for _, b := range stream {
+		send(p, b)
+	}
+	require.NoError(t, p.Close())
+
+	prints, err := filepath.Glob(filepath.Join(dir, "print-*.png"))
+	require.NoError(t, err)
+	require.Len(t, prints, 1)
+	data, err := os.ReadFile(prints[0])
+	require.NoError(t, err)
+	img := decodePNG(t, data).(*image.Gray)
+	require.Equal(t, image.Rect(0, 0, Width, 16), img.Bounds(), "two bands of 8 rows; margins add no rows")
+
+	for _, tc := range []struct {
+		name  string
+		y0    int
+		tile  [8]uint8
+		paper uint8 // colour 0, right of tile 0
+	}{
+		{"palette $E4", 0, [8]uint8{0x00, 0x00, 0xAA, 0xAA, 0x55, 0x55, 0xFF, 0xFF}, 0xFF},
+		{"palette $1B", 8, [8]uint8{0xFF, 0xFF, 0x55, 0x55, 0xAA, 0xAA, 0x00, 0x00}, 0x00},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for y := tc.y0; y < tc.y0+8; y++ {
+				for x := range 8 {
+					assert.Equal(t, tc.tile[x], img.GrayAt(x, y).Y, "(%d, %d)", x, y)
+				}
+				for x := 8; x < Width; x++ {
+					require.Equal(t, tc.paper, img.GrayAt(x, y).Y, "(%d, %d)", x, y)
+				}
+			}
+		})
+	}
+}
+
+func decodePNG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func TestPrinter_NeverOverwritesPrints(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "print-0001.png"), nil, 0o644))
+
+	p := New(dir)
+	for _, b := range goldenStreams["screen"]() {
+		send(p, b)
+	}
+
+	_, err := os.Stat(filepath.Join(dir, "print-0002.png"))
+	assert.NoError(t, err)
+	old, err := os.ReadFile(filepath.Join(dir, "print-0001.png"))
+	require.NoError(t, err)
+	assert.Empty(t, old)
+}
@@ -2,7 +2,10 @@ package internal
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -18,6 +21,8 @@ const CGB_CLOCK_SPEED = 4194304 // up to 8.388608 MHz or 8,388,608 cycles per se
 const GB_TIMER_FREQ = 16384     // 16,384 Hz or 16.384 kHz
 const GB_SCREEN_WIDTH = 160
 const GB_SCREEN_HEIGHT = 144
+const SGB_SCREEN_WIDTH = 256 // Super Game Boy picture, border included
+const SGB_SCREEN_HEIGHT = 224
 const DEFAULT_LOG_LEVEL = log.ErrorLevel
 
 var Logger = log.New()
@@ -220,42 +225,89 @@ type EntityState interface {
 	Deserialize(*bytes.Buffer) error
 }
 
-func StateToFile(romName string, state EntityState) {
-	statename := romName + ".state"
+// Save states start with STATE_MAGIC followed by STATE_VERSION so blobs
+// from another program or an incompatible build are rejected up front.
+// Bump STATE_VERSION whenever a Serialize layout changes.
+const STATE_MAGIC = "GOBC"
+const STATE_VERSION uint16 = 9
 
-	// create a file for writing
-	file, err := os.Create(statename)
-	if err != nil {
-		Logger.Panicf("Failed to create file: %s", err)
+var (
+	ErrStateVersion   = errors.New("state: not a save state for this version of gobc")
+	ErrTruncatedState = errors.New("state: save state is truncated")
+)
+
+// WriteStateHeader writes the magic and version that ReadStateHeader
+// expects at the start of a save state.
+func WriteStateHeader(buf *bytes.Buffer) {
+	buf.WriteString(STATE_MAGIC)
+	binary.Write(buf, binary.LittleEndian, STATE_VERSION)
+}
+
+// ReadStateHeader consumes and checks the header written by
+// WriteStateHeader.
+func ReadStateHeader(buf *bytes.Buffer) error {
+	magic := buf.Next(len(STATE_MAGIC))
+	if string(magic) != STATE_MAGIC {
+		return fmt.Errorf("%w: bad magic %q", ErrStateVersion, magic)
+	}
+	var version uint16
+	if err := binary.Read(buf, binary.LittleEndian, &version); err != nil {
+		return fmt.Errorf("%w: missing version", ErrTruncatedState)
+	}
+	if version != STATE_VERSION {
+		return fmt.Errorf("%w: got version %d, want %d", ErrStateVersion, version, STATE_VERSION)
+	}
+	return nil
+}
+
+// RestoreState deserializes data into state. If data is rejected part
+// way through, state is rolled back to what it was before the call so a
+// bad file never leaves the machine half-loaded. Short reads are
+// reported as ErrTruncatedState.
+func RestoreState(state EntityState, data []byte) error {
+	backup := state.Serialize()
+
+	err := state.Deserialize(bytes.NewBuffer(data))
+	if err == nil {
+		return nil
+	}
+	if rbErr := state.Deserialize(backup); rbErr != nil {
+		Logger.Errorf("Failed to roll back state: %v", rbErr)
 	}
-	defer file.Close()
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %v", ErrTruncatedState, err)
+	}
+	return err
+}
+
+func StateToFile(romName string, state EntityState) error {
+	statename := romName + ".state"
 
 	buf := state.Serialize().Bytes()
-	// Save to File
-	if _, err := file.Write(buf); err != nil {
-		Logger.Errorf("Failed to write to file: %s", err)
+	if err := os.WriteFile(statename, buf, 0o644); err != nil {
+		return fmt.Errorf("state: writing %s: %w", statename, err)
 	}
 
-	absPath, err := filepath.Abs(file.Name())
+	absPath, err := filepath.Abs(statename)
 	if err != nil {
 		Logger.Errorf("Error getting absolute path: %v", err)
 	}
 
 	Logger.Infof("Saved state (%.02f KB) to %s", float64(len(buf))/1024.0, absPath)
+	return nil
 }
 
-func LoadState(romName string, state EntityState) {
+func LoadState(romName string, state EntityState) error {
 	statename := romName + ".state"
 
-	// create a file for writing
 	data, err := os.ReadFile(statename)
 	if err != nil {
-		Logger.Panicf("Failed to read file: %s", err)
+		return fmt.Errorf("state: reading %s: %w", statename, err)
 	}
 
-	buf := bytes.NewBuffer(data)
-
-	state.Deserialize(buf)
+	if err := RestoreState(state, data); err != nil {
+		return err
+	}
 	Logger.Debugf("Loaded state (%.02f KB) from %s", float64(len(data))/1024.0, statename)
-
+	return nil
 }
@@ -55,6 +55,7 @@ func NewCpuViewWindow(gobc *GoBoyColor) *CpuViewWindow {
 	if err != nil {
 		logger.Panicf("Failed to create window: %s", err)
 	}
+	gobc.Mb.Cpu.RecordHist = true // the PC history pane reads it
 	return &CpuViewWindow{
 		Window:  memWin,
 		YOffset: 0,
@@ -155,14 +156,14 @@ func (mw *CpuViewWindow) Draw() {
 			opCodeName = internal.OPCODE_NAMES[tup.OpCode]
 		}
 
-		fmt.Fprintf(cpuConsoleTxt, "PC-%d: %04x (%02x) [%s]\n", cntr+1, tup.Addr, mw.hw.Mb.GetItem(tup.Addr), opCodeName)
+		fmt.Fprintf(cpuConsoleTxt, "PC-%d: %04x (%02x) [%s]\n", cntr+1, tup.Addr, mw.hw.Mb.Peek(tup.Addr), opCodeName)
 		cntr--
 	}
 
 	// look into the future by 5 steps
 	for i := uint16(1); i < 3; i++ {
 		fpc := mw.hw.Mb.Cpu.Registers.PC + i
-		fmt.Fprintf(cpuConsoleTxt, "PC+%d: %04x (%02x)\n", i, fpc, mw.hw.Mb.GetItem(fpc))
+		fmt.Fprintf(cpuConsoleTxt, "PC+%d: %04x (%02x)\n", i, fpc, mw.hw.Mb.Peek(fpc))
 	}
 
 	cpuConsoleTxt.Draw(mw.Window, pixel.IM.Scaled(cpuConsoleTxt.Orig, 1.5))
@@ -147,12 +147,12 @@ func (mw *IoViewWindow) Draw() {
 			{"$FF4F", "VBK", fmt.Sprintf("$%02x", mw.hw.Mb.Memory.IO[0x4F]), formatBitValue(mw.hw.Mb.Memory.IO[0x4F]), "Input:"},
 			{"", "", "", "", "$FF00", "JOYP", fmt.Sprintf("$%02x", mw.hw.Mb.Memory.IO[0x00]), formatBitValue(mw.hw.Mb.Memory.IO[0x00])},
 			{"GBC HDMA:", "", "", "", "SERIAL:"},
-			{"$FF51:$FF52", "Source", fmt.Sprintf("$%02x%02x", mw.hw.Mb.Memory.IO[0x51], mw.hw.Mb.Memory.IO[0x52]), "", "$FF01", "SB", fmt.Sprintf("$%02x", mw.hw.Mb.Memory.IO[0x01]), formatBitValue(mw.hw.Mb.Memory.IO[0x01])},
-			{"$FF53:$FF54", "Dest", fmt.Sprintf("$%02x%02x", mw.hw.Mb.Memory.IO[0x53], mw.hw.Mb.Memory.IO[0x54]), "", "$FF02", "SC", fmt.Sprintf("$%02x", mw.hw.Mb.Memory.IO[0x02]), formatBitValue(mw.hw.Mb.Memory.IO[0x02])},
+			{"$FF51:$FF52", "Source", fmt.Sprintf("$%02x%02x", mw.hw.Mb.Memory.IO[0x51], mw.hw.Mb.Memory.IO[0x52]), "", "$FF01", "SB", fmt.Sprintf("$%02x", mw.hw.Mb.Serial.SB), formatBitValue(mw.hw.Mb.Serial.SB)},
+			{"$FF53:$FF54", "Dest", fmt.Sprintf("$%02x%02x", mw.hw.Mb.Memory.IO[0x53], mw.hw.Mb.Memory.IO[0x54]), "", "$FF02", "SC", fmt.Sprintf("$%02x", mw.hw.Mb.Serial.ReadSC()), formatBitValue(mw.hw.Mb.Serial.ReadSC())},
 			{"$FF55", "LEN", fmt.Sprintf("$%02x", mw.hw.Mb.Memory.IO[0x55]), formatBitValue(mw.hw.Mb.Memory.IO[0x55]), ""},
 			{"", "", "", "", "LCDC Flags:"},
 			append(append([]string{"GBC INFRARED", "", "", ""}, mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_ENABLE, "ON", "OFF")...), mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_WINEN, "ON", "OFF")...),
-			append(append([]string{"$FF56", "RP", fmt.Sprintf("$%02x", mw.hw.Mb.Memory.IO[0x56]), formatBitValue(mw.hw.Mb.Memory.IO[0x56])}, mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_BGMAP, "$8000", "$8800")...), mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_WINMAP, "$9C00", "$9800")...),
+			append(append([]string{"$FF56", "RP", fmt.Sprintf("$%02x", mw.hw.Mb.Infrared.RP), formatBitValue(mw.hw.Mb.Infrared.RP)}, mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_BGMAP, "$8000", "$8800")...), mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_WINMAP, "$9C00", "$9800")...),
 			append(append([]string{"", "", "", ""}, mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_OBJEN, "ON", "OFF")...), mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_BGEN, "ON", "OFF")...),
 			append(append([]string{"STAT Flags:", "", "", ""}, mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_OBJSZ, "8x8", "8x16")...), mw.hw.Mb.Lcd.ReportOnLCDC(motherboard.LCDC_BGWIN, "$9C00", "$9800")...),
 			append(mw.hw.Mb.Lcd.ReportOnSTAT(motherboard.STAT_LYCINT), mw.hw.Mb.Lcd.ReportOnSTAT(motherboard.STAT_OAMINT)...),
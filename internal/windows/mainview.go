@@ -3,9 +3,12 @@ package windows
 import (
 	"fmt"
 	"image/color"
+	"time"
 
 	"github.com/chigopher/pathlib"
 	"github.com/duysqubix/gobc/internal"
+	"github.com/duysqubix/gobc/internal/audio"
+	"github.com/duysqubix/gobc/internal/cartridge"
 	"github.com/duysqubix/gobc/internal/motherboard"
 	pixel "github.com/gopxl/pixel/v2"
 	pixelgl "github.com/gopxl/pixel/v2/backends/opengl"
@@ -22,12 +25,6 @@ const (
 )
 
 var (
-	internalCycleCounter       int
-	globalCycles               int
-	globalFrames               int
-	internalCycleReturn        motherboard.OpCycles
-	internalStatus             bool
-	internalGamePaused         bool = false
 	internalConsoleTxt         *text.Text
 	internalShowGrid           bool = false
 	internalDebugCyclePerFrame int  = 1
@@ -45,7 +42,10 @@ type MainGameWindow struct {
 	gameTrueWidth  float64
 	gameTrueHeight float64
 	gameMapCanvas  *pixel.PictureData
+	sgbFrame       *motherboard.SGBFrame // composed picture on a Super Game Boy
 	cyclesFrame    int
+	gamePaused     bool // emulation paused from the debug keys
+	frames         int  // frames presented while unpaused
 }
 
 func (mw *MainGameWindow) Hw() *GoBoyColor {
@@ -69,22 +69,33 @@ func (mw *MainGameWindow) handleInput() {
 }
 
 func (mw *MainGameWindow) Update() error {
-	if !internalGamePaused {
-		globalFrames++
+	if !mw.gamePaused {
+		mw.frames++
 	}
 
 	mw.handleInput()
 
 	if mw.hw.Mb.GuiPause {
-		internalGamePaused = true
+		mw.gamePaused = true
 	}
 
-	if !internalGamePaused {
+	if !mw.gamePaused {
 		if !mw.hw.UpdateInternalGameState(mw.cyclesFrame) {
 			return nil
 		}
 	}
 
+	if mw.hw.Mb.Sgb {
+		mw.hw.Mb.Super.Compose(mw.sgbFrame)
+		for y := 0; y < internal.SGB_SCREEN_HEIGHT; y++ {
+			for x := 0; x < internal.SGB_SCREEN_WIDTH; x++ {
+				col := mw.sgbFrame[x][y]
+				mw.gameMapCanvas.Pix[((internal.SGB_SCREEN_HEIGHT-1-y)*internal.SGB_SCREEN_WIDTH)+x] = color.RGBA{R: col[0], G: col[1], B: col[2], A: 0xFF}
+			}
+		}
+		return nil
+	}
+
 	for y := 0; y < internal.GB_SCREEN_HEIGHT; y++ {
 		for x := 0; x < internal.GB_SCREEN_WIDTH; x++ {
 			col := mw.hw.Mb.Lcd.PreparedData[x][y]
@@ -115,11 +126,11 @@ func (mw *MainGameWindow) Draw() {
 	internalConsoleTxt.Clear()
 
 	// drawSprite(mw.Window, mw.gameMapCanvas, 1.5, 0, 0)
-	r, g, b := motherboard.GetPaletteColour(3)
+	r, g, b := mw.hw.Mb.GetPaletteColour(3)
 	bg := color.RGBA{R: r, G: g, B: b, A: 0xFF}
 	mw.Window.Clear(bg)
 
-	spr := pixel.NewSprite(mw.gameMapCanvas, pixel.R(0, 0, internal.GB_SCREEN_WIDTH, internal.GB_SCREEN_HEIGHT))
+	spr := pixel.NewSprite(mw.gameMapCanvas, mw.gameMapCanvas.Bounds())
 	spr.Draw(mw.Window, pixel.IM.Moved(mw.Window.Bounds().Center()).Scaled(mw.Window.Bounds().Center(), float64(mw.gameScale)))
 
 	if internalShowGrid {
@@ -147,12 +158,12 @@ func (mw *MainGameWindow) Draw() {
 		imd.Draw(mw.Window)
 	}
 
-	if internalGamePaused {
+	if mw.gamePaused {
 		fmt.Fprintf(internalConsoleTxt, "Game Paused\nN=%d\nF=%d\n", internalDebugCyclePerFrame, internalDebugCycleScaler)
 	}
 
 	if internalShowDebugInfo {
-		fmt.Fprintf(internalConsoleTxt, "\nCycles: %d\nTotal Frames: %d\nLY: %d", globalCycles, globalFrames, mw.hw.Mb.Lcd.CurrentScanline)
+		fmt.Fprintf(internalConsoleTxt, "\nCycles: %d\nTotal Frames: %d\nLY: %d", mw.hw.Cycles, mw.frames, mw.hw.Mb.Lcd.CurrentScanline)
 	}
 	internalConsoleTxt.Draw(mw.Window, pixel.IM.Scaled(internalConsoleTxt.Orig, 2))
 
@@ -174,26 +185,43 @@ type GoBoyColor struct {
 	DebugMode   bool
 	Breakpoints [2]uint16 // holds start and end address of breakpoint
 	ForceCgb    bool
+	Cycles      int // total cycles emulated since start-up
 }
 
-func NewGoBoyColor(romfile string, breakpoints []uint16, forceCgb bool, forceDmg bool, panicOnStuck bool, randomize bool, audioEnabled bool, audioSmooth bool) *GoBoyColor {
+func NewGoBoyColor(romfile string, breakpoints []uint16, forceCgb bool, forceDmg bool, sgb bool, panicOnStuck bool, randomize bool, audioEnabled bool, audioRate int, audioSmooth bool, fastCPU bool, mapper string, clock cartridge.ClockMode, clockStart time.Time) (*GoBoyColor, error) {
 	// read cartridge first
 
+	var audioOutput motherboard.AudioOutput
+	if audioEnabled {
+		audioOutput = audio.NewSpeaker()
+	}
+
+	mb, err := motherboard.NewMotherboard(&motherboard.MotherboardParams{
+		Filename:        pathlib.NewPath(romfile, pathlib.PathWithAfero(afero.NewOsFs())),
+		Mapper:          mapper,
+		Clock:           clock,
+		ClockStart:      clockStart,
+		Randomize:       randomize,
+		Breakpoints:     breakpoints,
+		ForceCgb:        forceCgb,
+		ForceDmg:        forceDmg,
+		Sgb:             sgb,
+		PanicOnStuck:    panicOnStuck,
+		AudioOutput:     audioOutput,
+		AudioSampleRate: audioRate,
+		AudioSmooth:     audioSmooth,
+		FastCPU:         fastCPU,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	gobc := &GoBoyColor{
-		Mb: motherboard.NewMotherboard(&motherboard.MotherboardParams{
-			Filename:     pathlib.NewPath(romfile, pathlib.PathWithAfero(afero.NewOsFs())),
-			Randomize:    randomize,
-			Breakpoints:  breakpoints,
-			ForceCgb:     forceCgb,
-			ForceDmg:     forceDmg,
-			PanicOnStuck: panicOnStuck,
-			AudioEnabled: audioEnabled,
-			AudioSmooth:  audioSmooth,
-		}),
+		Mb:      mb,
 		Stopped: false,
 		Paused:  false,
 	}
-	return gobc
+	return gobc, nil
 }
 
 func (g *GoBoyColor) Reset() {
@@ -206,6 +234,9 @@ func NewMainGameWindow(gobc *GoBoyColor) *MainGameWindow {
 	gameScale := 3
 	gameScreenWidth := internal.GB_SCREEN_WIDTH
 	gameScreenHeight := internal.GB_SCREEN_HEIGHT
+	if gobc.Mb.Sgb {
+		gameScreenWidth, gameScreenHeight = internal.SGB_SCREEN_WIDTH, internal.SGB_SCREEN_HEIGHT
+	}
 	cyclesFrame := CyclesFrameDMG
 	cyclesFrame = 70224
 
@@ -223,6 +254,9 @@ func NewMainGameWindow(gobc *GoBoyColor) *MainGameWindow {
 		gameMapCanvas:  pixel.MakePictureData(pixel.R(0, 0, float64(gameScreenWidth), float64(gameScreenHeight))),
 		cyclesFrame:    cyclesFrame,
 	}
+	if gobc.Mb.Sgb {
+		mgw.sgbFrame = new(motherboard.SGBFrame)
+	}
 
 	win, err := pixelgl.NewWindow(pixelgl.WindowConfig{
 		Title:       "gobc v0.1 | Main Game Window",
@@ -245,39 +279,16 @@ func (g *GoBoyColor) UpdateInternalGameState(every int) bool {
 	if g.Stopped {
 		return false
 	}
-
-	var still_good bool = true
-
-	internalCycleCounter = 0
-	for internalCycleCounter < every {
-		// logger.Debug("----------------Tick-----------------")
-		// if !g.Mb.BootRomEnabled() {
-		// 	internalGamePaused = true
-		// }
-		if g.Stopped {
-			still_good = false
-			break
-		}
-
-		if !g.Paused {
-			internalStatus, internalCycleReturn = g.Mb.Tick()
-			internalCycleCounter += int(internalCycleReturn)
-			globalCycles += int(internalCycleReturn)
-			if !internalStatus {
-				if g.Mb.GuiPause {
-					break
-				}
-				g.Stopped = true
-				break
-			}
-		}
+	if g.Paused {
+		return true
 	}
-	// totalProcessedCycles += int64(internalCycleCounter)
-	// fmt.Println("Total Cycles Processed: ", totalProcessedCycles)
-	if !still_good {
-		g.Stop()
+
+	cycles, ok := g.Mb.RunCycles(motherboard.OpCycles(every))
+	g.Cycles += int(cycles)
+	if !ok && !g.Mb.GuiPause {
+		g.Stopped = true
 	}
-	return still_good
+	return true
 }
 
 func (g *GoBoyColor) Stop() {
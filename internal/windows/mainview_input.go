@@ -4,7 +4,6 @@ import (
 	"math"
 
 	"github.com/duysqubix/gobc/internal"
-	"github.com/duysqubix/gobc/internal/cartridge"
 	"github.com/duysqubix/gobc/internal/motherboard"
 	pixelgl "github.com/gopxl/pixel/v2"
 )
@@ -12,22 +11,22 @@ import (
 func (mw *MainGameWindow) _handleDebugInput() {
 	if internalShowDebugInfo {
 		if mw.Window.JustPressed(pixelgl.KeySpace) || mw.Window.Repeated(pixelgl.KeySpace) {
-			internalGamePaused = !internalGamePaused
-			if !internalGamePaused {
+			mw.gamePaused = !mw.gamePaused
+			if !mw.gamePaused {
 				mw.hw.Mb.GuiPause = false
 			}
 		}
 
-		if (mw.Window.JustPressed(pixelgl.KeyN) || mw.Window.Repeated(pixelgl.KeyN)) && internalGamePaused {
+		if (mw.Window.JustPressed(pixelgl.KeyN) || mw.Window.Repeated(pixelgl.KeyN)) && mw.gamePaused {
 			mw.hw.UpdateInternalGameState(internalDebugCyclePerFrame) // update every tick
 		}
 
-		if (mw.Window.JustPressed(pixelgl.KeyM) || mw.Window.Repeated(pixelgl.KeyM)) && internalGamePaused {
+		if (mw.Window.JustPressed(pixelgl.KeyM) || mw.Window.Repeated(pixelgl.KeyM)) && mw.gamePaused {
 			internalDebugCycleScaler++
 			internalDebugCyclePerFrame = int(math.Pow10(internalDebugCycleScaler))
 		}
 
-		if (mw.Window.JustPressed(pixelgl.KeyB) || mw.Window.Repeated(pixelgl.KeyB)) && internalGamePaused {
+		if (mw.Window.JustPressed(pixelgl.KeyB) || mw.Window.Repeated(pixelgl.KeyB)) && mw.gamePaused {
 			internalDebugCycleScaler--
 			if internalDebugCycleScaler < 0 {
 				internalDebugCycleScaler = 0
@@ -35,9 +34,9 @@ func (mw *MainGameWindow) _handleDebugInput() {
 			internalDebugCyclePerFrame = int(math.Pow10(internalDebugCycleScaler))
 		}
 
-		if (mw.Window.JustPressed(pixelgl.KeyF) || mw.Window.Repeated(pixelgl.KeyF)) && internalGamePaused {
+		if (mw.Window.JustPressed(pixelgl.KeyF) || mw.Window.Repeated(pixelgl.KeyF)) && mw.gamePaused {
 			mw.hw.UpdateInternalGameState(mw.cyclesFrame) // update every tick
-			globalFrames++
+			mw.frames++
 		}
 	}
 
@@ -50,23 +49,32 @@ func (mw *MainGameWindow) _handleDebugInput() {
 	}
 
 	if (mw.Window.JustPressed(pixelgl.KeyF3) || mw.Window.Repeated(pixelgl.KeyF3)) && !mw.hw.Mb.Cgb {
-		motherboard.ChangePallete()
+		mw.hw.Mb.ChangePalette()
 	}
 
 	if mw.Window.JustPressed(pixelgl.KeyF4) || mw.Window.Repeated(pixelgl.KeyF4) {
-		cartridge.SaveSRAM(mw.hw.Mb.Cartridge.GetFilename(), &mw.hw.Mb.Cartridge.RamBanks, mw.hw.Mb.Cartridge.RamBankCount)
+		if err := mw.hw.Mb.Cartridge.Save(); err != nil {
+			logger.Errorf("Failed to save SRAM: %v", err)
+		}
 	}
 
 	if mw.Window.JustPressed(pixelgl.KeyF5) || mw.Window.Repeated(pixelgl.KeyF5) {
-		internal.StateToFile(mw.hw.Mb.Cartridge.GetFilename(), mw.hw.Mb)
+		if err := internal.StateToFile(mw.hw.Mb.Cartridge.GetFilename(), mw.hw.Mb); err != nil {
+			logger.Errorf("Failed to save state: %v", err)
+		}
 	}
 
 	if mw.Window.JustPressed(pixelgl.KeyF6) || mw.Window.Repeated(pixelgl.KeyF6) {
-		internal.LoadState(mw.hw.Mb.Cartridge.GetFilename(), mw.hw.Mb)
+		if err := internal.LoadState(mw.hw.Mb.Cartridge.GetFilename(), mw.hw.Mb); err != nil {
+			logger.Errorf("Failed to load state: %v", err)
+		}
 	}
 
 }
 
+// keyTilt is how far, in g, a tilt key tilts the cartridge.
+const keyTilt = 0.5
+
 func (mw *MainGameWindow) _handleJoyPadInput() {
 	/*
 		KeyA = Button B
@@ -144,4 +152,19 @@ func (mw *MainGameWindow) _handleJoyPadInput() {
 		mw.hw.Mb.ButtonEvent(motherboard.ARelease)
 	}
 
+	// I / J / K / L tilt the cartridge, for MBC7 games
+	var tiltX, tiltY float64
+	if mw.Window.Pressed(pixelgl.KeyJ) {
+		tiltX -= keyTilt
+	}
+	if mw.Window.Pressed(pixelgl.KeyL) {
+		tiltX += keyTilt
+	}
+	if mw.Window.Pressed(pixelgl.KeyI) {
+		tiltY -= keyTilt
+	}
+	if mw.Window.Pressed(pixelgl.KeyK) {
+		tiltY += keyTilt
+	}
+	mw.hw.Mb.TiltEvent(tiltX, tiltY)
 }
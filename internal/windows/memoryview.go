@@ -174,7 +174,7 @@ func (mw *MemoryViewWindow) Draw() {
 
 		for j := 0; j < 16; j++ {
 			addr := uint16(j + row_addr_start)
-			row_str += fmt.Sprintf("%02x ", mw.hw.Mb.GetItem(addr))
+			row_str += fmt.Sprintf("%02x ", mw.hw.Mb.Peek(addr))
 			if j == 7 {
 				row_str += "| "
 			}